@@ -2,18 +2,24 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"pulse/backend"
 )
 
 type App struct {
-	ctx         context.Context
-	dataDir     string
-	grpcManager *backend.GrpcStreamManager
-	wsManager   *backend.WebSocketManager
-	sseManager  *backend.SSEManager
-	httpHandler *backend.HTTPHandler
+	ctx          context.Context
+	dataDir      string
+	grpcManager  *backend.GrpcStreamManager
+	wsManager    *backend.WebSocketManager
+	gqlWSManager *backend.GraphQLWSManager
+	sseManager   *backend.SSEManager
+	mqttManager  *backend.MQTTManager
+	sockManager  *backend.SocketManager
+	redisManager *backend.RedisManager
+	httpHandler  *backend.HTTPHandler
+	gqlHandler   *backend.GraphQLHandler
 }
 
 func NewApp() *App {
@@ -25,8 +31,13 @@ func NewApp() *App {
 	app.dataDir = dataDir
 	app.grpcManager = backend.NewGrpcStreamManager(app)
 	app.wsManager = backend.NewWebSocketManager(app)
+	app.gqlWSManager = backend.NewGraphQLWSManager(app)
 	app.sseManager = backend.NewSSEManager(app)
+	app.mqttManager = backend.NewMQTTManager(app)
+	app.sockManager = backend.NewSocketManager(app)
+	app.redisManager = backend.NewRedisManager(app)
 	app.httpHandler = backend.NewHTTPHandler(app, dataDir)
+	app.gqlHandler = backend.NewGraphQLHandler(app.httpHandler)
 
 	return app
 }
@@ -34,6 +45,10 @@ func NewApp() *App {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	a.ensureDataDirectories()
+
+	if err := a.grpcManager.LoadSavedProtoSets(); err != nil {
+		fmt.Printf("[gRPC] Failed to load proto sets: %v\n", err)
+	}
 }
 
 // SSE handler functions
@@ -60,14 +75,158 @@ func (a *App) WebSocketDisconnect(connectionID string) error {
 	return a.wsManager.Disconnect(connectionID)
 }
 
+// GraphQL subscription functions
+
+func (a *App) GraphQLWSConnect(req backend.GraphQLWSConnectRequest) (string, error) {
+	return a.gqlWSManager.Connect(req)
+}
+
+func (a *App) GraphQLWSSubscribe(req backend.GraphQLSubscribeRequest) (string, error) {
+	return a.gqlWSManager.Subscribe(req)
+}
+
+func (a *App) GraphQLWSUnsubscribe(connectionID string, subscriptionID string) error {
+	return a.gqlWSManager.Unsubscribe(connectionID, subscriptionID)
+}
+
+func (a *App) GraphQLWSDisconnect(connectionID string) error {
+	return a.gqlWSManager.Disconnect(connectionID)
+}
+
+// MQTT handler functions
+
+func (a *App) MQTTConnect(req backend.MQTTConnectRequest) (string, error) {
+	return a.mqttManager.Connect(req)
+}
+
+func (a *App) MQTTSubscribe(req backend.MQTTSubscribeRequest) (byte, error) {
+	return a.mqttManager.Subscribe(req)
+}
+
+func (a *App) MQTTUnsubscribe(connectionID string, topic string) error {
+	return a.mqttManager.Unsubscribe(connectionID, topic)
+}
+
+func (a *App) MQTTPublish(req backend.MQTTPublishRequest) error {
+	return a.mqttManager.Publish(req)
+}
+
+func (a *App) MQTTDisconnect(connectionID string) error {
+	return a.mqttManager.Disconnect(connectionID)
+}
+
+// Socket handler functions
+
+func (a *App) SocketConnect(req backend.SocketConnectRequest) (string, error) {
+	return a.sockManager.Connect(req)
+}
+
+func (a *App) SocketListen(req backend.SocketListenRequest) (*backend.SocketListenResult, error) {
+	return a.sockManager.Listen(req)
+}
+
+func (a *App) SocketSend(req backend.SocketSendRequest) error {
+	return a.sockManager.Send(req)
+}
+
+func (a *App) SocketClose(connectionID string) error {
+	return a.sockManager.Close(connectionID)
+}
+
+func (a *App) SocketStopListening(listenerID string) error {
+	return a.sockManager.StopListening(listenerID)
+}
+
+// Redis handler functions
+
+func (a *App) RedisConnect(req backend.RedisConnectRequest) (string, error) {
+	return a.redisManager.Connect(req)
+}
+
+func (a *App) RedisDisconnect(connectionID string) error {
+	return a.redisManager.Disconnect(connectionID)
+}
+
+func (a *App) RedisXAdd(req backend.RedisXAddRequest) (string, error) {
+	return a.redisManager.XAdd(req)
+}
+
+func (a *App) RedisXRange(req backend.RedisRangeRequest) (*backend.RedisRangeResult, error) {
+	return a.redisManager.XRange(req)
+}
+
+func (a *App) RedisStartReader(req backend.RedisReadRequest) (string, error) {
+	return a.redisManager.StartReader(req)
+}
+
+func (a *App) RedisStopReader(connectionID string, readerID string) error {
+	return a.redisManager.StopReader(connectionID, readerID)
+}
+
+func (a *App) RedisAck(req backend.RedisAckRequest) (int64, error) {
+	return a.redisManager.Ack(req)
+}
+
+func (a *App) RedisClaim(req backend.RedisClaimRequest) (*backend.RedisClaimResult, error) {
+	return a.redisManager.Claim(req)
+}
+
+func (a *App) RedisPending(req backend.RedisPendingRequest) (*backend.RedisPendingResult, error) {
+	return a.redisManager.Pending(req)
+}
+
+func (a *App) RedisListGroups(connectionID string, stream string) ([]backend.RedisGroupInfo, error) {
+	return a.redisManager.ListGroups(connectionID, stream)
+}
+
+func (a *App) RedisCreateGroup(req backend.RedisGroupRequest) error {
+	return a.redisManager.CreateGroup(req)
+}
+
+func (a *App) RedisPublish(req backend.RedisPublishRequest) (int64, error) {
+	return a.redisManager.Publish(req)
+}
+
+func (a *App) RedisSubscribe(req backend.RedisSubscribeRequest) error {
+	return a.redisManager.Subscribe(req)
+}
+
+func (a *App) RedisUnsubscribe(req backend.RedisSubscribeRequest) error {
+	return a.redisManager.Unsubscribe(req)
+}
+
 // gRPC handler functions
 
 func (a *App) GrpcParseProtoFiles(req backend.ProtoFileUploadRequest) (*backend.ParsedProtoResponse, error) {
 	return a.grpcManager.ParseProtoFiles(req)
 }
 
-func (a *App) GrpcUseReflection(serverURL string, useTLS bool) (*backend.ParsedProtoResponse, error) {
-	return a.grpcManager.UseReflection(serverURL, useTLS)
+func (a *App) GrpcUseReflection(req backend.GrpcReflectionRequest) (*backend.ParsedProtoResponse, error) {
+	return a.grpcManager.UseReflection(req)
+}
+
+func (a *App) GrpcSaveProtoSet(req backend.SaveProtoSetRequest) (*backend.ProtoSet, error) {
+	return a.grpcManager.SaveProtoSet(req)
+}
+
+func (a *App) GrpcListProtoSets(workspaceID string) []backend.ProtoSet {
+	return a.grpcManager.ListProtoSets(workspaceID)
+}
+
+func (a *App) GrpcLoadProtoSet(id string) (*backend.ParsedProtoResponse, error) {
+	return a.grpcManager.LoadProtoSet(id)
+}
+
+func (a *App) GrpcDeleteProtoSet(id string) error {
+	return a.grpcManager.DeleteProtoSet(id)
+}
+
+func (a *App) GrpcDescribeMessage(messageType string) (*backend.MessageSchema, error) {
+	return a.grpcManager.DescribeMessage(messageType)
+}
+
+func (a *App) GrpcValidateMessage(req backend.GrpcValidateMessageRequest) (*backend.MessageValidation, error) {
+	return a.grpcManager.ValidateMessage(req)
 }
 
 func (a *App) GrpcConnect(req backend.GrpcConnectRequest) (string, error) {
@@ -78,16 +237,50 @@ func (a *App) GrpcSendMessage(req backend.GrpcSendMessageRequest) error {
 	return a.grpcManager.SendMessage(req)
 }
 
+func (a *App) GrpcCloseSend(connectionID string) error {
+	return a.grpcManager.CloseSend(connectionID)
+}
+
+func (a *App) GrpcStreamStatus(connectionID string) (*backend.GrpcStreamStatus, error) {
+	return a.grpcManager.StreamStatus(connectionID)
+}
+
 func (a *App) GrpcDisconnect(connectionID string) error {
 	return a.grpcManager.Disconnect(connectionID)
 }
 
+// GraphQL handler functions
+
+func (a *App) GraphQLSend(req backend.GraphQLRequest) (*backend.GraphQLResponse, error) {
+	return a.gqlHandler.Send(req)
+}
+
+func (a *App) GraphQLIntrospect(req backend.GraphQLSchemaRequest) (*backend.GraphQLSchemaInfo, error) {
+	return a.gqlHandler.IntrospectSchema(req)
+}
+
 // HTTP handler functions
 
 func (a *App) SendRequest(req backend.RequestData) (*backend.ResponseData, error) {
 	return a.httpHandler.SendRequest(req)
 }
 
+func (a *App) SendRequestWithVariables(req backend.RequestData, scopes backend.VariableScopes) (*backend.ResponseData, error) {
+	return a.httpHandler.SendRequestWithVariables(req, scopes)
+}
+
+func (a *App) ResolveRequest(req backend.RequestData, scopes backend.VariableScopes) *backend.ResolvedRequest {
+	return a.httpHandler.ResolveRequest(req, scopes)
+}
+
+func (a *App) LoadVariableScopes(workspaceID, collectionID, environmentID string) (backend.VariableScopes, error) {
+	return a.httpHandler.LoadVariableScopes(workspaceID, collectionID, environmentID)
+}
+
+func (a *App) ListDynamicVariables() []backend.DynamicVariableInfo {
+	return backend.ListDynamicVariables()
+}
+
 func (a *App) SaveWorkspaces(workspaces []backend.Workspace) error {
 	return a.httpHandler.SaveWorkspaces(workspaces)
 }
@@ -128,6 +321,14 @@ func (a *App) LoadSettings() (*backend.Settings, error) {
 	return a.httpHandler.LoadSettings()
 }
 
+func (a *App) SaveTLSProfiles(profiles []backend.TLSProfile) error {
+	return a.httpHandler.SaveTLSProfiles(profiles)
+}
+
+func (a *App) LoadTLSProfiles() ([]backend.TLSProfile, error) {
+	return a.httpHandler.LoadTLSProfiles()
+}
+
 func (a *App) KafkaConnect(config backend.KafkaConfig) (string, error) {
 	return backend.KafkaConnect(a, config)
 }
@@ -148,10 +349,78 @@ func (a *App) KafkaStopConsumer(connectionID string, consumerID string) error {
 	return backend.KafkaStopConsumer(a, connectionID, consumerID)
 }
 
-func (a *App) KafkaProduceMessage(config backend.ProducerConfig) error {
+func (a *App) KafkaCommitOffsets(req backend.CommitOffsetsRequest) ([]backend.PartitionOffset, error) {
+	return backend.KafkaCommitOffsets(a, req)
+}
+
+func (a *App) KafkaProduceMessage(config backend.ProducerConfig) (*backend.ProduceResult, error) {
 	return backend.KafkaProduceMessage(a, config)
 }
 
+func (a *App) KafkaDescribeTopic(connectionID string, topic string) (*backend.TopicDetails, error) {
+	return backend.KafkaDescribeTopic(a, connectionID, topic)
+}
+
+func (a *App) KafkaCreateTopic(req backend.CreateTopicRequest) error {
+	return backend.KafkaCreateTopic(a, req)
+}
+
+func (a *App) KafkaDeleteTopic(connectionID string, topic string) error {
+	return backend.KafkaDeleteTopic(a, connectionID, topic)
+}
+
+func (a *App) KafkaListConsumerGroups(connectionID string) ([]backend.ConsumerGroupSummary, error) {
+	return backend.KafkaListConsumerGroups(a, connectionID)
+}
+
+func (a *App) KafkaDescribeConsumerGroup(connectionID string, groupID string) (*backend.ConsumerGroupDetails, error) {
+	return backend.KafkaDescribeConsumerGroup(a, connectionID, groupID)
+}
+
+func (a *App) KafkaResetConsumerGroupOffsets(req backend.ResetOffsetsRequest) ([]backend.PartitionOffset, error) {
+	return backend.KafkaResetConsumerGroupOffsets(a, req)
+}
+
+func (a *App) KafkaListSchemaSubjects(connectionID string) ([]string, error) {
+	return backend.KafkaListSchemaSubjects(a, connectionID)
+}
+
+func (a *App) KafkaListSchemaVersions(connectionID string, subject string) ([]int, error) {
+	return backend.KafkaListSchemaVersions(a, connectionID, subject)
+}
+
+func (a *App) KafkaStartSearch(req backend.KafkaSearchRequest) (string, error) {
+	return backend.KafkaStartSearch(a, req)
+}
+
+func (a *App) KafkaCancelSearch(connectionID string, searchID string) error {
+	return backend.KafkaCancelSearch(a, connectionID, searchID)
+}
+
+func (a *App) KafkaStartBatchProduce(req backend.BatchProduceRequest) (string, error) {
+	return backend.KafkaStartBatchProduce(a, req)
+}
+
+func (a *App) KafkaStopBatchProduce(connectionID string, batchID string) error {
+	return backend.KafkaStopBatchProduce(a, connectionID, batchID)
+}
+
+func (a *App) KafkaStartReplay(req backend.KafkaReplayRequest) (string, error) {
+	return backend.KafkaStartReplay(a, req)
+}
+
+func (a *App) KafkaCancelReplay(connectionID string, replayID string) error {
+	return backend.KafkaCancelReplay(a, connectionID, replayID)
+}
+
+func (a *App) KafkaTestOAuthToken(config backend.KafkaConfig) (*backend.OAuthTokenInfo, error) {
+	return backend.KafkaTestOAuthToken(a, config)
+}
+
+func (a *App) KafkaClusterOverview(connectionID string) (*backend.ClusterOverview, error) {
+	return backend.KafkaClusterOverview(a, connectionID)
+}
+
 func (a *App) EmitStreamMessage(connectionID, direction, protocol, payload string) {
 	backend.EmitStreamMessage(a, connectionID, direction, protocol, payload)
 }
@@ -170,10 +439,6 @@ func (a *App) ensureDataDirectories() {
 	os.MkdirAll(filepath.Join(a.dataDir, "environments"), 0755)
 	os.MkdirAll(filepath.Join(a.dataDir, "history"), 0755)
 	os.MkdirAll(filepath.Join(a.dataDir, "settings"), 0755)
+	os.MkdirAll(filepath.Join(a.dataDir, "tls-profiles"), 0755)
+	os.MkdirAll(filepath.Join(a.dataDir, "protos"), 0755)
 }
-
-
-
-
-
-
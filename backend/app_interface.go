@@ -2,12 +2,16 @@ package backend
 
 import (
 	"context"
+	"fmt"
 	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // AppInterface describes what the main App struct needs to do
 type AppInterface interface {
 	GetCtx() context.Context
+	GetDataDirectory() string
 
 	// Kafka methods we need to implement
 	KafkaConnect(KafkaConfig) (string, error)
@@ -15,13 +19,45 @@ type AppInterface interface {
 	KafkaListTopics(string) ([]TopicInfo, error)
 	KafkaStartConsumer(ConsumerConfig) (string, error)
 	KafkaStopConsumer(string, string) error
-	KafkaProduceMessage(ProducerConfig) error
+	KafkaProduceMessage(ProducerConfig) (*ProduceResult, error)
+	KafkaCommitOffsets(CommitOffsetsRequest) ([]PartitionOffset, error)
+	KafkaDescribeTopic(string, string) (*TopicDetails, error)
+	KafkaCreateTopic(CreateTopicRequest) error
+	KafkaDeleteTopic(string, string) error
+	KafkaListConsumerGroups(string) ([]ConsumerGroupSummary, error)
+	KafkaDescribeConsumerGroup(string, string) (*ConsumerGroupDetails, error)
+	KafkaResetConsumerGroupOffsets(ResetOffsetsRequest) ([]PartitionOffset, error)
+	KafkaListSchemaSubjects(string) ([]string, error)
+	KafkaListSchemaVersions(string, string) ([]int, error)
+	KafkaStartSearch(KafkaSearchRequest) (string, error)
+	KafkaCancelSearch(string, string) error
+	KafkaStartBatchProduce(BatchProduceRequest) (string, error)
+	KafkaStopBatchProduce(string, string) error
+	KafkaStartReplay(KafkaReplayRequest) (string, error)
+	KafkaCancelReplay(string, string) error
+	KafkaTestOAuthToken(KafkaConfig) (*OAuthTokenInfo, error)
+	KafkaClusterOverview(string) (*ClusterOverview, error)
 	EmitStreamMessage(string, string, string, string)
 }
 
-// EmitStreamMessage is a wrapper for the EmitStreamMessage method
+// EmitStreamMessage sends a message to the frontend's stream viewer
 func EmitStreamMessage(app AppInterface, connectionID, direction, protocol, payload string) {
-	app.EmitStreamMessage(connectionID, direction, protocol, payload)
+	emitStreamEvent(app.GetCtx(), StreamMessage{
+		ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
+		Direction: direction,
+		Protocol:  protocol,
+		Payload:   payload,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"connectionId": connectionID,
+		},
+	})
+}
+
+// emitStreamEvent delivers a message to the frontend's stream viewer. Tests
+// replace it to see what a manager emits.
+var emitStreamEvent = func(ctx context.Context, msg StreamMessage) {
+	runtime.EventsEmit(ctx, "stream-message", msg)
 }
 
 // StreamMessage holds a message in the stream
@@ -32,4 +68,4 @@ type StreamMessage struct {
 	Payload   string                 `json:"payload"`
 	Timestamp time.Time              `json:"timestamp"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
-}
\ No newline at end of file
+}
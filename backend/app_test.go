@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testApp is the part of the app the stream managers use. Events emitted
+// with its context are sent to its channel.
+type testApp struct {
+	AppInterface
+	ctx context.Context
+	dir string
+}
+
+type streamEventsKey struct{}
+
+var installTestEmitter sync.Once
+
+// newTestApp returns an app whose events go to the returned channel. An
+// emit blocks until the test reads it, as the frontend would hold a
+// manager that emits faster than it renders, so no test passes because an
+// event was dropped; the test's context ending releases it.
+func newTestApp(t *testing.T) (*testApp, <-chan StreamMessage) {
+	t.Helper()
+
+	installTestEmitter.Do(func() {
+		emitStreamEvent = func(ctx context.Context, msg StreamMessage) {
+			events, ok := ctx.Value(streamEventsKey{}).(chan StreamMessage)
+			if !ok {
+				return
+			}
+			select {
+			case events <- msg:
+			case <-ctx.Done():
+			}
+		}
+	})
+
+	events := make(chan StreamMessage, 1024)
+	app := &testApp{
+		ctx: context.WithValue(t.Context(), streamEventsKey{}, events),
+		dir: t.TempDir(),
+	}
+	return app, events
+}
+
+func (a *testApp) GetCtx() context.Context  { return a.ctx }
+func (a *testApp) GetDataDirectory() string { return a.dir }
+
+func (a *testApp) EmitStreamMessage(connectionID, direction, protocol, payload string) {
+	EmitStreamMessage(a, connectionID, direction, protocol, payload)
+}
+
+// waitForMessage returns the first event the match accepts
+func waitForMessage(t *testing.T, events <-chan StreamMessage, match func(StreamMessage) bool) StreamMessage {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-events:
+			if match(msg) {
+				return msg
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for a stream message")
+			return StreamMessage{}
+		}
+	}
+}
+
+func inboundOn(topic string) func(StreamMessage) bool {
+	return func(msg StreamMessage) bool {
+		return msg.Direction == "inbound" && msg.Metadata["topic"] == topic
+	}
+}
+
+func systemMessage(text string) func(StreamMessage) bool {
+	return func(msg StreamMessage) bool {
+		return (msg.Direction == "system" || msg.Direction == "error") && strings.Contains(msg.Payload, text)
+	}
+}
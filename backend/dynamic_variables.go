@@ -0,0 +1,261 @@
+package backend
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DynamicVariableFunc produces a fresh value every time a {{$name args...}}
+// placeholder is substituted. lookup resolves ordinary variables so that
+// helpers like $base64 can encode them.
+type DynamicVariableFunc func(args []string, lookup func(string) (string, bool)) (string, error)
+
+// DynamicVariableInfo describes a dynamic variable for the editor's autocomplete
+type DynamicVariableInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Example     string `json:"example"`
+}
+
+type dynamicVariable struct {
+	info DynamicVariableInfo
+	fn   DynamicVariableFunc
+}
+
+var (
+	dynamicVariables   = make(map[string]dynamicVariable)
+	dynamicVariablesMu sync.RWMutex
+)
+
+const randomStringAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// maxRandomStringLength keeps a typo like {{$randomString 2000000000}} from
+// allocating gigabytes
+const maxRandomStringLength = 4096
+
+// errUnknownVariable is returned by helpers such as $base64 when the variable
+// they encode is not defined
+var errUnknownVariable = errors.New("unknown variable")
+
+func init() {
+	RegisterDynamicVariable(DynamicVariableInfo{
+		Name:        "uuid",
+		Description: "Random version 4 UUID",
+		Example:     "{{$uuid}}",
+	}, func(args []string, _ func(string) (string, bool)) (string, error) {
+		return uuid.New().String(), nil
+	})
+
+	RegisterDynamicVariable(DynamicVariableInfo{
+		Name:        "timestamp",
+		Description: "Unix time in seconds, optionally shifted by an offset such as -1h or +7d",
+		Example:     "{{$timestamp -15m}}",
+	}, func(args []string, _ func(string) (string, bool)) (string, error) {
+		t, err := offsetNow(args)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(t.Unix(), 10), nil
+	})
+
+	RegisterDynamicVariable(DynamicVariableInfo{
+		Name:        "timestampMs",
+		Description: "Unix time in milliseconds, optionally shifted by an offset",
+		Example:     "{{$timestampMs +30s}}",
+	}, func(args []string, _ func(string) (string, bool)) (string, error) {
+		t, err := offsetNow(args)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	})
+
+	RegisterDynamicVariable(DynamicVariableInfo{
+		Name:        "isoDate",
+		Description: "RFC 3339 UTC timestamp, optionally shifted by an offset",
+		Example:     "{{$isoDate -1d}}",
+	}, func(args []string, _ func(string) (string, bool)) (string, error) {
+		t, err := offsetNow(args)
+		if err != nil {
+			return "", err
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	})
+
+	RegisterDynamicVariable(DynamicVariableInfo{
+		Name:        "randomInt",
+		Description: "Random integer in [min, max], defaulting to [0, 1000]",
+		Example:     "{{$randomInt 1 100}}",
+	}, func(args []string, _ func(string) (string, bool)) (string, error) {
+		min, max := int64(0), int64(1000)
+		if len(args) > 0 {
+			if len(args) != 2 {
+				return "", fmt.Errorf("expected min and max")
+			}
+			var err error
+			if min, err = strconv.ParseInt(args[0], 10, 64); err != nil {
+				return "", fmt.Errorf("invalid min: %s", args[0])
+			}
+			if max, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+				return "", fmt.Errorf("invalid max: %s", args[1])
+			}
+			if max < min {
+				return "", fmt.Errorf("max is less than min")
+			}
+		}
+
+		// The span can exceed int64 for wide ranges, so count in big.Int
+		lo := big.NewInt(min)
+		span := new(big.Int).Sub(big.NewInt(max), lo)
+		span.Add(span, big.NewInt(1))
+
+		n, err := rand.Int(rand.Reader, span)
+		if err != nil {
+			return "", err
+		}
+		return n.Add(n, lo).String(), nil
+	})
+
+	RegisterDynamicVariable(DynamicVariableInfo{
+		Name:        "randomString",
+		Description: "Random alphanumeric string, 16 characters unless a length of up to 4096 is given",
+		Example:     "{{$randomString 8}}",
+	}, func(args []string, _ func(string) (string, bool)) (string, error) {
+		length := 16
+		if len(args) > 0 {
+			var err error
+			if length, err = strconv.Atoi(args[0]); err != nil || length <= 0 {
+				return "", fmt.Errorf("invalid length: %s", args[0])
+			}
+			if length > maxRandomStringLength {
+				return "", fmt.Errorf("length %d is over the limit of %d", length, maxRandomStringLength)
+			}
+		}
+
+		out := make([]byte, length)
+		limit := big.NewInt(int64(len(randomStringAlphabet)))
+		for i := range out {
+			n, err := rand.Int(rand.Reader, limit)
+			if err != nil {
+				return "", err
+			}
+			out[i] = randomStringAlphabet[n.Int64()]
+		}
+		return string(out), nil
+	})
+
+	RegisterDynamicVariable(DynamicVariableInfo{
+		Name:        "base64",
+		Description: "Standard base64 encoding of another variable",
+		Example:     "{{$base64 apiKey}}",
+	}, func(args []string, lookup func(string) (string, bool)) (string, error) {
+		value, err := lookupArg(args, lookup)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	})
+
+	RegisterDynamicVariable(DynamicVariableInfo{
+		Name:        "hex",
+		Description: "Lowercase hex encoding of another variable",
+		Example:     "{{$hex deviceId}}",
+	}, func(args []string, lookup func(string) (string, bool)) (string, error) {
+		value, err := lookupArg(args, lookup)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString([]byte(value)), nil
+	})
+}
+
+// RegisterDynamicVariable adds or replaces a dynamic variable. The name is
+// given without the leading $.
+func RegisterDynamicVariable(info DynamicVariableInfo, fn DynamicVariableFunc) {
+	dynamicVariablesMu.Lock()
+	defer dynamicVariablesMu.Unlock()
+	dynamicVariables[info.Name] = dynamicVariable{info: info, fn: fn}
+}
+
+// ListDynamicVariables returns every registered dynamic variable sorted by name
+func ListDynamicVariables() []DynamicVariableInfo {
+	dynamicVariablesMu.RLock()
+	defer dynamicVariablesMu.RUnlock()
+
+	out := make([]DynamicVariableInfo, 0, len(dynamicVariables))
+	for _, v := range dynamicVariables {
+		out = append(out, v.info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// evaluateDynamicVariable runs the expression after the $, e.g. "randomInt 1 6"
+func evaluateDynamicVariable(expr string, lookup func(string) (string, bool)) (string, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty dynamic variable")
+	}
+
+	dynamicVariablesMu.RLock()
+	v, ok := dynamicVariables[fields[0]]
+	dynamicVariablesMu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("unknown dynamic variable: $%s", fields[0])
+	}
+	return v.fn(fields[1:], lookup)
+}
+
+func offsetNow(args []string) (time.Time, error) {
+	now := time.Now()
+	if len(args) == 0 {
+		return now, nil
+	}
+
+	offset, err := parseOffset(args[0])
+	if err != nil {
+		return now, err
+	}
+	return now.Add(offset), nil
+}
+
+// parseOffset accepts Go durations plus a "d" suffix for whole days
+func parseOffset(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSuffix(s, "d"), "+"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid offset: %s", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(strings.TrimPrefix(s, "+"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset: %s", s)
+	}
+	return d, nil
+}
+
+func lookupArg(args []string, lookup func(string) (string, bool)) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected a variable name")
+	}
+
+	value, ok := lookup(args[0])
+	if !ok {
+		return "", fmt.Errorf("%w: %s", errUnknownVariable, args[0])
+	}
+	return value, nil
+}
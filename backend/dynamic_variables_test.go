@@ -0,0 +1,139 @@
+package backend
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDynamicVariables(t *testing.T) {
+	vars := map[string]string{"apiKey": "user:pass", "deviceId": "ab"}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		expr    string
+		want    string         // exact value, if set
+		pattern *regexp.Regexp // otherwise the value must match this
+		wantErr string
+	}{
+		{expr: "uuid", pattern: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+		{expr: "timestamp", pattern: regexp.MustCompile(`^\d{10}$`)},
+		{expr: "timestampMs", pattern: regexp.MustCompile(`^\d{13}$`)},
+		{expr: "isoDate", pattern: regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ$`)},
+		{expr: "timestamp soon", wantErr: "invalid offset: soon"},
+		{expr: "randomInt 7 7", want: "7"},
+		{expr: "randomInt -3 -3", want: "-3"},
+		{expr: "randomInt -9223372036854775808 9223372036854775807", pattern: regexp.MustCompile(`^-?\d+$`)},
+		{expr: "randomInt 1", wantErr: "expected min and max"},
+		{expr: "randomInt a 2", wantErr: "invalid min: a"},
+		{expr: "randomInt 1 b", wantErr: "invalid max: b"},
+		{expr: "randomInt 2 1", wantErr: "max is less than min"},
+		{expr: "randomString", pattern: regexp.MustCompile(`^[a-zA-Z0-9]{16}$`)},
+		{expr: "randomString 3", pattern: regexp.MustCompile(`^[a-zA-Z0-9]{3}$`)},
+		{expr: "randomString 4097", wantErr: "over the limit"},
+		{expr: "randomString 2000000000", wantErr: "over the limit"},
+		{expr: "randomString 0", wantErr: "invalid length: 0"},
+		{expr: "base64 apiKey", want: "dXNlcjpwYXNz"},
+		{expr: "hex deviceId", want: "6162"},
+		{expr: "hex", wantErr: "expected a variable name"},
+		{expr: "base64 missing", wantErr: "unknown variable: missing"},
+		{expr: "nope", wantErr: "unknown dynamic variable: $nope"},
+		{expr: "  ", wantErr: "empty dynamic variable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := evaluateDynamicVariable(tt.expr, lookup)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %q, %v; want error %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.pattern != nil && !tt.pattern.MatchString(got) || tt.pattern == nil && got != tt.want {
+				t.Errorf("got %q", got)
+			}
+		})
+	}
+}
+
+func TestRandomIntRange(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		got, err := evaluateDynamicVariable("randomInt -2 2", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := strconv.Atoi(got); err != nil || n < -2 || n > 2 {
+			t.Fatalf("got %q, want a value in [-2, 2]", got)
+		}
+	}
+}
+
+func TestRandomStringLimit(t *testing.T) {
+	got, err := evaluateDynamicVariable("randomString 4096", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != maxRandomStringLength || strings.Trim(got, randomStringAlphabet) != "" {
+		t.Errorf("got %d characters, want %d from the alphabet", len(got), maxRandomStringLength)
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "-15m", want: -15 * time.Minute},
+		{in: "+30s", want: 30 * time.Second},
+		{in: "1h30m", want: 90 * time.Minute},
+		{in: "+7d", want: 7 * 24 * time.Hour},
+		{in: "-1d", want: -24 * time.Hour},
+		{in: "1.5d", wantErr: true},
+		{in: "d", wantErr: true},
+		{in: "tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseOffset(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestDynamicVariablesAreFresh(t *testing.T) {
+	got, err := resolverFor(nil).ResolveString("{{$uuid}} {{$uuid}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Fields(got)
+	if len(parts) != 2 || parts[0] == parts[1] {
+		t.Errorf("got %q, want two different UUIDs", got)
+	}
+}
+
+func TestListDynamicVariables(t *testing.T) {
+	var names []string
+	for _, v := range ListDynamicVariables() {
+		names = append(names, v.Name)
+	}
+	want := "base64 hex isoDate randomInt randomString timestamp timestampMs uuid"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,450 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// GraphQLHandler sends GraphQL operations over HTTP. Requests go out
+// through HTTPHandler.SendRequest, so TLS profiles, auth and timing work as
+// they do for plain HTTP. Introspected schemas are cached per endpoint URL
+// and used to validate operations before they are sent.
+type GraphQLHandler struct {
+	http    *HTTPHandler
+	mu      sync.RWMutex
+	schemas map[string]*graphQLSchema // by endpoint URL
+}
+
+type GraphQLRequest struct {
+	URL           string       `json:"url"`
+	Headers       []KeyValue   `json:"headers"`
+	Auth          *RequestAuth `json:"auth"`
+	TLSProfile    string       `json:"tlsProfile,omitempty"`
+	Query         string       `json:"query"` // may hold several operations
+	OperationName string       `json:"operationName,omitempty"`
+	Variables     string       `json:"variables,omitempty"` // JSON object
+	// PersistedQuery sends only the query's SHA-256 hash first (Apollo
+	// automatic persisted queries) and the full query when the server
+	// doesn't know it yet
+	PersistedQuery bool            `json:"persistedQuery"`
+	Uploads        []GraphQLUpload `json:"uploads,omitempty"`
+	SkipValidation bool            `json:"skipValidation"` // send without introspecting
+	Scopes         *VariableScopes `json:"scopes,omitempty"`
+}
+
+// GraphQLOptions is the GraphQL part of a saved request, next to the query
+// in its body
+type GraphQLOptions struct {
+	OperationName  string          `json:"operationName,omitempty"`
+	Variables      string          `json:"variables,omitempty"`
+	PersistedQuery bool            `json:"persistedQuery,omitempty"`
+	SkipValidation bool            `json:"skipValidation,omitempty"`
+	Uploads        []GraphQLUpload `json:"uploads,omitempty"`
+}
+
+// GraphQLUpload attaches a file to a variable using the GraphQL multipart
+// request spec
+type GraphQLUpload struct {
+	Variable string `json:"variable"` // path inside variables, e.g. "file" or "input.attachments.0"
+	FilePath string `json:"filePath"`
+}
+
+type GraphQLResponse struct {
+	Response         *ResponseData          `json:"response,omitempty"` // nil when validation stopped the request
+	Data             interface{}            `json:"data,omitempty"`
+	Errors           []GraphQLError         `json:"errors,omitempty"`
+	Extensions       map[string]interface{} `json:"extensions,omitempty"`
+	ValidationErrors []GraphQLError         `json:"validationErrors,omitempty"`
+	OperationName    string                 `json:"operationName,omitempty"`
+	OperationType    string                 `json:"operationType,omitempty"`  // "query", "mutation" or "subscription"
+	PersistedQuery   string                 `json:"persistedQuery,omitempty"` // "hit", "registered" or "unsupported"
+}
+
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func NewGraphQLHandler(http *HTTPHandler) *GraphQLHandler {
+	return &GraphQLHandler{
+		http:    http,
+		schemas: make(map[string]*graphQLSchema),
+	}
+}
+
+// Send validates the selected operation and its variables against the
+// endpoint's schema and sends it. Validation problems come back in
+// ValidationErrors with no request made; errors the server reports are in
+// Errors.
+func (g *GraphQLHandler) Send(req GraphQLRequest) (*GraphQLResponse, error) {
+	resolved, err := resolverFor(req.Scopes).ResolveGraphQLRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return g.send(resolved)
+}
+
+// send is Send for a request whose variables have already been resolved
+func (g *GraphQLHandler) send(req GraphQLRequest) (*GraphQLResponse, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	variables := map[string]interface{}{}
+	if strings.TrimSpace(req.Variables) != "" {
+		if err := json.Unmarshal([]byte(req.Variables), &variables); err != nil {
+			return nil, fmt.Errorf("variables must be a JSON object: %w", err)
+		}
+	}
+
+	result := &GraphQLResponse{}
+
+	doc, gqlErr := parser.ParseQuery(&ast.Source{Input: req.Query})
+	if gqlErr != nil {
+		result.ValidationErrors = graphQLErrors(gqlErr)
+		return result, nil
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return nil, err
+	}
+	result.OperationName = op.Name
+	result.OperationType = string(op.Operation)
+	if op.Operation == ast.Subscription {
+		return nil, fmt.Errorf("subscriptions can't be sent over HTTP; use a GraphQL WebSocket subscription")
+	}
+
+	// uploads are checked as strings standing in for the Upload scalar,
+	// then sent as nulls as the multipart spec asks
+	for _, upload := range req.Uploads {
+		if err := setVariablePath(variables, upload.Variable, filepath.Base(upload.FilePath)); err != nil {
+			return nil, err
+		}
+	}
+
+	if !req.SkipValidation {
+		cached, err := g.schemaFor(GraphQLSchemaRequest{
+			URL:        req.URL,
+			Headers:    req.Headers,
+			Auth:       req.Auth,
+			TLSProfile: req.TLSProfile,
+		}, false)
+		if err != nil {
+			return nil, fmt.Errorf("%w (turn off validation to send without a schema)", err)
+		}
+
+		if errs := validator.ValidateWithRules(cached.schema, doc, nil); len(errs) > 0 {
+			result.ValidationErrors = graphQLErrorList(errs)
+			return result, nil
+		}
+		// validation resolved the definitions, so op now has what
+		// VariableValues needs
+		if _, err := validator.VariableValues(cached.schema, op, variables); err != nil {
+			result.ValidationErrors = graphQLErrors(err)
+			return result, nil
+		}
+	}
+
+	for _, upload := range req.Uploads {
+		setVariablePath(variables, upload.Variable, nil)
+	}
+
+	payload := map[string]interface{}{
+		"query":     req.Query,
+		"variables": variables,
+	}
+	if op.Name != "" {
+		payload["operationName"] = op.Name
+	}
+
+	if req.PersistedQuery {
+		hash := sha256.Sum256([]byte(req.Query))
+		payload["extensions"] = map[string]interface{}{
+			"persistedQuery": map[string]interface{}{
+				"version":    1,
+				"sha256Hash": hex.EncodeToString(hash[:]),
+			},
+		}
+		delete(payload, "query")
+
+		if err := g.post(req, payload, result); err != nil {
+			return nil, err
+		}
+		// a server that doesn't know the extension may answer with an error
+		// of its own, like a missing query, so only a response that ran the
+		// operation counts as a hit
+		code := persistedQueryError(result.Errors)
+		switch {
+		case code == "" && (result.Data != nil || len(result.Errors) == 0):
+			result.PersistedQuery = "hit"
+			return result, nil
+		case code == "PERSISTED_QUERY_NOT_SUPPORTED":
+			result.PersistedQuery = "unsupported"
+			delete(payload, "extensions")
+		default:
+			// the server stores the query under the hash it was sent with
+			result.PersistedQuery = "registered"
+		}
+		payload["query"] = req.Query
+	}
+
+	if err := g.post(req, payload, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// post sends one GraphQL request and fills in the response fields of result
+func (g *GraphQLHandler) post(req GraphQLRequest, payload map[string]interface{}, result *GraphQLResponse) error {
+	operations, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	body, contentType := string(operations), "application/json"
+	if len(req.Uploads) > 0 {
+		if body, contentType, err = multipartBody(operations, req.Uploads); err != nil {
+			return err
+		}
+	}
+
+	httpReq := g.httpRequest(req.URL, req.Headers, req.Auth, req.TLSProfile, body, contentType)
+	if len(req.Uploads) > 0 {
+		// Apollo Server and others refuse multipart requests that could
+		// be simple CORS requests unless a preflight header is present
+		httpReq.Headers = append(httpReq.Headers, KeyValue{Key: "Apollo-Require-Preflight", Value: "true", Enabled: true})
+	}
+
+	resp, err := g.http.send(httpReq)
+	if err != nil {
+		return err
+	}
+
+	result.Response = resp
+	result.Data = nil
+	result.Errors = nil
+	result.Extensions = nil
+
+	// a body that isn't a GraphQL response is left for the raw view
+	var parsed struct {
+		Data       interface{}            `json:"data"`
+		Errors     []GraphQLError         `json:"errors"`
+		Extensions map[string]interface{} `json:"extensions"`
+	}
+	if json.Unmarshal([]byte(resp.Body), &parsed) == nil {
+		result.Data = parsed.Data
+		result.Errors = parsed.Errors
+		result.Extensions = parsed.Extensions
+	}
+	return nil
+}
+
+// httpRequest builds the POST for an operation, with our Content-Type and
+// Accept taking the place of any the user set
+func (g *GraphQLHandler) httpRequest(url string, headers []KeyValue, auth *RequestAuth, tlsProfile, body, contentType string) RequestData {
+	out := RequestData{
+		Method:     "POST",
+		URL:        url,
+		Body:       body,
+		BodyType:   "graphql",
+		Auth:       auth,
+		TLSProfile: tlsProfile,
+	}
+	for _, h := range headers {
+		switch strings.ToLower(h.Key) {
+		case "content-type", "accept":
+			continue
+		}
+		out.Headers = append(out.Headers, h)
+	}
+	out.Headers = append(out.Headers,
+		KeyValue{Key: "Content-Type", Value: contentType, Enabled: true},
+		KeyValue{Key: "Accept", Value: "application/graphql-response+json, application/json", Enabled: true},
+	)
+	return out
+}
+
+// multipartBody lays out a request per the GraphQL multipart request spec:
+// the operations, then a map from file parts to variable paths, then the
+// files
+func multipartBody(operations []byte, uploads []GraphQLUpload) (string, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	if err := w.WriteField("operations", string(operations)); err != nil {
+		return "", "", err
+	}
+
+	fileMap := make(map[string][]string, len(uploads))
+	for i, upload := range uploads {
+		fileMap[strconv.Itoa(i)] = []string{"variables." + upload.Variable}
+	}
+	mapJSON, err := json.Marshal(fileMap)
+	if err != nil {
+		return "", "", err
+	}
+	if err := w.WriteField("map", string(mapJSON)); err != nil {
+		return "", "", err
+	}
+
+	for i, upload := range uploads {
+		data, err := os.ReadFile(upload.FilePath)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read upload for %s: %w", upload.Variable, err)
+		}
+		part, err := w.CreateFormFile(strconv.Itoa(i), filepath.Base(upload.FilePath))
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := part.Write(data); err != nil {
+			return "", "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+	return buf.String(), w.FormDataContentType(), nil
+}
+
+// selectOperation picks the operation to run. A document with several
+// operations needs operationName; one with a single operation may omit it.
+func selectOperation(doc *ast.QueryDocument, name string) (*ast.OperationDefinition, error) {
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("the document has no operations")
+	}
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("the document has %d operations; choose one with operationName", len(doc.Operations))
+		}
+		return doc.Operations[0], nil
+	}
+	op := doc.Operations.ForName(name)
+	if op == nil {
+		return nil, fmt.Errorf("no operation named %q in the document", name)
+	}
+	return op, nil
+}
+
+// setVariablePath sets a value at a dotted path inside the variables.
+// Numeric segments index into lists, which must already be long enough.
+func setVariablePath(variables map[string]interface{}, path string, value interface{}) error {
+	segments := strings.Split(path, ".")
+	if path == "" {
+		return fmt.Errorf("upload needs a variable path")
+	}
+
+	var current interface{} = variables
+	for i, seg := range segments {
+		last := i == len(segments)-1
+
+		switch node := current.(type) {
+		case map[string]interface{}:
+			if last {
+				node[seg] = value
+				return nil
+			}
+			next, ok := node[seg]
+			if !ok || next == nil {
+				next = map[string]interface{}{}
+				node[seg] = next
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(seg)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return fmt.Errorf("variables.%s: %s is not an index into a list of %d", path, seg, len(node))
+			}
+			if last {
+				node[idx] = value
+				return nil
+			}
+			current = node[idx]
+		default:
+			return fmt.Errorf("variables.%s: %s is not an object or list", path, strings.Join(segments[:i], "."))
+		}
+	}
+	return nil
+}
+
+// persistedQueryError returns the persisted query error code among the
+// server's errors, if there is one
+func persistedQueryError(errs []GraphQLError) string {
+	for _, e := range errs {
+		if code, ok := e.Extensions["code"].(string); ok && strings.HasPrefix(code, "PERSISTED_QUERY_") {
+			return code
+		}
+		switch e.Message {
+		case "PersistedQueryNotFound":
+			return "PERSISTED_QUERY_NOT_FOUND"
+		case "PersistedQueryNotSupported":
+			return "PERSISTED_QUERY_NOT_SUPPORTED"
+		}
+	}
+	return ""
+}
+
+func graphQLErrorList(errs gqlerror.List) []GraphQLError {
+	out := make([]error, len(errs))
+	for i, err := range errs {
+		out[i] = err
+	}
+	return graphQLErrors(out...)
+}
+
+func graphQLErrors(errs ...error) []GraphQLError {
+	out := make([]GraphQLError, 0, len(errs))
+	for _, err := range errs {
+		gqlErr, ok := err.(*gqlerror.Error)
+		if !ok {
+			out = append(out, GraphQLError{Message: err.Error()})
+			continue
+		}
+
+		e := GraphQLError{
+			Message:    gqlErr.Message,
+			Extensions: gqlErr.Extensions,
+		}
+		for _, loc := range gqlErr.Locations {
+			e.Locations = append(e.Locations, GraphQLLocation{Line: loc.Line, Column: loc.Column})
+		}
+		for _, elem := range gqlErr.Path {
+			switch p := elem.(type) {
+			case ast.PathName:
+				e.Path = append(e.Path, string(p))
+			case ast.PathIndex:
+				e.Path = append(e.Path, int(p))
+			}
+		}
+		if gqlErr.Rule != "" {
+			if e.Extensions == nil {
+				e.Extensions = map[string]interface{}{}
+			}
+			e.Extensions["rule"] = gqlErr.Rule
+		}
+		out = append(out, e)
+	}
+	return out
+}
@@ -0,0 +1,394 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSetVariablePath(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables string
+		path      string
+		want      string
+		wantErr   bool
+	}{
+		{name: "top level", variables: `{"file":null}`, path: "file", want: `{"file":"a.png"}`},
+		{name: "missing top level", variables: `{}`, path: "file", want: `{"file":"a.png"}`},
+		{name: "nested", variables: `{"input":{"id":1}}`, path: "input.file", want: `{"input":{"file":"a.png","id":1}}`},
+		{name: "creates objects", variables: `{}`, path: "input.attachment.file", want: `{"input":{"attachment":{"file":"a.png"}}}`},
+		{name: "null parent", variables: `{"input":null}`, path: "input.file", want: `{"input":{"file":"a.png"}}`},
+		{name: "list index", variables: `{"files":[null,null]}`, path: "files.1", want: `{"files":[null,"a.png"]}`},
+		{name: "object in list", variables: `{"items":[{"id":1}]}`, path: "items.0.file", want: `{"items":[{"file":"a.png","id":1}]}`},
+		{name: "empty path", variables: `{}`, path: "", wantErr: true},
+		{name: "index past the end", variables: `{"files":[null]}`, path: "files.1", wantErr: true},
+		{name: "negative index", variables: `{"files":[null]}`, path: "files.-1", wantErr: true},
+		{name: "name into a list", variables: `{"files":[null]}`, path: "files.first", wantErr: true},
+		{name: "into a scalar", variables: `{"input":"x"}`, path: "input.file", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variables := decodeTestJSON(t, tt.variables).(map[string]interface{})
+
+			err := setVariablePath(variables, tt.path, "a.png")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", variables)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := decodeTestJSON(t, tt.want); !reflect.DeepEqual(variables, want) {
+				t.Errorf("got %v, want %v", variables, want)
+			}
+		})
+	}
+}
+
+func encodeTestJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func decodeTestJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// testGraphQLSchema is the introspection result of
+//
+//	type Query { user(id: ID!): User }
+//	type Mutation { upload(file: Upload!): Boolean }
+//	type User { id: ID!, name: String }
+//	scalar Upload
+const testGraphQLSchema = `{"data":{"__schema":{
+	"queryType":{"name":"Query"},
+	"mutationType":{"name":"Mutation"},
+	"types":[
+		{"kind":"OBJECT","name":"Query","fields":[{"name":"user","args":[{"name":"id","type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR","name":"ID"}}}],"type":{"kind":"OBJECT","name":"User"}}]},
+		{"kind":"OBJECT","name":"Mutation","fields":[{"name":"upload","args":[{"name":"file","type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR","name":"Upload"}}}],"type":{"kind":"SCALAR","name":"Boolean"}}]},
+		{"kind":"OBJECT","name":"User","fields":[
+			{"name":"id","args":[],"type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR","name":"ID"}}},
+			{"name":"name","args":[],"type":{"kind":"SCALAR","name":"String"}}
+		]},
+		{"kind":"SCALAR","name":"Upload"}
+	],
+	"directives":[]
+}}}`
+
+// graphQLRequest is an operation as the test server received it
+type graphQLRequest struct {
+	header     http.Header
+	operations map[string]interface{}
+	fileMap    map[string][]string
+	files      map[string]string // part name to contents
+}
+
+// graphQLServer answers introspection with testGraphQLSchema and passes
+// every other operation to answer. The operations it was sent, introspection
+// aside, are returned in order.
+func graphQLServer(t *testing.T, answer func(op graphQLRequest) string) (*httptest.Server, func() []graphQLRequest) {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		received []graphQLRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := graphQLRequest{header: r.Header}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			json.Unmarshal([]byte(r.FormValue("operations")), &op.operations)
+			json.Unmarshal([]byte(r.FormValue("map")), &op.fileMap)
+			op.files = make(map[string]string)
+			for name, headers := range r.MultipartForm.File {
+				f, err := headers[0].Open()
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				data, _ := io.ReadAll(f)
+				f.Close()
+				op.files[name] = string(data)
+			}
+		} else if err := json.NewDecoder(r.Body).Decode(&op.operations); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if op.operations["operationName"] == "IntrospectionQuery" {
+			io.WriteString(w, testGraphQLSchema)
+			return
+		}
+		mu.Lock()
+		received = append(received, op)
+		mu.Unlock()
+		io.WriteString(w, answer(op))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []graphQLRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]graphQLRequest(nil), received...)
+	}
+}
+
+func newTestGraphQLHandler(t *testing.T) *GraphQLHandler {
+	return NewGraphQLHandler(NewHTTPHandler(nil, t.TempDir()))
+}
+
+func TestGraphQLSendValidation(t *testing.T) {
+	server, received := graphQLServer(t, func(graphQLRequest) string {
+		return `{"data":{"user":{"id":"1","name":"Ada"}}}`
+	})
+	g := newTestGraphQLHandler(t)
+
+	tests := []struct {
+		name      string
+		query     string
+		variables string
+		want      string // in the first validation error
+	}{
+		{name: "syntax error", query: `query { user(id: 1) {`, want: "Expected Name"},
+		{name: "unknown field", query: `query { user(id: 1) { email } }`, want: `Cannot query field "email"`},
+		{name: "missing argument", query: `query { user { id } }`, want: `argument "id" of type "ID!" is required`},
+		{name: "missing variable", query: `query ($id: ID!) { user(id: $id) { id } }`, want: "must be defined"},
+		{name: "wrong variable type", query: `query ($id: ID!) { user(id: $id) { id } }`, variables: `{"id":{"a":1}}`, want: "cannot use map as ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := g.send(GraphQLRequest{URL: server.URL, Query: tt.query, Variables: tt.variables})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.ValidationErrors) == 0 || !strings.Contains(resp.ValidationErrors[0].Message, tt.want) {
+				t.Fatalf("validation errors = %+v, want %q", resp.ValidationErrors, tt.want)
+			}
+			if resp.Response != nil {
+				t.Error("an invalid operation was sent")
+			}
+		})
+	}
+	if ops := received(); len(ops) != 0 {
+		t.Errorf("server received %d operations", len(ops))
+	}
+
+	resp, err := g.send(GraphQLRequest{URL: server.URL, Query: `query ($id: ID!) { user(id: $id) { id name } }`, Variables: `{"id":"1"}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ValidationErrors) > 0 {
+		t.Fatalf("validation errors = %+v", resp.ValidationErrors)
+	}
+	if got := encodeTestJSON(t, resp.Data); got != `{"user":{"id":"1","name":"Ada"}}` {
+		t.Errorf("data = %s", got)
+	}
+	if resp.OperationType != "query" {
+		t.Errorf("operation type = %q", resp.OperationType)
+	}
+
+	// unvalidated operations go out without introspection
+	resp, err = g.send(GraphQLRequest{URL: server.URL + "/other", Query: `{ anything }`, SkipValidation: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Response == nil || len(resp.ValidationErrors) > 0 {
+		t.Errorf("unvalidated operation was not sent: %+v", resp)
+	}
+}
+
+func TestGraphQLSendOperationName(t *testing.T) {
+	server, received := graphQLServer(t, func(graphQLRequest) string { return `{"data":{}}` })
+	g := newTestGraphQLHandler(t)
+
+	const doc = `query First { user(id: 1) { id } } query Second { user(id: 2) { name } }`
+
+	if _, err := g.send(GraphQLRequest{URL: server.URL, Query: doc}); err == nil || !strings.Contains(err.Error(), "choose one with operationName") {
+		t.Errorf("several operations without a name: err = %v", err)
+	}
+	if _, err := g.send(GraphQLRequest{URL: server.URL, Query: doc, OperationName: "Third"}); err == nil || !strings.Contains(err.Error(), `no operation named "Third"`) {
+		t.Errorf("unknown operation: err = %v", err)
+	}
+	if _, err := g.send(GraphQLRequest{URL: server.URL, Query: `subscription { ticks }`, SkipValidation: true}); err == nil || !strings.Contains(err.Error(), "WebSocket") {
+		t.Errorf("subscription over HTTP: err = %v", err)
+	}
+
+	resp, err := g.send(GraphQLRequest{URL: server.URL, Query: doc, OperationName: "Second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.OperationName != "Second" {
+		t.Errorf("operation name = %q", resp.OperationName)
+	}
+
+	// an anonymous operation is sent without operationName
+	if _, err := g.send(GraphQLRequest{URL: server.URL, Query: `{ user(id: 1) { id } }`}); err != nil {
+		t.Fatal(err)
+	}
+
+	ops := received()
+	if len(ops) != 2 {
+		t.Fatalf("server received %d operations, want 2", len(ops))
+	}
+	if name := ops[0].operations["operationName"]; name != "Second" {
+		t.Errorf("sent operationName = %v", name)
+	}
+	if ops[0].operations["query"] != doc {
+		t.Errorf("sent query = %v, want the whole document", ops[0].operations["query"])
+	}
+	if name, ok := ops[1].operations["operationName"]; ok {
+		t.Errorf("anonymous operation sent with operationName %v", name)
+	}
+}
+
+func TestGraphQLSendUploads(t *testing.T) {
+	server, received := graphQLServer(t, func(graphQLRequest) string { return `{"data":{"upload":true}}` })
+	g := newTestGraphQLHandler(t)
+
+	file := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(file, []byte("a,b\n1,2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.send(GraphQLRequest{
+		URL:     server.URL,
+		Query:   `mutation ($file: Upload!) { upload(file: $file) }`,
+		Uploads: []GraphQLUpload{{Variable: "file", FilePath: file}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ValidationErrors) > 0 {
+		t.Fatalf("validation errors = %+v", resp.ValidationErrors)
+	}
+
+	ops := received()
+	if len(ops) != 1 {
+		t.Fatalf("server received %d operations, want 1", len(ops))
+	}
+	op := ops[0]
+	if got := encodeTestJSON(t, op.operations["variables"]); got != `{"file":null}` {
+		t.Errorf("variables = %s, want the upload as null", got)
+	}
+	if !reflect.DeepEqual(op.fileMap, map[string][]string{"0": {"variables.file"}}) {
+		t.Errorf("map = %v", op.fileMap)
+	}
+	if op.files["0"] != "a,b\n1,2\n" {
+		t.Errorf("file part = %q", op.files["0"])
+	}
+	if op.header.Get("Apollo-Require-Preflight") != "true" {
+		t.Error("multipart request sent without Apollo-Require-Preflight")
+	}
+
+	// a file that can't be read stops the request
+	resp, err = g.send(GraphQLRequest{
+		URL:     server.URL,
+		Query:   `mutation ($file: Upload!) { upload(file: $file) }`,
+		Uploads: []GraphQLUpload{{Variable: "file", FilePath: filepath.Join(t.TempDir(), "missing.csv")}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to read upload") {
+		t.Errorf("missing upload file: resp = %+v, err = %v", resp, err)
+	}
+}
+
+func TestGraphQLPersistedQueries(t *testing.T) {
+	const query = `{ user(id: 1) { id } }`
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+
+	const (
+		data        = `{"data":{"user":{"id":"1"}}}`
+		notFound    = `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`
+		unsupported = `{"errors":[{"message":"PersistedQueryNotSupported"}]}`
+		noQuery     = `{"errors":[{"message":"Must provide query string."}]}`
+	)
+
+	tests := []struct {
+		name string
+		// answer answers the first request, which carries only the hash
+		answer     string
+		want       string
+		extensions bool // whether the retry still carries the hash
+	}{
+		{name: "hit", answer: data, want: "hit"},
+		{name: "hit with field errors", answer: `{"data":{"user":null},"errors":[{"message":"not allowed","path":["user"]}]}`, want: "hit"},
+		{name: "not found", answer: notFound, want: "registered", extensions: true},
+		{name: "not supported", answer: unsupported, want: "unsupported"},
+		{name: "server without persisted queries", answer: noQuery, want: "registered", extensions: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, received := graphQLServer(t, func(op graphQLRequest) string {
+				if _, ok := op.operations["query"]; ok {
+					return data
+				}
+				return tt.answer
+			})
+			g := newTestGraphQLHandler(t)
+
+			resp, err := g.send(GraphQLRequest{URL: server.URL, Query: query, PersistedQuery: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.PersistedQuery != tt.want {
+				t.Errorf("persisted query = %q, want %q", resp.PersistedQuery, tt.want)
+			}
+
+			ops := received()
+			if _, ok := ops[0].operations["query"]; ok {
+				t.Error("first request carried the query")
+			}
+			if got := encodeTestJSON(t, ops[0].operations["extensions"]); got != `{"persistedQuery":{"sha256Hash":"`+hash+`","version":1}}` {
+				t.Errorf("extensions = %s", got)
+			}
+
+			if tt.want == "hit" {
+				if len(ops) != 1 {
+					t.Errorf("server received %d operations, want 1", len(ops))
+				}
+				return
+			}
+			if len(ops) != 2 {
+				t.Fatalf("server received %d operations, want 2", len(ops))
+			}
+			retry := ops[1].operations
+			if retry["query"] != query {
+				t.Errorf("retry query = %v", retry["query"])
+			}
+			if _, ok := retry["extensions"]; ok != tt.extensions {
+				t.Errorf("retry extensions = %v, want them: %v", retry["extensions"], tt.extensions)
+			}
+			if len(resp.Errors) > 0 || encodeTestJSON(t, resp.Data) != `{"user":{"id":"1"}}` {
+				t.Errorf("response is not the retry's: data %v, errors %+v", resp.Data, resp.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,425 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// GraphQLSchemaRequest introspects an endpoint. The schema is cached by URL
+// until Refresh is set.
+type GraphQLSchemaRequest struct {
+	URL        string          `json:"url"`
+	Headers    []KeyValue      `json:"headers"`
+	Auth       *RequestAuth    `json:"auth"`
+	TLSProfile string          `json:"tlsProfile,omitempty"`
+	Refresh    bool            `json:"refresh"`
+	Scopes     *VariableScopes `json:"scopes,omitempty"`
+}
+
+// GraphQLSchemaInfo is an introspected schema, printed as SDL for the
+// schema browser
+type GraphQLSchemaInfo struct {
+	URL              string    `json:"url"`
+	SDL              string    `json:"sdl"`
+	QueryType        string    `json:"queryType"`
+	MutationType     string    `json:"mutationType,omitempty"`
+	SubscriptionType string    `json:"subscriptionType,omitempty"`
+	Types            []string  `json:"types"` // named types, built-ins left out
+	FetchedAt        time.Time `json:"fetchedAt"`
+}
+
+type graphQLSchema struct {
+	info   GraphQLSchemaInfo
+	schema *ast.Schema
+}
+
+// introspectionQuery asks for everything needed to rebuild the schema. It
+// sticks to fields every server supports, so no isRepeatable, specifiedByURL
+// or deprecated arguments.
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives {
+      name
+      description
+      locations
+      args { ...InputValue }
+    }
+  }
+}
+
+fragment FullType on __Type {
+  kind
+  name
+  description
+  fields(includeDeprecated: true) {
+    name
+    description
+    args { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated
+    deprecationReason
+  }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) {
+    name
+    description
+    isDeprecated
+    deprecationReason
+  }
+  possibleTypes { ...TypeRef }
+}
+
+fragment InputValue on __InputValue {
+  name
+  description
+  type { ...TypeRef }
+  defaultValue
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType {
+    kind
+    name
+    ofType {
+      kind
+      name
+      ofType {
+        kind
+        name
+        ofType {
+          kind
+          name
+          ofType {
+            kind
+            name
+            ofType {
+              kind
+              name
+              ofType { kind name }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type introspectionResult struct {
+	Schema *introspectionSchema `json:"__schema"`
+}
+
+type introspectionSchema struct {
+	QueryType        *introspectionTypeRef    `json:"queryType"`
+	MutationType     *introspectionTypeRef    `json:"mutationType"`
+	SubscriptionType *introspectionTypeRef    `json:"subscriptionType"`
+	Types            []introspectionType      `json:"types"`
+	Directives       []introspectionDirective `json:"directives"`
+}
+
+type introspectionType struct {
+	Kind          string                   `json:"kind"`
+	Name          string                   `json:"name"`
+	Description   string                   `json:"description"`
+	Fields        []introspectionField     `json:"fields"`
+	InputFields   []introspectionInput     `json:"inputFields"`
+	Interfaces    []introspectionTypeRef   `json:"interfaces"`
+	EnumValues    []introspectionEnumValue `json:"enumValues"`
+	PossibleTypes []introspectionTypeRef   `json:"possibleTypes"`
+}
+
+type introspectionField struct {
+	Name              string               `json:"name"`
+	Description       string               `json:"description"`
+	Args              []introspectionInput `json:"args"`
+	Type              introspectionTypeRef `json:"type"`
+	IsDeprecated      bool                 `json:"isDeprecated"`
+	DeprecationReason *string              `json:"deprecationReason"`
+}
+
+type introspectionInput struct {
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	Type         introspectionTypeRef `json:"type"`
+	DefaultValue *string              `json:"defaultValue"` // a GraphQL literal
+}
+
+type introspectionEnumValue struct {
+	Name              string  `json:"name"`
+	Description       string  `json:"description"`
+	IsDeprecated      bool    `json:"isDeprecated"`
+	DeprecationReason *string `json:"deprecationReason"`
+}
+
+type introspectionDirective struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Locations   []string             `json:"locations"`
+	Args        []introspectionInput `json:"args"`
+}
+
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+// builtinNames are the types and directives gqlparser's prelude already
+// declares; redeclaring them from introspection would fail to load
+var builtinNames = func() map[string]bool {
+	names := make(map[string]bool)
+	doc, err := parser.ParseSchema(validator.Prelude)
+	if err != nil {
+		panic(err)
+	}
+	for _, def := range doc.Definitions {
+		names[def.Name] = true
+	}
+	for _, dir := range doc.Directives {
+		names["@"+dir.Name] = true
+	}
+	return names
+}()
+
+// IntrospectSchema runs the introspection query against an endpoint, or
+// returns the cached schema
+func (g *GraphQLHandler) IntrospectSchema(req GraphQLSchemaRequest) (*GraphQLSchemaInfo, error) {
+	req, err := resolverFor(req.Scopes).ResolveGraphQLSchemaRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := g.schemaFor(req, req.Refresh)
+	if err != nil {
+		return nil, err
+	}
+	info := cached.info
+	return &info, nil
+}
+
+// schemaFor returns the cached schema for an endpoint, introspecting it on
+// first use. req must already be resolved.
+func (g *GraphQLHandler) schemaFor(req GraphQLSchemaRequest, refresh bool) (*graphQLSchema, error) {
+	if !refresh {
+		g.mu.RLock()
+		cached, ok := g.schemas[req.URL]
+		g.mu.RUnlock()
+		if ok {
+			return cached, nil
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":         introspectionQuery,
+		"operationName": "IntrospectionQuery",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.http.send(g.httpRequest(req.URL, req.Headers, req.Auth, req.TLSProfile, string(body), "application/json"))
+	if err != nil {
+		return nil, fmt.Errorf("introspection failed: %w", err)
+	}
+
+	var result struct {
+		Data   *introspectionResult `json:"data"`
+		Errors []GraphQLError       `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(resp.Body), &result); err != nil {
+		return nil, fmt.Errorf("introspection failed: %s returned %s, not a GraphQL response", req.URL, resp.StatusText)
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("introspection failed: %s", result.Errors[0].Message)
+	}
+	if result.Data == nil || result.Data.Schema == nil {
+		return nil, fmt.Errorf("introspection failed: no schema in the response")
+	}
+
+	sdl := printIntrospectedSchema(result.Data.Schema)
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: req.URL, Input: sdl})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load introspected schema: %w", err)
+	}
+
+	cached := &graphQLSchema{
+		info: GraphQLSchemaInfo{
+			URL:       req.URL,
+			SDL:       sdl,
+			QueryType: typeRefName(result.Data.Schema.QueryType),
+			FetchedAt: time.Now(),
+		},
+		schema: schema,
+	}
+	cached.info.MutationType = typeRefName(result.Data.Schema.MutationType)
+	cached.info.SubscriptionType = typeRefName(result.Data.Schema.SubscriptionType)
+	for _, t := range result.Data.Schema.Types {
+		if !strings.HasPrefix(t.Name, "__") && !builtinNames[t.Name] {
+			cached.info.Types = append(cached.info.Types, t.Name)
+		}
+	}
+	sort.Strings(cached.info.Types)
+
+	g.mu.Lock()
+	g.schemas[req.URL] = cached
+	g.mu.Unlock()
+
+	return cached, nil
+}
+
+// printIntrospectedSchema writes an introspection result back out as SDL
+func printIntrospectedSchema(s *introspectionSchema) string {
+	var b strings.Builder
+
+	b.WriteString("schema {\n")
+	fmt.Fprintf(&b, "  query: %s\n", typeRefName(s.QueryType))
+	if s.MutationType != nil {
+		fmt.Fprintf(&b, "  mutation: %s\n", s.MutationType.Name)
+	}
+	if s.SubscriptionType != nil {
+		fmt.Fprintf(&b, "  subscription: %s\n", s.SubscriptionType.Name)
+	}
+	b.WriteString("}\n")
+
+	for _, d := range s.Directives {
+		if builtinNames["@"+d.Name] {
+			continue
+		}
+		b.WriteString("\n")
+		printDescription(&b, "", d.Description)
+		fmt.Fprintf(&b, "directive @%s%s on %s\n", d.Name, printArgs(d.Args), strings.Join(d.Locations, " | "))
+	}
+
+	for _, t := range s.Types {
+		if strings.HasPrefix(t.Name, "__") || builtinNames[t.Name] {
+			continue
+		}
+		b.WriteString("\n")
+		printDescription(&b, "", t.Description)
+
+		switch t.Kind {
+		case "SCALAR":
+			fmt.Fprintf(&b, "scalar %s\n", t.Name)
+		case "OBJECT", "INTERFACE":
+			keyword := "type"
+			if t.Kind == "INTERFACE" {
+				keyword = "interface"
+			}
+			fmt.Fprintf(&b, "%s %s", keyword, t.Name)
+			if len(t.Interfaces) > 0 {
+				names := make([]string, len(t.Interfaces))
+				for i, iface := range t.Interfaces {
+					names[i] = iface.Name
+				}
+				fmt.Fprintf(&b, " implements %s", strings.Join(names, " & "))
+			}
+			b.WriteString(" {\n")
+			for _, f := range t.Fields {
+				printDescription(&b, "  ", f.Description)
+				fmt.Fprintf(&b, "  %s%s: %s%s\n", f.Name, printArgs(f.Args), printTypeRef(&f.Type), printDeprecated(f.IsDeprecated, f.DeprecationReason))
+			}
+			b.WriteString("}\n")
+		case "UNION":
+			names := make([]string, len(t.PossibleTypes))
+			for i, p := range t.PossibleTypes {
+				names[i] = p.Name
+			}
+			fmt.Fprintf(&b, "union %s = %s\n", t.Name, strings.Join(names, " | "))
+		case "ENUM":
+			fmt.Fprintf(&b, "enum %s {\n", t.Name)
+			for _, v := range t.EnumValues {
+				printDescription(&b, "  ", v.Description)
+				fmt.Fprintf(&b, "  %s%s\n", v.Name, printDeprecated(v.IsDeprecated, v.DeprecationReason))
+			}
+			b.WriteString("}\n")
+		case "INPUT_OBJECT":
+			fmt.Fprintf(&b, "input %s {\n", t.Name)
+			for _, f := range t.InputFields {
+				printDescription(&b, "  ", f.Description)
+				fmt.Fprintf(&b, "  %s\n", printInputValue(f))
+			}
+			b.WriteString("}\n")
+		}
+	}
+
+	return b.String()
+}
+
+func printDescription(b *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+	description = strings.ReplaceAll(description, `"""`, `\"""`)
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+	for _, line := range strings.Split(description, "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+}
+
+func printArgs(args []introspectionInput) string {
+	if len(args) == 0 {
+		return ""
+	}
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		// argument descriptions would need the multi-line form; the
+		// schema browser does without them
+		parts[i] = printInputValue(arg)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func printInputValue(v introspectionInput) string {
+	out := v.Name + ": " + printTypeRef(&v.Type)
+	if v.DefaultValue != nil {
+		out += " = " + *v.DefaultValue
+	}
+	return out
+}
+
+func printTypeRef(t *introspectionTypeRef) string {
+	if t == nil {
+		return ""
+	}
+	switch t.Kind {
+	case "NON_NULL":
+		return printTypeRef(t.OfType) + "!"
+	case "LIST":
+		return "[" + printTypeRef(t.OfType) + "]"
+	default:
+		return t.Name
+	}
+}
+
+func printDeprecated(deprecated bool, reason *string) string {
+	if !deprecated {
+		return ""
+	}
+	if reason == nil || *reason == "" {
+		return " @deprecated"
+	}
+	quoted, _ := json.Marshal(*reason)
+	return fmt.Sprintf(" @deprecated(reason: %s)", quoted)
+}
+
+func typeRefName(t *introspectionTypeRef) string {
+	if t == nil {
+		return ""
+	}
+	return t.Name
+}
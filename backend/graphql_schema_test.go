@@ -0,0 +1,159 @@
+package backend
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const testQueryType = `{"kind":"OBJECT","name":"Query","fields":[{"name":"ok","args":[],"type":{"kind":"SCALAR","name":"Boolean"}}]}`
+
+// introspect decodes the types and directives of an introspection result
+// for a schema whose query type is Query
+func introspect(t *testing.T, types, directives string) *introspectionSchema {
+	t.Helper()
+
+	var s introspectionSchema
+	doc := `{"queryType":{"name":"Query"},"types":[` + testQueryType
+	if types != "" {
+		doc += "," + types
+	}
+	doc += `],"directives":[` + directives + `]}`
+	if err := json.Unmarshal([]byte(doc), &s); err != nil {
+		t.Fatal(err)
+	}
+	return &s
+}
+
+func TestPrintIntrospectedSchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		types      string
+		directives string
+		want       string
+	}{
+		{
+			name:  "scalar with description",
+			types: `{"kind":"SCALAR","name":"DateTime","description":"An ISO-8601 timestamp"}`,
+			want:  "\"\"\"\nAn ISO-8601 timestamp\n\"\"\"\nscalar DateTime\n",
+		},
+		{
+			name: "object with wrapped types and arguments",
+			types: `{"kind":"OBJECT","name":"Order","interfaces":[{"kind":"INTERFACE","name":"Node"},{"kind":"INTERFACE","name":"Timestamped"}],"fields":[
+				{"name":"id","args":[],"type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR","name":"ID"}}},
+				{"name":"lines","args":[
+					{"name":"first","type":{"kind":"SCALAR","name":"Int"},"defaultValue":"10"},
+					{"name":"after","type":{"kind":"SCALAR","name":"String"}}
+				],"type":{"kind":"NON_NULL","ofType":{"kind":"LIST","ofType":{"kind":"NON_NULL","ofType":{"kind":"OBJECT","name":"Line"}}}}}
+			]}`,
+			want: "type Order implements Node & Timestamped {\n  id: ID!\n  lines(first: Int = 10, after: String): [Line!]!\n}\n",
+		},
+		{
+			name:  "interface",
+			types: `{"kind":"INTERFACE","name":"Node","fields":[{"name":"id","args":[],"type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR","name":"ID"}}}]}`,
+			want:  "interface Node {\n  id: ID!\n}\n",
+		},
+		{
+			name: "deprecated fields",
+			types: `{"kind":"OBJECT","name":"User","fields":[
+				{"name":"login","args":[],"type":{"kind":"SCALAR","name":"String"},"isDeprecated":true,"deprecationReason":"Use \"handle\""},
+				{"name":"nick","args":[],"type":{"kind":"SCALAR","name":"String"},"isDeprecated":true},
+				{"name":"handle","description":"Unique\nand public","args":[],"type":{"kind":"SCALAR","name":"String"}}
+			]}`,
+			want: "type User {\n  login: String @deprecated(reason: \"Use \\\"handle\\\"\")\n  nick: String @deprecated\n  \"\"\"\n  Unique\n  and public\n  \"\"\"\n  handle: String\n}\n",
+		},
+		{
+			name:  "union",
+			types: `{"kind":"UNION","name":"SearchResult","possibleTypes":[{"kind":"OBJECT","name":"Order"},{"kind":"OBJECT","name":"User"}]}`,
+			want:  "union SearchResult = Order | User\n",
+		},
+		{
+			name: "enum",
+			types: `{"kind":"ENUM","name":"Status","enumValues":[
+				{"name":"OPEN"},
+				{"name":"LEGACY","isDeprecated":true,"deprecationReason":""}
+			]}`,
+			want: "enum Status {\n  OPEN\n  LEGACY @deprecated\n}\n",
+		},
+		{
+			name: "input object",
+			types: `{"kind":"INPUT_OBJECT","name":"OrderInput","inputFields":[
+				{"name":"qty","description":"How many","type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR","name":"Int"}},"defaultValue":"1"},
+				{"name":"tags","type":{"kind":"LIST","ofType":{"kind":"SCALAR","name":"String"}},"defaultValue":"[\"new\"]"}
+			]}`,
+			want: "input OrderInput {\n  \"\"\"\n  How many\n  \"\"\"\n  qty: Int! = 1\n  tags: [String] = [\"new\"]\n}\n",
+		},
+		{
+			name:  "description with triple quotes",
+			types: `{"kind":"SCALAR","name":"Doc","description":"Wraps \"\"\"text\"\"\""}`,
+			want:  "\"\"\"\nWraps \\\"\"\"text\\\"\"\"\n\"\"\"\nscalar Doc\n",
+		},
+		{
+			name:       "custom directive",
+			directives: `{"name":"cached","description":"Cache hint","locations":["FIELD_DEFINITION","OBJECT"],"args":[{"name":"ttl","type":{"kind":"SCALAR","name":"Int"},"defaultValue":"60"}]}`,
+			want:       "\"\"\"\nCache hint\n\"\"\"\ndirective @cached(ttl: Int = 60) on FIELD_DEFINITION | OBJECT\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sdl := printIntrospectedSchema(introspect(t, tt.types, tt.directives))
+			if !strings.Contains(sdl, "\n"+tt.want) {
+				t.Errorf("SDL is missing\n%s\ngot\n%s", tt.want, sdl)
+			}
+		})
+	}
+}
+
+func TestPrintIntrospectedSchemaSkipsBuiltins(t *testing.T) {
+	s := introspect(t,
+		`{"kind":"SCALAR","name":"String"},
+		{"kind":"OBJECT","name":"__Type","fields":[]},
+		{"kind":"ENUM","name":"__TypeKind","enumValues":[{"name":"SCALAR"}]}`,
+		`{"name":"skip","locations":["FIELD"],"args":[{"name":"if","type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR","name":"Boolean"}}}]},
+		{"name":"deprecated","locations":["FIELD_DEFINITION"],"args":[]}`)
+
+	want := "schema {\n  query: Query\n}\n\ntype Query {\n  ok: Boolean\n}\n"
+	if got := printIntrospectedSchema(s); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPrintIntrospectedSchemaLoads(t *testing.T) {
+	s := introspect(t,
+		`{"kind":"OBJECT","name":"Mutation","fields":[{"name":"place","args":[{"name":"input","type":{"kind":"NON_NULL","ofType":{"kind":"INPUT_OBJECT","name":"OrderInput"}}}],"type":{"kind":"OBJECT","name":"Order"}}]},
+		{"kind":"OBJECT","name":"Subscription","fields":[{"name":"updates","args":[],"type":{"kind":"ENUM","name":"Status"}}]},
+		{"kind":"INTERFACE","name":"Node","fields":[{"name":"id","args":[],"type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR","name":"ID"}}}],"possibleTypes":[{"kind":"OBJECT","name":"Order"}]},
+		{"kind":"OBJECT","name":"Order","interfaces":[{"kind":"INTERFACE","name":"Node"}],"fields":[
+			{"name":"id","args":[],"type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR","name":"ID"}}},
+			{"name":"status","args":[],"type":{"kind":"ENUM","name":"Status"},"isDeprecated":true,"deprecationReason":"gone"}
+		]},
+		{"kind":"UNION","name":"Result","possibleTypes":[{"kind":"OBJECT","name":"Order"}]},
+		{"kind":"ENUM","name":"Status","enumValues":[{"name":"OPEN"},{"name":"CLOSED"}]},
+		{"kind":"INPUT_OBJECT","name":"OrderInput","inputFields":[{"name":"status","type":{"kind":"ENUM","name":"Status"},"defaultValue":"OPEN"}]}`,
+		`{"name":"cached","locations":["FIELD_DEFINITION"],"args":[{"name":"ttl","type":{"kind":"SCALAR","name":"Int"}}]}`)
+	s.MutationType = &introspectionTypeRef{Name: "Mutation"}
+	s.SubscriptionType = &introspectionTypeRef{Name: "Subscription"}
+
+	sdl := printIntrospectedSchema(s)
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: "test", Input: sdl})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, sdl)
+	}
+
+	if schema.Mutation == nil || schema.Mutation.Name != "Mutation" || schema.Subscription == nil || schema.Subscription.Name != "Subscription" {
+		t.Errorf("root types: mutation %v, subscription %v", schema.Mutation, schema.Subscription)
+	}
+	if got := schema.Types["OrderInput"].Fields.ForName("status").DefaultValue.String(); got != "OPEN" {
+		t.Errorf("default value = %s", got)
+	}
+	if schema.Directives["cached"] == nil {
+		t.Error("custom directive was not loaded")
+	}
+	if impls := schema.GetPossibleTypes(schema.Types["Node"]); len(impls) != 1 || impls[0].Name != "Order" {
+		t.Errorf("Node implementations = %v", impls)
+	}
+}
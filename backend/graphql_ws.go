@@ -0,0 +1,668 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// GraphQL over WebSocket subprotocols: graphql-transport-ws is the one the
+// graphql-ws library speaks, graphql-ws the legacy one from
+// subscriptions-transport-ws
+const (
+	graphQLTransportWS = "graphql-transport-ws"
+	graphQLWSLegacy    = "graphql-ws"
+)
+
+// GraphQLWSManager runs GraphQL subscriptions over WebSocket. It handles
+// the connection_init/ack handshake, keepalives and subscription ids, and
+// emits every next/error/complete as a stream message tagged with its
+// subscription.
+type GraphQLWSManager struct {
+	app         AppInterface
+	connections map[string]*GraphQLWSConnection
+	mu          sync.RWMutex
+	msgCounter  uint64 // Atomic counter for unique message IDs
+}
+
+type GraphQLWSConnection struct {
+	ID            string
+	URL           string
+	Subprotocol   string // negotiated with the server
+	Scopes        *VariableScopes
+	conn          *websocket.Conn
+	writeMu       sync.Mutex
+	ctx           context.Context
+	cancel        context.CancelFunc
+	subscriptions map[string]*graphQLSubscription // by operation id
+	nextID        uint64
+	mu            sync.Mutex
+}
+
+type graphQLSubscription struct {
+	id            string
+	operationName string
+	events        int
+}
+
+type GraphQLWSConnectRequest struct {
+	URL         string            `json:"url"`
+	Subprotocol string            `json:"subprotocol"` // "graphql-transport-ws", "graphql-ws" or empty to offer both
+	Headers     map[string]string `json:"headers"`
+	// ConnectionParams is a JSON object sent as the connection_init
+	// payload, where servers usually expect auth tokens
+	ConnectionParams string          `json:"connectionParams,omitempty"`
+	AckTimeout       int             `json:"ackTimeout"`   // milliseconds
+	PingInterval     int             `json:"pingInterval"` // milliseconds, graphql-transport-ws only; 0 turns pings off
+	TLSProfile       string          `json:"tlsProfile,omitempty"`
+	TLSSkipVerify    bool            `json:"tlsSkipVerify"`
+	Scopes           *VariableScopes `json:"scopes,omitempty"`
+}
+
+type GraphQLSubscribeRequest struct {
+	ConnectionID  string `json:"connectionId"`
+	Query         string `json:"query"`
+	OperationName string `json:"operationName,omitempty"`
+	Variables     string `json:"variables,omitempty"` // JSON object
+}
+
+// graphQLWSMessage is a frame of either subprotocol
+type graphQLWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func NewGraphQLWSManager(app AppInterface) *GraphQLWSManager {
+	return &GraphQLWSManager{
+		app:         app,
+		connections: make(map[string]*GraphQLWSConnection),
+	}
+}
+
+func (g *GraphQLWSManager) generateMessageID() string {
+	count := atomic.AddUint64(&g.msgCounter, 1)
+	return fmt.Sprintf("msg-%d-%d", time.Now().UnixNano(), count)
+}
+
+// Connect dials the endpoint and waits for connection_ack, so a rejected
+// init fails here rather than on the first subscription
+func (g *GraphQLWSManager) Connect(req GraphQLWSConnectRequest) (string, error) {
+	target, err := resolverFor(req.Scopes).ResolveGraphQLWSConnectRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	var initPayload json.RawMessage
+	if params := strings.TrimSpace(target.ConnectionParams); params != "" {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(params), &obj); err != nil {
+			return "", fmt.Errorf("connection params must be a JSON object: %w", err)
+		}
+		initPayload = json.RawMessage(params)
+	}
+
+	var subprotocols []string
+	switch target.Subprotocol {
+	case "":
+		subprotocols = []string{graphQLTransportWS, graphQLWSLegacy}
+	case graphQLTransportWS, graphQLWSLegacy:
+		subprotocols = []string{target.Subprotocol}
+	default:
+		return "", fmt.Errorf("unsupported subprotocol %q: use %s or %s", target.Subprotocol, graphQLTransportWS, graphQLWSLegacy)
+	}
+
+	headers := http.Header{}
+	for key, value := range target.Headers {
+		headers.Add(key, value)
+	}
+
+	tlsConfig, err := clientTLSConfig(g.app.GetDataDirectory(), req.TLSProfile, dialedHost(target.URL), &tls.Config{InsecureSkipVerify: req.TLSSkipVerify})
+	if err != nil {
+		return "", err
+	}
+
+	dialer := websocket.Dialer{
+		Subprotocols:     subprotocols,
+		HandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:  tlsConfig,
+	}
+
+	ws, resp, err := dialer.Dial(target.URL, headers)
+	if err != nil {
+		if resp != nil {
+			return "", fmt.Errorf("failed to connect: %w (HTTP %s)", err, resp.Status)
+		}
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	// servers that ignore the header still speak something; assume the
+	// first one offered
+	subprotocol := ws.Subprotocol()
+	if subprotocol == "" {
+		subprotocol = subprotocols[0]
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := &GraphQLWSConnection{
+		ID:            fmt.Sprintf("gqlws-%d", time.Now().UnixNano()),
+		URL:           target.URL,
+		Subprotocol:   subprotocol,
+		Scopes:        req.Scopes,
+		conn:          ws,
+		ctx:           ctx,
+		cancel:        cancel,
+		subscriptions: make(map[string]*graphQLSubscription),
+	}
+
+	ackTimeout := time.Duration(req.AckTimeout) * time.Millisecond
+	if ackTimeout <= 0 {
+		ackTimeout = 10 * time.Second
+	}
+	ackPayload, err := g.handshake(conn, initPayload, ackTimeout)
+	if err != nil {
+		cancel()
+		ws.Close()
+		return "", err
+	}
+
+	g.mu.Lock()
+	g.connections[conn.ID] = conn
+	g.mu.Unlock()
+
+	go g.readMessages(conn)
+
+	if subprotocol == graphQLTransportWS && req.PingInterval > 0 {
+		go g.sendPings(conn, time.Duration(req.PingInterval)*time.Millisecond)
+	}
+
+	metadata := map[string]interface{}{"subprotocol": subprotocol}
+	if len(ackPayload) > 0 {
+		metadata["ackPayload"] = ackPayload
+	}
+	g.emitSystem(conn, fmt.Sprintf("Connected to %s (%s)", target.URL, subprotocol), metadata)
+
+	return conn.ID, nil
+}
+
+// handshake sends connection_init and reads until connection_ack,
+// answering pings in the meantime
+func (g *GraphQLWSManager) handshake(conn *GraphQLWSConnection, payload json.RawMessage, timeout time.Duration) (json.RawMessage, error) {
+	if err := conn.write(graphQLWSMessage{Type: "connection_init", Payload: payload}); err != nil {
+		return nil, fmt.Errorf("failed to send connection_init: %w", err)
+	}
+
+	conn.conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.conn.SetReadDeadline(time.Time{})
+
+	for {
+		msg, err := conn.read()
+		if err != nil {
+			var netErr interface{ Timeout() bool }
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil, fmt.Errorf("server did not acknowledge the connection within %s", timeout)
+			}
+			return nil, fmt.Errorf("connection rejected: %s", graphQLWSCloseReason(err))
+		}
+
+		switch msg.Type {
+		case "connection_ack":
+			return msg.Payload, nil
+		case "ka":
+		case "ping":
+			if err := conn.write(graphQLWSMessage{Type: "pong", Payload: msg.Payload}); err != nil {
+				return nil, err
+			}
+		case "connection_error":
+			return nil, fmt.Errorf("connection rejected: %s", graphQLErrorText(parseGraphQLWSErrors(msg.Payload)))
+		default:
+			return nil, fmt.Errorf("unexpected %q message before connection_ack", msg.Type)
+		}
+	}
+}
+
+// Subscribe starts an operation and returns its id. Subscriptions run
+// until the server completes them or Unsubscribe is called; queries and
+// mutations complete after their single result.
+func (g *GraphQLWSManager) Subscribe(req GraphQLSubscribeRequest) (string, error) {
+	conn, err := g.lookup(req.ConnectionID)
+	if err != nil {
+		return "", err
+	}
+
+	req, err = resolverFor(conn.Scopes).ResolveGraphQLSubscribeRequest(req)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return "", fmt.Errorf("query is required")
+	}
+
+	variables := map[string]interface{}{}
+	if strings.TrimSpace(req.Variables) != "" {
+		if err := json.Unmarshal([]byte(req.Variables), &variables); err != nil {
+			return "", fmt.Errorf("variables must be a JSON object: %w", err)
+		}
+	}
+
+	doc, gqlErr := parser.ParseQuery(&ast.Source{Input: req.Query})
+	if gqlErr != nil {
+		return "", gqlErr
+	}
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return "", err
+	}
+
+	payload := map[string]interface{}{
+		"query":     req.Query,
+		"variables": variables,
+	}
+	if req.OperationName != "" {
+		payload["operationName"] = req.OperationName
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	sub := &graphQLSubscription{
+		id:            strconv.FormatUint(atomic.AddUint64(&conn.nextID, 1), 10),
+		operationName: op.Name,
+	}
+
+	// registered before sending so an immediate result finds it
+	conn.mu.Lock()
+	conn.subscriptions[sub.id] = sub
+	conn.mu.Unlock()
+
+	msgType := "subscribe"
+	if conn.Subprotocol == graphQLWSLegacy {
+		msgType = "start"
+	}
+	if err := conn.write(graphQLWSMessage{ID: sub.id, Type: msgType, Payload: raw}); err != nil {
+		conn.mu.Lock()
+		delete(conn.subscriptions, sub.id)
+		conn.mu.Unlock()
+		g.emitError(conn, fmt.Sprintf("Failed to subscribe: %s", err.Error()), nil)
+		return "", err
+	}
+
+	metadata := g.subscriptionMetadata(conn, sub, msgType)
+	metadata["operationType"] = string(op.Operation)
+	if len(variables) > 0 {
+		metadata["variables"] = variables
+	}
+	g.emitMessage(StreamMessage{
+		ID:        g.generateMessageID(),
+		Direction: "outbound",
+		Protocol:  "GraphQL",
+		Payload:   req.Query,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+
+	return sub.id, nil
+}
+
+// Unsubscribe stops one subscription and leaves the others running
+func (g *GraphQLWSManager) Unsubscribe(connectionID, subscriptionID string) error {
+	conn, err := g.lookup(connectionID)
+	if err != nil {
+		return err
+	}
+
+	sub := conn.endSubscription(subscriptionID)
+	if sub == nil {
+		return fmt.Errorf("subscription not found: %s", subscriptionID)
+	}
+
+	msgType := "complete"
+	if conn.Subprotocol == graphQLWSLegacy {
+		msgType = "stop"
+	}
+	if err := conn.write(graphQLWSMessage{ID: sub.id, Type: msgType}); err != nil {
+		g.emitError(conn, fmt.Sprintf("Failed to stop subscription %s: %s", sub.label(), err.Error()), nil)
+		return err
+	}
+
+	g.emitSystem(conn, fmt.Sprintf("Stopped subscription %s after %d events", sub.label(), sub.events), g.subscriptionMetadata(conn, sub, msgType))
+	return nil
+}
+
+func (g *GraphQLWSManager) Disconnect(connectionID string) error {
+	g.mu.Lock()
+	conn, ok := g.connections[connectionID]
+	if ok {
+		delete(g.connections, connectionID)
+	}
+	g.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("connection not found")
+	}
+
+	conn.cancel()
+
+	if conn.Subprotocol == graphQLWSLegacy {
+		conn.write(graphQLWSMessage{Type: "connection_terminate"})
+	}
+	conn.writeMu.Lock()
+	conn.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(100*time.Millisecond))
+	conn.writeMu.Unlock()
+	conn.conn.Close()
+
+	g.emitSystem(conn, "Disconnected", nil)
+	return nil
+}
+
+func (g *GraphQLWSManager) lookup(connectionID string) (*GraphQLWSConnection, error) {
+	g.mu.RLock()
+	conn, ok := g.connections[connectionID]
+	g.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("connection not found: %s", connectionID)
+	}
+	return conn, nil
+}
+
+func (g *GraphQLWSManager) readMessages(conn *GraphQLWSConnection) {
+	for {
+		msg, err := conn.read()
+		if err != nil {
+			select {
+			case <-conn.ctx.Done():
+				return
+			default:
+			}
+
+			// the server went away: nothing will complete the open
+			// subscriptions, so end them here
+			g.mu.Lock()
+			delete(g.connections, conn.ID)
+			g.mu.Unlock()
+			conn.cancel()
+			conn.conn.Close()
+
+			g.emitError(conn, fmt.Sprintf("Connection closed: %s", graphQLWSCloseReason(err)), map[string]interface{}{"closed": true})
+			conn.mu.Lock()
+			open := len(conn.subscriptions)
+			conn.subscriptions = make(map[string]*graphQLSubscription)
+			conn.mu.Unlock()
+			if open > 0 {
+				g.emitSystem(conn, fmt.Sprintf("%d open subscriptions ended with the connection", open), nil)
+			}
+			return
+		}
+
+		switch msg.Type {
+		case "next", "data":
+			g.onNext(conn, msg)
+		case "error":
+			g.onError(conn, msg)
+		case "complete":
+			if sub := conn.endSubscription(msg.ID); sub != nil {
+				g.emitSystem(conn, fmt.Sprintf("Subscription %s completed after %d events", sub.label(), sub.events), g.subscriptionMetadata(conn, sub, msg.Type))
+			}
+		case "ping":
+			if err := conn.write(graphQLWSMessage{Type: "pong", Payload: msg.Payload}); err != nil {
+				g.emitError(conn, fmt.Sprintf("Pong failed: %s", err.Error()), nil)
+			}
+		case "pong", "ka":
+		case "connection_error":
+			g.emitError(conn, fmt.Sprintf("Connection error: %s", graphQLErrorText(parseGraphQLWSErrors(msg.Payload))), nil)
+		default:
+			g.emitSystem(conn, fmt.Sprintf("Ignored unexpected %q message", msg.Type), map[string]interface{}{"frame": msg})
+		}
+	}
+}
+
+func (g *GraphQLWSManager) onNext(conn *GraphQLWSConnection, msg graphQLWSMessage) {
+	var event int
+	conn.mu.Lock()
+	sub, ok := conn.subscriptions[msg.ID]
+	if ok {
+		sub.events++
+		event = sub.events
+	}
+	conn.mu.Unlock()
+
+	if !ok {
+		// results can still arrive for a subscription we just stopped
+		return
+	}
+
+	var result struct {
+		Data       interface{}            `json:"data"`
+		Errors     []GraphQLError         `json:"errors"`
+		Extensions map[string]interface{} `json:"extensions"`
+	}
+	json.Unmarshal(msg.Payload, &result)
+
+	metadata := g.subscriptionMetadata(conn, sub, msg.Type)
+	metadata["event"] = event
+	metadata["data"] = result.Data
+	if len(result.Errors) > 0 {
+		metadata["errors"] = result.Errors
+	}
+	if len(result.Extensions) > 0 {
+		metadata["extensions"] = result.Extensions
+	}
+
+	payload := string(msg.Payload)
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, msg.Payload, "", "  ") == nil {
+		payload = pretty.String()
+	}
+
+	g.emitMessage(StreamMessage{
+		ID:        g.generateMessageID(),
+		Direction: "inbound",
+		Protocol:  "GraphQL",
+		Payload:   payload,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+// onError handles an operation error, which ends the subscription
+func (g *GraphQLWSManager) onError(conn *GraphQLWSConnection, msg graphQLWSMessage) {
+	sub := conn.endSubscription(msg.ID)
+	if sub == nil {
+		sub = &graphQLSubscription{id: msg.ID}
+	}
+
+	errs := parseGraphQLWSErrors(msg.Payload)
+	metadata := g.subscriptionMetadata(conn, sub, msg.Type)
+	metadata["errors"] = errs
+
+	g.emitError(conn, fmt.Sprintf("Subscription %s failed: %s", sub.label(), graphQLErrorText(errs)), metadata)
+}
+
+func (g *GraphQLWSManager) sendPings(conn *GraphQLWSConnection, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-conn.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.write(graphQLWSMessage{Type: "ping"}); err != nil {
+				g.emitError(conn, fmt.Sprintf("Ping failed: %s", err.Error()), nil)
+				return
+			}
+		}
+	}
+}
+
+func (g *GraphQLWSManager) subscriptionMetadata(conn *GraphQLWSConnection, sub *graphQLSubscription, msgType string) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"connectionId":   conn.ID,
+		"subscriptionId": sub.id,
+		"type":           msgType,
+	}
+	if sub.operationName != "" {
+		metadata["operationName"] = sub.operationName
+	}
+	return metadata
+}
+
+func (g *GraphQLWSManager) emitSystem(conn *GraphQLWSConnection, text string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["connectionId"] = conn.ID
+
+	g.emitMessage(StreamMessage{
+		ID:        g.generateMessageID(),
+		Direction: "system",
+		Protocol:  "GraphQL",
+		Payload:   text,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+func (g *GraphQLWSManager) emitError(conn *GraphQLWSConnection, text string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["connectionId"] = conn.ID
+
+	g.emitMessage(StreamMessage{
+		ID:        g.generateMessageID(),
+		Direction: "error",
+		Protocol:  "GraphQL",
+		Payload:   text,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+// emitMessage emits on the caller's goroutine, so a subscription's results
+// and its completion reach the viewer in the order the server sent them
+func (g *GraphQLWSManager) emitMessage(msg StreamMessage) {
+	if g.app == nil || g.app.GetCtx() == nil {
+		fmt.Printf("[GraphQL WS] Cannot emit message - app context not initialized yet\n")
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("[GraphQL WS] Event emit panic recovered: %v\n", r)
+		}
+	}()
+	emitStreamEvent(g.app.GetCtx(), msg)
+}
+
+func (c *GraphQLWSConnection) write(msg graphQLWSMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *GraphQLWSConnection) read() (graphQLWSMessage, error) {
+	var msg graphQLWSMessage
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return msg, err
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return msg, fmt.Errorf("invalid message from server: %w", err)
+	}
+	return msg, nil
+}
+
+// endSubscription forgets a subscription and returns a copy of it as it
+// ended, or nil when it already had. The copy's event count is safe to read
+// without the lock while the read loop is still running.
+func (c *GraphQLWSConnection) endSubscription(id string) *graphQLSubscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sub, ok := c.subscriptions[id]
+	if !ok {
+		return nil
+	}
+	delete(c.subscriptions, id)
+	ended := *sub
+	return &ended
+}
+
+func (s *graphQLSubscription) label() string {
+	if s.operationName != "" {
+		return fmt.Sprintf("%s (#%s)", s.operationName, s.id)
+	}
+	return "#" + s.id
+}
+
+// parseGraphQLWSErrors reads an error payload: a list of GraphQL errors on
+// graphql-transport-ws, usually a single error object on the legacy
+// protocol
+func parseGraphQLWSErrors(payload json.RawMessage) []GraphQLError {
+	var list []GraphQLError
+	if json.Unmarshal(payload, &list) == nil {
+		return list
+	}
+	var single GraphQLError
+	if json.Unmarshal(payload, &single) == nil && single.Message != "" {
+		return []GraphQLError{single}
+	}
+	if len(payload) > 0 {
+		return []GraphQLError{{Message: string(payload)}}
+	}
+	return nil
+}
+
+func graphQLErrorText(errs []GraphQLError) string {
+	if len(errs) == 0 {
+		return "unknown error"
+	}
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// graphQLWSCloseReason describes why the server closed the socket, naming
+// the close codes the GraphQL subprotocols define
+func graphQLWSCloseReason(err error) string {
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) {
+		return err.Error()
+	}
+
+	reason := map[int]string{
+		4400: "invalid message",
+		4401: "unauthorized",
+		4403: "forbidden",
+		4406: "subprotocol not acceptable",
+		4408: "connection initialisation timeout",
+		4409: "subscriber for that id already exists",
+		4429: "too many initialisation requests",
+		4500: "internal server error",
+	}[closeErr.Code]
+	if reason == "" {
+		return closeErr.Error()
+	}
+	if closeErr.Text != "" && !strings.EqualFold(closeErr.Text, reason) {
+		return fmt.Sprintf("%d %s (%s)", closeErr.Code, reason, closeErr.Text)
+	}
+	return fmt.Sprintf("%d %s", closeErr.Code, reason)
+}
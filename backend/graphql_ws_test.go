@@ -0,0 +1,421 @@
+package backend
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// graphQLWSServer speaks subprotocol over TLS with a certificate no system
+// root trusts. Every frame a client sends, connection_init included, goes to
+// serve, which answers on ws; a nil serve acknowledges the init and ignores
+// the rest.
+func graphQLWSServer(t *testing.T, subprotocol string, serve func(ws *graphQLWSPeer, msg graphQLWSMessage)) *httptest.Server {
+	t.Helper()
+
+	if serve == nil {
+		serve = func(ws *graphQLWSPeer, msg graphQLWSMessage) {
+			if msg.Type == "connection_init" {
+				ws.send(graphQLWSMessage{Type: "connection_ack"})
+			}
+		}
+	}
+
+	upgrader := websocket.Upgrader{Subprotocols: []string{subprotocol}}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		peer := &graphQLWSPeer{ws: ws}
+		for {
+			var msg graphQLWSMessage
+			if err := ws.ReadJSON(&msg); err != nil {
+				return
+			}
+			serve(peer, msg)
+		}
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // the rejected handshake is expected
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// graphQLWSPeer is the server's end of a connection. Writes are locked so
+// serve can hand a subscription to its own goroutine.
+type graphQLWSPeer struct {
+	ws *websocket.Conn
+	mu sync.Mutex
+}
+
+func (p *graphQLWSPeer) send(msg graphQLWSMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ws.WriteJSON(msg)
+}
+
+func (p *graphQLWSPeer) close(code int, text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+}
+
+func graphQLWSURL(server *httptest.Server) string {
+	return "wss" + strings.TrimPrefix(server.URL, "https")
+}
+
+// connectGraphQLWS connects with verification skipped and disconnects when
+// the test ends
+func connectGraphQLWS(t *testing.T, g *GraphQLWSManager, req GraphQLWSConnectRequest) string {
+	t.Helper()
+
+	req.TLSSkipVerify = true
+	id, err := g.Connect(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { g.Disconnect(id) })
+	return id
+}
+
+// graphQLResult matches a subscription result, next or legacy data
+func graphQLResult(msg StreamMessage) bool {
+	return msg.Direction == "inbound" && msg.Protocol == "GraphQL"
+}
+
+func TestGraphQLWSVerifiesCertificates(t *testing.T) {
+	server := graphQLWSServer(t, graphQLTransportWS, nil)
+	url := graphQLWSURL(server)
+
+	app, _ := newTestApp(t)
+	g := NewGraphQLWSManager(app)
+
+	if _, err := g.Connect(GraphQLWSConnectRequest{URL: url}); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("connect to an untrusted server: err = %v, want a certificate error", err)
+	}
+
+	id, err := g.Connect(GraphQLWSConnectRequest{URL: url, TLSSkipVerify: true})
+	if err != nil {
+		t.Fatalf("connect with verification skipped: %v", err)
+	}
+	if err := g.Disconnect(id); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGraphQLWSConnectionInit(t *testing.T) {
+	var initPayload json.RawMessage
+	server := graphQLWSServer(t, graphQLTransportWS, func(ws *graphQLWSPeer, msg graphQLWSMessage) {
+		if msg.Type == "connection_init" {
+			initPayload = msg.Payload
+			ws.send(graphQLWSMessage{Type: "ping"})
+			ws.send(graphQLWSMessage{Type: "connection_ack", Payload: json.RawMessage(`{"server":"test"}`)})
+		}
+	})
+
+	app, events := newTestApp(t)
+	g := NewGraphQLWSManager(app)
+	connectGraphQLWS(t, g, GraphQLWSConnectRequest{URL: graphQLWSURL(server), ConnectionParams: `{"token":"secret"}`})
+
+	if string(initPayload) != `{"token":"secret"}` {
+		t.Errorf("connection_init payload = %s", initPayload)
+	}
+	msg := waitForMessage(t, events, systemMessage("Connected to"))
+	if msg.Metadata["subprotocol"] != graphQLTransportWS {
+		t.Errorf("subprotocol = %v", msg.Metadata["subprotocol"])
+	}
+	if ack, _ := msg.Metadata["ackPayload"].(json.RawMessage); string(ack) != `{"server":"test"}` {
+		t.Errorf("ack payload = %s", ack)
+	}
+
+	if _, err := g.Connect(GraphQLWSConnectRequest{URL: graphQLWSURL(server), ConnectionParams: `["token"]`}); err == nil || !strings.Contains(err.Error(), "JSON object") {
+		t.Errorf("connection params that aren't an object: err = %v", err)
+	}
+}
+
+func TestGraphQLWSConnectionRejected(t *testing.T) {
+	tests := []struct {
+		name  string
+		serve func(ws *graphQLWSPeer, msg graphQLWSMessage)
+		want  string
+	}{
+		{
+			name: "connection_error",
+			serve: func(ws *graphQLWSPeer, msg graphQLWSMessage) {
+				ws.send(graphQLWSMessage{Type: "connection_error", Payload: json.RawMessage(`{"message":"bad token"}`)})
+			},
+			want: "connection rejected: bad token",
+		},
+		{
+			name: "close code",
+			serve: func(ws *graphQLWSPeer, msg graphQLWSMessage) {
+				ws.close(4403, "Forbidden")
+			},
+			want: "connection rejected: 4403 forbidden",
+		},
+		{
+			name:  "no ack",
+			serve: func(*graphQLWSPeer, graphQLWSMessage) {},
+			want:  "did not acknowledge the connection within 100ms",
+		},
+		{
+			name: "other message first",
+			serve: func(ws *graphQLWSPeer, msg graphQLWSMessage) {
+				ws.send(graphQLWSMessage{ID: "1", Type: "next"})
+			},
+			want: `unexpected "next" message before connection_ack`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := graphQLWSServer(t, graphQLTransportWS, tt.serve)
+			app, _ := newTestApp(t)
+			g := NewGraphQLWSManager(app)
+
+			_, err := g.Connect(GraphQLWSConnectRequest{URL: graphQLWSURL(server), AckTimeout: 100, TLSSkipVerify: true})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphQLWSSubscription(t *testing.T) {
+	const count = 50
+	server := graphQLWSServer(t, graphQLTransportWS, func(ws *graphQLWSPeer, msg graphQLWSMessage) {
+		switch msg.Type {
+		case "connection_init":
+			ws.send(graphQLWSMessage{Type: "connection_ack"})
+		case "subscribe":
+			for i := 1; i <= count; i++ {
+				ws.send(graphQLWSMessage{ID: msg.ID, Type: "next", Payload: json.RawMessage(`{"data":{"tick":` + strconv.Itoa(i) + `}}`)})
+			}
+			ws.send(graphQLWSMessage{ID: msg.ID, Type: "complete"})
+		}
+	})
+
+	app, events := newTestApp(t)
+	g := NewGraphQLWSManager(app)
+	connID := connectGraphQLWS(t, g, GraphQLWSConnectRequest{URL: graphQLWSURL(server)})
+
+	subID, err := g.Subscribe(GraphQLSubscribeRequest{
+		ConnectionID: connID,
+		Query:        `subscription Ticks($every: Int) { tick(every: $every) }`,
+		Variables:    `{"every":1}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := waitForMessage(t, events, func(msg StreamMessage) bool { return msg.Direction == "outbound" })
+	if sent.Metadata["type"] != "subscribe" || sent.Metadata["subscriptionId"] != subID || sent.Metadata["operationType"] != "subscription" {
+		t.Errorf("outbound metadata = %v", sent.Metadata)
+	}
+
+	// results are emitted in the order they arrived, then the completion
+	for i := 1; i <= count; i++ {
+		msg := waitForMessage(t, events, graphQLResult)
+		if msg.Metadata["event"] != i {
+			t.Fatalf("result %d has event %v", i, msg.Metadata["event"])
+		}
+		if data := msg.Metadata["data"].(map[string]interface{}); data["tick"] != float64(i) {
+			t.Fatalf("result %d has data %v", i, data)
+		}
+		if msg.Metadata["operationName"] != "Ticks" || msg.Metadata["type"] != "next" {
+			t.Fatalf("result metadata = %v", msg.Metadata)
+		}
+	}
+	waitForMessage(t, events, systemMessage("Subscription Ticks (#1) completed after 50 events"))
+
+	if err := g.Unsubscribe(connID, subID); err == nil {
+		t.Error("unsubscribed from a completed subscription")
+	}
+	if _, err := g.Subscribe(GraphQLSubscribeRequest{ConnectionID: connID, Query: `subscription { tick`}); err == nil {
+		t.Error("subscribed with a query that doesn't parse")
+	}
+}
+
+func TestGraphQLWSOperationError(t *testing.T) {
+	server := graphQLWSServer(t, graphQLTransportWS, func(ws *graphQLWSPeer, msg graphQLWSMessage) {
+		switch msg.Type {
+		case "connection_init":
+			ws.send(graphQLWSMessage{Type: "connection_ack"})
+		case "subscribe":
+			ws.send(graphQLWSMessage{ID: msg.ID, Type: "error", Payload: json.RawMessage(`[{"message":"Cannot query field \"nope\""},{"message":"second"}]`)})
+		}
+	})
+
+	app, events := newTestApp(t)
+	g := NewGraphQLWSManager(app)
+	connID := connectGraphQLWS(t, g, GraphQLWSConnectRequest{URL: graphQLWSURL(server)})
+
+	subID, err := g.Subscribe(GraphQLSubscribeRequest{ConnectionID: connID, Query: `subscription { nope }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := waitForMessage(t, events, systemMessage("Subscription #1 failed"))
+	if msg.Direction != "error" || !strings.HasSuffix(msg.Payload, `Cannot query field "nope"; second`) {
+		t.Errorf("error message = %s %q", msg.Direction, msg.Payload)
+	}
+	if errs, _ := msg.Metadata["errors"].([]GraphQLError); len(errs) != 2 {
+		t.Errorf("errors = %v", msg.Metadata["errors"])
+	}
+
+	// the error ended the subscription
+	if err := g.Unsubscribe(connID, subID); err == nil || !strings.Contains(err.Error(), "subscription not found") {
+		t.Errorf("unsubscribe after an error: err = %v", err)
+	}
+}
+
+func TestGraphQLWSUnsubscribe(t *testing.T) {
+	stopped := make(chan string, 1)
+	server := graphQLWSServer(t, graphQLTransportWS, func(ws *graphQLWSPeer, msg graphQLWSMessage) {
+		switch msg.Type {
+		case "connection_init":
+			ws.send(graphQLWSMessage{Type: "connection_ack"})
+		case "subscribe":
+			// results keep coming while the client unsubscribes
+			go func() {
+				for i := 0; i < 200; i++ {
+					if ws.send(graphQLWSMessage{ID: msg.ID, Type: "next", Payload: json.RawMessage(`{"data":{"tick":1}}`)}) != nil {
+						return
+					}
+				}
+			}()
+		case "complete":
+			stopped <- msg.ID
+		}
+	})
+
+	app, events := newTestApp(t)
+	g := NewGraphQLWSManager(app)
+	connID := connectGraphQLWS(t, g, GraphQLWSConnectRequest{URL: graphQLWSURL(server)})
+
+	subID, err := g.Subscribe(GraphQLSubscribeRequest{ConnectionID: connID, Query: `subscription { tick }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForMessage(t, events, graphQLResult)
+
+	if err := g.Unsubscribe(connID, subID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id := <-stopped:
+		if id != subID {
+			t.Errorf("server got complete for %s, want %s", id, subID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server never got complete")
+	}
+	waitForMessage(t, events, systemMessage("Stopped subscription #1 after"))
+}
+
+func TestGraphQLWSLegacyProtocol(t *testing.T) {
+	frames := make(chan graphQLWSMessage, 16)
+	server := graphQLWSServer(t, graphQLWSLegacy, func(ws *graphQLWSPeer, msg graphQLWSMessage) {
+		frames <- msg
+		switch msg.Type {
+		case "connection_init":
+			ws.send(graphQLWSMessage{Type: "connection_ack"})
+			ws.send(graphQLWSMessage{Type: "ka"})
+		case "start":
+			ws.send(graphQLWSMessage{ID: msg.ID, Type: "data", Payload: json.RawMessage(`{"data":{"tick":1}}`)})
+			ws.send(graphQLWSMessage{ID: msg.ID, Type: "data", Payload: json.RawMessage(`{"data":{"tick":2}}`)})
+		case "stop":
+			// a legacy server may still send a result that was in flight
+			ws.send(graphQLWSMessage{ID: msg.ID, Type: "data", Payload: json.RawMessage(`{"data":{"tick":3}}`)})
+			ws.send(graphQLWSMessage{ID: msg.ID, Type: "complete"})
+		}
+	})
+
+	app, events := newTestApp(t)
+	g := NewGraphQLWSManager(app)
+
+	connID, err := g.Connect(GraphQLWSConnectRequest{URL: graphQLWSURL(server), TLSSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := waitForMessage(t, events, systemMessage("Connected to")); msg.Metadata["subprotocol"] != graphQLWSLegacy {
+		t.Errorf("subprotocol = %v", msg.Metadata["subprotocol"])
+	}
+
+	subID, err := g.Subscribe(GraphQLSubscribeRequest{ConnectionID: connID, Query: `subscription { tick }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 2; i++ {
+		msg := waitForMessage(t, events, graphQLResult)
+		if msg.Metadata["type"] != "data" || msg.Metadata["event"] != i {
+			t.Fatalf("result %d metadata = %v", i, msg.Metadata)
+		}
+	}
+
+	if err := g.Unsubscribe(connID, subID); err != nil {
+		t.Fatal(err)
+	}
+	msg := waitForMessage(t, events, systemMessage("Stopped subscription #1 after 2 events"))
+	if msg.Metadata["type"] != "stop" {
+		t.Errorf("stop metadata = %v", msg.Metadata)
+	}
+
+	if err := g.Disconnect(connID); err != nil {
+		t.Fatal(err)
+	}
+	waitForMessage(t, events, systemMessage("Disconnected"))
+
+	var types []string
+	timeout := time.After(5 * time.Second)
+	for len(types) == 0 || types[len(types)-1] != "connection_terminate" {
+		select {
+		case msg := <-frames:
+			types = append(types, msg.Type)
+		case <-timeout:
+			t.Fatalf("server got %v, never connection_terminate", types)
+		}
+	}
+	if want := "connection_init start stop connection_terminate"; strings.Join(types, " ") != want {
+		t.Errorf("server got %v, want %s", types, want)
+	}
+}
+
+func TestGraphQLWSServerGoesAway(t *testing.T) {
+	server := graphQLWSServer(t, graphQLTransportWS, func(ws *graphQLWSPeer, msg graphQLWSMessage) {
+		switch msg.Type {
+		case "connection_init":
+			ws.send(graphQLWSMessage{Type: "connection_ack"})
+		case "subscribe":
+			ws.close(4500, "")
+		}
+	})
+
+	app, events := newTestApp(t)
+	g := NewGraphQLWSManager(app)
+	connID := connectGraphQLWS(t, g, GraphQLWSConnectRequest{URL: graphQLWSURL(server)})
+
+	if _, err := g.Subscribe(GraphQLSubscribeRequest{ConnectionID: connID, Query: `subscription { tick }`}); err != nil {
+		t.Fatal(err)
+	}
+	msg := waitForMessage(t, events, systemMessage("Connection closed: 4500 internal server error"))
+	if msg.Metadata["closed"] != true {
+		t.Errorf("close metadata = %v", msg.Metadata)
+	}
+	waitForMessage(t, events, systemMessage("1 open subscriptions ended with the connection"))
+
+	if _, err := g.Subscribe(GraphQLSubscribeRequest{ConnectionID: connID, Query: `subscription { tick }`}); err == nil {
+		t.Error("subscribed on a closed connection")
+	}
+}
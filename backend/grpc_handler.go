@@ -9,17 +9,15 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
 	"github.com/jhump/protoreflect/desc"
-	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/jhump/protoreflect/dynamic"
 	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
-	"github.com/jhump/protoreflect/grpcreflect"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
 // GrpcStreamManager handles gRPC connections and streaming
@@ -32,27 +30,53 @@ type GrpcStreamManager struct {
 
 // GrpcConnection holds an active gRPC connection
 type GrpcConnection struct {
-	ID         string
-	ServerURL  string
-	Conn       *grpc.ClientConn
-	Stub       grpcdynamic.Stub
-	MethodDesc *desc.MethodDescriptor
-	StreamType string // "unary", "server", "client", "bidi"
-	Context    context.Context
-	Cancel     context.CancelFunc
-	Metadata   metadata.MD
+	ID           string
+	ServerURL    string
+	Conn         *grpc.ClientConn
+	Stub         grpcdynamic.Stub
+	MethodDesc   *desc.MethodDescriptor
+	StreamType   string // "unary", "server", "client", "bidi"
+	Context      context.Context
+	Cancel       context.CancelFunc
+	Metadata     metadata.MD
+	Scopes       *VariableScopes
+	Deadline     time.Duration
+	ClientStream *grpcdynamic.ClientStream
+	BidiStream   *grpcdynamic.BidiStream
+	streamCancel context.CancelFunc
+	sendMu       sync.Mutex
+	status       GrpcStreamStatus
+	statusMu     sync.Mutex
+}
+
+// GrpcStreamStatus reports the lifecycle of a connection's call or stream.
+// Headers arrive with the first response; Status and Trailers are filled in
+// once the server finishes the call.
+type GrpcStreamStatus struct {
+	ConnectionID     string              `json:"connectionId"`
+	StreamType       string              `json:"streamType"`
+	State            string              `json:"state"` // "open", "half-closed", "closed"
+	MessagesSent     int                 `json:"messagesSent"`
+	MessagesReceived int                 `json:"messagesReceived"`
+	StatusCode       string              `json:"statusCode,omitempty"`
+	StatusMessage    string              `json:"statusMessage,omitempty"`
+	Status           *GrpcStatusInfo     `json:"status,omitempty"`
+	Headers          map[string][]string `json:"headers,omitempty"`
+	Trailers         map[string][]string `json:"trailers,omitempty"`
 }
 
 // ProtoRegistry keeps track of proto files and descriptors
 type ProtoRegistry struct {
 	files    map[string]*desc.FileDescriptor
 	services map[string]*desc.ServiceDescriptor
+	sets     map[string]*protoSet
 	mu       sync.RWMutex
 }
 
-
 type ProtoFileUploadRequest struct {
-	Files []ProtoFile `json:"files"`
+	Files        []ProtoFile `json:"files"`
+	ImportPaths  []string    `json:"importPaths,omitempty"`  // local directories searched for imports
+	BufWorkspace string      `json:"bufWorkspace,omitempty"` // directory holding buf.yaml or buf.work.yaml
 }
 
 type ProtoFile struct {
@@ -61,12 +85,18 @@ type ProtoFile struct {
 }
 
 type ParsedProtoResponse struct {
-	Services []ServiceInfo `json:"services"`
+	ProtoSetID  string            `json:"protoSetId,omitempty"`
+	Services    []ServiceInfo     `json:"services"`
+	Diagnostics []ProtoDiagnostic `json:"diagnostics,omitempty"` // warnings; errors fail the parse
+
+	ReflectionVersion string             `json:"reflectionVersion,omitempty"` // "v1" or "v1alpha"
+	ServiceErrors     []GrpcServiceError `json:"serviceErrors,omitempty"`
 }
 
 type ServiceInfo struct {
-	Name    string       `json:"name"`
-	Methods []MethodInfo `json:"methods"`
+	Name     string       `json:"name"`
+	FullName string       `json:"fullName"`
+	Methods  []MethodInfo `json:"methods"`
 }
 
 type MethodInfo struct {
@@ -84,6 +114,9 @@ type GrpcConnectRequest struct {
 	Deadline    int               `json:"deadline"` // milliseconds
 	Compression string            `json:"compression"`
 	Metadata    map[string]string `json:"metadata"`
+	TLSProfile  string            `json:"tlsProfile,omitempty"`
+	ProtoSetID  string            `json:"protoSetId,omitempty"` // look the service up in this set
+	Scopes      *VariableScopes   `json:"scopes,omitempty"`
 }
 
 type GrpcSendMessageRequest struct {
@@ -98,152 +131,76 @@ func NewGrpcStreamManager(app AppInterface) *GrpcStreamManager {
 		protoRegistry: &ProtoRegistry{
 			files:    make(map[string]*desc.FileDescriptor),
 			services: make(map[string]*desc.ServiceDescriptor),
+			sets:     make(map[string]*protoSet),
 		},
 	}
 }
 
 func (g *GrpcStreamManager) ParseProtoFiles(req ProtoFileUploadRequest) (*ParsedProtoResponse, error) {
-	g.protoRegistry.mu.Lock()
-	defer g.protoRegistry.mu.Unlock()
-
-	parser := protoparse.Parser{
-		ImportPaths:           []string{},
-		IncludeSourceCodeInfo: true,
-	}
-
-	var protoFileNames []string
-	fileContents := make(map[string]string)
-
-	for _, file := range req.Files {
-		protoFileNames = append(protoFileNames, file.Name)
-		fileContents[file.Name] = file.Content
+	sources, protoFileNames, err := newProtoSources(req)
+	if err != nil {
+		return nil, err
 	}
 
-	parser.Accessor = protoparse.FileContentsFromMap(fileContents)
-
+	parser := sources.parser()
 	fileDescriptors, err := parser.ParseFiles(protoFileNames...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse proto files: %w", err)
+		return nil, sources.failure(err)
 	}
 
+	g.protoRegistry.mu.Lock()
+	defer g.protoRegistry.mu.Unlock()
+
+	set := newProtoSet("files", "", req.Files)
+
 	for _, fd := range fileDescriptors {
-		g.protoRegistry.files[fd.GetName()] = fd
+		g.registerFile(fd)
+		set.addFile(fd)
 
 		for _, svc := range fd.GetServices() {
 			g.protoRegistry.services[svc.GetFullyQualifiedName()] = svc
+			set.addService(svc)
 		}
 	}
 
-	response := &ParsedProtoResponse{
-		Services: []ServiceInfo{},
-	}
-
-	for _, svc := range g.protoRegistry.services {
-		serviceInfo := ServiceInfo{
-			Name:    svc.GetName(),
-			Methods: []MethodInfo{},
-		}
-
-		for _, method := range svc.GetMethods() {
-			methodType := getMethodType(method)
-
-			serviceInfo.Methods = append(serviceInfo.Methods, MethodInfo{
-				Name:       method.GetName(),
-				Type:       methodType,
-				InputType:  method.GetInputType().GetFullyQualifiedName(),
-				OutputType: method.GetOutputType().GetFullyQualifiedName(),
-			})
-		}
-
-		response.Services = append(response.Services, serviceInfo)
-	}
+	g.addUnsavedSet(set)
 
+	response := set.response()
+	response.Diagnostics = sources.warnings()
 	return response, nil
 }
 
-func (g *GrpcStreamManager) UseReflection(serverURL string, useTLS bool) (*ParsedProtoResponse, error) {
-	var opts []grpc.DialOption
-	if useTLS {
-		creds := credentials.NewTLS(nil)
-		opts = append(opts, grpc.WithTransportCredentials(creds))
-	} else {
-		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	}
-
-	conn, err := grpc.Dial(serverURL, opts...)
+func (g *GrpcStreamManager) Connect(req GrpcConnectRequest) (string, error) {
+	req, err := resolverFor(req.Scopes).ResolveGrpcConnectRequest(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect: %w", err)
+		return "", err
 	}
-	defer conn.Close()
-
-	refClient := grpcreflect.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn))
-	defer refClient.Reset()
 
-	services, err := refClient.ListServices()
+	opts, err := g.dialOptions(req.ServerURL, req.UseTLS, req.TLSProfile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list services: %w", err)
+		return "", err
 	}
 
-	response := &ParsedProtoResponse{
-		Services: []ServiceInfo{},
+	conn, err := grpc.Dial(req.ServerURL, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
 	}
 
-	g.protoRegistry.mu.Lock()
-	defer g.protoRegistry.mu.Unlock()
-
-	for _, serviceName := range services {
-		if serviceName == "grpc.reflection.v1alpha.ServerReflection" {
-			continue
-		}
-
-		svcDesc, err := refClient.ResolveService(serviceName)
+	var svc *desc.ServiceDescriptor
+	if req.ProtoSetID != "" {
+		services, err := g.lookupProtoSet(req.ProtoSetID)
 		if err != nil {
-			continue
+			conn.Close()
+			return "", err
 		}
-
-		g.protoRegistry.services[serviceName] = svcDesc
-
-		serviceInfo := ServiceInfo{
-			Name:    svcDesc.GetName(),
-			Methods: []MethodInfo{},
-		}
-
-		for _, method := range svcDesc.GetMethods() {
-			methodType := getMethodType(method)
-
-			serviceInfo.Methods = append(serviceInfo.Methods, MethodInfo{
-				Name:       method.GetName(),
-				Type:       methodType,
-				InputType:  method.GetInputType().GetFullyQualifiedName(),
-				OutputType: method.GetOutputType().GetFullyQualifiedName(),
-			})
-		}
-
-		response.Services = append(response.Services, serviceInfo)
-	}
-
-	return response, nil
-}
-
-func (g *GrpcStreamManager) Connect(req GrpcConnectRequest) (string, error) {
-	var opts []grpc.DialOption
-	if req.UseTLS {
-		creds := credentials.NewTLS(nil)
-		opts = append(opts, grpc.WithTransportCredentials(creds))
+		svc = findService(services, req.Service)
 	} else {
-		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		g.protoRegistry.mu.RLock()
+		svc = findService(g.protoRegistry.services, req.Service)
+		g.protoRegistry.mu.RUnlock()
 	}
 
-	conn, err := grpc.Dial(req.ServerURL, opts...)
-	if err != nil {
-		return "", fmt.Errorf("failed to connect: %w", err)
-	}
-
-	g.protoRegistry.mu.RLock()
-	svc, ok := g.protoRegistry.services[req.Service]
-	g.protoRegistry.mu.RUnlock()
-
-	if !ok {
+	if svc == nil {
 		conn.Close()
 		return "", fmt.Errorf("service not found: %s", req.Service)
 	}
@@ -254,20 +211,15 @@ func (g *GrpcStreamManager) Connect(req GrpcConnectRequest) (string, error) {
 		return "", fmt.Errorf("method not found: %s", req.Method)
 	}
 
-	ctx := context.Background()
-	if req.Deadline > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Deadline)*time.Millisecond)
-		defer cancel()
-	}
-
+	// The deadline applies per call (or per stream for client and bidi
+	// methods), so the connection context itself only carries metadata.
 	md := metadata.New(req.Metadata)
-	ctx = metadata.NewOutgoingContext(ctx, md)
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(context.Background(), md))
 
 	stub := grpcdynamic.NewStub(conn)
 
 	connID := fmt.Sprintf("grpc-%d", time.Now().UnixNano())
-	ctx, cancel := context.WithCancel(ctx)
+	streamType := getMethodType(methodDesc)
 
 	grpcConn := &GrpcConnection{
 		ID:         connID,
@@ -275,10 +227,25 @@ func (g *GrpcStreamManager) Connect(req GrpcConnectRequest) (string, error) {
 		Conn:       conn,
 		Stub:       stub,
 		MethodDesc: methodDesc,
-		StreamType: getMethodType(methodDesc),
+		StreamType: streamType,
 		Context:    ctx,
 		Cancel:     cancel,
 		Metadata:   md,
+		Scopes:     req.Scopes,
+		Deadline:   time.Duration(req.Deadline) * time.Millisecond,
+		status: GrpcStreamStatus{
+			ConnectionID: connID,
+			StreamType:   streamType,
+			State:        "open",
+		},
+	}
+
+	if streamType == "client" || streamType == "bidi" {
+		if err := g.openStream(grpcConn); err != nil {
+			cancel()
+			conn.Close()
+			return "", err
+		}
 	}
 
 	g.mu.Lock()
@@ -305,6 +272,12 @@ func (g *GrpcStreamManager) SendMessage(req GrpcSendMessageRequest) error {
 		return fmt.Errorf("connection not found: %s", req.ConnectionID)
 	}
 
+	message, err := resolverFor(conn.Scopes).ResolveField("message", req.Message)
+	if err != nil {
+		return err
+	}
+	req.Message = message
+
 	var msgData map[string]interface{}
 	if err := json.Unmarshal([]byte(req.Message), &msgData); err != nil {
 		return fmt.Errorf("invalid JSON: %w", err)
@@ -338,7 +311,17 @@ func (g *GrpcStreamManager) SendMessage(req GrpcSendMessageRequest) error {
 }
 
 func (g *GrpcStreamManager) handleUnary(conn *GrpcConnection, inputMsg *dynamic.Message) error {
-	outputMsg, err := conn.Stub.InvokeRpc(conn.Context, conn.MethodDesc, inputMsg)
+	ctx, cancel := g.callContext(conn)
+	defer cancel()
+
+	conn.recordSent()
+
+	var header, trailer metadata.MD
+	outputMsg, err := conn.Stub.InvokeRpc(ctx, conn.MethodDesc, inputMsg, grpc.Header(&header), grpc.Trailer(&trailer))
+
+	st := g.statusInfo(err)
+	conn.recordStatus(st, header, trailer)
+
 	if err != nil {
 		g.emitMessage(StreamMessage{
 			ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
@@ -346,91 +329,369 @@ func (g *GrpcStreamManager) handleUnary(conn *GrpcConnection, inputMsg *dynamic.
 			Protocol:  "gRPC",
 			Payload:   err.Error(),
 			Timestamp: time.Now(),
+			Metadata:  callMetadata(header, trailer, st),
 		})
 		return err
 	}
 
-	jsonData, err := outputMsg.(*dynamic.Message).MarshalJSONPB(&jsonpb.Marshaler{})
-	if err != nil {
-		jsonData = []byte(outputMsg.String())
-	}
-
-	g.emitMessage(StreamMessage{
-		ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
-		Direction: "inbound",
-		Protocol:  "gRPC",
-		Payload:   string(jsonData),
-		Timestamp: time.Now(),
-	})
+	g.emitResponse(conn, outputMsg, callMetadata(header, trailer, st))
 
 	return nil
 }
 
 func (g *GrpcStreamManager) handleServerStream(conn *GrpcConnection, inputMsg *dynamic.Message) error {
-	stream, err := conn.Stub.InvokeRpcServerStream(conn.Context, conn.MethodDesc, inputMsg)
+	ctx, cancel := g.callContext(conn)
+
+	conn.recordSent()
+
+	// The call options fill in the header and trailer when the call fails
+	// before the stream is handed back.
+	var header, trailer metadata.MD
+	stream, err := conn.Stub.InvokeRpcServerStream(ctx, conn.MethodDesc, inputMsg, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
+		cancel()
+		st := g.statusInfo(err)
+		conn.recordStatus(st, header, trailer)
+		conn.setState("closed")
 		g.emitMessage(StreamMessage{
 			ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
 			Direction: "error",
 			Protocol:  "gRPC",
 			Payload:   err.Error(),
 			Timestamp: time.Now(),
+			Metadata:  callMetadata(header, trailer, st),
 		})
 		return err
 	}
 
 	go func() {
+		defer cancel()
+
+		first := true
 		for {
 			outputMsg, err := stream.RecvMsg()
-
 			if err == io.EOF {
-				g.emitMessage(StreamMessage{
-					ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
-					Direction: "system",
-					Protocol:  "gRPC",
-					Payload:   "Server closed stream",
-					Timestamp: time.Now(),
-				})
+				g.finishStream(conn, nil, streamHeader(first, stream), stream.Trailer())
 				return
 			}
-
 			if err != nil {
-				g.emitMessage(StreamMessage{
-					ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
-					Direction: "error",
-					Protocol:  "gRPC",
-					Payload:   err.Error(),
-					Timestamp: time.Now(),
-				})
+				if conn.Context.Err() != nil {
+					// Disconnected by the user
+					return
+				}
+				g.finishStream(conn, err, streamHeader(first, stream), stream.Trailer())
 				return
 			}
 
-			jsonData, err := outputMsg.(*dynamic.Message).MarshalJSONPB(&jsonpb.Marshaler{})
-			if err != nil {
-				jsonData = []byte(outputMsg.String())
+			var meta map[string]interface{}
+			if first {
+				first = false
+				header, _ := stream.Header()
+				conn.recordHeaders(header)
+				meta = callMetadata(header, nil, nil)
 			}
-
-			g.emitMessage(StreamMessage{
-				ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
-				Direction: "inbound",
-				Protocol:  "gRPC",
-				Payload:   string(jsonData),
-				Timestamp: time.Now(),
-			})
+			g.emitResponse(conn, outputMsg, meta)
 		}
 	}()
 
 	return nil
 }
 
+// openStream starts the client or bidi call up front so that SendMessage
+// can push messages onto it one at a time.
+func (g *GrpcStreamManager) openStream(conn *GrpcConnection) error {
+	ctx, cancel := g.callContext(conn)
+
+	switch conn.StreamType {
+	case "client":
+		stream, err := conn.Stub.InvokeRpcClientStream(ctx, conn.MethodDesc)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to open client stream: %w", err)
+		}
+		conn.ClientStream = stream
+	case "bidi":
+		stream, err := conn.Stub.InvokeRpcBidiStream(ctx, conn.MethodDesc)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to open bidirectional stream: %w", err)
+		}
+		conn.BidiStream = stream
+		go g.receiveBidi(conn, stream)
+	default:
+		cancel()
+		return fmt.Errorf("method is not client or bidirectional streaming")
+	}
+
+	conn.streamCancel = cancel
+	return nil
+}
+
 func (g *GrpcStreamManager) handleClientStreamSend(conn *GrpcConnection, inputMsg *dynamic.Message) error {
-	// TODO
-	return fmt.Errorf("client streaming not yet implemented")
+	conn.sendMu.Lock()
+	defer conn.sendMu.Unlock()
+
+	if conn.ClientStream == nil {
+		return fmt.Errorf("client stream is closed")
+	}
+
+	if err := conn.ClientStream.SendMsg(inputMsg); err != nil {
+		if err == io.EOF {
+			// The server ended the call early; the real status comes from
+			// CloseAndReceive.
+			_, err = conn.ClientStream.CloseAndReceive()
+			conn.ClientStream = nil
+			g.finishStream(conn, err, nil, nil)
+			return fmt.Errorf("stream closed by server")
+		}
+		g.emitSendError(err)
+		return err
+	}
+
+	conn.recordSent()
+	return nil
 }
 
 func (g *GrpcStreamManager) handleBidiStreamSend(conn *GrpcConnection, inputMsg *dynamic.Message) error {
-	// TODO
-	return fmt.Errorf("bidirectional streaming not yet implemented")
+	conn.sendMu.Lock()
+	defer conn.sendMu.Unlock()
+
+	if conn.BidiStream == nil || conn.currentState() != "open" {
+		return fmt.Errorf("stream is no longer accepting messages")
+	}
+
+	if err := conn.BidiStream.SendMsg(inputMsg); err != nil {
+		if err == io.EOF {
+			// The receive goroutine reports the final status
+			return fmt.Errorf("stream closed by server")
+		}
+		g.emitSendError(err)
+		return err
+	}
+
+	conn.recordSent()
+	return nil
+}
+
+// CloseSend half-closes a client or bidi stream. Client streams then wait for
+// the single response; bidi streams keep receiving until the server finishes.
+func (g *GrpcStreamManager) CloseSend(connectionID string) error {
+	g.mu.RLock()
+	conn, ok := g.connections[connectionID]
+	g.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("connection not found: %s", connectionID)
+	}
+
+	conn.sendMu.Lock()
+	defer conn.sendMu.Unlock()
+
+	switch conn.StreamType {
+	case "client":
+		if conn.ClientStream == nil {
+			return fmt.Errorf("client stream is closed")
+		}
+		stream := conn.ClientStream
+		conn.ClientStream = nil
+		conn.setState("half-closed")
+
+		outputMsg, err := stream.CloseAndReceive()
+		if err == nil {
+			header, _ := stream.Header()
+			conn.recordHeaders(header)
+			g.emitResponse(conn, outputMsg, callMetadata(header, nil, nil))
+		}
+		g.finishStream(conn, err, streamHeader(err != nil, stream), stream.Trailer())
+		return nil
+	case "bidi":
+		if conn.BidiStream == nil || conn.currentState() != "open" {
+			return fmt.Errorf("stream is already half-closed")
+		}
+		if err := conn.BidiStream.CloseSend(); err != nil {
+			return fmt.Errorf("failed to half-close stream: %w", err)
+		}
+		conn.setState("half-closed")
+
+		g.emitMessage(StreamMessage{
+			ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
+			Direction: "system",
+			Protocol:  "gRPC",
+			Payload:   "Client half-closed stream",
+			Timestamp: time.Now(),
+		})
+		return nil
+	default:
+		return fmt.Errorf("half-close is only supported for client and bidirectional streams")
+	}
+}
+
+// StreamStatus returns a snapshot of the connection's call state
+func (g *GrpcStreamManager) StreamStatus(connectionID string) (*GrpcStreamStatus, error) {
+	g.mu.RLock()
+	conn, ok := g.connections[connectionID]
+	g.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("connection not found: %s", connectionID)
+	}
+
+	conn.statusMu.Lock()
+	defer conn.statusMu.Unlock()
+
+	status := conn.status
+	return &status, nil
+}
+
+func (g *GrpcStreamManager) receiveBidi(conn *GrpcConnection, stream *grpcdynamic.BidiStream) {
+	first := true
+	for {
+		outputMsg, err := stream.RecvMsg()
+		if err == io.EOF {
+			g.finishStream(conn, nil, streamHeader(first, stream), stream.Trailer())
+			return
+		}
+		if err != nil {
+			if conn.Context.Err() != nil {
+				// Disconnected by the user
+				return
+			}
+			g.finishStream(conn, err, streamHeader(first, stream), stream.Trailer())
+			return
+		}
+
+		var meta map[string]interface{}
+		if first {
+			first = false
+			header, _ := stream.Header()
+			conn.recordHeaders(header)
+			meta = callMetadata(header, nil, nil)
+		}
+		g.emitResponse(conn, outputMsg, meta)
+	}
+}
+
+// callContext derives the context for one call, applying the deadline
+func (g *GrpcStreamManager) callContext(conn *GrpcConnection) (context.Context, context.CancelFunc) {
+	if conn.Deadline > 0 {
+		return context.WithTimeout(conn.Context, conn.Deadline)
+	}
+	return context.WithCancel(conn.Context)
+}
+
+func (g *GrpcStreamManager) emitResponse(conn *GrpcConnection, outputMsg proto.Message, meta map[string]interface{}) {
+	conn.recordReceived()
+
+	var jsonData []byte
+	if dm, ok := outputMsg.(*dynamic.Message); ok {
+		var err error
+		jsonData, err = dm.MarshalJSONPB(&jsonpb.Marshaler{})
+		if err != nil {
+			jsonData = []byte(outputMsg.String())
+		}
+	} else {
+		jsonData = []byte(outputMsg.String())
+	}
+
+	g.emitMessage(StreamMessage{
+		ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
+		Direction: "inbound",
+		Protocol:  "gRPC",
+		Payload:   string(jsonData),
+		Timestamp: time.Now(),
+		Metadata:  meta,
+	})
+}
+
+func (g *GrpcStreamManager) emitSendError(err error) {
+	g.emitMessage(StreamMessage{
+		ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
+		Direction: "error",
+		Protocol:  "gRPC",
+		Payload:   fmt.Sprintf("Failed to send: %s", err.Error()),
+		Timestamp: time.Now(),
+	})
+}
+
+// finishStream records the final status and trailers of a call and reports
+// them as a system (OK) or error message. header is nil when it was already
+// reported with the first response.
+func (g *GrpcStreamManager) finishStream(conn *GrpcConnection, err error, header, trailer metadata.MD) {
+	st := g.statusInfo(err)
+
+	conn.recordStatus(st, header, trailer)
+	conn.setState("closed")
+
+	direction := "system"
+	payload := "Server closed stream"
+	if st.CodeNumber != uint32(codes.OK) {
+		direction = "error"
+		payload = fmt.Sprintf("Stream failed: %s: %s", st.Code, st.Message)
+	}
+
+	g.emitMessage(StreamMessage{
+		ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
+		Direction: direction,
+		Protocol:  "gRPC",
+		Payload:   payload,
+		Timestamp: time.Now(),
+		Metadata:  callMetadata(header, trailer, st),
+	})
+}
+
+// streamHeader returns the header of a stream that ends before its first
+// response, so a call that fails straight away still reports it.
+func streamHeader(first bool, stream interface{ Header() (metadata.MD, error) }) metadata.MD {
+	if !first {
+		return nil
+	}
+	header, _ := stream.Header()
+	return header
+}
+
+func (c *GrpcConnection) recordSent() {
+	c.statusMu.Lock()
+	c.status.MessagesSent++
+	c.statusMu.Unlock()
+}
+
+func (c *GrpcConnection) recordReceived() {
+	c.statusMu.Lock()
+	c.status.MessagesReceived++
+	c.statusMu.Unlock()
+}
+
+func (c *GrpcConnection) recordHeaders(header metadata.MD) {
+	c.statusMu.Lock()
+	c.status.Headers = displayMetadata(header)
+	c.statusMu.Unlock()
+}
+
+// recordStatus stores a finished call's outcome; a nil header keeps the one
+// captured from the first response.
+func (c *GrpcConnection) recordStatus(st *GrpcStatusInfo, header, trailer metadata.MD) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	c.status.Status = st
+	c.status.StatusCode = st.Code
+	c.status.StatusMessage = st.Message
+	c.status.Trailers = displayMetadata(trailer)
+	if header != nil {
+		c.status.Headers = displayMetadata(header)
+	}
+}
+
+func (c *GrpcConnection) setState(state string) {
+	c.statusMu.Lock()
+	c.status.State = state
+	c.statusMu.Unlock()
+}
+
+func (c *GrpcConnection) currentState() string {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.status.State
 }
 
 func (g *GrpcStreamManager) Disconnect(connectionID string) error {
@@ -445,6 +706,9 @@ func (g *GrpcStreamManager) Disconnect(connectionID string) error {
 		return fmt.Errorf("connection not found")
 	}
 
+	if conn.streamCancel != nil {
+		conn.streamCancel()
+	}
 	conn.Cancel()
 	conn.Conn.Close()
 
@@ -460,7 +724,52 @@ func (g *GrpcStreamManager) Disconnect(connectionID string) error {
 }
 
 func (g *GrpcStreamManager) emitMessage(msg StreamMessage) {
-	runtime.EventsEmit(g.app.GetCtx(), "stream-message", msg)
+	emitStreamEvent(g.app.GetCtx(), msg)
+}
+
+func (g *GrpcStreamManager) dialOptions(serverURL string, useTLS bool, tlsProfile string) ([]grpc.DialOption, error) {
+	if !useTLS && tlsProfile == "" {
+		return []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, nil
+	}
+
+	tlsConfig, err := clientTLSConfig(g.app.GetDataDirectory(), tlsProfile, dialedHost(serverURL), nil)
+	if err != nil {
+		return nil, err
+	}
+	return []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}, nil
+}
+
+// findService matches the fully qualified name first and falls back to the
+// short name the service picker shows
+func findService(services map[string]*desc.ServiceDescriptor, name string) *desc.ServiceDescriptor {
+	if svc, ok := services[name]; ok {
+		return svc
+	}
+	for _, svc := range services {
+		if svc.GetName() == name {
+			return svc
+		}
+	}
+	return nil
+}
+
+func describeService(svc *desc.ServiceDescriptor) ServiceInfo {
+	serviceInfo := ServiceInfo{
+		Name:     svc.GetName(),
+		FullName: svc.GetFullyQualifiedName(),
+		Methods:  []MethodInfo{},
+	}
+
+	for _, method := range svc.GetMethods() {
+		serviceInfo.Methods = append(serviceInfo.Methods, MethodInfo{
+			Name:       method.GetName(),
+			Type:       getMethodType(method),
+			InputType:  method.GetInputType().GetFullyQualifiedName(),
+			OutputType: method.GetOutputType().GetFullyQualifiedName(),
+		})
+	}
+
+	return serviceInfo
 }
 
 func getMethodType(method *desc.MethodDescriptor) string {
@@ -475,4 +784,3 @@ func getMethodType(method *desc.MethodDescriptor) string {
 	}
 	return "bidi"
 }
-
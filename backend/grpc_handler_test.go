@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testEchoProto = `syntax = "proto3";
+package test;
+
+message Request { string text = 1; }
+message Reply { string text = 1; }
+
+service Echo {
+  rpc Count(Request) returns (stream Reply);
+  rpc Fail(Request) returns (stream Reply);
+}
+`
+
+// newGrpcTestServer serves test.Echo without generated code: Count sends
+// two replies and Fail ends the call before any reply, with a header, a
+// trailer and a status detail.
+func newGrpcTestServer(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		if err := stream.RecvMsg(&emptypb.Empty{}); err != nil {
+			return err
+		}
+
+		switch method {
+		case "/test.Echo/Count":
+			stream.SetHeader(metadata.Pairs("x-request", "count"))
+			for _, text := range []string{"one", "two"} {
+				if err := stream.SendMsg(wrapperspb.String(text)); err != nil {
+					return err
+				}
+			}
+			return nil
+		case "/test.Echo/Fail":
+			stream.SetHeader(metadata.Pairs("x-request", "fail"))
+			stream.SetTrailer(metadata.Pairs("x-trace", "abc"))
+			st, _ := status.New(codes.FailedPrecondition, "quota used up").WithDetails(&errdetails.ErrorInfo{Reason: "QUOTA"})
+			return st.Err()
+		}
+		return status.Error(codes.Unimplemented, method)
+	}))
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	return ln.Addr().String()
+}
+
+func connectEcho(t *testing.T, method string) (*GrpcStreamManager, string, <-chan StreamMessage) {
+	t.Helper()
+
+	app, events := newTestApp(t)
+	g := NewGrpcStreamManager(app)
+	if _, err := g.ParseProtoFiles(ProtoFileUploadRequest{Files: []ProtoFile{{Name: "echo.proto", Content: testEchoProto}}}); err != nil {
+		t.Fatal(err)
+	}
+	connID, err := g.Connect(GrpcConnectRequest{ServerURL: newGrpcTestServer(t), Service: "test.Echo", Method: method})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { g.Disconnect(connID) })
+
+	return g, connID, events
+}
+
+func TestGrpcServerStream(t *testing.T) {
+	g, connID, events := connectEcho(t, "Count")
+
+	if err := g.SendMessage(GrpcSendMessageRequest{ConnectionID: connID, Message: `{"text":"hi"}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	first := waitForMessage(t, events, func(msg StreamMessage) bool { return msg.Direction == "inbound" })
+	if first.Payload != `{"text":"one"}` {
+		t.Errorf("first reply = %s", first.Payload)
+	}
+	if headers, _ := first.Metadata["headers"].(metadata.MD); strings.Join(headers.Get("x-request"), ",") != "count" {
+		t.Errorf("first reply metadata = %v, want the header", first.Metadata)
+	}
+	second := waitForMessage(t, events, func(msg StreamMessage) bool { return msg.Direction == "inbound" })
+	if second.Payload != `{"text":"two"}` || second.Metadata["headers"] != nil {
+		t.Errorf("second reply = %s %v", second.Payload, second.Metadata)
+	}
+
+	done := waitForMessage(t, events, func(msg StreamMessage) bool { return msg.Direction == "system" })
+	if done.Payload != "Server closed stream" || done.Metadata["headers"] != nil {
+		t.Errorf("close = %s %v", done.Payload, done.Metadata)
+	}
+}
+
+// A stream that fails before its first reply still reports its header,
+// trailer and status details
+func TestGrpcServerStreamError(t *testing.T) {
+	g, connID, events := connectEcho(t, "Fail")
+
+	if err := g.SendMessage(GrpcSendMessageRequest{ConnectionID: connID, Message: `{}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := waitForMessage(t, events, func(msg StreamMessage) bool { return msg.Direction == "error" })
+	if !strings.Contains(msg.Payload, "FailedPrecondition: quota used up") {
+		t.Errorf("payload = %s", msg.Payload)
+	}
+	headers, _ := msg.Metadata["headers"].(metadata.MD)
+	trailers, _ := msg.Metadata["trailers"].(metadata.MD)
+	if len(headers.Get("x-request")) == 0 || len(trailers.Get("x-trace")) == 0 {
+		t.Errorf("metadata = %v, want the header and trailer", msg.Metadata)
+	}
+	st, _ := msg.Metadata["status"].(*GrpcStatusInfo)
+	if st == nil || len(st.Details) != 1 || st.Details[0].Type != "google.rpc.ErrorInfo" || !strings.Contains(string(st.Details[0].Value), "QUOTA") {
+		t.Fatalf("status = %+v", st)
+	}
+
+	state, err := g.StreamStatus(connID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.State != "closed" || state.StatusCode != "FailedPrecondition" || strings.Join(state.Headers["x-request"], ",") != "fail" || strings.Join(state.Trailers["x-trace"], ",") != "abc" {
+		t.Errorf("stream status = %+v", state)
+	}
+}
@@ -0,0 +1,171 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	refv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	refv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+type GrpcReflectionRequest struct {
+	ServerURL  string            `json:"serverUrl"`
+	UseTLS     bool              `json:"useTLS"`
+	TLSProfile string            `json:"tlsProfile,omitempty"`
+	Metadata   map[string]string `json:"metadata"` // sent on every reflection call, e.g. auth tokens
+	Deadline   int               `json:"deadline"` // milliseconds, 0 for none
+	Scopes     *VariableScopes   `json:"scopes,omitempty"`
+}
+
+// GrpcServiceError reports a listed service whose descriptors could not be
+// resolved
+type GrpcServiceError struct {
+	Service string `json:"service"`
+	Error   string `json:"error"`
+}
+
+var reflectionServices = map[string]bool{
+	"grpc.reflection.v1.ServerReflection":      true,
+	"grpc.reflection.v1alpha.ServerReflection": true,
+}
+
+// UseReflection loads a server's services over reflection. It tries
+// grpc.reflection.v1 first and falls back to v1alpha when the server doesn't
+// implement it. Imports the server can't supply fall back to the bundled
+// well-known and Google API protos.
+func (g *GrpcStreamManager) UseReflection(req GrpcReflectionRequest) (*ParsedProtoResponse, error) {
+	req, err := resolverFor(req.Scopes).ResolveGrpcReflectionRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := g.dialOptions(req.ServerURL, req.UseTLS, req.TLSProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.Dial(req.ServerURL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.New(req.Metadata))
+	if req.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Deadline)*time.Millisecond)
+		defer cancel()
+	}
+
+	refClient, version, services, err := negotiateReflection(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer refClient.Reset()
+
+	set := newProtoSet("reflection", req.ServerURL, nil)
+	set.info.ReflectionVersion = version
+
+	var serviceErrors []GrpcServiceError
+
+	// Resolving makes round trips to the server, so the registry is only
+	// locked once every service has been fetched
+	for _, serviceName := range services {
+		if reflectionServices[serviceName] {
+			continue
+		}
+
+		svcDesc, err := refClient.ResolveService(serviceName)
+		if err != nil {
+			serviceErrors = append(serviceErrors, GrpcServiceError{
+				Service: serviceName,
+				Error:   err.Error(),
+			})
+			continue
+		}
+		set.addService(svcDesc)
+	}
+
+	g.protoRegistry.mu.Lock()
+	for name, svcDesc := range set.services {
+		g.protoRegistry.services[name] = svcDesc
+		g.registerFile(svcDesc.GetFile())
+	}
+	g.addUnsavedSet(set)
+	g.protoRegistry.mu.Unlock()
+
+	response := set.response()
+	response.ReflectionVersion = version
+	response.ServiceErrors = serviceErrors
+	return response, nil
+}
+
+// negotiateReflection lists services with the newest reflection version the
+// server implements. v1 is probed directly because the reflection client
+// doesn't report which version it ended up using.
+func negotiateReflection(ctx context.Context, conn *grpc.ClientConn) (*grpcreflect.Client, string, []string, error) {
+	version := "v1"
+	if err := probeReflectionV1(ctx, conn); err != nil {
+		if status.Code(err) != codes.Unimplemented {
+			return nil, "", nil, fmt.Errorf("failed to list services: %w", err)
+		}
+		version = "v1alpha"
+	}
+
+	var refClient *grpcreflect.Client
+	if version == "v1" {
+		refClient = grpcreflect.NewClientAuto(ctx, conn)
+	} else {
+		refClient = grpcreflect.NewClientV1Alpha(ctx, refv1alpha.NewServerReflectionClient(conn))
+	}
+	refClient.AllowFallbackResolver(protoregistry.GlobalFiles, protoregistry.GlobalTypes)
+
+	services, err := refClient.ListServices()
+	if err != nil {
+		refClient.Reset()
+		if status.Code(err) == codes.Unimplemented {
+			return nil, "", nil, fmt.Errorf("server does not support reflection: %w", err)
+		}
+		return nil, "", nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	return refClient, version, services, nil
+}
+
+func probeReflectionV1(ctx context.Context, conn *grpc.ClientConn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := refv1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return err
+	}
+	err = stream.Send(&refv1.ServerReflectionRequest{
+		MessageRequest: &refv1.ServerReflectionRequest_ListServices{ListServices: "*"},
+	})
+	if err == nil || err == io.EOF {
+		// On EOF the real error comes from Recv
+		_, err = stream.Recv()
+	}
+	return err
+}
+
+// registerFile adds a file and its imports to the registry so their message
+// types can be looked up. The registry lock must be held.
+func (g *GrpcStreamManager) registerFile(fd *desc.FileDescriptor) {
+	g.protoRegistry.files[fd.GetName()] = fd
+	for _, dep := range fd.GetDependencies() {
+		if _, ok := g.protoRegistry.files[dep.GetName()]; !ok {
+			g.registerFile(dep)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	greflection "google.golang.org/grpc/reflection"
+	refv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+// reflectionServer serves the health service and a service without
+// descriptors, with reflection "v1" (which also serves v1alpha), "v1alpha"
+// only, or none. A non-empty token is required as authorization metadata.
+func reflectionServer(t *testing.T, version, token string) string {
+	t.Helper()
+
+	var opts []grpc.ServerOption
+	if token != "" {
+		opts = append(opts, grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			md, _ := metadata.FromIncomingContext(ss.Context())
+			if strings.Join(md.Get("authorization"), "") != token {
+				return status.Error(codes.Unauthenticated, "bad token")
+			}
+			return handler(srv, ss)
+		}))
+	}
+	server := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "ghost.Ghost",
+		HandlerType: (*interface{})(nil),
+		Metadata:    "ghost.proto",
+	}, struct{}{})
+
+	switch version {
+	case "v1":
+		greflection.Register(server)
+	case "v1alpha":
+		refv1alpha.RegisterServerReflectionServer(server, greflection.NewServer(greflection.ServerOptions{Services: server}))
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+	return ln.Addr().String()
+}
+
+func TestUseReflection(t *testing.T) {
+	for _, version := range []string{"v1", "v1alpha"} {
+		t.Run(version, func(t *testing.T) {
+			g := NewGrpcStreamManager(nil)
+
+			resp, err := g.UseReflection(GrpcReflectionRequest{ServerURL: reflectionServer(t, version, "")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.ReflectionVersion != version {
+				t.Errorf("version = %s, want %s", resp.ReflectionVersion, version)
+			}
+			if len(resp.Services) != 1 || resp.Services[0].FullName != "grpc.health.v1.Health" {
+				t.Errorf("services = %+v, want only the health service", resp.Services)
+			}
+			if len(resp.ServiceErrors) != 1 || resp.ServiceErrors[0].Service != "ghost.Ghost" {
+				t.Errorf("service errors = %+v", resp.ServiceErrors)
+			}
+
+			// The reflected service is usable without naming the set
+			if _, ok := g.protoRegistry.services["grpc.health.v1.Health"]; !ok {
+				t.Error("the service was not registered")
+			}
+			if g.findMessageDescriptor("grpc.health.v1.HealthCheckResponse") == nil {
+				t.Error("the service's messages were not registered")
+			}
+			set := g.protoRegistry.sets[resp.ProtoSetID]
+			if set == nil || set.info.Source != "reflection" || set.info.ReflectionVersion != version {
+				t.Fatalf("proto set = %+v", set)
+			}
+
+			// Reflecting the same server again replaces its unsaved set
+			again, err := g.UseReflection(GrpcReflectionRequest{ServerURL: set.info.ServerURL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := g.protoRegistry.sets[resp.ProtoSetID]; ok || len(g.protoRegistry.sets) != 1 || g.protoRegistry.sets[again.ProtoSetID] == nil {
+				t.Errorf("sets after reflecting again: %d", len(g.protoRegistry.sets))
+			}
+		})
+	}
+}
+
+func TestUseReflectionMetadata(t *testing.T) {
+	g := NewGrpcStreamManager(nil)
+	addr := reflectionServer(t, "v1", "Bearer secret")
+
+	if _, err := g.UseReflection(GrpcReflectionRequest{ServerURL: addr}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without metadata: err = %v, want Unauthenticated", err)
+	}
+
+	resp, err := g.UseReflection(GrpcReflectionRequest{
+		ServerURL: addr,
+		Metadata:  map[string]string{"authorization": "Bearer {{token}}"},
+		Scopes:    &VariableScopes{Global: map[string]string{"token": "secret"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, svc := range resp.Services {
+		names = append(names, svc.FullName)
+	}
+	if !reflect.DeepEqual(names, []string{"grpc.health.v1.Health"}) {
+		t.Errorf("services = %v", names)
+	}
+}
+
+func TestUseReflectionUnsupported(t *testing.T) {
+	g := NewGrpcStreamManager(nil)
+
+	_, err := g.UseReflection(GrpcReflectionRequest{ServerURL: reflectionServer(t, "", "")})
+	if err == nil || !strings.Contains(err.Error(), "server does not support reflection") {
+		t.Errorf("err = %v", err)
+	}
+	if len(g.protoRegistry.sets) != 0 {
+		t.Error("a failed reflection left a proto set")
+	}
+}
+
+func TestRegisterFile(t *testing.T) {
+	g := NewGrpcStreamManager(nil)
+	if _, err := g.ParseProtoFiles(ProtoFileUploadRequest{Files: []ProtoFile{{Name: "orders.proto", Content: testOrdersProto}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := g.protoRegistry.files["google/protobuf/timestamp.proto"]; !ok {
+		t.Error("imports are not registered")
+	}
+	if _, ok := g.protoRegistry.files["orders.proto"]; !ok {
+		t.Error("the parsed file is not registered")
+	}
+}
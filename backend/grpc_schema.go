@@ -0,0 +1,331 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// MessageSchema is the field tree of a message, for scaffolding and checking
+// request JSON in the editor
+type MessageSchema struct {
+	Name     string          `json:"name"` // fully qualified
+	Comment  string          `json:"comment,omitempty"`
+	Fields   []FieldSchema   `json:"fields"`
+	Oneofs   []OneofSchema   `json:"oneofs,omitempty"`
+	Skeleton json.RawMessage `json:"skeleton"` // default-filled JSON, one field per oneof
+}
+
+// FieldSchema describes one field. Message fields are expanded in Fields
+// unless the type is a well-known type with its own JSON form, or would
+// recurse into a type already being expanded.
+type FieldSchema struct {
+	Name       string            `json:"name"`
+	JSONName   string            `json:"jsonName"`
+	Number     int32             `json:"number"`
+	Type       string            `json:"type"`               // "string", "int64", "message", "enum", ...
+	TypeName   string            `json:"typeName,omitempty"` // message or enum name
+	Repeated   bool              `json:"repeated"`
+	Optional   bool              `json:"optional"` // proto3 optional or proto2 optional
+	Required   bool              `json:"required"`
+	Oneof      string            `json:"oneof,omitempty"`
+	Comment    string            `json:"comment,omitempty"`
+	EnumValues []EnumValueSchema `json:"enumValues,omitempty"`
+	MapKey     *FieldSchema      `json:"mapKey,omitempty"`
+	MapValue   *FieldSchema      `json:"mapValue,omitempty"`
+	Fields     []FieldSchema     `json:"fields,omitempty"`
+	WellKnown  bool              `json:"wellKnown,omitempty"`
+	Recursive  bool              `json:"recursive,omitempty"`
+}
+
+type OneofSchema struct {
+	Name    string   `json:"name"`
+	Fields  []string `json:"fields"`
+	Comment string   `json:"comment,omitempty"`
+}
+
+type EnumValueSchema struct {
+	Name    string `json:"name"`
+	Number  int32  `json:"number"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type GrpcValidateMessageRequest struct {
+	MessageType string          `json:"messageType"`
+	Message     string          `json:"message"` // JSON string
+	Scopes      *VariableScopes `json:"scopes,omitempty"`
+}
+
+type MessageValidation struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// wellKnownSkeletons are the JSON forms of well-known types that don't
+// serialize as plain objects
+var wellKnownSkeletons = map[string]string{
+	"google.protobuf.Timestamp":   `"1970-01-01T00:00:00Z"`,
+	"google.protobuf.Duration":    `"0s"`,
+	"google.protobuf.FieldMask":   `""`,
+	"google.protobuf.Struct":      `{}`,
+	"google.protobuf.Value":       `null`,
+	"google.protobuf.ListValue":   `[]`,
+	"google.protobuf.Any":         `{"@type":""}`,
+	"google.protobuf.Empty":       `{}`,
+	"google.protobuf.DoubleValue": `0`,
+	"google.protobuf.FloatValue":  `0`,
+	"google.protobuf.Int64Value":  `"0"`,
+	"google.protobuf.UInt64Value": `"0"`,
+	"google.protobuf.Int32Value":  `0`,
+	"google.protobuf.UInt32Value": `0`,
+	"google.protobuf.BoolValue":   `false`,
+	"google.protobuf.StringValue": `""`,
+	"google.protobuf.BytesValue":  `""`,
+}
+
+// DescribeMessage returns the field tree and a JSON skeleton for a message
+// from the loaded protos
+func (g *GrpcStreamManager) DescribeMessage(name string) (*MessageSchema, error) {
+	md := g.findMessageDescriptor(strings.TrimPrefix(name, "."))
+	if md == nil {
+		return nil, fmt.Errorf("message not found: %s", name)
+	}
+
+	skeleton, err := json.MarshalIndent(messageSkeleton(md, map[string]bool{}), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build skeleton: %w", err)
+	}
+
+	schema := &MessageSchema{
+		Name:     md.GetFullyQualifiedName(),
+		Comment:  comment(md.GetSourceInfo()),
+		Fields:   describeFields(md, map[string]bool{md.GetFullyQualifiedName(): true}),
+		Skeleton: skeleton,
+	}
+	for _, oo := range md.GetOneOfs() {
+		if oo.IsSynthetic() {
+			continue
+		}
+		group := OneofSchema{
+			Name:    oo.GetName(),
+			Comment: comment(oo.GetSourceInfo()),
+		}
+		for _, choice := range oo.GetChoices() {
+			group.Fields = append(group.Fields, choice.GetName())
+		}
+		schema.Oneofs = append(schema.Oneofs, group)
+	}
+
+	return schema, nil
+}
+
+// ValidateMessage checks request JSON against a message type the same way
+// SendMessage decodes it
+func (g *GrpcStreamManager) ValidateMessage(req GrpcValidateMessageRequest) (*MessageValidation, error) {
+	md := g.findMessageDescriptor(strings.TrimPrefix(req.MessageType, "."))
+	if md == nil {
+		return nil, fmt.Errorf("message not found: %s", req.MessageType)
+	}
+
+	message, err := resolverFor(req.Scopes).ResolveField("message", req.Message)
+	if err != nil {
+		return &MessageValidation{Error: err.Error()}, nil
+	}
+
+	var msgData map[string]interface{}
+	if err := json.Unmarshal([]byte(message), &msgData); err != nil {
+		return &MessageValidation{Error: fmt.Sprintf("invalid JSON: %v", err)}, nil
+	}
+
+	if err := dynamic.NewMessage(md).UnmarshalJSON([]byte(message)); err != nil {
+		return &MessageValidation{Error: err.Error()}, nil
+	}
+
+	return &MessageValidation{Valid: true}, nil
+}
+
+// describeFields expands a message's fields. seen holds the message types
+// on the current path so recursive types stop after one level.
+func describeFields(md *desc.MessageDescriptor, seen map[string]bool) []FieldSchema {
+	fields := []FieldSchema{}
+	for _, fd := range md.GetFields() {
+		fields = append(fields, describeField(fd, seen))
+	}
+	return fields
+}
+
+func describeField(fd *desc.FieldDescriptor, seen map[string]bool) FieldSchema {
+	field := FieldSchema{
+		Name:     fd.GetName(),
+		JSONName: fd.GetJSONName(),
+		Number:   fd.GetNumber(),
+		Type:     fieldType(fd),
+		Repeated: fd.IsRepeated() && !fd.IsMap(),
+		Optional: fd.IsProto3Optional() || (!fd.GetFile().IsProto3() && fd.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+		Required: fd.IsRequired(),
+		Comment:  comment(fd.GetSourceInfo()),
+	}
+
+	if oo := fd.GetOneOf(); oo != nil && !oo.IsSynthetic() {
+		field.Oneof = oo.GetName()
+	}
+
+	if fd.IsMap() {
+		field.Type = "map"
+		key := describeField(fd.GetMapKeyType(), seen)
+		value := describeField(fd.GetMapValueType(), seen)
+		field.MapKey = &key
+		field.MapValue = &value
+		return field
+	}
+
+	if et := fd.GetEnumType(); et != nil {
+		field.TypeName = et.GetFullyQualifiedName()
+		for _, v := range et.GetValues() {
+			field.EnumValues = append(field.EnumValues, EnumValueSchema{
+				Name:    v.GetName(),
+				Number:  v.GetNumber(),
+				Comment: comment(v.GetSourceInfo()),
+			})
+		}
+	}
+
+	if mt := fd.GetMessageType(); mt != nil {
+		name := mt.GetFullyQualifiedName()
+		field.TypeName = name
+
+		switch {
+		case wellKnownSkeletons[name] != "":
+			field.WellKnown = true
+		case seen[name]:
+			field.Recursive = true
+		default:
+			seen[name] = true
+			field.Fields = describeFields(mt, seen)
+			delete(seen, name)
+		}
+	}
+
+	return field
+}
+
+// orderedObject keeps fields in declaration order when marshaled
+type orderedObject []orderedField
+
+type orderedField struct {
+	key   string
+	value interface{}
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func messageSkeleton(md *desc.MessageDescriptor, seen map[string]bool) interface{} {
+	name := md.GetFullyQualifiedName()
+	if wk, ok := wellKnownSkeletons[name]; ok {
+		return json.RawMessage(wk)
+	}
+	if seen[name] {
+		return orderedObject{}
+	}
+	seen[name] = true
+	defer delete(seen, name)
+
+	obj := orderedObject{}
+	filledOneofs := make(map[string]bool)
+	for _, fd := range md.GetFields() {
+		if oo := fd.GetOneOf(); oo != nil && !oo.IsSynthetic() {
+			if filledOneofs[oo.GetName()] {
+				continue
+			}
+			filledOneofs[oo.GetName()] = true
+		}
+		obj = append(obj, orderedField{key: fd.GetJSONName(), value: fieldSkeleton(fd, seen)})
+	}
+	return obj
+}
+
+func fieldSkeleton(fd *desc.FieldDescriptor, seen map[string]bool) interface{} {
+	if fd.IsMap() {
+		key := fmt.Sprint(scalarSkeleton(fd.GetMapKeyType()))
+		if key == "" {
+			key = "key"
+		}
+		return orderedObject{{key: key, value: singleSkeleton(fd.GetMapValueType(), seen)}}
+	}
+	if fd.IsRepeated() {
+		return []interface{}{singleSkeleton(fd, seen)}
+	}
+	return singleSkeleton(fd, seen)
+}
+
+func singleSkeleton(fd *desc.FieldDescriptor, seen map[string]bool) interface{} {
+	if mt := fd.GetMessageType(); mt != nil {
+		return messageSkeleton(mt, seen)
+	}
+	return scalarSkeleton(fd)
+}
+
+// scalarSkeleton is the proto3 JSON default of a scalar or enum field.
+// 64-bit integers are strings in proto3 JSON.
+func scalarSkeleton(fd *desc.FieldDescriptor) interface{} {
+	if et := fd.GetEnumType(); et != nil {
+		if values := et.GetValues(); len(values) > 0 {
+			return values[0].GetName()
+		}
+		return 0
+	}
+
+	switch fd.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_BOOL:
+		return false
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+		return ""
+	case descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT64, descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return "0"
+	default:
+		return 0
+	}
+}
+
+func fieldType(fd *desc.FieldDescriptor) string {
+	return strings.ToLower(strings.TrimPrefix(fd.GetType().String(), "TYPE_"))
+}
+
+func comment(loc *descriptorpb.SourceCodeInfo_Location) string {
+	if loc == nil {
+		return ""
+	}
+	text := loc.GetLeadingComments()
+	if text == "" {
+		text = loc.GetTrailingComments()
+	}
+	return strings.TrimSpace(text)
+}
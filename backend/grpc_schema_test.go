@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSchemaProto = `syntax = "proto3";
+package shop;
+
+import "google/protobuf/timestamp.proto";
+
+// An order placed by a customer
+message Order {
+  string id = 1; // server assigned
+  int64 total_cents = 2;
+  Status status = 3;
+  repeated Line lines = 4;
+  map<string, Line> by_sku = 5;
+  map<int32, string> notes = 6;
+  google.protobuf.Timestamp placed_at = 7;
+  optional string coupon = 8;
+  // How the order is paid
+  oneof payment {
+    string card = 9;
+    Invoice invoice = 10;
+  }
+  Category category = 11;
+  bytes signature = 12;
+}
+
+enum Status {
+  STATUS_UNKNOWN = 0;
+  STATUS_PAID = 1; // money received
+}
+
+message Line {
+  string sku = 1;
+  double price = 2;
+}
+
+message Invoice { string number = 1; }
+
+message Category {
+  string name = 1;
+  Category parent = 2;
+  repeated Category children = 3;
+}
+`
+
+func schemaManager(t *testing.T) *GrpcStreamManager {
+	t.Helper()
+
+	g := NewGrpcStreamManager(nil)
+	if _, err := g.ParseProtoFiles(ProtoFileUploadRequest{Files: []ProtoFile{{Name: "schema.proto", Content: testSchemaProto}}}); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestMessageSkeleton(t *testing.T) {
+	schema, err := schemaManager(t).DescribeMessage(".shop.Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Declaration order, one field per oneof, 64-bit integers as strings,
+	// and an empty object where a type recurses
+	want := `{"id":"","totalCents":"0","status":"STATUS_UNKNOWN","lines":[{"sku":"","price":0}],` +
+		`"bySku":{"key":{"sku":"","price":0}},"notes":{"0":""},"placedAt":"1970-01-01T00:00:00Z","coupon":"","card":"",` +
+		`"category":{"name":"","parent":{},"children":[{}]},"signature":""}`
+	if got := compactJSON(schema.Skeleton); got != want {
+		t.Errorf("skeleton =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDescribeMessage(t *testing.T) {
+	schema, err := schemaManager(t).DescribeMessage("shop.Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if schema.Name != "shop.Order" || schema.Comment != "An order placed by a customer" {
+		t.Errorf("schema = %s %q", schema.Name, schema.Comment)
+	}
+	if len(schema.Oneofs) != 1 || schema.Oneofs[0].Name != "payment" || strings.Join(schema.Oneofs[0].Fields, ",") != "card,invoice" || schema.Oneofs[0].Comment != "How the order is paid" {
+		t.Errorf("oneofs = %+v", schema.Oneofs)
+	}
+
+	fields := make(map[string]FieldSchema)
+	for _, f := range schema.Fields {
+		fields[f.Name] = f
+	}
+
+	if f := fields["id"]; f.Type != "string" || f.JSONName != "id" || f.Comment != "server assigned" || f.Optional {
+		t.Errorf("id = %+v", f)
+	}
+	if f := fields["status"]; f.Type != "enum" || f.TypeName != "shop.Status" || len(f.EnumValues) != 2 || f.EnumValues[1].Comment != "money received" {
+		t.Errorf("status = %+v", f)
+	}
+	if f := fields["lines"]; !f.Repeated || f.Type != "message" || len(f.Fields) != 2 {
+		t.Errorf("lines = %+v", f)
+	}
+	if f := fields["by_sku"]; f.Type != "map" || f.Repeated || f.MapKey.Type != "string" || f.MapValue.TypeName != "shop.Line" || len(f.MapValue.Fields) != 2 {
+		t.Errorf("by_sku = %+v", f)
+	}
+	if f := fields["placed_at"]; !f.WellKnown || f.Fields != nil {
+		t.Errorf("placed_at = %+v", f)
+	}
+	if f := fields["coupon"]; !f.Optional || f.Oneof != "" {
+		t.Errorf("coupon = %+v", f)
+	}
+	if f := fields["invoice"]; f.Oneof != "payment" {
+		t.Errorf("invoice = %+v", f)
+	}
+
+	category := fields["category"]
+	if len(category.Fields) != 3 || !category.Fields[1].Recursive || category.Fields[1].Fields != nil || !category.Fields[2].Recursive {
+		t.Errorf("category = %+v", category)
+	}
+
+	if _, err := schemaManager(t).DescribeMessage("shop.Missing"); err == nil || err.Error() != "message not found: shop.Missing" {
+		t.Errorf("missing message: err = %v", err)
+	}
+}
+
+func TestValidateMessage(t *testing.T) {
+	g := schemaManager(t)
+	scopes := &VariableScopes{Global: map[string]string{"sku": "A-1"}}
+
+	tests := []struct {
+		name    string
+		message string
+		wantErr string
+	}{
+		{name: "skeleton", message: `{"id":"1","totalCents":"250","status":"STATUS_PAID","placedAt":"2026-01-01T00:00:00Z"}`},
+		{name: "variables", message: `{"lines":[{"sku":"{{sku}}"}]}`},
+		{name: "enum number", message: `{"status":1}`},
+		{name: "not JSON", message: `{"id":`, wantErr: "invalid JSON"},
+		{name: "not an object", message: `[1]`, wantErr: "invalid JSON"},
+		{name: "unknown field", message: `{"nope":1}`, wantErr: "nope"},
+		{name: "wrong type", message: `{"totalCents":true}`, wantErr: "bad input"},
+		{name: "bad enum", message: `{"status":"STATUS_LOST"}`, wantErr: "STATUS_LOST"},
+		{name: "undefined variable", message: `{"id":"{{missing}}"}`, wantErr: "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.ValidateMessage(GrpcValidateMessageRequest{MessageType: "shop.Order", Message: tt.message, Scopes: scopes})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr == "" {
+				if !got.Valid || got.Error != "" {
+					t.Errorf("got %+v, want valid", got)
+				}
+				return
+			}
+			if got.Valid || !strings.Contains(got.Error, tt.wantErr) {
+				t.Errorf("got %+v, want an error containing %q", got, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := g.ValidateMessage(GrpcValidateMessageRequest{MessageType: "shop.Missing", Message: "{}"}); err == nil {
+		t.Error("expected an error for an unknown message type")
+	}
+}
@@ -0,0 +1,162 @@
+package backend
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	// Registers the google.rpc error detail types (BadRequest, RetryInfo,
+	// ErrorInfo, ...) so status details decode without user protos.
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// GrpcStatusInfo is the final status of a call with its decoded details
+type GrpcStatusInfo struct {
+	Code       string             `json:"code"`
+	CodeNumber uint32             `json:"codeNumber"`
+	Message    string             `json:"message"`
+	Details    []GrpcStatusDetail `json:"details,omitempty"`
+}
+
+// GrpcStatusDetail is one google.rpc.Status detail. Value holds the decoded
+// message as JSON; Raw holds the base64 bytes when the type is unknown.
+type GrpcStatusDetail struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Raw   string          `json:"raw,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+func (g *GrpcStreamManager) statusInfo(err error) *GrpcStatusInfo {
+	st := status.Convert(err)
+
+	info := &GrpcStatusInfo{
+		Code:       st.Code().String(),
+		CodeNumber: uint32(st.Code()),
+		Message:    st.Message(),
+	}
+
+	for _, detail := range st.Proto().GetDetails() {
+		info.Details = append(info.Details, g.decodeStatusDetail(detail))
+	}
+
+	return info
+}
+
+// decodeStatusDetail tries the globally registered types first (which covers
+// the standard google.rpc details) and then the user's loaded protos.
+func (g *GrpcStreamManager) decodeStatusDetail(detail *anypb.Any) GrpcStatusDetail {
+	typeName := detail.GetTypeUrl()
+	if i := strings.LastIndex(typeName, "/"); i >= 0 {
+		typeName = typeName[i+1:]
+	}
+
+	out := GrpcStatusDetail{Type: typeName}
+
+	if msg, err := detail.UnmarshalNew(); err == nil {
+		if jsonData, err := protojson.Marshal(msg); err == nil {
+			out.Value = jsonData
+			return out
+		}
+	}
+
+	if md := g.findMessageDescriptor(typeName); md != nil {
+		dm := dynamic.NewMessage(md)
+		if err := dm.Unmarshal(detail.GetValue()); err == nil {
+			if jsonData, err := dm.MarshalJSONPB(&jsonpb.Marshaler{}); err == nil {
+				out.Value = jsonData
+				return out
+			}
+		}
+	}
+
+	out.Raw = base64.StdEncoding.EncodeToString(detail.GetValue())
+	out.Error = "unknown detail type"
+	return out
+}
+
+func (g *GrpcStreamManager) findMessageDescriptor(name string) *desc.MessageDescriptor {
+	g.protoRegistry.mu.RLock()
+	defer g.protoRegistry.mu.RUnlock()
+
+	for _, fd := range g.protoRegistry.files {
+		if md := findMessageInFile(fd, name); md != nil {
+			return md
+		}
+	}
+	for _, svc := range g.protoRegistry.services {
+		if md := findMessageInFile(svc.GetFile(), name); md != nil {
+			return md
+		}
+	}
+	for _, set := range g.protoRegistry.sets {
+		for _, fd := range set.files {
+			if md := findMessageInFile(fd, name); md != nil {
+				return md
+			}
+		}
+	}
+	return nil
+}
+
+// findMessageInFile searches a file and everything it imports
+func findMessageInFile(fd *desc.FileDescriptor, name string) *desc.MessageDescriptor {
+	if md := fd.FindMessage(name); md != nil {
+		return md
+	}
+	for _, dep := range fd.GetDependencies() {
+		if md := findMessageInFile(dep, name); md != nil {
+			return md
+		}
+	}
+	return nil
+}
+
+// callMetadata builds the StreamMessage metadata for a call's headers,
+// trailers and status. Empty parts are left out.
+func callMetadata(header, trailer metadata.MD, st *GrpcStatusInfo) map[string]interface{} {
+	meta := make(map[string]interface{})
+	if md := displayMetadata(header); len(md) > 0 {
+		meta["headers"] = md
+	}
+	if md := displayMetadata(trailer); len(md) > 0 {
+		meta["trailers"] = md
+	}
+	if st != nil {
+		meta["status"] = st
+	}
+	return meta
+}
+
+// displayMetadata makes metadata JSON friendly: binary "-bin" values are
+// base64 encoded, and the raw status details trailer is dropped because it is
+// already decoded into GrpcStatusInfo.
+func displayMetadata(md metadata.MD) metadata.MD {
+	if md == nil {
+		return nil
+	}
+
+	out := metadata.MD{}
+	for key, values := range md {
+		if key == "grpc-status-details-bin" {
+			continue
+		}
+		if strings.HasSuffix(key, "-bin") {
+			encoded := make([]string, len(values))
+			for i, v := range values {
+				encoded[i] = base64.StdEncoding.EncodeToString([]byte(v))
+			}
+			values = encoded
+		}
+		out[key] = values
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestStatusInfo(t *testing.T) {
+	g := NewGrpcStreamManager(nil)
+	if _, err := g.ParseProtoFiles(ProtoFileUploadRequest{Files: []ProtoFile{{
+		Name:    "quota.proto",
+		Content: "syntax = \"proto3\";\npackage acme;\nmessage QuotaHint { string text = 1; }\n",
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	retry, _ := anypb.New(&errdetails.RetryInfo{RetryDelay: durationpb.New(1500000000)})
+	hintValue, _ := proto.Marshal(wrapperspb.String("try tomorrow"))
+	st := status.FromProto(&spb.Status{
+		Code:    int32(codes.ResourceExhausted),
+		Message: "slow down",
+		Details: []*anypb.Any{
+			retry,
+			{TypeUrl: "type.googleapis.com/acme.QuotaHint", Value: hintValue},
+			{TypeUrl: "type.googleapis.com/acme.Unknown", Value: []byte{1, 2}},
+		},
+	})
+
+	got := g.statusInfo(st.Err())
+	want := &GrpcStatusInfo{
+		Code:       "ResourceExhausted",
+		CodeNumber: 8,
+		Message:    "slow down",
+		Details: []GrpcStatusDetail{
+			{Type: "google.rpc.RetryInfo", Value: []byte(`{"retryDelay":"1.500s"}`)},
+			{Type: "acme.QuotaHint", Value: []byte(`{"text":"try tomorrow"}`)},
+			{Type: "acme.Unknown", Raw: "AQI=", Error: "unknown detail type"},
+		},
+	}
+	if got.Code != want.Code || got.CodeNumber != want.CodeNumber || got.Message != want.Message || len(got.Details) != len(want.Details) {
+		t.Fatalf("got %+v", got)
+	}
+	for i, detail := range got.Details {
+		w := want.Details[i]
+		// protojson varies its spacing, so compare the values compacted
+		if detail.Type != w.Type || detail.Raw != w.Raw || detail.Error != w.Error || compactJSON(detail.Value) != string(w.Value) {
+			t.Errorf("detail %d = %s %s %s %s, want %+v", i, detail.Type, detail.Value, detail.Raw, detail.Error, w)
+		}
+	}
+}
+
+func TestStatusInfoWithoutAStatus(t *testing.T) {
+	g := NewGrpcStreamManager(nil)
+
+	if got := g.statusInfo(nil); got.Code != "OK" || got.CodeNumber != 0 || got.Details != nil {
+		t.Errorf("nil error = %+v", got)
+	}
+	if got := g.statusInfo(errors.New("connection reset")); got.Code != "Unknown" || got.Message != "connection reset" {
+		t.Errorf("plain error = %+v", got)
+	}
+}
+
+func TestCallMetadata(t *testing.T) {
+	st := &GrpcStatusInfo{Code: "OK"}
+
+	if got := callMetadata(nil, metadata.MD{}, nil); len(got) != 0 {
+		t.Errorf("empty parts = %v, want nothing", got)
+	}
+
+	got := callMetadata(metadata.Pairs("x-a", "1"), metadata.Pairs("x-b", "2"), st)
+	want := map[string]interface{}{
+		"headers":  metadata.Pairs("x-a", "1"),
+		"trailers": metadata.Pairs("x-b", "2"),
+		"status":   st,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDisplayMetadata(t *testing.T) {
+	if displayMetadata(nil) != nil {
+		t.Error("nil metadata should stay nil")
+	}
+
+	got := displayMetadata(metadata.MD{
+		"x-plain":                 {"a", "b"},
+		"x-token-bin":             {"\x00\xff"},
+		"grpc-status-details-bin": {"raw"},
+	})
+	want := metadata.MD{
+		"x-plain":     {"a", "b"},
+		"x-token-bin": {base64.StdEncoding.EncodeToString([]byte("\x00\xff"))},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func compactJSON(data []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
@@ -0,0 +1,134 @@
+package backend
+
+import (
+	"crypto/tls"
+	"math"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ResponseTiming breaks a request's latency down by phase, in milliseconds.
+// Phases that did not happen, such as DNS on a reused connection, are zero.
+type ResponseTiming struct {
+	DNSLookup       float64 `json:"dnsLookup"`
+	TCPConnect      float64 `json:"tcpConnect"`
+	TLSHandshake    float64 `json:"tlsHandshake"`
+	TimeToFirstByte float64 `json:"timeToFirstByte"`
+	ContentTransfer float64 `json:"contentTransfer"`
+	Total           float64 `json:"total"`
+	ReusedConn      bool    `json:"reusedConn"`
+}
+
+// TLSInfo describes the negotiated TLS session
+type TLSInfo struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipherSuite"`
+	ServerName  string `json:"serverName"`
+	ALPN        string `json:"alpn,omitempty"`
+}
+
+// requestTimer collects httptrace callbacks for a single request
+type requestTimer struct {
+	mu         sync.Mutex
+	start      time.Time
+	dnsStart   time.Time
+	dnsDone    time.Time
+	connStart  time.Time
+	connDone   time.Time
+	tlsStart   time.Time
+	tlsDone    time.Time
+	firstByte  time.Time
+	done       time.Time
+	remoteAddr string
+	reused     bool
+}
+
+func newRequestTimer() *requestTimer {
+	return &requestTimer{start: time.Now()}
+}
+
+func (t *requestTimer) trace() *httptrace.ClientTrace {
+	mark := func(field *time.Time) {
+		t.mu.Lock()
+		*field = time.Now()
+		t.mu.Unlock()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { mark(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { mark(&t.dnsDone) },
+		ConnectStart:         func(string, string) { mark(&t.connStart) },
+		ConnectDone:          func(string, string, error) { mark(&t.connDone) },
+		TLSHandshakeStart:    func() { mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { mark(&t.tlsDone) },
+		GotFirstResponseByte: func() { mark(&t.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+	}
+}
+
+func (t *requestTimer) finish() {
+	t.mu.Lock()
+	t.done = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *requestTimer) timing() *ResponseTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timing := &ResponseTiming{
+		DNSLookup:    phaseMs(t.dnsStart, t.dnsDone),
+		TCPConnect:   phaseMs(t.connStart, t.connDone),
+		TLSHandshake: phaseMs(t.tlsStart, t.tlsDone),
+		Total:        phaseMs(t.start, t.done),
+		ReusedConn:   t.reused,
+	}
+	if !t.firstByte.IsZero() {
+		timing.TimeToFirstByte = phaseMs(t.start, t.firstByte)
+		timing.ContentTransfer = phaseMs(t.firstByte, t.done)
+	}
+	return timing
+}
+
+func phaseMs(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return math.Round(float64(to.Sub(from).Microseconds())) / 1000
+}
+
+func tlsInfo(state *tls.ConnectionState) *TLSInfo {
+	if state == nil {
+		return nil
+	}
+	return &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+		ALPN:        state.NegotiatedProtocol,
+	}
+}
+
+// formatBytes mirrors the frontend's size formatting
+func formatBytes(n int) string {
+	if n == 0 {
+		return "0 Bytes"
+	}
+
+	sizes := []string{"Bytes", "KB", "MB"}
+	i := int(math.Floor(math.Log(float64(n)) / math.Log(1024)))
+	if i >= len(sizes) {
+		i = len(sizes) - 1
+	}
+	value := math.Round(float64(n)/math.Pow(1024, float64(i))*100) / 100
+	return strconv.FormatFloat(value, 'f', -1, 64) + " " + sizes[i]
+}
@@ -0,0 +1,135 @@
+package backend
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRequestTimerTiming(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(ms float64) time.Time {
+		return start.Add(time.Duration(ms * float64(time.Millisecond)))
+	}
+
+	tests := []struct {
+		name  string
+		timer *requestTimer
+		want  ResponseTiming
+	}{
+		{
+			name: "new TLS connection",
+			timer: &requestTimer{
+				start:    start,
+				dnsStart: at(0.1), dnsDone: at(2.6),
+				connStart: at(2.7), connDone: at(12.7),
+				tlsStart: at(12.8), tlsDone: at(40.05),
+				firstByte: at(90),
+				done:      at(100.5),
+			},
+			want: ResponseTiming{DNSLookup: 2.5, TCPConnect: 10, TLSHandshake: 27.25, TimeToFirstByte: 90, ContentTransfer: 10.5, Total: 100.5},
+		},
+		{
+			name:  "reused connection",
+			timer: &requestTimer{start: start, firstByte: at(5), done: at(7), reused: true},
+			want:  ResponseTiming{TimeToFirstByte: 5, ContentTransfer: 2, Total: 7, ReusedConn: true},
+		},
+		{
+			name:  "failed before a response",
+			timer: &requestTimer{start: start, dnsStart: at(1), connStart: at(2), done: at(3)},
+			want:  ResponseTiming{Total: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.timer.timing(); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhaseMs(t *testing.T) {
+	start := time.Now()
+	if got := phaseMs(start, start.Add(1234567*time.Nanosecond)); got != 1.234 {
+		t.Errorf("got %v, want 1.234", got)
+	}
+	if got := phaseMs(start.Add(time.Second), start); got != 0 {
+		t.Errorf("backwards phase = %v, want 0", got)
+	}
+	if got := phaseMs(time.Time{}, start); got != 0 {
+		t.Errorf("phase without a start = %v, want 0", got)
+	}
+}
+
+func TestRequestTimerTrace(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	send := func() *requestTimer {
+		timer := newRequestTimer()
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), timer.trace()))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		timer.finish()
+		return timer
+	}
+
+	first := send()
+	timing := first.timing()
+	if timing.ReusedConn || timing.TCPConnect == 0 || timing.TLSHandshake == 0 || timing.TimeToFirstByte == 0 || timing.Total < timing.TimeToFirstByte {
+		t.Errorf("first request timing = %+v", timing)
+	}
+	if first.remoteAddr != server.Listener.Addr().String() {
+		t.Errorf("remote address = %s, want %s", first.remoteAddr, server.Listener.Addr())
+	}
+
+	if timing := send().timing(); !timing.ReusedConn || timing.TCPConnect != 0 || timing.TLSHandshake != 0 {
+		t.Errorf("second request timing = %+v", timing)
+	}
+}
+
+func TestTLSInfo(t *testing.T) {
+	if tlsInfo(nil) != nil {
+		t.Error("expected nil for a plain HTTP response")
+	}
+
+	got := tlsInfo(&tls.ConnectionState{
+		Version:            tls.VersionTLS13,
+		CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+		ServerName:         "api.example.com",
+		NegotiatedProtocol: "h2",
+	})
+	want := &TLSInfo{Version: "TLS 1.3", CipherSuite: "TLS_AES_128_GCM_SHA256", ServerName: "api.example.com", ALPN: "h2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int]string{
+		0:             "0 Bytes",
+		512:           "512 Bytes",
+		1024:          "1 KB",
+		1536:          "1.5 KB",
+		5 << 20:       "5 MB",
+		3 << 30:       "3072 MB",
+		1024*1024 - 1: "1024 KB",
+	} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
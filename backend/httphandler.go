@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"os"
 	"path/filepath"
 	"strings"
@@ -30,13 +31,28 @@ type KeyValue struct {
 }
 
 type RequestData struct {
-	Method   string       `json:"method"`
-	URL      string       `json:"url"`
-	Params   []KeyValue   `json:"params"`
-	Headers  []KeyValue   `json:"headers"`
-	Body     string       `json:"body"`
-	BodyType string       `json:"bodyType"`
-	Auth     *RequestAuth `json:"auth"`
+	Method     string       `json:"method"`
+	URL        string       `json:"url"`
+	Params     []KeyValue   `json:"params"`
+	Headers    []KeyValue   `json:"headers"`
+	Body       string       `json:"body"`
+	BodyType   string       `json:"bodyType"`
+	Auth       *RequestAuth `json:"auth"`
+	TLSProfile string       `json:"tlsProfile,omitempty"`
+	// StreamingConfig is set for saved WebSocket, SSE, gRPC and Kafka
+	// requests; Method then names the protocol instead of an HTTP verb.
+	StreamingConfig *StreamingConfig `json:"streamingConfig,omitempty"`
+	// GraphQL is set when BodyType is "graphql"; Body then holds the query
+	// document
+	GraphQL *GraphQLOptions `json:"graphql,omitempty"`
+}
+
+// StreamingConfig is the saved setup of a streaming request. Config belongs
+// to the protocol's editor and is stored as-is.
+type StreamingConfig struct {
+	Protocol   string          `json:"protocol"`
+	ProtoSetID string          `json:"protoSetId,omitempty"` // gRPC only
+	Config     json.RawMessage `json:"config,omitempty"`
 }
 
 type ResponseData struct {
@@ -44,12 +60,20 @@ type ResponseData struct {
 	StatusText string            `json:"statusText"`
 	Headers    map[string]string `json:"headers"`
 	Body       string            `json:"body"`
+	Time       string            `json:"time"`
+	Size       string            `json:"size"`
+	SizeBytes  int               `json:"sizeBytes"`
+	Timing     *ResponseTiming   `json:"timing,omitempty"`
+	RemoteAddr string            `json:"remoteAddr,omitempty"`
+	Protocol   string            `json:"protocol,omitempty"`
+	TLS        *TLSInfo          `json:"tls,omitempty"`
 }
 
 type Workspace struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Variables map[string]string `json:"variables,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
 }
 
 type WorkspaceData struct {
@@ -68,6 +92,7 @@ type Collection struct {
 	Name        string              `json:"name"`
 	WorkspaceID string              `json:"workspaceId"`
 	Requests    []CollectionRequest `json:"requests"`
+	Variables   map[string]string   `json:"variables,omitempty"`
 	CreatedAt   time.Time           `json:"createdAt"`
 }
 
@@ -99,13 +124,14 @@ type HistoryData struct {
 }
 
 type Settings struct {
-	UIScale              int    `json:"uiScale"`
-	Theme                string `json:"theme"`
-	LayoutMode           string `json:"layoutMode"`
-	AutoSaveHistory      bool   `json:"autoSaveHistory"`
-	MaxHistoryItems      int    `json:"maxHistoryItems"`
-	DefaultTimeout       int    `json:"defaultTimeout"`
-	PrettyPrintByDefault bool   `json:"prettyPrintByDefault"`
+	UIScale              int               `json:"uiScale"`
+	Theme                string            `json:"theme"`
+	LayoutMode           string            `json:"layoutMode"`
+	AutoSaveHistory      bool              `json:"autoSaveHistory"`
+	MaxHistoryItems      int               `json:"maxHistoryItems"`
+	DefaultTimeout       int               `json:"defaultTimeout"`
+	PrettyPrintByDefault bool              `json:"prettyPrintByDefault"`
+	GlobalVariables      map[string]string `json:"globalVariables,omitempty"`
 }
 
 // HTTPHandler manages HTTP-related functionality
@@ -121,20 +147,49 @@ func NewHTTPHandler(app AppInterface, dataDir string) *HTTPHandler {
 	}
 }
 
+// SendRequest expands dynamic variables in a request the frontend has
+// already substituted and sends it
 func (h *HTTPHandler) SendRequest(req RequestData) (*ResponseData, error) {
+	resolved, err := resolverFor(nil).ResolveRequestData(req)
+	if err != nil {
+		return nil, err
+	}
+	return h.send(resolved)
+}
+
+// send performs a request whose variables have already been resolved. Every
+// entry point resolves exactly once before calling it, so text that only
+// looks like a placeholder after substitution is sent as is.
+func (h *HTTPHandler) send(req RequestData) (*ResponseData, error) {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
 	}
 
+	if req.TLSProfile != "" {
+		tlsConfig, err := clientTLSConfig(h.dataDir, req.TLSProfile, dialedHost(req.URL), nil)
+		if err != nil {
+			return nil, err
+		}
+		// Profiles can change between requests, so each request gets its own
+		// transport and closes its keep-alive connections when done
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+		defer transport.CloseIdleConnections()
+	}
+
 	var bodyReader io.Reader
 	if req.Body != "" {
 		bodyReader = strings.NewReader(req.Body)
 	}
 
+	timer := newRequestTimer()
+
 	httpReq, err := http.NewRequest(req.Method, req.URL, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	httpReq = httpReq.WithContext(httptrace.WithClientTrace(httpReq.Context(), timer.trace()))
 
 	for _, header := range req.Headers {
 		if header.Enabled && header.Key != "" {
@@ -164,6 +219,8 @@ func (h *HTTPHandler) SendRequest(req RequestData) (*ResponseData, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	timer.finish()
+	timing := timer.timing()
 
 	headers := make(map[string]string)
 	for key, values := range resp.Header {
@@ -177,9 +234,93 @@ func (h *HTTPHandler) SendRequest(req RequestData) (*ResponseData, error) {
 		StatusText: resp.Status,
 		Headers:    headers,
 		Body:       string(bodyBytes),
+		Time:       fmt.Sprintf("%dms", int64(timing.Total)),
+		Size:       formatBytes(len(bodyBytes)),
+		SizeBytes:  len(bodyBytes),
+		Timing:     timing,
+		RemoteAddr: timer.remoteAddr,
+		Protocol:   resp.Proto,
+		TLS:        tlsInfo(resp.TLS),
 	}, nil
 }
 
+// SendRequestWithVariables resolves {{variables}} from the given scopes, folds
+// enabled params into the URL and sends the request, refusing to send if any
+// placeholder is left unresolved.
+func (h *HTTPHandler) SendRequestWithVariables(req RequestData, scopes VariableScopes) (*ResponseData, error) {
+	resolved, err := prepareRequest(req, NewVariableResolver(scopes))
+	if err != nil {
+		return nil, err
+	}
+	return h.send(resolved)
+}
+
+// ResolveRequest substitutes variables without sending, so the UI can preview
+// the final request and highlight unresolved names.
+func (h *HTTPHandler) ResolveRequest(req RequestData, scopes VariableScopes) *ResolvedRequest {
+	resolved, err := NewVariableResolver(scopes).ResolveRequestData(req)
+
+	result := &ResolvedRequest{
+		Request:    resolved,
+		Unresolved: []UnresolvedVariable{},
+	}
+	if unresolved, ok := err.(*UnresolvedVariablesError); ok {
+		result.Unresolved = unresolved.Variables
+	}
+	return result
+}
+
+// LoadVariableScopes assembles the stored variable scopes for a request.
+// Any of the IDs may be empty to leave that scope out.
+func (h *HTTPHandler) LoadVariableScopes(workspaceID, collectionID, environmentID string) (VariableScopes, error) {
+	var scopes VariableScopes
+
+	if settings, err := h.LoadSettings(); err == nil {
+		scopes.Global = settings.GlobalVariables
+	}
+
+	if workspaceID != "" {
+		workspaces, err := h.LoadWorkspaces()
+		if err != nil {
+			return scopes, err
+		}
+		for _, ws := range workspaces {
+			if ws.ID == workspaceID {
+				scopes.Workspace = ws.Variables
+				break
+			}
+		}
+	}
+
+	if collectionID != "" {
+		collections, err := h.LoadCollections()
+		if err != nil {
+			return scopes, err
+		}
+		for _, c := range collections {
+			if c.ID == collectionID {
+				scopes.Collection = c.Variables
+				break
+			}
+		}
+	}
+
+	if environmentID != "" {
+		environments, err := h.LoadEnvironments()
+		if err != nil {
+			return scopes, err
+		}
+		for i, env := range environments {
+			if env.ID == environmentID {
+				scopes.Environment = &environments[i]
+				break
+			}
+		}
+	}
+
+	return scopes, nil
+}
+
 func (h *HTTPHandler) SaveWorkspaces(workspaces []Workspace) error {
 	data := WorkspaceData{Workspaces: workspaces}
 	return h.saveJSON(filepath.Join(h.dataDir, "workspaces", "data.json"), data)
@@ -267,4 +408,4 @@ func (h *HTTPHandler) loadJSON(path string, data interface{}) error {
 
 func (h *HTTPHandler) GetDataDirectory() string {
 	return h.dataDir
-}
\ No newline at end of file
+}
@@ -0,0 +1,680 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// TopicDetails is everything KafkaDescribeTopic knows about one topic
+type TopicDetails struct {
+	Name       string             `json:"name"`
+	Internal   bool               `json:"internal"`
+	Partitions []PartitionDetails `json:"partitions"`
+	Configs    []TopicConfigEntry `json:"configs"`
+}
+
+// PartitionDetails lists broker IDs for the leader and replicas. Leader is
+// -1 while the partition has no leader.
+type PartitionDetails struct {
+	Partition       int    `json:"partition"`
+	Leader          int    `json:"leader"`
+	LeaderAddress   string `json:"leaderAddress,omitempty"`
+	Replicas        []int  `json:"replicas"`
+	ISR             []int  `json:"isr"`
+	OfflineReplicas []int  `json:"offlineReplicas,omitempty"`
+	EarliestOffset  int64  `json:"earliestOffset"`
+	LatestOffset    int64  `json:"latestOffset"`
+	Messages        int64  `json:"messages"` // latest - earliest; compaction can make it an overestimate
+	Error           string `json:"error,omitempty"`
+}
+
+type TopicConfigEntry struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Source    string `json:"source"` // "topic", "broker", "default", ...
+	Default   bool   `json:"default"`
+	ReadOnly  bool   `json:"readOnly"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+// CreateTopicRequest creates a topic. Zero Partitions or ReplicationFactor
+// leaves the choice to the broker defaults.
+type CreateTopicRequest struct {
+	ConnectionID      string            `json:"connectionId"`
+	Topic             string            `json:"topic"`
+	Partitions        int               `json:"partitions"`
+	ReplicationFactor int               `json:"replicationFactor"`
+	Configs           map[string]string `json:"configs"`
+}
+
+type ConsumerGroupSummary struct {
+	GroupID      string `json:"groupId"`
+	State        string `json:"state"`
+	ProtocolType string `json:"protocolType"`
+	Members      int    `json:"members"`
+	Coordinator  int    `json:"coordinator"`
+}
+
+// ConsumerGroupDetails is the group's members plus one row per partition
+// that has a committed offset or is assigned to a member
+type ConsumerGroupDetails struct {
+	GroupID  string               `json:"groupId"`
+	State    string               `json:"state"`
+	Members  []GroupMemberInfo    `json:"members"`
+	Offsets  []GroupPartitionInfo `json:"offsets"`
+	TotalLag int64                `json:"totalLag"`
+}
+
+type GroupMemberInfo struct {
+	MemberID    string           `json:"memberId"`
+	ClientID    string           `json:"clientId"`
+	Host        string           `json:"host"`
+	Assignments map[string][]int `json:"assignments"` // topic -> partitions
+}
+
+// GroupPartitionInfo is one partition's progress. CommittedOffset and Lag
+// are -1 when the group has not committed on the partition yet.
+type GroupPartitionInfo struct {
+	Topic           string `json:"topic"`
+	Partition       int    `json:"partition"`
+	CommittedOffset int64  `json:"committedOffset"`
+	LatestOffset    int64  `json:"latestOffset"`
+	Lag             int64  `json:"lag"`
+	MemberID        string `json:"memberId,omitempty"`
+	ClientID        string `json:"clientId,omitempty"`
+	Host            string `json:"host,omitempty"`
+	Metadata        string `json:"metadata,omitempty"`
+}
+
+// ResetOffsetsRequest moves a group's committed offsets on one topic.
+// Strategy is "earliest", "latest", "timestamp" (Timestamp in Unix
+// milliseconds) or "offset" (Offset, clamped to the partition's range).
+// Empty Partitions means every partition of the topic.
+type ResetOffsetsRequest struct {
+	ConnectionID string `json:"connectionId"`
+	GroupID      string `json:"groupId"`
+	Topic        string `json:"topic"`
+	Partitions   []int  `json:"partitions"`
+	Strategy     string `json:"strategy"`
+	Timestamp    int64  `json:"timestamp"`
+	Offset       int64  `json:"offset"`
+}
+
+var configSources = map[int8]string{
+	1: "topic",
+	2: "broker",
+	3: "cluster default",
+	4: "static broker",
+	5: "default",
+	6: "broker logger",
+}
+
+func newKafkaTransport(dialer *kafka.Dialer) *kafka.Transport {
+	return &kafka.Transport{
+		DialTimeout: dialer.Timeout,
+		ClientID:    dialer.ClientID,
+		TLS:         dialer.TLS,
+		SASL:        dialer.SASLMechanism,
+	}
+}
+
+// adminClient routes admin requests through the connection's shared
+// transport, so they reuse its TLS and SASL settings and broker connections
+func (c *KafkaConnection) adminClient() *kafka.Client {
+	return &kafka.Client{
+		Addr:      kafka.TCP(c.Brokers...),
+		Transport: c.transport,
+		Timeout:   10 * time.Second,
+	}
+}
+
+func lookupKafkaConnection(connectionID string) (*KafkaConnection, error) {
+	kafkaMutex.RLock()
+	conn, exists := kafkaConnections[connectionID]
+	kafkaMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("connection not found: %s", connectionID)
+	}
+	return conn, nil
+}
+
+func KafkaDescribeTopic(app AppInterface, connectionID, topic string) (*TopicDetails, error) {
+	conn, err := lookupKafkaConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	client := conn.adminClient()
+
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	if len(meta.Topics) == 0 {
+		return nil, fmt.Errorf("topic not found: %s", topic)
+	}
+	t := meta.Topics[0]
+	if t.Error != nil {
+		return nil, fmt.Errorf("failed to describe topic %s: %w", topic, t.Error)
+	}
+
+	partitionIDs := make([]int, len(t.Partitions))
+	for i, p := range t.Partitions {
+		partitionIDs[i] = p.ID
+	}
+
+	earliest, err := listOffsets(ctx, client, topic, partitionIDs, kafka.FirstOffset)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := listOffsets(ctx, client, topic, partitionIDs, kafka.LastOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &TopicDetails{
+		Name:       t.Name,
+		Internal:   t.Internal,
+		Partitions: make([]PartitionDetails, 0, len(t.Partitions)),
+	}
+
+	for _, p := range t.Partitions {
+		pd := PartitionDetails{
+			Partition:       p.ID,
+			Leader:          -1,
+			Replicas:        brokerIDs(p.Replicas),
+			ISR:             brokerIDs(p.Isr),
+			OfflineReplicas: brokerIDs(p.OfflineReplicas),
+			EarliestOffset:  earliest[p.ID],
+			LatestOffset:    latest[p.ID],
+		}
+		if p.Leader.Host != "" {
+			pd.Leader = p.Leader.ID
+			pd.LeaderAddress = fmt.Sprintf("%s:%d", p.Leader.Host, p.Leader.Port)
+		}
+		if pd.LatestOffset > pd.EarliestOffset {
+			pd.Messages = pd.LatestOffset - pd.EarliestOffset
+		}
+		if p.Error != nil {
+			pd.Error = p.Error.Error()
+		}
+		details.Partitions = append(details.Partitions, pd)
+	}
+	sort.Slice(details.Partitions, func(i, j int) bool {
+		return details.Partitions[i].Partition < details.Partitions[j].Partition
+	})
+
+	details.Configs, err = describeTopicConfigs(ctx, client, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	return details, nil
+}
+
+func KafkaCreateTopic(app AppInterface, req CreateTopicRequest) error {
+	conn, err := lookupKafkaConnection(req.ConnectionID)
+	if err != nil {
+		return err
+	}
+	if req.Topic == "" {
+		return fmt.Errorf("topic name is required")
+	}
+
+	topicConfig := kafka.TopicConfig{
+		Topic:             req.Topic,
+		NumPartitions:     req.Partitions,
+		ReplicationFactor: req.ReplicationFactor,
+	}
+	if topicConfig.NumPartitions <= 0 {
+		topicConfig.NumPartitions = -1
+	}
+	if topicConfig.ReplicationFactor <= 0 {
+		topicConfig.ReplicationFactor = -1
+	}
+
+	names := make([]string, 0, len(req.Configs))
+	for name := range req.Configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		topicConfig.ConfigEntries = append(topicConfig.ConfigEntries, kafka.ConfigEntry{
+			ConfigName:  name,
+			ConfigValue: req.Configs[name],
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	resp, err := conn.adminClient().CreateTopics(ctx, &kafka.CreateTopicsRequest{
+		Topics: []kafka.TopicConfig{topicConfig},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create topic: %w", err)
+	}
+	if err := resp.Errors[req.Topic]; err != nil {
+		return fmt.Errorf("failed to create topic %s: %w", req.Topic, err)
+	}
+
+	log.Printf("[Kafka] Created topic: %s", req.Topic)
+	emitStreamMessage(app, req.ConnectionID, "system", "kafka", fmt.Sprintf("Created topic: %s", req.Topic))
+	return nil
+}
+
+func KafkaDeleteTopic(app AppInterface, connectionID, topic string) error {
+	conn, err := lookupKafkaConnection(connectionID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	resp, err := conn.adminClient().DeleteTopics(ctx, &kafka.DeleteTopicsRequest{Topics: []string{topic}})
+	if err != nil {
+		return fmt.Errorf("failed to delete topic: %w", err)
+	}
+	if err := resp.Errors[topic]; err != nil {
+		return fmt.Errorf("failed to delete topic %s: %w", topic, err)
+	}
+
+	log.Printf("[Kafka] Deleted topic: %s", topic)
+	emitStreamMessage(app, connectionID, "system", "kafka", fmt.Sprintf("Deleted topic: %s", topic))
+	return nil
+}
+
+// KafkaListConsumerGroups asks every broker for the groups it coordinates,
+// since ListGroups only answers for the broker it is sent to
+func KafkaListConsumerGroups(app AppInterface, connectionID string) ([]ConsumerGroupSummary, error) {
+	conn, err := lookupKafkaConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	client := conn.adminClient()
+
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	var groups []ConsumerGroupSummary
+	var groupIDs []string
+	seen := make(map[string]bool)
+	for _, broker := range meta.Brokers {
+		resp, err := client.ListGroups(ctx, &kafka.ListGroupsRequest{
+			Addr: kafka.TCP(fmt.Sprintf("%s:%d", broker.Host, broker.Port)),
+		})
+		if err == nil {
+			err = resp.Error
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list groups on broker %d: %w", broker.ID, err)
+		}
+		for _, g := range resp.Groups {
+			if seen[g.GroupID] {
+				continue
+			}
+			seen[g.GroupID] = true
+			groups = append(groups, ConsumerGroupSummary{
+				GroupID:      g.GroupID,
+				ProtocolType: g.ProtocolType,
+				Coordinator:  g.Coordinator,
+			})
+			groupIDs = append(groupIDs, g.GroupID)
+		}
+	}
+
+	if len(groupIDs) > 0 {
+		resp, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: groupIDs})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe groups: %w", err)
+		}
+		described := make(map[string]kafka.DescribeGroupsResponseGroup, len(resp.Groups))
+		for _, g := range resp.Groups {
+			described[g.GroupID] = g
+		}
+		for i := range groups {
+			if g, ok := described[groups[i].GroupID]; ok && g.Error == nil {
+				groups[i].State = g.GroupState
+				groups[i].Members = len(g.Members)
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].GroupID < groups[j].GroupID })
+	if groups == nil {
+		groups = []ConsumerGroupSummary{}
+	}
+	return groups, nil
+}
+
+// KafkaDescribeConsumerGroup returns a group's members, committed offsets
+// and lag against each partition's latest offset
+func KafkaDescribeConsumerGroup(app AppInterface, connectionID, groupID string) (*ConsumerGroupDetails, error) {
+	conn, err := lookupKafkaConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	client := conn.adminClient()
+
+	group, err := describeGroup(ctx, client, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &ConsumerGroupDetails{
+		GroupID: groupID,
+		State:   group.GroupState,
+		Members: []GroupMemberInfo{},
+		Offsets: []GroupPartitionInfo{},
+	}
+
+	type topicPartition struct {
+		topic     string
+		partition int
+	}
+	rows := make(map[topicPartition]*GroupPartitionInfo)
+	row := func(topic string, partition int) *GroupPartitionInfo {
+		key := topicPartition{topic, partition}
+		if rows[key] == nil {
+			rows[key] = &GroupPartitionInfo{Topic: topic, Partition: partition, CommittedOffset: -1, Lag: -1}
+		}
+		return rows[key]
+	}
+
+	for _, m := range group.Members {
+		member := GroupMemberInfo{
+			MemberID:    m.MemberID,
+			ClientID:    m.ClientID,
+			Host:        m.ClientHost,
+			Assignments: make(map[string][]int),
+		}
+		for _, t := range m.MemberAssignments.Topics {
+			member.Assignments[t.Topic] = append(member.Assignments[t.Topic], t.Partitions...)
+			for _, p := range t.Partitions {
+				r := row(t.Topic, p)
+				r.MemberID = m.MemberID
+				r.ClientID = m.ClientID
+				r.Host = m.ClientHost
+			}
+		}
+		details.Members = append(details.Members, member)
+	}
+
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: groupID})
+	if err == nil {
+		err = committed.Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", err)
+	}
+	for topic, partitions := range committed.Topics {
+		for _, p := range partitions {
+			if p.Error != nil || p.CommittedOffset < 0 {
+				continue
+			}
+			r := row(topic, p.Partition)
+			r.CommittedOffset = p.CommittedOffset
+			r.Metadata = p.Metadata
+		}
+	}
+
+	byTopic := make(map[string][]int)
+	for key := range rows {
+		byTopic[key.topic] = append(byTopic[key.topic], key.partition)
+	}
+	for topic, partitions := range byTopic {
+		latest, err := listOffsets(ctx, client, topic, partitions, kafka.LastOffset)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range partitions {
+			r := rows[topicPartition{topic, p}]
+			r.LatestOffset = latest[p]
+			if r.CommittedOffset >= 0 {
+				r.Lag = r.LatestOffset - r.CommittedOffset
+				if r.Lag < 0 {
+					r.Lag = 0
+				}
+				details.TotalLag += r.Lag
+			}
+		}
+	}
+
+	for _, r := range rows {
+		details.Offsets = append(details.Offsets, *r)
+	}
+	sort.Slice(details.Offsets, func(i, j int) bool {
+		a, b := details.Offsets[i], details.Offsets[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Partition < b.Partition
+	})
+	sort.Slice(details.Members, func(i, j int) bool { return details.Members[i].MemberID < details.Members[j].MemberID })
+
+	return details, nil
+}
+
+// KafkaResetConsumerGroupOffsets commits new offsets for a group and returns
+// them. Brokers only accept this while the group has no active members.
+func KafkaResetConsumerGroupOffsets(app AppInterface, req ResetOffsetsRequest) ([]PartitionOffset, error) {
+	conn, err := lookupKafkaConnection(req.ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	if req.GroupID == "" || req.Topic == "" {
+		return nil, fmt.Errorf("group and topic are required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	client := conn.adminClient()
+
+	group, err := describeGroup(ctx, client, req.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(group.Members) > 0 {
+		return nil, fmt.Errorf("group %s has %d active members; stop its consumers before resetting offsets", req.GroupID, len(group.Members))
+	}
+
+	partitions := req.Partitions
+	if len(partitions) == 0 {
+		partitions, err = lookupPartitions(conn, req.Topic)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	earliest, err := listOffsets(ctx, client, req.Topic, partitions, kafka.FirstOffset)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := listOffsets(ctx, client, req.Topic, partitions, kafka.LastOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[int]int64, len(partitions))
+	switch req.Strategy {
+	case "earliest":
+		targets = earliest
+	case "latest":
+		targets = latest
+	case "timestamp":
+		byTime, err := listOffsets(ctx, client, req.Topic, partitions, req.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range partitions {
+			// Nothing at or after the timestamp: start at the end
+			if offset := byTime[p]; offset >= 0 {
+				targets[p] = offset
+			} else {
+				targets[p] = latest[p]
+			}
+		}
+	case "offset":
+		for _, p := range partitions {
+			targets[p] = min(max(req.Offset, earliest[p]), latest[p])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported reset strategy: %s", req.Strategy)
+	}
+
+	commits := make([]kafka.OffsetCommit, 0, len(partitions))
+	for _, p := range partitions {
+		commits = append(commits, kafka.OffsetCommit{Partition: p, Offset: targets[p]})
+	}
+
+	resp, err := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      req.GroupID,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{req.Topic: commits},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit offsets: %w", err)
+	}
+	var failed []error
+	for _, p := range resp.Topics[req.Topic] {
+		if p.Error != nil {
+			failed = append(failed, fmt.Errorf("partition %d: %w", p.Partition, p.Error))
+		}
+	}
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("failed to commit offsets: %w", errors.Join(failed...))
+	}
+
+	reset := make([]PartitionOffset, 0, len(partitions))
+	parts := make([]string, 0, len(partitions))
+	for _, p := range partitions {
+		reset = append(reset, PartitionOffset{Partition: p, Offset: targets[p]})
+	}
+	sort.Slice(reset, func(i, j int) bool { return reset[i].Partition < reset[j].Partition })
+	for _, r := range reset {
+		parts = append(parts, fmt.Sprintf("%d@%d", r.Partition, r.Offset))
+	}
+
+	log.Printf("[Kafka] Reset offsets of group %s on %s (%s)", req.GroupID, req.Topic, req.Strategy)
+	emitStreamMessage(app, req.ConnectionID, "system", "kafka", fmt.Sprintf("Reset group %s on %s to %s (partition@offset): %s", req.GroupID, req.Topic, req.Strategy, strings.Join(parts, ", ")))
+
+	return reset, nil
+}
+
+func describeGroup(ctx context.Context, client *kafka.Client, groupID string) (*kafka.DescribeGroupsResponseGroup, error) {
+	resp, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{groupID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe group: %w", err)
+	}
+	if len(resp.Groups) == 0 {
+		return nil, fmt.Errorf("group not found: %s", groupID)
+	}
+	group := resp.Groups[0]
+	if group.Error != nil {
+		return nil, fmt.Errorf("failed to describe group %s: %w", groupID, group.Error)
+	}
+	return &group, nil
+}
+
+func describeTopicConfigs(ctx context.Context, client *kafka.Client, topic string) ([]TopicConfigEntry, error) {
+	resp, err := client.DescribeConfigs(ctx, &kafka.DescribeConfigsRequest{
+		Resources: []kafka.DescribeConfigRequestResource{{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: topic,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topic configs: %w", err)
+	}
+
+	configs := []TopicConfigEntry{}
+	for _, resource := range resp.Resources {
+		if resource.Error != nil {
+			return nil, fmt.Errorf("failed to describe topic configs: %w", resource.Error)
+		}
+		for _, entry := range resource.ConfigEntries {
+			configs = append(configs, TopicConfigEntry{
+				Name:      entry.ConfigName,
+				Value:     entry.ConfigValue,
+				Source:    configSources[entry.ConfigSource],
+				Default:   entry.IsDefault || entry.ConfigSource == 5,
+				ReadOnly:  entry.ReadOnly,
+				Sensitive: entry.IsSensitive,
+			})
+		}
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	return configs, nil
+}
+
+// listOffsets resolves kafka.FirstOffset, kafka.LastOffset or a Unix
+// millisecond timestamp to an offset per partition; -1 means no message at
+// or after the timestamp. kafka-go files each result under the timestamp the
+// broker echoes back, which brokers set to -1 for both earliest and latest
+// lookups, so the value is read back from wherever it landed.
+func listOffsets(ctx context.Context, client *kafka.Client, topic string, partitions []int, spec int64) (map[int]int64, error) {
+	requests := make([]kafka.OffsetRequest, len(partitions))
+	for i, p := range partitions {
+		requests[i] = kafka.OffsetRequest{Partition: p, Timestamp: spec}
+	}
+
+	resp, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", err)
+	}
+
+	offsets := make(map[int]int64, len(partitions))
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets for partition %d: %w", p.Partition, p.Error)
+		}
+
+		offset := int64(-1)
+		for o := range p.Offsets {
+			offset = o
+		}
+		if len(p.Offsets) == 0 {
+			switch {
+			case spec == kafka.FirstOffset && p.LastOffset < 0:
+				offset = p.FirstOffset
+			case spec == kafka.FirstOffset, spec == kafka.LastOffset:
+				offset = p.LastOffset
+			}
+		}
+		offsets[p.Partition] = offset
+	}
+	return offsets, nil
+}
+
+func brokerIDs(brokers []kafka.Broker) []int {
+	ids := make([]int, len(brokers))
+	for i, b := range brokers {
+		ids[i] = b.ID
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestListOffsets(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 2})
+	start := time.UnixMilli(1_700_000_000_000)
+	k.produce("orders", 0, start, "a", "b", "c")
+	k.truncate("orders", 0, 1)
+
+	app, _ := newTestApp(t)
+	conn, _ := lookupKafkaConnection(connectTestKafka(t, app, k))
+	client := conn.adminClient()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		spec int64
+		want map[int]int64
+	}{
+		// Partition 1 is empty: both ends are 0
+		{name: "earliest", spec: kafka.FirstOffset, want: map[int]int64{0: 1, 1: 0}},
+		{name: "latest", spec: kafka.LastOffset, want: map[int]int64{0: 3, 1: 0}},
+		{name: "timestamp", spec: start.Add(1500 * time.Millisecond).UnixMilli(), want: map[int]int64{0: 2, 1: -1}},
+		{name: "timestamp past the end", spec: start.Add(time.Hour).UnixMilli(), want: map[int]int64{0: -1, 1: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := listOffsets(ctx, client, "orders", []int{0, 1}, tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := listOffsets(ctx, client, "orders", []int{7}, kafka.LastOffset); err == nil || !strings.Contains(err.Error(), "partition 7") {
+		t.Errorf("unknown partition: err = %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+// KafkaOAuthConfig fetches OAUTHBEARER tokens with the OAuth 2.0 client
+// credentials grant. An empty client ID or secret is read from the
+// KAFKA_OAUTH_CLIENT_ID and KAFKA_OAUTH_CLIENT_SECRET environment variables.
+type KafkaOAuthConfig struct {
+	TokenURL     string            `json:"tokenUrl"`
+	ClientID     string            `json:"clientId"`
+	ClientSecret string            `json:"clientSecret"`
+	Scope        string            `json:"scope,omitempty"`      // space separated
+	Audience     string            `json:"audience,omitempty"`   // for providers that require one, such as Auth0
+	Extensions   map[string]string `json:"extensions,omitempty"` // SASL extensions, e.g. logicalCluster on Confluent Cloud
+}
+
+// KafkaAWSConfig selects the credentials MSK IAM signs with. Without an
+// access key the default AWS chain is used: environment variables, the
+// shared config and credentials files (Profile, or AWS_PROFILE), SSO, and
+// instance or container roles.
+type KafkaAWSConfig struct {
+	Region          string `json:"region,omitempty"` // from the profile, AWS_REGION or the broker hostname when empty
+	Profile         string `json:"profile,omitempty"`
+	AccessKeyID     string `json:"accessKeyId,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+	SessionToken    string `json:"sessionToken,omitempty"`
+}
+
+// OAuthTokenInfo describes a fetched token without exposing it
+type OAuthTokenInfo struct {
+	TokenType string    `json:"tokenType"`
+	Scope     string    `json:"scope,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"` // zero when the provider didn't say
+	RefreshAt time.Time `json:"refreshAt"`
+}
+
+// KafkaTestOAuthToken fetches a token with the config's OAuth settings, to
+// check them before connecting
+func KafkaTestOAuthToken(app AppInterface, config KafkaConfig) (*OAuthTokenInfo, error) {
+	config, err := resolverFor(config.Scopes).ResolveKafkaConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	source, err := newOAuthTokenSource(config.OAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if _, err := source.token(ctx); err != nil {
+		return nil, err
+	}
+	return source.info(), nil
+}
+
+// oauthTokenSource caches a client credentials token and fetches a new one
+// once most of its lifetime has passed, so connections opened near expiry
+// never authenticate with a token about to lapse
+type oauthTokenSource struct {
+	config KafkaOAuthConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	current   string
+	tokenType string
+	scope     string
+	expiresAt time.Time
+	refreshAt time.Time
+}
+
+const (
+	// tokens are refreshed after this share of their lifetime...
+	oauthRefreshRatio = 0.8
+	// ...but at least this long before they expire
+	oauthMinRefreshMargin = 30 * time.Second
+)
+
+func newOAuthTokenSource(config *KafkaOAuthConfig) (*oauthTokenSource, error) {
+	if config == nil || config.TokenURL == "" {
+		return nil, fmt.Errorf("OAuth token URL is required")
+	}
+	resolved := *config
+	if resolved.ClientID == "" {
+		resolved.ClientID = os.Getenv("KAFKA_OAUTH_CLIENT_ID")
+	}
+	if resolved.ClientSecret == "" {
+		resolved.ClientSecret = os.Getenv("KAFKA_OAUTH_CLIENT_SECRET")
+	}
+	if resolved.ClientID == "" {
+		return nil, fmt.Errorf("OAuth client ID is required")
+	}
+
+	return &oauthTokenSource{
+		config: resolved,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+func (s *oauthTokenSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != "" && time.Now().Before(s.refreshAt) {
+		return s.current, nil
+	}
+	if err := s.fetch(ctx); err != nil {
+		return "", err
+	}
+	return s.current, nil
+}
+
+func (s *oauthTokenSource) fetch(ctx context.Context) error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if s.config.Scope != "" {
+		form.Set("scope", s.config.Scope)
+	}
+	if s.config.Audience != "" {
+		form.Set("audience", s.config.Audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("invalid OAuth token URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.config.ClientID), url.QueryEscape(s.config.ClientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OAuth token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read OAuth token response: %w", err)
+	}
+
+	var token struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		ExpiresIn        json.Number `json:"expires_in"` // some providers send a string
+		Scope            string      `json:"scope"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil && resp.StatusCode == http.StatusOK {
+		return fmt.Errorf("invalid OAuth token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		reason := strings.TrimSpace(token.Error + " " + token.ErrorDescription)
+		if reason == "" {
+			reason = strings.TrimSpace(string(body))
+		}
+		return fmt.Errorf("OAuth token request failed: %s: %s", resp.Status, reason)
+	}
+
+	now := time.Now()
+	s.current, s.tokenType, s.scope = token.AccessToken, token.TokenType, token.Scope
+	s.expiresAt, s.refreshAt = time.Time{}, now // without an expiry, fetch for every connection
+	if seconds, err := token.ExpiresIn.Float64(); err == nil && seconds > 0 {
+		lifetime := time.Duration(seconds * float64(time.Second))
+		s.expiresAt = now.Add(lifetime)
+		margin := max(time.Duration(float64(lifetime)*(1-oauthRefreshRatio)), oauthMinRefreshMargin)
+		s.refreshAt = s.expiresAt.Add(-min(margin, lifetime))
+	}
+	return nil
+}
+
+func (s *oauthTokenSource) info() *OAuthTokenInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &OAuthTokenInfo{
+		TokenType: s.tokenType,
+		Scope:     s.scope,
+		ExpiresAt: s.expiresAt,
+		RefreshAt: s.refreshAt,
+	}
+}
+
+// oauthBearerMechanism implements SASL/OAUTHBEARER (RFC 7628) for kafka-go
+type oauthBearerMechanism struct {
+	tokens     *oauthTokenSource
+	extensions map[string]string
+}
+
+func (m *oauthBearerMechanism) Name() string {
+	return "OAUTHBEARER"
+}
+
+func (m *oauthBearerMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	token, err := m.tokens.token(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// extensions go between the auth field and the final separator,
+	// sorted to keep the message stable
+	keys := make([]string, 0, len(m.extensions))
+	for k := range m.extensions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var msg strings.Builder
+	msg.WriteString("n,,\x01auth=Bearer " + token + "\x01")
+	for _, k := range keys {
+		msg.WriteString(k + "=" + m.extensions[k] + "\x01")
+	}
+	msg.WriteString("\x01")
+	return m, []byte(msg.String()), nil
+}
+
+// Next sees a challenge only when the broker rejected the token; it holds
+// a JSON error such as {"status":"invalid_token"}
+func (m *oauthBearerMechanism) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	if len(challenge) > 0 {
+		return false, nil, fmt.Errorf("OAUTHBEARER authentication rejected: %s", challenge)
+	}
+	return true, nil, nil
+}
+
+func newOAuthBearerMechanism(config *KafkaOAuthConfig) (sasl.Mechanism, error) {
+	tokens, err := newOAuthTokenSource(config)
+	if err != nil {
+		return nil, err
+	}
+	for k := range config.Extensions {
+		if k == "auth" || !saslExtensionKey.MatchString(k) {
+			return nil, fmt.Errorf("invalid SASL extension name: %q", k)
+		}
+	}
+	return &oauthBearerMechanism{tokens: tokens, extensions: config.Extensions}, nil
+}
+
+var saslExtensionKey = regexp.MustCompile(`^[A-Za-z]+$`)
+
+// mskIAMMechanism implements AWS_MSK_IAM: the client sends a SigV4
+// presigned kafka-cluster:Connect request for the broker it's talking to
+type mskIAMMechanism struct {
+	region      string
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+}
+
+const (
+	mskSignService = "kafka-cluster"
+	mskSignAction  = "kafka-cluster:Connect"
+	mskSignVersion = "2020_10_22"
+	mskSignExpiry  = 5 * time.Minute
+)
+
+// sha256 of an empty payload, which is what a presigned GET carries
+var emptyPayloadHash = hex.EncodeToString(sha256.New().Sum(nil))
+
+// mskRegionPattern finds the region in MSK broker hostnames such as
+// b-1.demo.abc123.c2.kafka.eu-west-1.amazonaws.com
+var mskRegionPattern = regexp.MustCompile(`\.kafka(?:-serverless)?\.([a-z0-9-]+)\.amazonaws\.com`)
+
+func (m *mskIAMMechanism) Name() string {
+	return "AWS_MSK_IAM"
+}
+
+func (m *mskIAMMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	metadata := sasl.MetadataFromContext(ctx)
+	if metadata == nil {
+		return nil, nil, fmt.Errorf("AWS_MSK_IAM needs the broker address to sign for")
+	}
+
+	creds, err := m.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load AWS credentials: %w", err)
+	}
+
+	query := url.Values{
+		"Action":        {mskSignAction},
+		"X-Amz-Expires": {fmt.Sprint(int(mskSignExpiry.Seconds()))},
+	}
+	target := url.URL{Scheme: "kafka", Host: metadata.Host, Path: "/", RawQuery: query.Encode()}
+	req, err := http.NewRequest(http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signed, _, err := m.signer.PresignHTTP(ctx, creds, req, emptyPayloadHash, mskSignService, m.region, time.Now().UTC())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign MSK IAM request: %w", err)
+	}
+	signedURL, err := url.Parse(signed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The broker expects the presigned query as lowercase JSON keys
+	payload := map[string]string{
+		"version":    mskSignVersion,
+		"host":       signedURL.Host,
+		"user-agent": "pulse",
+		"action":     mskSignAction,
+	}
+	for key, values := range signedURL.Query() {
+		payload[strings.ToLower(key)] = values[0]
+	}
+	msg, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, msg, nil
+}
+
+func (m *mskIAMMechanism) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	// the broker answers with the authenticated principal; any failure is
+	// reported as an error by the SASL handshake itself
+	return true, nil, nil
+}
+
+func newMSKIAMMechanism(config KafkaConfig) (sasl.Mechanism, error) {
+	settings := KafkaAWSConfig{}
+	if config.AWS != nil {
+		settings = *config.AWS
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var options []func(*awsconfig.LoadOptions) error
+	if settings.Profile != "" {
+		options = append(options, awsconfig.WithSharedConfigProfile(settings.Profile))
+	}
+	if settings.Region != "" {
+		options = append(options, awsconfig.WithRegion(settings.Region))
+	}
+	if settings.AccessKeyID != "" {
+		options = append(options, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(settings.AccessKeyID, settings.SecretAccessKey, settings.SessionToken)))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	region := awsCfg.Region
+	if region == "" {
+		for _, server := range config.BootstrapServers {
+			if match := mskRegionPattern.FindStringSubmatch(server); match != nil {
+				region = match[1]
+				break
+			}
+		}
+	}
+	if region == "" {
+		return nil, fmt.Errorf("AWS region is required: set it, AWS_REGION, or a profile region")
+	}
+
+	// LoadDefaultConfig wraps providers in a cache that renews temporary
+	// credentials before they expire
+	return &mskIAMMechanism{
+		region:      region,
+		credentials: awsCfg.Credentials,
+		signer:      v4.NewSigner(),
+	}, nil
+}
@@ -0,0 +1,193 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer answers every token request with the given status and body
+// and counts the requests
+func tokenServer(t *testing.T, status int, body string) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("bad form: %v", err)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "client_credentials" {
+			t.Errorf("grant_type = %q", got)
+		}
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client%40id" || secret != "s3cret" {
+			t.Errorf("basic auth = %q %q %v", id, secret, ok)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newTestTokenSource(t *testing.T, tokenURL string) *oauthTokenSource {
+	t.Helper()
+
+	source, err := newOAuthTokenSource(&KafkaOAuthConfig{
+		TokenURL:     tokenURL,
+		ClientID:     "client@id",
+		ClientSecret: "s3cret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+func TestOAuthTokenExpiresIn(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		lifetime  time.Duration // zero when no expiry is reported
+		refreshIn time.Duration // time from fetch until refresh
+	}{
+		{"number", `{"access_token":"t","expires_in":3600}`, time.Hour, 48 * time.Minute},
+		{"string", `{"access_token":"t","expires_in":"3600"}`, time.Hour, 48 * time.Minute},
+		{"fractional", `{"access_token":"t","expires_in":1.5}`, 1500 * time.Millisecond, 0},
+		{"minimum margin", `{"access_token":"t","expires_in":60}`, time.Minute, 30 * time.Second},
+		{"shorter than margin", `{"access_token":"t","expires_in":10}`, 10 * time.Second, 0},
+		{"missing", `{"access_token":"t"}`, 0, 0},
+		{"zero", `{"access_token":"t","expires_in":0}`, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := tokenServer(t, http.StatusOK, tt.body)
+			source := newTestTokenSource(t, server.URL)
+
+			before := time.Now()
+			token, err := source.token(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token != "t" {
+				t.Errorf("token = %q", token)
+			}
+
+			info := source.info()
+			if tt.lifetime == 0 {
+				if !info.ExpiresAt.IsZero() {
+					t.Errorf("ExpiresAt = %v, want zero", info.ExpiresAt)
+				}
+			} else if got := info.ExpiresAt.Sub(before); got < tt.lifetime || got > tt.lifetime+time.Second {
+				t.Errorf("expires after %v, want %v", got, tt.lifetime)
+			}
+
+			if got := info.RefreshAt.Sub(before); got < tt.refreshIn || got > tt.refreshIn+time.Second {
+				t.Errorf("refreshes after %v, want %v", got, tt.refreshIn)
+			}
+		})
+	}
+}
+
+func TestOAuthTokenCaching(t *testing.T) {
+	server, requests := tokenServer(t, http.StatusOK, `{"access_token":"t","expires_in":3600}`)
+	source := newTestTokenSource(t, server.URL)
+
+	for i := 0; i < 3; i++ {
+		if _, err := source.token(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Errorf("fetched %d tokens, want 1", n)
+	}
+
+	// Past the refresh point a new token is fetched
+	source.refreshAt = time.Now().Add(-time.Second)
+	if _, err := source.token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(requests); n != 2 {
+		t.Errorf("fetched %d tokens, want 2", n)
+	}
+}
+
+func TestOAuthTokenWithoutExpiryIsFetchedEveryTime(t *testing.T) {
+	server, requests := tokenServer(t, http.StatusOK, `{"access_token":"t"}`)
+	source := newTestTokenSource(t, server.URL)
+
+	for i := 0; i < 2; i++ {
+		if _, err := source.token(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(requests); n != 2 {
+		t.Errorf("fetched %d tokens, want 2", n)
+	}
+}
+
+func TestOAuthTokenErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"oauth error", http.StatusUnauthorized, `{"error":"invalid_client","error_description":"bad secret"}`, "401 Unauthorized: invalid_client bad secret"},
+		{"plain text", http.StatusBadGateway, `upstream down`, "502 Bad Gateway: upstream down"},
+		{"no access token", http.StatusOK, `{"token_type":"bearer"}`, `200 OK: {"token_type":"bearer"}`},
+		{"invalid JSON", http.StatusOK, `<html>`, "invalid OAuth token response"},
+		{"invalid expires_in", http.StatusOK, `{"access_token":"t","expires_in":"soon"}`, "invalid OAuth token response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := tokenServer(t, tt.status, tt.body)
+			source := newTestTokenSource(t, server.URL)
+
+			_, err := source.token(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOAuthBearerInitialResponse(t *testing.T) {
+	server, _ := tokenServer(t, http.StatusOK, `{"access_token":"abc","expires_in":3600}`)
+
+	mechanism, err := newOAuthBearerMechanism(&KafkaOAuthConfig{
+		TokenURL:     server.URL,
+		ClientID:     "client@id",
+		ClientSecret: "s3cret",
+		Extensions:   map[string]string{"logicalCluster": "lkc-1", "identityPoolId": "pool-2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, msg, err := mechanism.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "n,,\x01auth=Bearer abc\x01identityPoolId=pool-2\x01logicalCluster=lkc-1\x01\x01"
+	if string(msg) != want {
+		t.Errorf("initial response = %q, want %q", msg, want)
+	}
+
+	if _, err := newOAuthBearerMechanism(&KafkaOAuthConfig{
+		TokenURL:   server.URL,
+		ClientID:   "id",
+		Extensions: map[string]string{"auth": "x"},
+	}); err == nil {
+		t.Error("expected the reserved auth extension to be rejected")
+	}
+}
@@ -0,0 +1,504 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+// BatchProduceRequest produces many messages through the connection's
+// pooled writers. Messages come from a JSONL or CSV file, or from key, value
+// and header templates evaluated per message, where {{index}} is the
+// message's sequence number (unless a variable named index is defined) and
+// dynamic variables such as {{$uuid}} are fresh each time.
+//
+// JSONL lines are {"key": ..., "value": ..., "headers": {...}}; a value that
+// isn't a string is sent as compact JSON. CSV needs a header row with a
+// value column, and optionally key and header.<name> columns.
+type BatchProduceRequest struct {
+	ConnectionID  string            `json:"connectionId"`
+	Topic         string            `json:"topic"`
+	Partition     int               `json:"partition"` // -1 lets the balancer choose
+	Compression   string            `json:"compression"`
+	Acks          int               `json:"acks"`
+	FilePath      string            `json:"filePath,omitempty"` // .jsonl/.ndjson or .csv; templates are used without it
+	KeyTemplate   string            `json:"keyTemplate,omitempty"`
+	ValueTemplate string            `json:"valueTemplate,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"` // templates too
+	KeySchema     *SchemaSelection  `json:"keySchema,omitempty"`
+	ValueSchema   *SchemaSelection  `json:"valueSchema,omitempty"`
+	Count         int               `json:"count"` // messages to send; for files 0 sends each record once, more cycles through them
+	Rate          float64           `json:"rate"`  // messages per second; 0 sends as fast as the writer accepts
+}
+
+// BatchProduceProgress is sent as the metadata of system messages while a
+// batch runs, and once more when it ends
+type BatchProduceProgress struct {
+	BatchID    string  `json:"batchId"`
+	State      string  `json:"state"` // "running", "completed" or "stopped"
+	Total      int     `json:"total"`
+	Sent       int     `json:"sent"`
+	Acked      int     `json:"acked"`
+	Failed     int     `json:"failed"`
+	Bytes      int64   `json:"bytes"`
+	ElapsedMs  int64   `json:"elapsedMs"`
+	Throughput float64 `json:"throughput"` // acked messages per second
+	LatencyP50 float64 `json:"latencyP50"` // ms from queueing to acknowledgement
+	LatencyP95 float64 `json:"latencyP95"`
+	LatencyP99 float64 `json:"latencyP99"`
+	LatencyMax float64 `json:"latencyMax"`
+}
+
+// batchRecord is one message before variables and schemas are applied
+type batchRecord struct {
+	key     string
+	value   string
+	headers map[string]string
+}
+
+type batchProducer struct {
+	id       string
+	req      BatchProduceRequest
+	records  []batchRecord // from the file; nil for templates
+	resolver *VariableResolver
+	cancel   context.CancelFunc
+	inFlight sync.WaitGroup
+	done     chan struct{} // closed once run has returned
+
+	mu        sync.Mutex
+	progress  BatchProduceProgress
+	started   time.Time
+	latencies []float64 // reservoir sample, see recordLatency
+	seen      int
+	reported  int // per-message errors emitted so far
+}
+
+const (
+	batchMaxInFlight     = 1000
+	batchLatencySamples  = 10000
+	batchMaxErrorReports = 100
+)
+
+// KafkaStartBatchProduce checks the request and its input file, then
+// produces in the background. Progress and per-message errors arrive as
+// stream messages carrying the returned batch ID.
+func KafkaStartBatchProduce(app AppInterface, req BatchProduceRequest) (string, error) {
+	conn, err := lookupKafkaConnection(req.ConnectionID)
+	if err != nil {
+		return "", err
+	}
+	if req.Topic == "" {
+		return "", fmt.Errorf("topic is required")
+	}
+	if req.Count < 0 || req.Rate < 0 {
+		return "", fmt.Errorf("count and rate can't be negative")
+	}
+
+	batch := &batchProducer{
+		id:       uuid.New().String(),
+		req:      req,
+		resolver: resolverFor(conn.Config.Scopes),
+		done:     make(chan struct{}),
+	}
+
+	total := req.Count
+	if req.FilePath != "" {
+		if batch.records, err = readBatchFile(req.FilePath); err != nil {
+			return "", err
+		}
+		if len(batch.records) == 0 {
+			return "", fmt.Errorf("no records in %s", req.FilePath)
+		}
+		if total == 0 {
+			total = len(batch.records)
+		}
+	} else if total == 0 {
+		return "", fmt.Errorf("count is required when producing from templates")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	batch.cancel = cancel
+	batch.progress = BatchProduceProgress{BatchID: batch.id, State: "running", Total: total}
+
+	conn.mu.Lock()
+	conn.Batches[batch.id] = batch
+	conn.mu.Unlock()
+
+	log.Printf("[Kafka] Batch %s started: %d messages to %s", batch.id, total, req.Topic)
+	go batch.run(ctx, app, conn)
+
+	return batch.id, nil
+}
+
+// KafkaStopBatchProduce stops queueing new messages; ones already queued
+// are still acknowledged before the final progress message
+func KafkaStopBatchProduce(app AppInterface, connectionID, batchID string) error {
+	conn, err := lookupKafkaConnection(connectionID)
+	if err != nil {
+		return err
+	}
+
+	conn.mu.RLock()
+	batch, exists := conn.Batches[batchID]
+	conn.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("batch not found: %s", batchID)
+	}
+
+	batch.stop()
+	return nil
+}
+
+func (b *batchProducer) stop() {
+	b.mu.Lock()
+	if b.progress.State == "running" {
+		b.progress.State = "stopped"
+	}
+	b.mu.Unlock()
+	b.cancel()
+}
+
+func (b *batchProducer) run(ctx context.Context, app AppInterface, conn *KafkaConnection) {
+	defer close(b.done)
+	defer b.cancel()
+
+	writer := conn.writer(b.req.Topic, b.req.Partition, b.req.Compression, b.req.Acks)
+	slots := make(chan struct{}, batchMaxInFlight)
+
+	b.mu.Lock()
+	b.started = time.Now()
+	b.mu.Unlock()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 0; i < b.progress.Total; i++ {
+		if b.req.Rate > 0 {
+			due := b.started.Add(time.Duration(float64(i) / b.req.Rate * float64(time.Second)))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+				}
+			}
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+			b.emitProgress(app)
+		default:
+		}
+
+		msg, err := b.message(ctx, conn, i)
+		if err != nil {
+			<-slots
+			b.fail(app, i, err)
+			continue
+		}
+
+		queued := time.Now()
+		b.inFlight.Add(1)
+		msg.WriterData = writeCallback(func(_ kafka.Message, err error) {
+			defer b.inFlight.Done()
+			<-slots
+			if err != nil {
+				b.fail(app, i, err)
+				return
+			}
+			b.ack(time.Since(queued))
+		})
+
+		if err := writer.WriteMessages(ctx, msg); err != nil {
+			b.inFlight.Done()
+			<-slots
+			b.fail(app, i, err)
+			continue
+		}
+
+		b.mu.Lock()
+		b.progress.Sent++
+		b.progress.Bytes += int64(len(msg.Key) + len(msg.Value))
+		b.mu.Unlock()
+	}
+
+	// Whatever was queued is still acknowledged, even after a stop
+	acked := make(chan struct{})
+	go func() {
+		b.inFlight.Wait()
+		close(acked)
+	}()
+	for waiting := true; waiting; {
+		select {
+		case <-acked:
+			waiting = false
+		case <-ticker.C:
+			b.emitProgress(app)
+		}
+	}
+
+	b.mu.Lock()
+	if b.progress.State == "running" {
+		b.progress.State = "completed"
+	}
+	b.mu.Unlock()
+
+	conn.mu.Lock()
+	delete(conn.Batches, b.id)
+	conn.mu.Unlock()
+
+	progress := b.snapshot()
+	log.Printf("[Kafka] Batch %s %s: %d acked, %d failed", b.id, progress.State, progress.Acked, progress.Failed)
+	b.emitProgress(app)
+}
+
+// message builds the i-th message from the file or the templates
+func (b *batchProducer) message(ctx context.Context, conn *KafkaConnection, i int) (kafka.Message, error) {
+	var record batchRecord
+	if b.records != nil {
+		record = b.records[i%len(b.records)]
+	} else {
+		res := b.resolver.begin()
+		res.fallback = map[string]string{"index": strconv.Itoa(i)}
+		record = batchRecord{
+			key:     res.text("key", b.req.KeyTemplate),
+			value:   res.text("value", b.req.ValueTemplate),
+			headers: res.stringMap("headers", b.req.Headers),
+		}
+		if err := res.err(); err != nil {
+			return kafka.Message{}, err
+		}
+	}
+
+	key, _, err := serializeRecordData(ctx, conn, b.req.KeySchema, b.req.Topic+"-key", record.key)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to serialize key: %w", err)
+	}
+	value, _, err := serializeRecordData(ctx, conn, b.req.ValueSchema, b.req.Topic+"-value", record.value)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to serialize value: %w", err)
+	}
+
+	msg := kafka.Message{Key: key, Value: value, Time: time.Now()}
+	for k, v := range record.headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return msg, nil
+}
+
+func (b *batchProducer) ack(latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.progress.Acked++
+
+	// Algorithm R keeps a uniform sample of every latency seen so far
+	ms := float64(latency.Microseconds()) / 1000
+	b.seen++
+	if len(b.latencies) < batchLatencySamples {
+		b.latencies = append(b.latencies, ms)
+	} else if j := rand.Intn(b.seen); j < batchLatencySamples {
+		b.latencies[j] = ms
+	}
+	b.progress.LatencyMax = max(b.progress.LatencyMax, ms)
+}
+
+// fail counts a failed message and reports the first few individually
+func (b *batchProducer) fail(app AppInterface, index int, err error) {
+	b.mu.Lock()
+	b.progress.Failed++
+	report := b.reported < batchMaxErrorReports
+	if report {
+		b.reported++
+	}
+	last := b.reported == batchMaxErrorReports && report
+	b.mu.Unlock()
+
+	if !report {
+		return
+	}
+	payload := fmt.Sprintf("Message %d failed: %v", index, err)
+	if last {
+		payload += " (further errors are only counted)"
+	}
+	emitKafkaEvent(app, b.req.ConnectionID, "error", payload, map[string]interface{}{
+		"batchId": b.id,
+		"index":   index,
+		"error":   err.Error(),
+	})
+}
+
+func (b *batchProducer) snapshot() BatchProduceProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	progress := b.progress
+	if !b.started.IsZero() {
+		elapsed := time.Since(b.started)
+		progress.ElapsedMs = elapsed.Milliseconds()
+		if elapsed > 0 {
+			progress.Throughput = float64(progress.Acked) / elapsed.Seconds()
+		}
+	}
+
+	if len(b.latencies) > 0 {
+		sorted := append([]float64(nil), b.latencies...)
+		sort.Float64s(sorted)
+		percentile := func(p float64) float64 {
+			return sorted[int(p*float64(len(sorted)-1))]
+		}
+		progress.LatencyP50 = percentile(0.50)
+		progress.LatencyP95 = percentile(0.95)
+		progress.LatencyP99 = percentile(0.99)
+	}
+	return progress
+}
+
+func (b *batchProducer) emitProgress(app AppInterface) {
+	p := b.snapshot()
+
+	text := fmt.Sprintf("Batch %s: %d/%d acked, %d failed, %.0f msg/s, p50 %.1fms, p99 %.1fms",
+		p.State, p.Acked, p.Total, p.Failed, p.Throughput, p.LatencyP50, p.LatencyP99)
+	if p.State == "running" {
+		text = fmt.Sprintf("Producing: %d/%d acked, %d failed, %.0f msg/s, p50 %.1fms, p99 %.1fms",
+			p.Acked, p.Total, p.Failed, p.Throughput, p.LatencyP50, p.LatencyP99)
+	}
+
+	emitKafkaSystem(app, b.req.ConnectionID, text, map[string]interface{}{
+		"batchId": b.id,
+		"batch":   p,
+	})
+}
+
+func readBatchFile(path string) ([]batchRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return readJSONLRecords(file)
+	case ".csv":
+		return readCSVRecords(file)
+	default:
+		return nil, fmt.Errorf("unsupported file type %q: use .jsonl, .ndjson or .csv", filepath.Ext(path))
+	}
+}
+
+func readJSONLRecords(r io.Reader) ([]batchRecord, error) {
+	var records []batchRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var raw struct {
+			Key     json.RawMessage   `json:"key"`
+			Value   json.RawMessage   `json:"value"`
+			Headers map[string]string `json:"headers"`
+		}
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, batchRecord{
+			key:     rawJSONText(raw.Key),
+			value:   rawJSONText(raw.Value),
+			headers: raw.Headers,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// rawJSONText unquotes JSON strings and keeps anything else as compact JSON
+func rawJSONText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, raw) != nil {
+		return string(raw)
+	}
+	return compact.String()
+}
+
+func readCSVRecords(r io.Reader) ([]batchRecord, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	keyCol, valueCol := -1, -1
+	headerCols := make(map[int]string)
+	for i, name := range header {
+		switch name = strings.TrimSpace(name); {
+		case name == "key":
+			keyCol = i
+		case name == "value":
+			valueCol = i
+		case strings.HasPrefix(name, "header."):
+			headerCols[i] = strings.TrimPrefix(name, "header.")
+		}
+	}
+	if valueCol < 0 {
+		return nil, fmt.Errorf("CSV needs a value column")
+	}
+
+	var records []batchRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := batchRecord{value: row[valueCol]}
+		if keyCol >= 0 {
+			record.key = row[keyCol]
+		}
+		if len(headerCols) > 0 {
+			record.headers = make(map[string]string, len(headerCols))
+			for i, name := range headerCols {
+				record.headers[name] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,253 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestReadJSONLRecords(t *testing.T) {
+	records, err := readJSONLRecords(strings.NewReader(`{"key":"A-1","value":{"total": 42, "items": [1, 2]},"headers":{"trace":"t-1"}}
+
+{"key":7,"value":"plain text"}
+{"value":null}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []batchRecord{
+		{key: "A-1", value: `{"total":42,"items":[1,2]}`, headers: map[string]string{"trace": "t-1"}},
+		{key: "7", value: "plain text"},
+		{},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %+v, want %+v", records, want)
+	}
+
+	if _, err := readJSONLRecords(strings.NewReader("{\"value\":1}\n{\"value\":\n")); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("bad line: err = %v", err)
+	}
+}
+
+func TestRawJSONText(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"", ""},
+		{"null", ""},
+		{`"quoted \"text\""`, `quoted "text"`},
+		{"42", "42"},
+		{"true", "true"},
+		{`{ "a" : [ 1, 2 ] }`, `{"a":[1,2]}`},
+	}
+	for _, tt := range tests {
+		if got := rawJSONText([]byte(tt.raw)); got != tt.want {
+			t.Errorf("rawJSONText(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestReadCSVRecords(t *testing.T) {
+	records, err := readCSVRecords(strings.NewReader("header.trace, value ,key,ignored\nt-1,\"{\"\"id\"\":1}\",A-1,x\nt-2,second,,y\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []batchRecord{
+		{key: "A-1", value: `{"id":1}`, headers: map[string]string{"trace": "t-1"}},
+		{key: "", value: "second", headers: map[string]string{"trace": "t-2"}},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %+v, want %+v", records, want)
+	}
+
+	if records, err := readCSVRecords(strings.NewReader("value\nonly\n")); err != nil || len(records) != 1 || records[0].headers != nil {
+		t.Errorf("value only: %+v, %v", records, err)
+	}
+	if _, err := readCSVRecords(strings.NewReader("key\nA-1\n")); err == nil || err.Error() != "CSV needs a value column" {
+		t.Errorf("no value column: err = %v", err)
+	}
+	if _, err := readCSVRecords(strings.NewReader("")); err == nil || !strings.Contains(err.Error(), "failed to read CSV header") {
+		t.Errorf("empty file: err = %v", err)
+	}
+	if _, err := readCSVRecords(strings.NewReader("key,value\nA-1\n")); err == nil {
+		t.Error("a short row should fail")
+	}
+}
+
+func TestReadBatchFile(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"orders.NDJSON": `{"value":"a"}`,
+		"orders.csv":    "value\nb\n",
+		"orders.txt":    "c",
+	})
+
+	if records, err := readBatchFile(filepath.Join(dir, "orders.NDJSON")); err != nil || len(records) != 1 || records[0].value != "a" {
+		t.Errorf("ndjson: %+v, %v", records, err)
+	}
+	if records, err := readBatchFile(filepath.Join(dir, "orders.csv")); err != nil || len(records) != 1 || records[0].value != "b" {
+		t.Errorf("csv: %+v, %v", records, err)
+	}
+	if _, err := readBatchFile(filepath.Join(dir, "orders.txt")); err == nil || !strings.Contains(err.Error(), `unsupported file type ".txt"`) {
+		t.Errorf("txt: err = %v", err)
+	}
+	if _, err := readBatchFile(filepath.Join(dir, "missing.csv")); err == nil || !strings.Contains(err.Error(), "failed to open") {
+		t.Errorf("missing file: err = %v", err)
+	}
+}
+
+func TestBatchLatencyPercentiles(t *testing.T) {
+	b := &batchProducer{}
+	for _, ms := range shuffled100() {
+		b.ack(time.Duration(ms) * time.Millisecond)
+	}
+
+	p := b.snapshot()
+	if p.Acked != 100 || p.LatencyP50 != 50 || p.LatencyP95 != 95 || p.LatencyP99 != 99 || p.LatencyMax != 100 {
+		t.Errorf("progress = %+v", p)
+	}
+	if (&batchProducer{}).snapshot().LatencyP50 != 0 {
+		t.Error("no samples should report no latency")
+	}
+}
+
+// shuffled100 is 1 to 100 out of order
+func shuffled100() []int {
+	values := make([]int, 100)
+	for i := range values {
+		values[i] = (i*37)%100 + 1
+	}
+	return values
+}
+
+func TestBatchLatencyReservoir(t *testing.T) {
+	b := &batchProducer{}
+	total := batchLatencySamples * 3
+	for i := 0; i < total; i++ {
+		b.ack(time.Duration(i%1000) * time.Microsecond)
+	}
+
+	if len(b.latencies) != batchLatencySamples || b.seen != total || b.progress.Acked != total {
+		t.Errorf("%d samples of %d seen, %d acked", len(b.latencies), b.seen, b.progress.Acked)
+	}
+	// A uniform sample of 0-999µs has its median near the middle
+	if p50 := b.snapshot().LatencyP50; p50 < 0.4 || p50 > 0.6 {
+		t.Errorf("p50 = %.3fms", p50)
+	}
+}
+
+// batchResult waits for a batch's final progress
+func batchResult(t *testing.T, events <-chan StreamMessage, batchID string) BatchProduceProgress {
+	t.Helper()
+
+	msg := waitForMessage(t, events, func(msg StreamMessage) bool {
+		p, ok := msg.Metadata["batch"].(BatchProduceProgress)
+		return ok && p.BatchID == batchID && p.State != "running"
+	})
+	return msg.Metadata["batch"].(BatchProduceProgress)
+}
+
+func TestKafkaBatchProduceTemplates(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 1})
+	app, events := newTestApp(t)
+
+	id, err := KafkaConnect(app, KafkaConfig{
+		BootstrapServers: []string{k.addr()},
+		Scopes:           &VariableScopes{Global: map[string]string{"region": "eu"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { KafkaDisconnect(app, id) })
+
+	batchID, err := KafkaStartBatchProduce(app, BatchProduceRequest{
+		ConnectionID:  id,
+		Topic:         "orders",
+		Partition:     0,
+		Acks:          1,
+		ValueTemplate: `{"id":{{index}},"region":"{{region}}"}`,
+		Count:         3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := batchResult(t, events, batchID); p.State != "completed" || p.Sent != 3 || p.Acked != 3 || p.Failed != 0 {
+		t.Errorf("progress = %+v", p)
+	}
+	want := []string{`{"id":0,"region":"eu"}`, `{"id":1,"region":"eu"}`, `{"id":2,"region":"eu"}`}
+	if got := k.values("orders", 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("values = %v", got)
+	}
+}
+
+func TestBatchIndexDoesNotShadowVariables(t *testing.T) {
+	b := &batchProducer{
+		req:      BatchProduceRequest{ValueTemplate: "{{index}}/{{$base64 index}}"},
+		resolver: NewVariableResolver(VariableScopes{Global: map[string]string{"index": "mine"}}),
+	}
+	msg, err := b.message(context.Background(), nil, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Value) != "mine/bWluZQ==" {
+		t.Errorf("value = %s", msg.Value)
+	}
+
+	b.resolver = NewVariableResolver(VariableScopes{})
+	if msg, err := b.message(context.Background(), nil, 4); err != nil || string(msg.Value) != "4/NA==" {
+		t.Errorf("value = %s, %v", msg.Value, err)
+	}
+	if _, ok := b.resolver.Lookup("index"); ok {
+		t.Error("the index leaked into the resolver")
+	}
+}
+
+func TestKafkaDisconnectWaitsForBatches(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 1})
+	app, events := newTestApp(t)
+	id, err := KafkaConnect(app, KafkaConfig{BootstrapServers: []string{k.addr()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _ := lookupKafkaConnection(id)
+
+	batchID, err := KafkaStartBatchProduce(app, BatchProduceRequest{ConnectionID: id, Topic: "orders", Partition: -1, Acks: 1, ValueTemplate: "v{{index}}", Count: 1000, Rate: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForMessage(t, events, func(msg StreamMessage) bool {
+		return msg.Direction == "system" && strings.HasPrefix(msg.Payload, "Producing")
+	})
+
+	if err := KafkaDisconnect(app, id); err != nil {
+		t.Fatal(err)
+	}
+
+	// The batch ended, and was acknowledged, before disconnecting returned
+	conn.mu.RLock()
+	batches := len(conn.Batches)
+	conn.mu.RUnlock()
+	if batches != 0 {
+		t.Error("the batch is still registered")
+	}
+	p := batchResult(t, events, batchID)
+	if p.State != "stopped" || p.Acked != p.Sent || p.Acked != len(k.values("orders", 0)) {
+		t.Errorf("progress = %+v, %d on the broker", p, len(k.values("orders", 0)))
+	}
+
+	// Late writes fail instead of opening a writer on a closed connection
+	err = conn.writer("orders", -1, "", 1).WriteMessages(context.Background(), kafka.Message{Value: []byte("late")})
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("late write: err = %v", err)
+	}
+	if conn.writers != nil {
+		t.Errorf("%d writers after disconnecting", len(conn.writers))
+	}
+}
@@ -0,0 +1,289 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ClusterOverview is the cluster as its metadata describes it, with every
+// registered broker probed for reachability
+type ClusterOverview struct {
+	ClusterID  string       `json:"clusterId"`
+	Controller int          `json:"controller"` // broker ID, -1 when unknown
+	Brokers    []BrokerInfo `json:"brokers"`
+	Topics     int          `json:"topics"`
+	Partitions int          `json:"partitions"`
+}
+
+type BrokerInfo struct {
+	ID         int    `json:"id"`
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	Rack       string `json:"rack,omitempty"`
+	Controller bool   `json:"controller"`
+	Reachable  bool   `json:"reachable"`
+	Error      string `json:"error,omitempty"` // why the probe failed
+}
+
+const (
+	// bootstrapTimeout bounds each bootstrap attempt when the connection
+	// has no timeout of its own
+	bootstrapTimeout    = 10 * time.Second
+	kafkaProbeTimeout   = 5 * time.Second
+	kafkaHealthInterval = 15 * time.Second
+)
+
+// eachBootstrap runs fn against the bootstrap servers in turn, starting at
+// first and wrapping around, until one succeeds. A server that can't be
+// dialed or fails fn is skipped. It returns the index of the server that
+// worked. Errors the broker itself returns, such as an unknown topic, are
+// final.
+func eachBootstrap(dialer *kafka.Dialer, servers []string, first int, fn func(*kafka.Conn) error) (int, error) {
+	if len(servers) == 0 {
+		return -1, fmt.Errorf("no bootstrap servers configured")
+	}
+
+	timeout := dialer.Timeout
+	if timeout <= 0 {
+		timeout = bootstrapTimeout
+	}
+
+	var failures []string
+	for i := range servers {
+		idx := (first + i) % len(servers)
+		err := tryBootstrap(dialer, servers[idx], timeout, fn)
+		if err == nil {
+			return idx, nil
+		}
+		var kafkaErr kafka.Error
+		if errors.As(err, &kafkaErr) {
+			// the broker answered; another one would say the same
+			return -1, err
+		}
+		log.Printf("[Kafka] Bootstrap server %s failed: %v", servers[idx], err)
+		failures = append(failures, fmt.Sprintf("%s: %v", servers[idx], err))
+	}
+	return -1, fmt.Errorf("no bootstrap server reachable (%s)", strings.Join(failures, "; "))
+}
+
+func tryBootstrap(dialer *kafka.Dialer, server string, timeout time.Duration, fn func(*kafka.Conn) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	return fn(conn)
+}
+
+// withBootstrap is eachBootstrap for an open connection. It starts with
+// the server that answered last time, so a dead first server only costs
+// one failed attempt.
+func (c *KafkaConnection) withBootstrap(fn func(*kafka.Conn) error) error {
+	idx, err := eachBootstrap(c.Dialer, c.Brokers, int(atomic.LoadInt32(&c.bootstrap)), fn)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&c.bootstrap, int32(idx))
+	return nil
+}
+
+func KafkaClusterOverview(app AppInterface, connectionID string) (*ClusterOverview, error) {
+	conn, err := lookupKafkaConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	return conn.overview(ctx)
+}
+
+func (c *KafkaConnection) overview(ctx context.Context) (*ClusterOverview, error) {
+	// the admin client's transport spreads metadata requests across all
+	// the bootstrap servers
+	meta, err := c.adminClient().Metadata(ctx, &kafka.MetadataRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster metadata: %w", err)
+	}
+
+	overview := &ClusterOverview{
+		ClusterID:  meta.ClusterID,
+		Controller: -1,
+		Brokers:    make([]BrokerInfo, len(meta.Brokers)),
+		Topics:     len(meta.Topics),
+	}
+	if meta.Controller.Host != "" {
+		overview.Controller = meta.Controller.ID
+	}
+	for _, t := range meta.Topics {
+		overview.Partitions += len(t.Partitions)
+	}
+
+	var wg sync.WaitGroup
+	for i, b := range meta.Brokers {
+		overview.Brokers[i] = BrokerInfo{
+			ID:         b.ID,
+			Host:       b.Host,
+			Port:       b.Port,
+			Rack:       b.Rack,
+			Controller: b.ID == overview.Controller,
+		}
+		wg.Add(1)
+		go func(info *BrokerInfo) {
+			defer wg.Done()
+			if err := c.probeBroker(ctx, info.address()); err != nil {
+				info.Error = err.Error()
+			} else {
+				info.Reachable = true
+			}
+		}(&overview.Brokers[i])
+	}
+	wg.Wait()
+
+	sort.Slice(overview.Brokers, func(i, j int) bool {
+		return overview.Brokers[i].ID < overview.Brokers[j].ID
+	})
+	return overview, nil
+}
+
+// probeBroker opens a fresh connection to one broker, with the
+// connection's TLS and SASL settings, and asks for its API versions
+func (c *KafkaConnection) probeBroker(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, kafkaProbeTimeout)
+	defer cancel()
+
+	conn, err := c.Dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(kafkaProbeTimeout))
+	_, err = conn.ApiVersions()
+	return err
+}
+
+func (b BrokerInfo) address() string {
+	return fmt.Sprintf("%s:%d", b.Host, b.Port)
+}
+
+// brokerState is what the health check last saw of a broker
+type brokerState struct {
+	address string
+	up      bool
+}
+
+// clusterHealth is what the health check has seen so far
+type clusterHealth struct {
+	brokers   map[int]*brokerState
+	clusterUp bool
+	first     bool
+}
+
+func newClusterHealth() *clusterHealth {
+	return &clusterHealth{brokers: make(map[int]*brokerState), clusterUp: true, first: true}
+}
+
+// monitorHealth checks the cluster every kafkaHealthInterval until ctx is
+// done and emits a system message whenever a broker goes away or comes
+// back. A broker is away when it can't be reached or drops out of the
+// cluster metadata.
+func (c *KafkaConnection) monitorHealth(app AppInterface, ctx context.Context) {
+	health := newClusterHealth()
+
+	for {
+		overview, err := c.overview(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		c.checkHealth(app, health, overview, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(kafkaHealthInterval):
+		}
+	}
+}
+
+// checkHealth compares one overview, or the error reading it, with what the
+// health check saw before and emits the differences
+func (c *KafkaConnection) checkHealth(app AppInterface, h *clusterHealth, overview *ClusterOverview, err error) {
+	switch {
+	case err != nil && h.clusterUp:
+		h.clusterUp = false
+		c.emitHealth(app, fmt.Sprintf("Cluster unreachable: %v", err), "cluster-down", nil, err.Error())
+	case err == nil && !h.clusterUp:
+		h.clusterUp = true
+		c.emitHealth(app, "Cluster reachable again", "cluster-up", nil, "")
+	}
+	if err != nil {
+		return
+	}
+
+	seen := make(map[int]bool, len(overview.Brokers))
+	for i := range overview.Brokers {
+		info := &overview.Brokers[i]
+		seen[info.ID] = true
+
+		state, known := h.brokers[info.ID]
+		if !known {
+			state = &brokerState{address: info.address(), up: true}
+			h.brokers[info.ID] = state
+			if !h.first {
+				c.emitHealth(app, fmt.Sprintf("Broker %d (%s) joined the cluster", info.ID, state.address), "broker-joined", info, "")
+			}
+		}
+		state.address = info.address()
+
+		switch {
+		case state.up && !info.Reachable:
+			state.up = false
+			c.emitHealth(app, fmt.Sprintf("Broker %d (%s) is down: %s", info.ID, state.address, info.Error), "broker-down", info, info.Error)
+		case !state.up && info.Reachable:
+			state.up = true
+			c.emitHealth(app, fmt.Sprintf("Broker %d (%s) is back", info.ID, state.address), "broker-up", info, "")
+		}
+	}
+
+	for id, state := range h.brokers {
+		if !seen[id] && state.up {
+			state.up = false
+			c.emitHealth(app, fmt.Sprintf("Broker %d (%s) left the cluster metadata", id, state.address), "broker-down", &BrokerInfo{ID: id}, "not in cluster metadata")
+		}
+	}
+	h.first = false
+}
+
+func (c *KafkaConnection) emitHealth(app AppInterface, text, event string, broker *BrokerInfo, errText string) {
+	log.Printf("[Kafka] %s: %s", c.ID, text)
+
+	metadata := map[string]interface{}{
+		"healthEvent": event,
+	}
+	if broker != nil {
+		metadata["brokerId"] = broker.ID
+		if broker.Host != "" {
+			metadata["broker"] = *broker
+		}
+	}
+	if errText != "" {
+		metadata["error"] = errText
+	}
+	emitKafkaSystem(app, c.ID, text, metadata)
+}
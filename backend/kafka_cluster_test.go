@@ -0,0 +1,214 @@
+package backend
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// listenStub accepts connections and does nothing with them, which is all
+// eachBootstrap needs to dial a server
+func listenStub(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var conns []net.Conn
+	t.Cleanup(func() {
+		ln.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range conns {
+			c.Close()
+		}
+	})
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, c)
+			mu.Unlock()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// closedPort is an address nothing listens on
+func closedPort(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestEachBootstrap(t *testing.T) {
+	dialer := &kafka.Dialer{Timeout: 2 * time.Second}
+	closed, a, b := closedPort(t), listenStub(t), listenStub(t)
+	servers := []string{closed, a, b}
+
+	// visit records the servers fn runs against and fails on those listed
+	visit := func(visited *[]string, failing ...string) func(*kafka.Conn) error {
+		return func(conn *kafka.Conn) error {
+			addr := conn.RemoteAddr().String()
+			*visited = append(*visited, addr)
+			for _, f := range failing {
+				if addr == f {
+					return errors.New("metadata failed")
+				}
+			}
+			return nil
+		}
+	}
+
+	var visited []string
+	idx, err := eachBootstrap(dialer, servers, 0, visit(&visited))
+	if err != nil || idx != 1 || !reflect.DeepEqual(visited, []string{a}) {
+		t.Errorf("from the closed port: idx = %d, visited = %v, err = %v", idx, visited, err)
+	}
+
+	// Starting at the last server wraps around past the closed port
+	visited = nil
+	idx, err = eachBootstrap(dialer, servers, 2, visit(&visited, b))
+	if err != nil || idx != 1 || !reflect.DeepEqual(visited, []string{b, a}) {
+		t.Errorf("wrapping around: idx = %d, visited = %v, err = %v", idx, visited, err)
+	}
+
+	visited = nil
+	_, err = eachBootstrap(dialer, servers, 1, visit(&visited, a, b))
+	if err == nil || !strings.HasPrefix(err.Error(), "no bootstrap server reachable (") || !strings.Contains(err.Error(), closed+": ") || !strings.Contains(err.Error(), b+": metadata failed") {
+		t.Errorf("all failing: err = %v", err)
+	}
+	if !reflect.DeepEqual(visited, []string{a, b}) {
+		t.Errorf("all failing: visited = %v", visited)
+	}
+
+	// An answer from the broker itself is final
+	visited = nil
+	idx, err = eachBootstrap(dialer, servers, 1, func(conn *kafka.Conn) error {
+		visited = append(visited, conn.RemoteAddr().String())
+		return kafka.UnknownTopicOrPartition
+	})
+	if idx != -1 || !errors.Is(err, kafka.UnknownTopicOrPartition) || len(visited) != 1 {
+		t.Errorf("broker error: idx = %d, visited = %v, err = %v", idx, visited, err)
+	}
+
+	if _, err := eachBootstrap(dialer, nil, 0, visit(&visited)); err == nil || err.Error() != "no bootstrap servers configured" {
+		t.Errorf("no servers: err = %v", err)
+	}
+}
+
+// withBootstrap starts from the server that answered last
+func TestWithBootstrap(t *testing.T) {
+	a, b := listenStub(t), listenStub(t)
+	conn := &KafkaConnection{Dialer: &kafka.Dialer{Timeout: 2 * time.Second}, Brokers: []string{a, b}}
+
+	failA := true
+	var visited []string
+	fn := func(c *kafka.Conn) error {
+		addr := c.RemoteAddr().String()
+		visited = append(visited, addr)
+		if addr == a && failA {
+			return errors.New("metadata failed")
+		}
+		return nil
+	}
+
+	if err := conn.withBootstrap(fn); err != nil || conn.bootstrap != 1 {
+		t.Fatalf("bootstrap = %d, err = %v", conn.bootstrap, err)
+	}
+	failA = false
+	visited = nil
+	if err := conn.withBootstrap(fn); err != nil || !reflect.DeepEqual(visited, []string{b}) || conn.bootstrap != 1 {
+		t.Errorf("visited = %v, bootstrap = %d, err = %v", visited, conn.bootstrap, err)
+	}
+}
+
+// healthEvents drains what the health check emitted
+func healthEvents(events <-chan StreamMessage) []string {
+	var out []string
+	for {
+		select {
+		case msg := <-events:
+			out = append(out, msg.Metadata["healthEvent"].(string)+": "+msg.Payload)
+		default:
+			return out
+		}
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	app, events := newTestApp(t)
+	conn := &KafkaConnection{ID: "c1"}
+	health := newClusterHealth()
+
+	broker := func(id int, reachable bool) BrokerInfo {
+		info := BrokerInfo{ID: id, Host: "kafka", Port: 9090 + id, Reachable: reachable}
+		if !reachable {
+			info.Error = "connection refused"
+		}
+		return info
+	}
+	steps := []struct {
+		name     string
+		overview *ClusterOverview
+		err      error
+		want     []string
+	}{
+		{name: "first look", overview: &ClusterOverview{Brokers: []BrokerInfo{broker(1, true), broker(2, true)}}},
+		{name: "unchanged", overview: &ClusterOverview{Brokers: []BrokerInfo{broker(1, true), broker(2, true)}}},
+		{
+			name:     "down",
+			overview: &ClusterOverview{Brokers: []BrokerInfo{broker(1, true), broker(2, false)}},
+			want:     []string{"broker-down: Broker 2 (kafka:9092) is down: connection refused"},
+		},
+		{name: "still down", overview: &ClusterOverview{Brokers: []BrokerInfo{broker(1, true), broker(2, false)}}},
+		{
+			name:     "back",
+			overview: &ClusterOverview{Brokers: []BrokerInfo{broker(1, true), broker(2, true)}},
+			want:     []string{"broker-up: Broker 2 (kafka:9092) is back"},
+		},
+		{
+			name:     "left the metadata",
+			overview: &ClusterOverview{Brokers: []BrokerInfo{broker(1, true)}},
+			want:     []string{"broker-down: Broker 2 (kafka:9092) left the cluster metadata"},
+		},
+		{
+			name: "cluster down",
+			err:  errors.New("failed to read cluster metadata: timeout"),
+			want: []string{"cluster-down: Cluster unreachable: failed to read cluster metadata: timeout"},
+		},
+		{name: "cluster still down", err: errors.New("timeout")},
+		{
+			name:     "cluster back with a new broker",
+			overview: &ClusterOverview{Brokers: []BrokerInfo{broker(1, true), broker(2, true), broker(3, true)}},
+			want: []string{
+				"cluster-up: Cluster reachable again",
+				"broker-up: Broker 2 (kafka:9092) is back",
+				"broker-joined: Broker 3 (kafka:9093) joined the cluster",
+			},
+		},
+	}
+	for _, step := range steps {
+		conn.checkHealth(app, health, step.overview, step.err)
+		if got := healthEvents(events); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: events = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
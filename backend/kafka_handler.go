@@ -3,8 +3,11 @@ package backend
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,32 +19,54 @@ import (
 )
 
 type KafkaConnection struct {
-	ID        string
-	Brokers   []string
-	Config    *KafkaConfig
-	Dialer    *kafka.Dialer
-	Consumers map[string]*ConsumerInstance
-	mu        sync.RWMutex
+	ID         string
+	Brokers    []string
+	Config     *KafkaConfig
+	Dialer     *kafka.Dialer
+	Consumers  map[string]*ConsumerInstance
+	Searches   map[string]*kafkaSearch
+	Batches    map[string]*batchProducer
+	Replays    map[string]*kafkaReplay // replays reading from this connection
+	mu         sync.RWMutex
+	writers    map[string]*kafka.Writer // pooled producers, see writer()
+	transport  *kafka.Transport         // admin requests and producers
+	registry   *schemaRegistry          // nil without a schema registry
+	bootstrap  int32                    // index of the bootstrap server that last answered
+	stopHealth context.CancelFunc       // ends monitorHealth
 }
 
+// ConsumerInstance is one started consumer. In group mode the group hands
+// out partitions one generation at a time and each generation reads them
+// with readers of its own; otherwise there is one reader per partition.
 type ConsumerInstance struct {
 	ID            string
 	Topic         string
 	ConsumerGroup string
-	Reader        *kafka.Reader
+	AutoCommit    bool
+	Readers       []*kafka.Reader
 	Cancel        context.CancelFunc
 	IsActive      bool
+	group         *kafka.ConsumerGroup
+	generation    *kafka.Generation     // current group generation, nil while joining
+	pending       map[int]kafka.Message // last fetched per partition, awaiting a manual commit
+	pendingMu     sync.Mutex            // guards generation and pending
+	registry      *schemaRegistry
 }
 
 type KafkaConfig struct {
-	BootstrapServers  []string `json:"bootstrapServers"`
-	ClientID          string   `json:"clientId"`
-	AuthMechanism     string   `json:"authMechanism"`
-	SaslUsername      string   `json:"saslUsername"`
-	SaslPassword      string   `json:"saslPassword"`
-	UseTLS            bool     `json:"useTLS"`
-	TLSSkipVerify     bool     `json:"tlsSkipVerify"`
-	ConnectionTimeout int      `json:"connectionTimeout"`
+	BootstrapServers  []string              `json:"bootstrapServers"`
+	ClientID          string                `json:"clientId"`
+	AuthMechanism     string                `json:"authMechanism"` // "none", "plain", "scram-sha-256", "scram-sha-512", "oauthbearer" or "aws-msk-iam"
+	SaslUsername      string                `json:"saslUsername"`
+	SaslPassword      string                `json:"saslPassword"`
+	UseTLS            bool                  `json:"useTLS"`
+	TLSSkipVerify     bool                  `json:"tlsSkipVerify"`
+	ConnectionTimeout int                   `json:"connectionTimeout"`
+	TLSProfile        string                `json:"tlsProfile,omitempty"`
+	OAuth             *KafkaOAuthConfig     `json:"oauth,omitempty"` // for "oauthbearer"
+	AWS               *KafkaAWSConfig       `json:"aws,omitempty"`   // for "aws-msk-iam"
+	SchemaRegistry    *SchemaRegistryConfig `json:"schemaRegistry,omitempty"`
+	Scopes            *VariableScopes       `json:"scopes,omitempty"`
 }
 
 type TopicInfo struct {
@@ -49,14 +74,33 @@ type TopicInfo struct {
 	Partitions int    `json:"partitions"`
 }
 
+// ConsumerConfig starts a consumer. With a ConsumerGroup the group assigns
+// partitions and Partitions is ignored; the offset strategy then only applies
+// when the group has no committed offset. Without a group every listed
+// partition (all of them when empty) gets its own reader.
 type ConsumerConfig struct {
-	ConnectionID   string `json:"connectionId"`
-	Topic          string `json:"topic"`
-	Partitions     []int  `json:"partitions"`
-	ConsumerGroup  string `json:"consumerGroup"`
-	OffsetStrategy string `json:"offsetStrategy"`
-	CustomOffset   int64  `json:"customOffset"`
-	AutoCommit     bool   `json:"autoCommit"`
+	ConnectionID    string `json:"connectionId"`
+	Topic           string `json:"topic"`
+	Partitions      []int  `json:"partitions"`
+	ConsumerGroup   string `json:"consumerGroup"`
+	OffsetStrategy  string `json:"offsetStrategy"` // "latest", "earliest", "custom" or "timestamp"
+	CustomOffset    int64  `json:"customOffset"`
+	CustomTimestamp int64  `json:"customTimestamp"` // unix ms, for "timestamp"
+	AutoCommit      bool   `json:"autoCommit"`
+}
+
+// CommitOffsetsRequest commits a group consumer's progress. Offsets are the
+// last processed offset per partition; the group resumes after them. When
+// Offsets is empty everything fetched so far is committed.
+type CommitOffsetsRequest struct {
+	ConnectionID string            `json:"connectionId"`
+	ConsumerID   string            `json:"consumerId"`
+	Offsets      []PartitionOffset `json:"offsets"`
+}
+
+type PartitionOffset struct {
+	Partition int   `json:"partition"`
+	Offset    int64 `json:"offset"`
 }
 
 type ProducerConfig struct {
@@ -68,6 +112,8 @@ type ProducerConfig struct {
 	Headers      map[string]string `json:"headers"`
 	Compression  string            `json:"compression"`
 	Acks         int               `json:"acks"`
+	KeySchema    *SchemaSelection  `json:"keySchema,omitempty"`   // serialize Key through the schema registry
+	ValueSchema  *SchemaSelection  `json:"valueSchema,omitempty"` // serialize Value through the schema registry
 }
 
 var (
@@ -76,6 +122,22 @@ var (
 )
 
 func KafkaConnect(app AppInterface, config KafkaConfig) (string, error) {
+	config, err := resolverFor(config.Scopes).ResolveKafkaConfig(config)
+	if err != nil {
+		return "", err
+	}
+
+	servers := config.BootstrapServers[:0]
+	for _, server := range config.BootstrapServers {
+		if server = strings.TrimSpace(server); server != "" {
+			servers = append(servers, server)
+		}
+	}
+	if len(servers) == 0 {
+		return "", fmt.Errorf("at least one bootstrap server is required")
+	}
+	config.BootstrapServers = servers
+
 	log.Printf("[Kafka] Connecting to brokers: %v", config.BootstrapServers)
 
 	connectionID := uuid.New().String()
@@ -86,48 +148,67 @@ func KafkaConnect(app AppInterface, config KafkaConfig) (string, error) {
 		ClientID:  config.ClientID,
 	}
 
-	if config.UseTLS {
-		dialer.TLS = &tls.Config{
+	if config.UseTLS || config.TLSProfile != "" {
+		// Every broker shares the config, so there is no single host to pass
+		tlsConfig, err := clientTLSConfig(app.GetDataDirectory(), config.TLSProfile, "", &tls.Config{
 			InsecureSkipVerify: config.TLSSkipVerify,
+		})
+		if err != nil {
+			return "", err
 		}
+		dialer.TLS = tlsConfig
 	}
 
 	if config.AuthMechanism != "none" && config.AuthMechanism != "" {
-		mechanism, err := createSASLMechanism(config.AuthMechanism, config.SaslUsername, config.SaslPassword)
+		mechanism, err := createSASLMechanism(config)
 		if err != nil {
 			return "", fmt.Errorf("failed to create SASL mechanism: %w", err)
 		}
 		dialer.SASLMechanism = mechanism
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	registry, err := newSchemaRegistry(app.GetDataDirectory(), config.SchemaRegistry)
+	if err != nil {
+		return "", fmt.Errorf("invalid schema registry config: %w", err)
+	}
 
-	conn, err := dialer.DialContext(ctx, "tcp", config.BootstrapServers[0])
+	bootstrap, err := eachBootstrap(dialer, config.BootstrapServers, 0, func(conn *kafka.Conn) error {
+		if _, err := conn.Brokers(); err != nil {
+			return fmt.Errorf("failed to get cluster metadata: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to connect to broker: %w", err)
 	}
-	defer conn.Close()
 
-	_, err = conn.Brokers()
-	if err != nil {
-		return "", fmt.Errorf("failed to get cluster metadata: %w", err)
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	conn := &KafkaConnection{
+		ID:         connectionID,
+		Brokers:    config.BootstrapServers,
+		Config:     &config,
+		Dialer:     dialer,
+		Consumers:  make(map[string]*ConsumerInstance),
+		Searches:   make(map[string]*kafkaSearch),
+		Batches:    make(map[string]*batchProducer),
+		Replays:    make(map[string]*kafkaReplay),
+		writers:    make(map[string]*kafka.Writer),
+		transport:  newKafkaTransport(dialer),
+		registry:   registry,
+		bootstrap:  int32(bootstrap),
+		stopHealth: stopHealth,
 	}
 
 	kafkaMutex.Lock()
-	kafkaConnections[connectionID] = &KafkaConnection{
-		ID:        connectionID,
-		Brokers:   config.BootstrapServers,
-		Config:    &config,
-		Dialer:    dialer,
-		Consumers: make(map[string]*ConsumerInstance),
-	}
+	kafkaConnections[connectionID] = conn
 	kafkaMutex.Unlock()
 
 	log.Printf("[Kafka] Connected successfully with ID: %s", connectionID)
 
 	emitStreamMessage(app, connectionID, "system", "kafka", fmt.Sprintf("Connected to Kafka cluster: %v", config.BootstrapServers))
 
+	go conn.monitorHealth(app, healthCtx)
+
 	return connectionID, nil
 }
 
@@ -143,17 +224,44 @@ func KafkaDisconnect(app AppInterface, connectionID string) error {
 	delete(kafkaConnections, connectionID)
 	kafkaMutex.Unlock()
 
+	conn.stopHealth()
+
 	conn.mu.Lock()
+	var consumers []*ConsumerInstance
 	for _, consumer := range conn.Consumers {
-		if consumer.IsActive && consumer.Cancel != nil {
-			consumer.Cancel()
-		}
-		if consumer.Reader != nil {
-			consumer.Reader.Close()
+		if consumer.IsActive {
+			consumer.IsActive = false
+			consumers = append(consumers, consumer)
 		}
 	}
+	for _, search := range conn.Searches {
+		search.setState("cancelled")
+		search.cancel()
+	}
+	var running []chan struct{}
+	for _, batch := range conn.Batches {
+		batch.stop()
+		running = append(running, batch.done)
+	}
+	for _, replay := range conn.Replays {
+		replay.stop()
+		running = append(running, replay.done)
+	}
 	conn.mu.Unlock()
 
+	// Closing waits for the readers' goroutines, so it happens unlocked
+	for _, consumer := range consumers {
+		consumer.stop()
+	}
+	// Batches and replays flush what they queued through the pooled
+	// writers, and unregister themselves, before the writers can close
+	for _, done := range running {
+		<-done
+	}
+
+	conn.closeWriters()
+	conn.transport.CloseIdleConnections()
+
 	log.Printf("[Kafka] Disconnected: %s", connectionID)
 	return nil
 }
@@ -167,16 +275,12 @@ func KafkaListTopics(app AppInterface, connectionID string) ([]TopicInfo, error)
 		return nil, fmt.Errorf("connection not found: %s", connectionID)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	kafkaConn, err := conn.Dialer.DialContext(ctx, "tcp", conn.Brokers[0])
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect: %w", err)
-	}
-	defer kafkaConn.Close()
-
-	partitions, err := kafkaConn.ReadPartitions()
+	var partitions []kafka.Partition
+	err := conn.withBootstrap(func(kafkaConn *kafka.Conn) error {
+		var err error
+		partitions, err = kafkaConn.ReadPartitions()
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to read partitions: %w", err)
 	}
@@ -200,7 +304,6 @@ func KafkaListTopics(app AppInterface, connectionID string) ([]TopicInfo, error)
 
 func KafkaStartConsumer(app AppInterface, config ConsumerConfig) (string, error) {
 	log.Printf("[Kafka] Starting consumer for topic: %s", config.Topic)
-	log.Printf("[Kafka] Received ConsumerGroup value: '%s' (length: %d)", config.ConsumerGroup, len(config.ConsumerGroup))
 
 	kafkaMutex.RLock()
 	conn, exists := kafkaConnections[config.ConnectionID]
@@ -210,6 +313,16 @@ func KafkaStartConsumer(app AppInterface, config ConsumerConfig) (string, error)
 		return "", fmt.Errorf("connection not found: %s", config.ConnectionID)
 	}
 
+	// Group readers only accept the first or last offset as a starting point
+	if config.ConsumerGroup != "" {
+		switch config.OffsetStrategy {
+		case "timestamp":
+			return "", fmt.Errorf("timestamp offsets need a consumer without a group; reset the group's offsets to a timestamp instead")
+		case "custom":
+			return "", fmt.Errorf("custom offsets need a consumer without a group; reset the group's offsets to the offset instead")
+		}
+	}
+
 	consumerID := uuid.New().String()
 
 	var startOffset int64
@@ -224,10 +337,22 @@ func KafkaStartConsumer(app AppInterface, config ConsumerConfig) (string, error)
 		startOffset = kafka.LastOffset
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
+	consumer := &ConsumerInstance{
+		ID:            consumerID,
+		Topic:         config.Topic,
+		ConsumerGroup: config.ConsumerGroup,
+		AutoCommit:    config.AutoCommit,
+		Cancel:        cancel,
+		IsActive:      true,
+		pending:       make(map[int]kafka.Message),
+		registry:      conn.registry,
+	}
+
 	readerConfig := kafka.ReaderConfig{
 		Brokers:     conn.Brokers,
 		Topic:       config.Topic,
-		Partition:   0, // Always read from partition 0
 		StartOffset: startOffset,
 		Dialer:      conn.Dialer,
 		MaxWait:     500 * time.Millisecond,
@@ -235,32 +360,72 @@ func KafkaStartConsumer(app AppInterface, config ConsumerConfig) (string, error)
 		MaxBytes:    10e6, // 10MB
 	}
 
-	if len(config.Partitions) > 0 {
-		readerConfig.Partition = config.Partitions[0]
-	}
+	var started string
+	if config.ConsumerGroup != "" {
+		group, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+			ID:          config.ConsumerGroup,
+			Brokers:     conn.Brokers,
+			Dialer:      conn.Dialer,
+			Topics:      []string{config.Topic},
+			StartOffset: startOffset,
+		})
+		if err != nil {
+			cancel()
+			return "", fmt.Errorf("failed to join consumer group: %w", err)
+		}
+		consumer.group = group
 
-	log.Printf("[Kafka] Consumer config - Topic: %s, Partition: %d, StartOffset: %d (NO CONSUMER GROUP)",
-		config.Topic, readerConfig.Partition, startOffset)
+		commitMode := "manual commit"
+		if config.AutoCommit {
+			commitMode = "auto commit"
+		}
+		started = fmt.Sprintf("group: %s, %s", config.ConsumerGroup, commitMode)
+	} else {
+		partitions := config.Partitions
+		if len(partitions) == 0 {
+			all, err := lookupPartitions(conn, config.Topic)
+			if err != nil {
+				cancel()
+				return "", err
+			}
+			partitions = all
+		}
 
-	reader := kafka.NewReader(readerConfig)
+		// Readers without a group ignore StartOffset, so each one is
+		// positioned explicitly
+		offsets, err := startOffsets(conn, config.Topic, partitions, config)
+		if err != nil {
+			cancel()
+			return "", err
+		}
 
-	ctx, cancel := context.WithCancel(context.Background())
+		for _, partition := range partitions {
+			partitionConfig := readerConfig
+			partitionConfig.Partition = partition
+			reader := kafka.NewReader(partitionConfig)
+			if err := reader.SetOffset(offsets[partition]); err != nil {
+				reader.Close()
+				consumer.stop()
+				return "", fmt.Errorf("failed to seek partition %d: %w", partition, err)
+			}
+			consumer.Readers = append(consumer.Readers, reader)
+		}
+		started = fmt.Sprintf("partitions: %v", partitions)
+	}
 
 	conn.mu.Lock()
-	conn.Consumers[consumerID] = &ConsumerInstance{
-		ID:            consumerID,
-		Topic:         config.Topic,
-		ConsumerGroup: config.ConsumerGroup,
-		Reader:        reader,
-		Cancel:        cancel,
-		IsActive:      true,
-	}
+	conn.Consumers[consumerID] = consumer
 	conn.mu.Unlock()
 
-	go consumeMessages(app, ctx, config.ConnectionID, consumerID, reader)
+	if consumer.group != nil {
+		go consumeGroup(app, ctx, config.ConnectionID, consumer, readerConfig)
+	}
+	for _, reader := range consumer.Readers {
+		go consumeMessages(app, ctx, config.ConnectionID, consumer, reader, nil)
+	}
 
-	log.Printf("[Kafka] Consumer started: %s for topic: %s (partition mode, no groups)", consumerID, config.Topic)
-	emitStreamMessage(app, config.ConnectionID, "system", "kafka", fmt.Sprintf("Started consumer %s for topic: %s (partition: %d)", consumerID[:8], config.Topic, readerConfig.Partition))
+	log.Printf("[Kafka] Consumer started: %s for topic: %s (%s)", consumerID, config.Topic, started)
+	emitStreamMessage(app, config.ConnectionID, "system", "kafka", fmt.Sprintf("Started consumer %s for topic: %s (%s)", consumerID[:8], config.Topic, started))
 
 	return consumerID, nil
 }
@@ -287,25 +452,93 @@ func KafkaStopConsumer(app AppInterface, connectionID string, consumerID string)
 		conn.mu.Unlock()
 		return fmt.Errorf("consumer already stopped")
 	}
-
-	if consumer.Cancel != nil {
-		consumer.Cancel()
-	}
-
-	if consumer.Reader != nil {
-		consumer.Reader.Close()
-	}
-
 	consumer.IsActive = false
 	conn.mu.Unlock()
 
+	// Closing waits for the readers' goroutines, so it happens unlocked
+	consumer.stop()
+
 	log.Printf("[Kafka] Consumer stopped: %s", consumerID)
 	emitStreamMessage(app, connectionID, "system", "kafka", fmt.Sprintf("Consumer %s stopped", consumerID[:8]))
 
 	return nil
 }
 
-func KafkaProduceMessage(app AppInterface, config ProducerConfig) error {
+// KafkaCommitOffsets commits a manual-commit group consumer's offsets and
+// returns what was committed
+func KafkaCommitOffsets(app AppInterface, req CommitOffsetsRequest) ([]PartitionOffset, error) {
+	kafkaMutex.RLock()
+	conn, exists := kafkaConnections[req.ConnectionID]
+	kafkaMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("connection not found: %s", req.ConnectionID)
+	}
+
+	conn.mu.RLock()
+	consumer, exists := conn.Consumers[req.ConsumerID]
+	active := exists && consumer.IsActive
+	conn.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("consumer not found: %s", req.ConsumerID)
+	}
+	if consumer.ConsumerGroup == "" {
+		return nil, fmt.Errorf("consumer %s is not in a consumer group", req.ConsumerID[:8])
+	}
+	if !active {
+		return nil, fmt.Errorf("consumer already stopped")
+	}
+
+	consumer.pendingMu.Lock()
+	gen := consumer.generation
+	committed := make([]PartitionOffset, 0, len(req.Offsets))
+	if len(req.Offsets) == 0 {
+		for _, msg := range consumer.pending {
+			committed = append(committed, PartitionOffset{Partition: msg.Partition, Offset: msg.Offset})
+		}
+	} else {
+		committed = append(committed, req.Offsets...)
+	}
+	consumer.pendingMu.Unlock()
+
+	if len(committed) == 0 {
+		return []PartitionOffset{}, nil
+	}
+	if gen == nil {
+		return nil, fmt.Errorf("consumer %s is joining its group; commit again once partitions are assigned", req.ConsumerID[:8])
+	}
+
+	// The group resumes from the committed offset, so it is the one after
+	// the last processed message
+	offsets := make(map[int]int64, len(committed))
+	for _, c := range committed {
+		offsets[c.Partition] = c.Offset + 1
+	}
+	if err := gen.CommitOffsets(map[string]map[int]int64{consumer.Topic: offsets}); err != nil {
+		return nil, fmt.Errorf("failed to commit offsets: %w", err)
+	}
+
+	consumer.pendingMu.Lock()
+	for _, c := range committed {
+		if pending, ok := consumer.pending[c.Partition]; ok && pending.Offset <= c.Offset {
+			delete(consumer.pending, c.Partition)
+		}
+	}
+	consumer.pendingMu.Unlock()
+
+	sort.Slice(committed, func(i, j int) bool { return committed[i].Partition < committed[j].Partition })
+
+	parts := make([]string, len(committed))
+	for i, c := range committed {
+		parts[i] = fmt.Sprintf("%d@%d", c.Partition, c.Offset)
+	}
+	emitStreamMessage(app, req.ConnectionID, "system", "kafka", fmt.Sprintf("Consumer %s committed offsets (partition@offset): %s", req.ConsumerID[:8], strings.Join(parts, ", ")))
+
+	return committed, nil
+}
+
+func KafkaProduceMessage(app AppInterface, config ProducerConfig) (*ProduceResult, error) {
 	log.Printf("[Kafka] Producing message to topic: %s", config.Topic)
 
 	kafkaMutex.RLock()
@@ -313,35 +546,29 @@ func KafkaProduceMessage(app AppInterface, config ProducerConfig) error {
 	kafkaMutex.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("connection not found: %s", config.ConnectionID)
+		return nil, fmt.Errorf("connection not found: %s", config.ConnectionID)
 	}
 
-	var compression kafka.Compression
-	switch config.Compression {
-	case "gzip":
-		compression = kafka.Gzip
-	case "snappy":
-		compression = kafka.Snappy
-	case "lz4":
-		compression = kafka.Lz4
-	case "zstd":
-		compression = kafka.Zstd
+	config, err := resolverFor(conn.Config.Scopes).ResolveProducerConfig(config)
+	if err != nil {
+		return nil, err
 	}
 
-	writer := &kafka.Writer{
-		Addr:         kafka.TCP(conn.Brokers...),
-		Topic:        config.Topic,
-		Balancer:     &kafka.LeastBytes{},
-		MaxAttempts:  3,
-		RequiredAcks: kafka.RequiredAcks(config.Acks),
-		Async:        false,
-		Compression:  compression,
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	key, keyNote, err := serializeRecordData(ctx, conn, config.KeySchema, config.Topic+"-key", config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize key: %w", err)
+	}
+	value, valueNote, err := serializeRecordData(ctx, conn, config.ValueSchema, config.Topic+"-value", config.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize value: %w", err)
 	}
-	defer writer.Close()
 
 	msg := kafka.Message{
-		Key:   []byte(config.Key),
-		Value: []byte(config.Value),
+		Key:   key,
+		Value: value,
 		Time:  time.Now(),
 	}
 
@@ -356,26 +583,56 @@ func KafkaProduceMessage(app AppInterface, config ProducerConfig) error {
 		msg.Headers = headers
 	}
 
-	if config.Partition >= 0 {
-		msg.Partition = config.Partition
+	done := make(chan writeOutcome, 1)
+	msg.WriterData = writeCallback(func(m kafka.Message, err error) {
+		done <- writeOutcome{msg: m, err: err}
+	})
+
+	writer := conn.writer(config.Topic, config.Partition, config.Compression, config.Acks)
+	if err := writer.WriteMessages(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to produce message: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	var produced kafka.Message
+	select {
+	case outcome := <-done:
+		if outcome.err != nil {
+			return nil, fmt.Errorf("failed to produce message: %w", outcome.err)
+		}
+		produced = outcome.msg
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to produce message: %w", ctx.Err())
+	}
 
-	err := writer.WriteMessages(ctx, msg)
-	if err != nil {
-		return fmt.Errorf("failed to produce message: %w", err)
+	if config.Acks == 0 {
+		// Nothing comes back from the broker, so only an explicit partition is known
+		produced.Topic, produced.Partition, produced.Offset = config.Topic, config.Partition, -1
 	}
 
-	log.Printf("[Kafka] Message produced successfully to topic: %s", config.Topic)
+	log.Printf("[Kafka] Message produced to %s partition %d at offset %d", produced.Topic, produced.Partition, produced.Offset)
 
-	emitStreamMessage(app, config.ConnectionID, "outbound", "kafka", fmt.Sprintf("Key: %s\nValue: %s", config.Key, config.Value))
+	keyData := newRecordData(key)
+	if keyNote != "" {
+		keyData.Text, keyData.Format, keyData.Schema = config.Key, "json", keyNote
+	}
+	valueData := newRecordData(value)
+	if valueNote != "" {
+		valueData.Text, valueData.Format, valueData.Schema = config.Value, "json", valueNote
+	}
+	emitKafkaRecord(app, "outbound", kafkaRecordMetadata(config.ConnectionID, produced, keyData, valueData))
 
-	return nil
+	return &ProduceResult{
+		Topic:     produced.Topic,
+		Partition: produced.Partition,
+		Offset:    produced.Offset,
+		Timestamp: produced.Time,
+	}, nil
 }
 
-func consumeMessages(app AppInterface, ctx context.Context, connectionID string, consumerID string, reader *kafka.Reader) {
+// consumeMessages reads one reader until ctx ends. commit, set for group
+// consumers, is called with each message once it has been emitted.
+func consumeMessages(app AppInterface, ctx context.Context, connectionID string, consumer *ConsumerInstance, reader *kafka.Reader, commit func(kafka.Message)) {
+	consumerID := consumer.ID
 	log.Printf("[Kafka] Consumer goroutine started: %s", consumerID)
 
 	retryCount := 0
@@ -412,45 +669,321 @@ func consumeMessages(app AppInterface, ctx context.Context, connectionID string,
 			// Reset retry count on successful read
 			retryCount = 0
 
-			payload := fmt.Sprintf("Topic: %s\nPartition: %d\nOffset: %d\nKey: %s\nValue: %s\nTimestamp: %s",
-				msg.Topic,
-				msg.Partition,
-				msg.Offset,
-				string(msg.Key),
-				string(msg.Value),
-				msg.Time.Format(time.RFC3339),
-			)
-
-			if len(msg.Headers) > 0 {
-				payload += "\nHeaders:"
-				for _, h := range msg.Headers {
-					payload += fmt.Sprintf("\n  %s: %s", h.Key, string(h.Value))
+			metadata := kafkaRecordMetadata(connectionID, msg,
+				decodeRecordData(consumer.registry, msg.Key),
+				decodeRecordData(consumer.registry, msg.Value))
+			metadata["consumerId"] = consumerID
+			if consumer.ConsumerGroup != "" {
+				metadata["consumerGroup"] = consumer.ConsumerGroup
+			}
+			emitKafkaRecord(app, "inbound", metadata)
+
+			if commit != nil {
+				commit(msg)
+			}
+		}
+	}
+}
+
+// consumeGroup follows the consumer group from generation to generation.
+// Each generation reads its assigned partitions with a reader apiece; the
+// group ends it on a rebalance and waits for the readers before handing out
+// the next one.
+func consumeGroup(app AppInterface, ctx context.Context, connectionID string, consumer *ConsumerInstance, readerConfig kafka.ReaderConfig) {
+	short := consumer.ID[:8]
+
+	for {
+		gen, err := consumer.group.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, kafka.ErrGroupClosed) {
+				return
+			}
+			emitStreamMessage(app, connectionID, "error", "kafka", fmt.Sprintf("Consumer %s failed to join group %s: %v", short, consumer.ConsumerGroup, err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		consumer.setGeneration(gen)
+		assignments := gen.Assignments[consumer.Topic]
+
+		emitStreamMessage(app, connectionID, "system", "kafka", fmt.Sprintf("Consumer %s joined group %s (generation %d)", short, gen.GroupID, gen.ID))
+		if len(assignments) == 0 {
+			emitStreamMessage(app, connectionID, "system", "kafka", fmt.Sprintf("Consumer %s has no partitions assigned; the group has more members than partitions", short))
+		} else {
+			emitStreamMessage(app, connectionID, "system", "kafka", fmt.Sprintf("Consumer %s assigned partitions: %s", short, describeAssignments(assignments)))
+		}
+
+		commit := func(msg kafka.Message) {
+			if !consumer.AutoCommit {
+				consumer.track(msg)
+				return
+			}
+			err := gen.CommitOffsets(map[string]map[int]int64{msg.Topic: {msg.Partition: msg.Offset + 1}})
+			if err != nil && ctx.Err() == nil {
+				emitStreamMessage(app, connectionID, "error", "kafka", fmt.Sprintf("Failed to commit offset %d on partition %d: %v", msg.Offset, msg.Partition, err))
+			}
+		}
+
+		for _, assignment := range assignments {
+			partitionConfig := readerConfig
+			partitionConfig.Partition = assignment.ID
+			offset := assignment.Offset
+
+			gen.Start(func(genCtx context.Context) {
+				reader := kafka.NewReader(partitionConfig)
+				defer reader.Close()
+
+				if err := reader.SetOffset(offset); err != nil {
+					emitStreamMessage(app, connectionID, "error", "kafka", fmt.Sprintf("Consumer %s failed to seek partition %d: %v", short, partitionConfig.Partition, err))
+					return
+				}
+				consumeMessages(app, genCtx, connectionID, consumer, reader, commit)
+			})
+		}
+
+		gen.Start(func(genCtx context.Context) {
+			<-genCtx.Done()
+			consumer.setGeneration(nil)
+			if ctx.Err() == nil {
+				emitStreamMessage(app, connectionID, "system", "kafka", fmt.Sprintf("Consumer %s is rebalancing", short))
+			}
+		})
+	}
+}
+
+// stop ends the consumer and waits for its readers. The caller marks it
+// inactive first and must not hold conn.mu.
+func (c *ConsumerInstance) stop() {
+	if c.Cancel != nil {
+		c.Cancel()
+	}
+	if c.group != nil {
+		c.group.Close()
+	}
+	for _, reader := range c.Readers {
+		reader.Close()
+	}
+}
+
+// setGeneration switches to a new group generation. Messages fetched in the
+// previous one are no longer this member's to commit.
+func (c *ConsumerInstance) setGeneration(gen *kafka.Generation) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	c.generation = gen
+	if gen != nil {
+		c.pending = make(map[int]kafka.Message)
+	}
+}
+
+func (c *ConsumerInstance) track(msg kafka.Message) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	c.pending[msg.Partition] = msg
+}
+
+func lookupPartitions(conn *KafkaConnection, topic string) ([]int, error) {
+	var partitions []kafka.Partition
+	err := conn.withBootstrap(func(kafkaConn *kafka.Conn) error {
+		var err error
+		partitions, err = kafkaConn.ReadPartitions(topic)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up partitions: %w", err)
+	}
+
+	ids := make([]int, len(partitions))
+	for i, p := range partitions {
+		ids[i] = p.ID
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// writeCallback is carried in Message.WriterData to hand each pooled
+// writer's result back to whoever queued the message
+type writeCallback func(kafka.Message, error)
+
+type writeOutcome struct {
+	msg kafka.Message
+	err error
+}
+
+// keepPartition asks writer() for a writer that sends each message to its
+// own Message.Partition, wrapping around when the topic has fewer
+const keepPartition = -2
+
+// writer returns the connection's pooled writer for a topic and producer
+// settings, creating it on first use. Partition is -1 to balance, a fixed
+// partition, or keepPartition. Pooled writers are async: the result of each
+// message arrives through the writeCallback in its WriterData.
+func (c *KafkaConnection) writer(topic string, partition int, compression string, acks int) *kafka.Writer {
+	key := fmt.Sprintf("%s/%d/%s/%d", topic, partition, compression, acks)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if w, ok := c.writers[key]; ok {
+		return w
+	}
+	if c.writers == nil {
+		// Disconnected: a closed writer fails every write with
+		// io.ErrClosedPipe instead of leaking a new one
+		w := &kafka.Writer{Addr: kafka.TCP(c.Brokers...), Topic: topic}
+		w.Close()
+		return w
+	}
+
+	// The writer picks partitions through its balancer and ignores
+	// Message.Partition, so an explicit partition needs its own balancer
+	var balancer kafka.Balancer = &kafka.LeastBytes{}
+	switch {
+	case partition == keepPartition:
+		balancer = kafka.BalancerFunc(func(m kafka.Message, partitions ...int) int {
+			return partitions[m.Partition%len(partitions)]
+		})
+	case partition >= 0:
+		balancer = kafka.BalancerFunc(func(kafka.Message, ...int) int {
+			return partition
+		})
+	}
+
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(c.Brokers...),
+		Topic:        topic,
+		Balancer:     balancer,
+		MaxAttempts:  3,
+		BatchTimeout: 10 * time.Millisecond,
+		RequiredAcks: kafka.RequiredAcks(acks),
+		Async:        true,
+		Compression:  kafkaCompression(compression),
+		Completion: func(messages []kafka.Message, err error) {
+			for _, m := range messages {
+				if done, ok := m.WriterData.(writeCallback); ok {
+					done(m, err)
 				}
 			}
+		},
+		Transport: c.transport,
+	}
+	c.writers[key] = w
+	return w
+}
 
-			emitStreamMessage(app, connectionID, "inbound", "kafka", payload)
+// closeWriters flushes and closes the pooled writers. The connection hands
+// out no new ones afterwards.
+func (c *KafkaConnection) closeWriters() {
+	c.mu.Lock()
+	writers := c.writers
+	c.writers = nil
+	c.mu.Unlock()
+
+	for _, w := range writers {
+		w.Close()
+	}
+}
+
+func kafkaCompression(name string) kafka.Compression {
+	switch name {
+	case "gzip":
+		return kafka.Gzip
+	case "snappy":
+		return kafka.Snappy
+	case "lz4":
+		return kafka.Lz4
+	case "zstd":
+		return kafka.Zstd
+	default:
+		return 0
+	}
+}
+
+// startOffsets resolves an offset strategy to a concrete offset on each
+// partition. Custom offsets are clamped to what the partition still holds,
+// and a timestamp past the last message starts at the end.
+func startOffsets(conn *KafkaConnection, topic string, partitions []int, config ConsumerConfig) (map[int]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := conn.adminClient()
+	latest, err := listOffsets(ctx, client, topic, partitions, kafka.LastOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.OffsetStrategy {
+	case "earliest":
+		return listOffsets(ctx, client, topic, partitions, kafka.FirstOffset)
+
+	case "custom":
+		earliest, err := listOffsets(ctx, client, topic, partitions, kafka.FirstOffset)
+		if err != nil {
+			return nil, err
+		}
+		offsets := make(map[int]int64, len(partitions))
+		for _, p := range partitions {
+			offsets[p] = min(max(config.CustomOffset, earliest[p]), latest[p])
+		}
+		return offsets, nil
+
+	case "timestamp":
+		offsets, err := listOffsets(ctx, client, topic, partitions, config.CustomTimestamp)
+		if err != nil {
+			return nil, err
+		}
+		for p, offset := range offsets {
+			if offset < 0 {
+				offsets[p] = latest[p]
+			}
+		}
+		return offsets, nil
+
+	default:
+		return latest, nil
+	}
+}
 
-			// NO COMMIT - we're not using consumer groups
+// describeAssignments lists where a generation starts on each partition,
+// e.g. "0 (from 42), 1 (no committed offset)"
+func describeAssignments(assignments []kafka.PartitionAssignment) string {
+	sorted := append([]kafka.PartitionAssignment(nil), assignments...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	parts := make([]string, len(sorted))
+	for i, a := range sorted {
+		if a.Offset < 0 {
+			parts[i] = fmt.Sprintf("%d (no committed offset)", a.ID)
+		} else {
+			parts[i] = fmt.Sprintf("%d (from %d)", a.ID, a.Offset)
 		}
 	}
+	return strings.Join(parts, ", ")
 }
 
 func emitStreamMessage(app AppInterface, connectionID, direction, protocol, payload string) {
 	app.EmitStreamMessage(connectionID, direction, protocol, payload)
 }
 
-func createSASLMechanism(mechanism, username, password string) (sasl.Mechanism, error) {
-	switch mechanism {
+func createSASLMechanism(config KafkaConfig) (sasl.Mechanism, error) {
+	switch config.AuthMechanism {
 	case "plain":
 		return plain.Mechanism{
-			Username: username,
-			Password: password,
+			Username: config.SaslUsername,
+			Password: config.SaslPassword,
 		}, nil
 	case "scram-sha-256":
-		return scram.Mechanism(scram.SHA256, username, password)
+		return scram.Mechanism(scram.SHA256, config.SaslUsername, config.SaslPassword)
 	case "scram-sha-512":
-		return scram.Mechanism(scram.SHA512, username, password)
+		return scram.Mechanism(scram.SHA512, config.SaslUsername, config.SaslPassword)
+	case "oauthbearer":
+		return newOAuthBearerMechanism(config.OAuth)
+	case "aws-msk-iam":
+		return newMSKIAMMechanism(config)
 	default:
-		return nil, fmt.Errorf("unsupported SASL mechanism: %s", mechanism)
+		return nil, fmt.Errorf("unsupported SASL mechanism: %s", config.AuthMechanism)
 	}
 }
@@ -0,0 +1,760 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/fetch"
+	"github.com/segmentio/kafka-go/protocol/findcoordinator"
+	"github.com/segmentio/kafka-go/protocol/heartbeat"
+	"github.com/segmentio/kafka-go/protocol/joingroup"
+	"github.com/segmentio/kafka-go/protocol/leavegroup"
+	"github.com/segmentio/kafka-go/protocol/listoffsets"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/offsetcommit"
+	"github.com/segmentio/kafka-go/protocol/offsetfetch"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/segmentio/kafka-go/protocol/syncgroup"
+)
+
+// testKafka is just enough of a one-broker Kafka cluster for the consumers,
+// producers and admin client: metadata, produce, fetch, list offsets and
+// consumer groups with one member at a time. Logs are kept in memory.
+type testKafka struct {
+	ln     net.Listener
+	port   int32
+	mu     sync.Mutex
+	topics map[string][]*testPartition
+	groups map[string]*testGroup
+	conns  []net.Conn
+}
+
+type testPartition struct {
+	start   int64 // log start offset
+	records []testRecord
+}
+
+type testRecord struct {
+	time    time.Time
+	key     []byte
+	value   []byte
+	headers []protocol.Header
+}
+
+type testGroup struct {
+	generation  int32
+	members     map[string]bool
+	leader      string
+	rebalance   bool // fail heartbeats until the members rejoin
+	assignments map[string][]byte
+	commits     map[string]map[int32]int64
+}
+
+// Kafka error codes the fake returns
+const (
+	kafkaOffsetOutOfRange    = 1
+	kafkaUnknownTopic        = 3
+	kafkaIllegalGeneration   = 22
+	kafkaUnknownMember       = 25
+	kafkaRebalanceInProgress = 27
+)
+
+// The newest version of each API the fake speaks, all below the flexible
+// versions that would need tagged fields
+var testKafkaVersions = map[protocol.ApiKey]int16{
+	protocol.ApiVersions:     2,
+	protocol.Metadata:        8,
+	protocol.Fetch:           10,
+	protocol.ListOffsets:     5,
+	protocol.FindCoordinator: 2,
+	protocol.JoinGroup:       5,
+	protocol.SyncGroup:       3,
+	protocol.Heartbeat:       3,
+	protocol.LeaveGroup:      2,
+	protocol.OffsetFetch:     5,
+	protocol.OffsetCommit:    7,
+	protocol.Produce:         8,
+}
+
+// newTestKafka starts a broker with the given partition count per topic
+func newTestKafka(t *testing.T, topics map[string]int) *testKafka {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := &testKafka{
+		ln:     ln,
+		port:   int32(ln.Addr().(*net.TCPAddr).Port),
+		topics: make(map[string][]*testPartition),
+		groups: make(map[string]*testGroup),
+	}
+	for topic, count := range topics {
+		for i := 0; i < count; i++ {
+			k.topics[topic] = append(k.topics[topic], &testPartition{})
+		}
+	}
+	t.Cleanup(func() {
+		ln.Close()
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		for _, c := range k.conns {
+			c.Close()
+		}
+	})
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			k.mu.Lock()
+			k.conns = append(k.conns, c)
+			k.mu.Unlock()
+			go k.serve(c)
+		}
+	}()
+	return k
+}
+
+func (k *testKafka) addr() string { return k.ln.Addr().String() }
+
+// produce appends values to a partition, one second apart from at
+func (k *testKafka) produce(topic string, partition int, at time.Time, values ...string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	p := k.topics[topic][partition]
+	for i, v := range values {
+		p.records = append(p.records, testRecord{time: at.Add(time.Duration(i) * time.Second), value: []byte(v)})
+	}
+}
+
+// values returns a partition's record values
+func (k *testKafka) values(topic string, partition int) []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var values []string
+	for _, r := range k.topics[topic][partition].records {
+		values = append(values, string(r.value))
+	}
+	return values
+}
+
+// truncate deletes a partition's records below start, as retention does
+func (k *testKafka) truncate(topic string, partition int, start int64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	p := k.topics[topic][partition]
+	p.records = p.records[start-p.start:]
+	p.start = start
+}
+
+// committed is a group's committed offset, -1 when there is none
+func (k *testKafka) committed(groupID, topic string, partition int) int64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if g, ok := k.groups[groupID]; ok {
+		if offset, ok := g.commits[topic][int32(partition)]; ok {
+			return offset
+		}
+	}
+	return -1
+}
+
+// rebalance makes the group's members rejoin at their next heartbeat
+func (k *testKafka) rebalance(groupID string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.group(groupID).rebalance = true
+}
+
+func (k *testKafka) group(id string) *testGroup {
+	g, ok := k.groups[id]
+	if !ok {
+		g = &testGroup{
+			members:     make(map[string]bool),
+			assignments: make(map[string][]byte),
+			commits:     make(map[string]map[int32]int64),
+		}
+		k.groups[id] = g
+	}
+	return g
+}
+
+func (p *testPartition) end() int64 { return p.start + int64(len(p.records)) }
+
+func (k *testKafka) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	for {
+		version, correlationID, _, msg, err := protocol.ReadRequest(r)
+		if err != nil {
+			return
+		}
+
+		if req, ok := msg.(*fetch.Request); ok {
+			if err := k.fetch(c, version, correlationID, req); err != nil {
+				return
+			}
+			continue
+		}
+
+		resp := k.handle(msg)
+		if resp == nil {
+			continue // acks=0 produce
+		}
+		if err := protocol.WriteResponse(c, version, correlationID, resp); err != nil {
+			return
+		}
+	}
+}
+
+func (k *testKafka) handle(msg protocol.Message) protocol.Message {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	switch req := msg.(type) {
+	case *apiversions.Request:
+		resp := &apiversions.Response{}
+		for key, max := range testKafkaVersions {
+			resp.ApiKeys = append(resp.ApiKeys, apiversions.ApiKeyResponse{ApiKey: int16(key), MaxVersion: max})
+		}
+		return resp
+
+	case *metadata.Request:
+		resp := &metadata.Response{
+			Brokers:   []metadata.ResponseBroker{{NodeID: 0, Host: "127.0.0.1", Port: k.port}},
+			ClusterID: "test-cluster",
+		}
+		names := req.TopicNames
+		if len(names) == 0 {
+			for name := range k.topics {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+		for _, name := range names {
+			topic := metadata.ResponseTopic{Name: name}
+			partitions, ok := k.topics[name]
+			if !ok {
+				topic.ErrorCode = kafkaUnknownTopic
+			}
+			for i := range partitions {
+				topic.Partitions = append(topic.Partitions, metadata.ResponsePartition{
+					PartitionIndex: int32(i),
+					ReplicaNodes:   []int32{0},
+					IsrNodes:       []int32{0},
+				})
+			}
+			resp.Topics = append(resp.Topics, topic)
+		}
+		return resp
+
+	case *listoffsets.Request:
+		resp := &listoffsets.Response{}
+		for _, t := range req.Topics {
+			topic := listoffsets.ResponseTopic{Topic: t.Topic}
+			for _, rp := range t.Partitions {
+				part := listoffsets.ResponsePartition{Partition: rp.Partition, Timestamp: -1, Offset: -1}
+				p := k.partition(t.Topic, rp.Partition)
+				switch {
+				case p == nil:
+					part.ErrorCode = kafkaUnknownTopic
+				case rp.Timestamp == kafka.FirstOffset:
+					part.Offset = p.start
+				case rp.Timestamp == kafka.LastOffset:
+					part.Offset = p.end()
+				default:
+					for i, rec := range p.records {
+						if rec.time.UnixMilli() >= rp.Timestamp {
+							part.Offset, part.Timestamp = p.start+int64(i), rec.time.UnixMilli()
+							break
+						}
+					}
+				}
+				topic.Partitions = append(topic.Partitions, part)
+			}
+			resp.Topics = append(resp.Topics, topic)
+		}
+		return resp
+
+	case *produce.Request:
+		resp := &produce.Response{}
+		for _, t := range req.Topics {
+			topic := produce.ResponseTopic{Topic: t.Topic}
+			for _, rp := range t.Partitions {
+				part := produce.ResponsePartition{Partition: rp.Partition}
+				if p := k.partition(t.Topic, rp.Partition); p == nil {
+					part.ErrorCode = kafkaUnknownTopic
+				} else {
+					part.BaseOffset = p.end()
+					p.records = append(p.records, readTestRecords(rp.RecordSet.Records)...)
+				}
+				topic.Partitions = append(topic.Partitions, part)
+			}
+			resp.Topics = append(resp.Topics, topic)
+		}
+		if req.Acks == 0 {
+			return nil
+		}
+		return resp
+
+	case *findcoordinator.Request:
+		return &findcoordinator.Response{NodeID: 0, Host: "127.0.0.1", Port: k.port}
+
+	case *joingroup.Request:
+		g := k.group(req.GroupID)
+		member := req.MemberID
+		if member == "" {
+			member = fmt.Sprintf("member-%d", len(g.members)+int(g.generation)+1)
+		}
+		g.members[member] = true
+		g.generation++
+		g.leader = member
+		g.rebalance = false
+		var meta []byte
+		if len(req.Protocols) > 0 {
+			meta = req.Protocols[0].Metadata
+		}
+		resp := &joingroup.Response{
+			GenerationID: g.generation,
+			LeaderID:     member,
+			MemberID:     member,
+			Members:      []joingroup.ResponseMember{{MemberID: member, Metadata: meta}},
+		}
+		if len(req.Protocols) > 0 {
+			resp.ProtocolName = req.Protocols[0].Name
+		}
+		return resp
+
+	case *syncgroup.Request:
+		g := k.group(req.GroupID)
+		if req.GenerationID != g.generation {
+			return &syncgroup.Response{ErrorCode: kafkaIllegalGeneration}
+		}
+		if req.MemberID == g.leader {
+			g.assignments = make(map[string][]byte)
+			for _, a := range req.Assignments {
+				g.assignments[a.MemberID] = a.Assignment
+			}
+		}
+		return &syncgroup.Response{Assignments: g.assignments[req.MemberID]}
+
+	case *heartbeat.Request:
+		g := k.group(req.GroupID)
+		switch {
+		case !g.members[req.MemberID]:
+			return &heartbeat.Response{ErrorCode: kafkaUnknownMember}
+		case g.rebalance:
+			return &heartbeat.Response{ErrorCode: kafkaRebalanceInProgress}
+		case req.GenerationID != g.generation:
+			return &heartbeat.Response{ErrorCode: kafkaIllegalGeneration}
+		}
+		return &heartbeat.Response{}
+
+	case *leavegroup.Request:
+		delete(k.group(req.GroupID).members, req.MemberID)
+		return &leavegroup.Response{}
+
+	case *offsetfetch.Request:
+		g := k.group(req.GroupID)
+		resp := &offsetfetch.Response{}
+		for _, t := range req.Topics {
+			topic := offsetfetch.ResponseTopic{Name: t.Name}
+			for _, p := range t.PartitionIndexes {
+				offset, ok := g.commits[t.Name][p]
+				if !ok {
+					offset = -1
+				}
+				topic.Partitions = append(topic.Partitions, offsetfetch.ResponsePartition{PartitionIndex: p, CommittedOffset: offset})
+			}
+			resp.Topics = append(resp.Topics, topic)
+		}
+		return resp
+
+	case *offsetcommit.Request:
+		g := k.group(req.GroupID)
+		resp := &offsetcommit.Response{}
+		for _, t := range req.Topics {
+			topic := offsetcommit.ResponseTopic{Name: t.Name}
+			if g.commits[t.Name] == nil {
+				g.commits[t.Name] = make(map[int32]int64)
+			}
+			for _, p := range t.Partitions {
+				part := offsetcommit.ResponsePartition{PartitionIndex: p.PartitionIndex}
+				if req.GenerationID >= 0 && (req.GenerationID != g.generation || !g.members[req.MemberID]) {
+					part.ErrorCode = kafkaIllegalGeneration
+				} else {
+					g.commits[t.Name][p.PartitionIndex] = p.CommittedOffset
+				}
+				topic.Partitions = append(topic.Partitions, part)
+			}
+			resp.Topics = append(resp.Topics, topic)
+		}
+		return resp
+	}
+
+	panic(fmt.Sprintf("testKafka: unexpected %T", msg))
+}
+
+func (k *testKafka) partition(topic string, partition int32) *testPartition {
+	partitions := k.topics[topic]
+	if partition < 0 || int(partition) >= len(partitions) {
+		return nil
+	}
+	return partitions[partition]
+}
+
+func readTestRecords(records protocol.RecordReader) []testRecord {
+	var out []testRecord
+	for {
+		r, err := records.ReadRecord()
+		if err != nil {
+			return out
+		}
+		rec := testRecord{time: r.Time, headers: append([]protocol.Header(nil), r.Headers...)}
+		if r.Key != nil {
+			rec.key, _ = protocol.ReadAll(r.Key)
+		}
+		if r.Value != nil {
+			rec.value, _ = protocol.ReadAll(r.Value)
+		}
+		out = append(out, rec)
+	}
+}
+
+// fetch answers by hand: the protocol package encodes every record batch
+// at base offset 0. It waits up to the request's MaxWaitTime for records.
+func (k *testKafka) fetch(w io.Writer, version int16, correlationID int32, req *fetch.Request) error {
+	deadline := time.Now().Add(time.Duration(req.MaxWaitTime) * time.Millisecond)
+	for !k.hasRecords(req) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var body bytes.Buffer
+	put := func(v interface{}) { binary.Write(&body, binary.BigEndian, v) }
+	putString := func(s string) { put(int16(len(s))); body.WriteString(s) }
+
+	if version >= 1 {
+		put(int32(0)) // throttle time
+	}
+	if version >= 7 {
+		put(int16(0))      // error code
+		put(req.SessionID) // session ID
+	}
+	put(int32(len(req.Topics)))
+	for _, t := range req.Topics {
+		putString(t.Topic)
+		put(int32(len(t.Partitions)))
+		for _, rp := range t.Partitions {
+			p := k.partition(t.Topic, rp.Partition)
+			var errorCode int16
+			var highWatermark, logStart int64 = -1, -1
+			var batch []byte
+			switch {
+			case p == nil:
+				errorCode = kafkaUnknownTopic
+			case rp.FetchOffset < p.start || rp.FetchOffset > p.end():
+				errorCode = kafkaOffsetOutOfRange
+				highWatermark, logStart = p.end(), p.start
+			default:
+				highWatermark, logStart = p.end(), p.start
+				batch = encodeTestBatch(rp.FetchOffset, p.records[rp.FetchOffset-p.start:])
+			}
+
+			put(rp.Partition)
+			put(errorCode)
+			put(highWatermark)
+			if version >= 4 {
+				put(highWatermark) // last stable offset
+			}
+			if version >= 5 {
+				put(logStart)
+			}
+			if version >= 4 {
+				put(int32(0)) // aborted transactions
+			}
+			put(int32(len(batch)))
+			body.Write(batch)
+		}
+	}
+
+	var frame bytes.Buffer
+	binary.Write(&frame, binary.BigEndian, int32(body.Len()+4))
+	binary.Write(&frame, binary.BigEndian, correlationID)
+	frame.Write(body.Bytes())
+	_, err := w.Write(frame.Bytes())
+	return err
+}
+
+func (k *testKafka) hasRecords(req *fetch.Request) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, t := range req.Topics {
+		for _, rp := range t.Partitions {
+			if p := k.partition(t.Topic, rp.Partition); p == nil || rp.FetchOffset < p.start || rp.FetchOffset < p.end() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodeTestBatch encodes records as one v2 batch starting at base
+func encodeTestBatch(base int64, records []testRecord) []byte {
+	if len(records) == 0 {
+		return nil
+	}
+	recs := make([]protocol.Record, len(records))
+	for i, r := range records {
+		recs[i] = protocol.Record{Offset: base + int64(i), Time: r.time, Headers: r.headers}
+		if r.key != nil {
+			recs[i].Key = protocol.NewBytes(r.key)
+		}
+		if r.value != nil {
+			recs[i].Value = protocol.NewBytes(r.value)
+		}
+	}
+
+	var buf bytes.Buffer
+	rs := protocol.RecordSet{Version: 2, Records: protocol.NewRecordReader(recs...)}
+	if _, err := rs.WriteTo(&buf); err != nil {
+		panic(err)
+	}
+	// Skip the set's size prefix; the base offset isn't covered by the CRC
+	batch := buf.Bytes()[4:]
+	binary.BigEndian.PutUint64(batch[0:8], uint64(base))
+	return batch
+}
+
+// connectTestKafka opens a connection to the broker and closes it when the
+// test ends
+func connectTestKafka(t *testing.T, app *testApp, k *testKafka) string {
+	t.Helper()
+
+	id, err := KafkaConnect(app, KafkaConfig{BootstrapServers: []string{k.addr()}, ConnectionTimeout: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { KafkaDisconnect(app, id) })
+	return id
+}
+
+func TestKafkaGroupConsumer(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 2})
+	app, events := newTestApp(t)
+	connID := connectTestKafka(t, app, k)
+
+	config := ConsumerConfig{ConnectionID: connID, Topic: "orders", ConsumerGroup: "billing", OffsetStrategy: "earliest"}
+	consumerID, err := KafkaStartConsumer(app, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForMessage(t, events, systemMessage("joined group billing (generation 1)"))
+	waitForMessage(t, events, systemMessage("assigned partitions: 0 (no committed offset), 1 (no committed offset)"))
+
+	// Explicit offsets are the last processed ones; the group resumes after them
+	committed, err := KafkaCommitOffsets(app, CommitOffsetsRequest{
+		ConnectionID: connID,
+		ConsumerID:   consumerID,
+		Offsets:      []PartitionOffset{{Partition: 1, Offset: 6}, {Partition: 0, Offset: 4}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(committed) != "[{0 4} {1 6}]" {
+		t.Errorf("committed = %v", committed)
+	}
+	if got := k.committed("billing", "orders", 0); got != 5 {
+		t.Errorf("committed offset = %d, want 5", got)
+	}
+	waitForMessage(t, events, systemMessage("committed offsets (partition@offset): 0@4, 1@6"))
+
+	// Nothing was fetched, so committing everything fetched is a no-op
+	if committed, err := KafkaCommitOffsets(app, CommitOffsetsRequest{ConnectionID: connID, ConsumerID: consumerID}); err != nil || len(committed) != 0 {
+		t.Errorf("empty commit = %v, %v", committed, err)
+	}
+
+	if err := KafkaStopConsumer(app, connID, consumerID); err != nil {
+		t.Fatal(err)
+	}
+	if err := KafkaStopConsumer(app, connID, consumerID); err == nil || err.Error() != "consumer already stopped" {
+		t.Errorf("second stop: err = %v", err)
+	}
+
+	// A new consumer in the group picks up where the commit left off
+	if _, err := KafkaStartConsumer(app, config); err != nil {
+		t.Fatal(err)
+	}
+	waitForMessage(t, events, systemMessage("assigned partitions: 0 (from 5), 1 (from 7)"))
+}
+
+func TestKafkaGroupRebalance(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 1})
+	app, events := newTestApp(t)
+	connID := connectTestKafka(t, app, k)
+
+	if _, err := KafkaStartConsumer(app, ConsumerConfig{ConnectionID: connID, Topic: "orders", ConsumerGroup: "billing"}); err != nil {
+		t.Fatal(err)
+	}
+	waitForMessage(t, events, systemMessage("joined group billing (generation 1)"))
+
+	// The next heartbeat, a few seconds in, ends the generation
+	k.rebalance("billing")
+	waitForMessage(t, events, systemMessage("is rebalancing"))
+	waitForMessage(t, events, systemMessage("joined group billing (generation 2)"))
+}
+
+func TestKafkaCommitOffsetsErrors(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 1})
+	app, _ := newTestApp(t)
+	connID := connectTestKafka(t, app, k)
+
+	plainID, err := KafkaStartConsumer(app, ConsumerConfig{ConnectionID: connID, Topic: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := KafkaCommitOffsets(app, CommitOffsetsRequest{ConnectionID: connID, ConsumerID: plainID}); err == nil || !strings.Contains(err.Error(), "is not in a consumer group") {
+		t.Errorf("consumer without a group: err = %v", err)
+	}
+
+	// A group consumer between generations has no one to commit through
+	conn, _ := lookupKafkaConnection(connID)
+	joining := &ConsumerInstance{
+		ID:            "joining-consumer",
+		ConsumerGroup: "billing",
+		IsActive:      true,
+		pending:       map[int]kafka.Message{0: {Partition: 0, Offset: 3}},
+	}
+	conn.mu.Lock()
+	conn.Consumers[joining.ID] = joining
+	conn.mu.Unlock()
+	if _, err := KafkaCommitOffsets(app, CommitOffsetsRequest{ConnectionID: connID, ConsumerID: joining.ID}); err == nil || !strings.Contains(err.Error(), "is joining its group") {
+		t.Errorf("joining consumer: err = %v", err)
+	}
+
+	if _, err := KafkaCommitOffsets(app, CommitOffsetsRequest{ConnectionID: connID, ConsumerID: "missing"}); err == nil || err.Error() != "consumer not found: missing" {
+		t.Errorf("missing consumer: err = %v", err)
+	}
+}
+
+func TestDescribeAssignments(t *testing.T) {
+	got := describeAssignments([]kafka.PartitionAssignment{{ID: 2, Offset: 10}, {ID: 0, Offset: kafka.FirstOffset}, {ID: 1, Offset: 0}})
+	if want := "0 (no committed offset), 1 (from 0), 2 (from 10)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStartOffsets(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 2})
+	start := time.UnixMilli(1_700_000_000_000)
+	k.produce("orders", 0, start, "a", "b", "c", "d", "e")
+	k.produce("orders", 1, start.Add(10*time.Second), "f", "g")
+	k.truncate("orders", 0, 2)
+
+	app, _ := newTestApp(t)
+	conn, _ := lookupKafkaConnection(connectTestKafka(t, app, k))
+
+	tests := []struct {
+		name   string
+		config ConsumerConfig
+		want   map[int]int64
+	}{
+		{name: "earliest", config: ConsumerConfig{OffsetStrategy: "earliest"}, want: map[int]int64{0: 2, 1: 0}},
+		{name: "latest", config: ConsumerConfig{OffsetStrategy: "latest"}, want: map[int]int64{0: 5, 1: 2}},
+		{name: "custom inside the log", config: ConsumerConfig{OffsetStrategy: "custom", CustomOffset: 3}, want: map[int]int64{0: 3, 1: 2}},
+		{name: "custom below the log start", config: ConsumerConfig{OffsetStrategy: "custom", CustomOffset: 0}, want: map[int]int64{0: 2, 1: 0}},
+		{name: "custom past the end", config: ConsumerConfig{OffsetStrategy: "custom", CustomOffset: 99}, want: map[int]int64{0: 5, 1: 2}},
+		{name: "timestamp", config: ConsumerConfig{OffsetStrategy: "timestamp", CustomTimestamp: start.Add(3 * time.Second).UnixMilli()}, want: map[int]int64{0: 3, 1: 0}},
+		{name: "timestamp past the end", config: ConsumerConfig{OffsetStrategy: "timestamp", CustomTimestamp: start.Add(time.Hour).UnixMilli()}, want: map[int]int64{0: 5, 1: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := startOffsets(conn, "orders", []int{0, 1}, tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKafkaGroupAutoCommit(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 1})
+	k.produce("orders", 0, time.Now(), "a", "b", "c")
+	app, events := newTestApp(t)
+	connID := connectTestKafka(t, app, k)
+
+	if _, err := KafkaStartConsumer(app, ConsumerConfig{ConnectionID: connID, Topic: "orders", ConsumerGroup: "billing", OffsetStrategy: "earliest", AutoCommit: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"a", "b", "c"} {
+		if msg := waitForMessage(t, events, inboundOn("orders")); msg.Payload != want || msg.Metadata["consumerGroup"] != "billing" {
+			t.Errorf("got %q (%v), want %q", msg.Payload, msg.Metadata["consumerGroup"], want)
+		}
+	}
+
+	// Each message is committed once it has been emitted
+	deadline := time.Now().Add(5 * time.Second)
+	for k.committed("billing", "orders", 0) != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("committed offset = %d, want 3", k.committed("billing", "orders", 0))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestKafkaGroupManualCommit(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 2})
+	k.produce("orders", 0, time.Now(), "a", "b")
+	k.produce("orders", 1, time.Now(), "c")
+	app, events := newTestApp(t)
+	connID := connectTestKafka(t, app, k)
+
+	consumerID, err := KafkaStartConsumer(app, ConsumerConfig{ConnectionID: connID, Topic: "orders", ConsumerGroup: "billing", OffsetStrategy: "earliest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		waitForMessage(t, events, inboundOn("orders"))
+	}
+	if got := k.committed("billing", "orders", 0); got != -1 {
+		t.Errorf("committed before a commit: %d", got)
+	}
+
+	// Without offsets, everything fetched so far is committed
+	committed, err := KafkaCommitOffsets(app, CommitOffsetsRequest{ConnectionID: connID, ConsumerID: consumerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(committed) != "[{0 1} {1 0}]" {
+		t.Errorf("committed = %v", committed)
+	}
+	if k.committed("billing", "orders", 0) != 2 || k.committed("billing", "orders", 1) != 1 {
+		t.Errorf("broker offsets = %d, %d", k.committed("billing", "orders", 0), k.committed("billing", "orders", 1))
+	}
+
+	// Committed messages are no longer pending
+	if committed, err := KafkaCommitOffsets(app, CommitOffsetsRequest{ConnectionID: connID, ConsumerID: consumerID}); err != nil || len(committed) != 0 {
+		t.Errorf("second commit = %v, %v", committed, err)
+	}
+}
@@ -0,0 +1,155 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaRecordData is a record key or value as shown in the stream viewer,
+// with the raw bytes alongside for copying
+type KafkaRecordData struct {
+	Text        string `json:"text"`   // decoded JSON, UTF-8 text, or hex for binary
+	Format      string `json:"format"` // "json", "utf8", "binary" or "empty"
+	Base64      string `json:"base64"`
+	Hex         string `json:"hex"`
+	Size        int    `json:"size"`
+	Schema      string `json:"schema,omitempty"` // registry schema the bytes were encoded with
+	SchemaError string `json:"schemaError,omitempty"`
+}
+
+// KafkaHeader is a record header. Keys may repeat, so headers are kept as a list.
+type KafkaHeader struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Base64 string `json:"base64"`
+}
+
+// ProduceResult is the broker's acknowledgement of a produced message.
+// With acks=0 the broker doesn't reply: Offset is -1, and so is Partition
+// unless one was chosen.
+type ProduceResult struct {
+	Topic     string    `json:"topic"`
+	Partition int       `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func newRecordData(data []byte) KafkaRecordData {
+	record := KafkaRecordData{
+		Format: detectRecordFormat(data),
+		Base64: base64.StdEncoding.EncodeToString(data),
+		Hex:    hex.EncodeToString(data),
+		Size:   len(data),
+	}
+	if record.Format == "binary" {
+		record.Text = record.Hex
+	} else {
+		record.Text = string(data)
+	}
+	return record
+}
+
+// detectRecordFormat sniffs a key or value. Only objects and arrays count as
+// JSON: a bare number or string is far more likely a plain text payload.
+func detectRecordFormat(data []byte) string {
+	switch {
+	case len(data) == 0:
+		return "empty"
+	case isJSONDocument(data):
+		return "json"
+	case utf8.Valid(data) && isPrintable(string(data)):
+		return "utf8"
+	default:
+		return "binary"
+	}
+}
+
+func isJSONDocument(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed)
+}
+
+func isPrintable(text string) bool {
+	for _, r := range text {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// kafkaRecordMetadata is the StreamMessage metadata for a consumed or
+// produced record
+func kafkaRecordMetadata(connectionID string, msg kafka.Message, key, value KafkaRecordData) map[string]interface{} {
+	headers := make([]KafkaHeader, 0, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers = append(headers, KafkaHeader{
+			Key:    h.Key,
+			Value:  string(h.Value),
+			Base64: base64.StdEncoding.EncodeToString(h.Value),
+		})
+	}
+
+	return map[string]interface{}{
+		"connectionId": connectionID,
+		"topic":        msg.Topic,
+		"partition":    msg.Partition,
+		"offset":       msg.Offset,
+		"timestamp":    msg.Time,
+		"key":          key,
+		"value":        value,
+		"headers":      headers,
+	}
+}
+
+// kafkaMsgCounter keeps message IDs unique when batches, searches and
+// replays emit faster than the clock ticks
+var kafkaMsgCounter uint64
+
+func kafkaMessageID() string {
+	count := atomic.AddUint64(&kafkaMsgCounter, 1)
+	return fmt.Sprintf("msg-%d-%d", time.Now().UnixNano(), count)
+}
+
+// emitKafkaSystem sends a system message with structured metadata, such as
+// search progress
+func emitKafkaSystem(app AppInterface, connectionID, payload string, metadata map[string]interface{}) {
+	emitKafkaEvent(app, connectionID, "system", payload, metadata)
+}
+
+// emitKafkaEvent is emitKafkaSystem for any direction, e.g. errors that
+// belong to a batch
+func emitKafkaEvent(app AppInterface, connectionID, direction, payload string, metadata map[string]interface{}) {
+	metadata["connectionId"] = connectionID
+	emitStreamEvent(app.GetCtx(), StreamMessage{
+		ID:        kafkaMessageID(),
+		Direction: direction,
+		Protocol:  "kafka",
+		Payload:   payload,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+// emitKafkaRecord sends a record to the stream viewer. The payload is the
+// value's text; everything else travels in the metadata.
+func emitKafkaRecord(app AppInterface, direction string, metadata map[string]interface{}) {
+	value, _ := metadata["value"].(KafkaRecordData)
+	emitStreamEvent(app.GetCtx(), StreamMessage{
+		ID:        kafkaMessageID(),
+		Direction: direction,
+		Protocol:  "kafka",
+		Payload:   value.Text,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectRecordFormat(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"", "empty"},
+		{`{"id":1}`, "json"},
+		{` [1, 2] `, "json"},
+		{"\n{}\n", "json"},
+		{"42", "utf8"},
+		{`"quoted"`, "utf8"},
+		{"true", "utf8"},
+		{"null", "utf8"},
+		{`{"id":`, "utf8"},
+		{"héllo\tworld\n", "utf8"},
+		{"\x00\x01\x02", "binary"},
+		{"\xff\xfe", "binary"},
+		{"bell\a", "binary"},
+	}
+	for _, tt := range tests {
+		if got := detectRecordFormat([]byte(tt.data)); got != tt.want {
+			t.Errorf("detectRecordFormat(%q) = %s, want %s", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestNewRecordData(t *testing.T) {
+	binary := newRecordData([]byte{0x00, 0xff})
+	if binary.Text != "00ff" || binary.Hex != "00ff" || binary.Base64 != "AP8=" || binary.Size != 2 {
+		t.Errorf("binary = %+v", binary)
+	}
+	if text := newRecordData([]byte("42")); text.Text != "42" || text.Format != "utf8" {
+		t.Errorf("text = %+v", text)
+	}
+}
+
+// Produced and consumed records reach the stream viewer as structured
+// events, with the value's text as the payload
+func TestKafkaRecordEvents(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 1})
+	app, events := newTestApp(t)
+	connID := connectTestKafka(t, app, k)
+
+	consumerID, err := KafkaStartConsumer(app, ConsumerConfig{ConnectionID: connID, Topic: "orders", OffsetStrategy: "earliest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := KafkaProduceMessage(app, ProducerConfig{
+		ConnectionID: connID,
+		Topic:        "orders",
+		Partition:    0,
+		Key:          "A-1",
+		Value:        `{"total":42}`,
+		Headers:      map[string]string{"trace": "t-1"},
+		Acks:         1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Partition != 0 || result.Offset != 0 {
+		t.Errorf("result = %+v", result)
+	}
+
+	outbound := waitForMessage(t, events, func(msg StreamMessage) bool { return msg.Direction == "outbound" })
+	inbound := waitForMessage(t, events, inboundOn("orders"))
+
+	for _, msg := range []StreamMessage{outbound, inbound} {
+		key, _ := msg.Metadata["key"].(KafkaRecordData)
+		value, _ := msg.Metadata["value"].(KafkaRecordData)
+		headers, _ := msg.Metadata["headers"].([]KafkaHeader)
+		if msg.Protocol != "kafka" || msg.Payload != `{"total":42}` || msg.Metadata["connectionId"] != connID {
+			t.Errorf("%s message = %+v", msg.Direction, msg)
+		}
+		if key.Text != "A-1" || key.Format != "utf8" || value.Format != "json" {
+			t.Errorf("%s key = %+v, value = %+v", msg.Direction, key, value)
+		}
+		if len(headers) != 1 || headers[0] != (KafkaHeader{Key: "trace", Value: "t-1", Base64: "dC0x"}) {
+			t.Errorf("%s headers = %+v", msg.Direction, headers)
+		}
+	}
+	if inbound.Metadata["consumerId"] != consumerID || inbound.Metadata["offset"] != int64(0) {
+		t.Errorf("inbound metadata = %+v", inbound.Metadata)
+	}
+	if _, ok := inbound.Metadata["timestamp"].(time.Time); !ok {
+		t.Errorf("timestamp = %v", inbound.Metadata["timestamp"])
+	}
+}
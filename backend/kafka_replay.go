@@ -0,0 +1,500 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaReplayRequest copies the records a search matches from one
+// connection's topic to a topic on the same or another connection. Keys and
+// headers are copied as-is; values too, unless transforms rewrite them.
+type KafkaReplayRequest struct {
+	Source             KafkaSearchRequest `json:"source"`             // topic, time range and filters; MaxResults caps the records copied, 0 copies all
+	TargetConnectionID string             `json:"targetConnectionId"` // optional for a dry run
+	TargetTopic        string             `json:"targetTopic"`        // defaults to the source topic
+	KeepPartitions     bool               `json:"keepPartitions"`     // wraps around when the target has fewer partitions
+	KeepTimestamps     bool               `json:"keepTimestamps"`     // otherwise records get the time they're replayed
+	Transforms         []ReplayTransform  `json:"transforms"`
+	Rate               float64            `json:"rate"`   // records per second; 0 is unthrottled
+	DryRun             bool               `json:"dryRun"` // show what would be written without writing
+}
+
+// ReplayTransform rewrites part of a JSON value. Paths use the search syntax,
+// so "$.items[*].price" rewrites the price of every item, and a missing last
+// field is added. Values that aren't JSON can't be transformed and fail.
+type ReplayTransform struct {
+	Path   string `json:"path"`
+	Value  string `json:"value"`  // JSON, or a string when it isn't valid JSON
+	Remove bool   `json:"remove"` // delete the field or element instead
+}
+
+// KafkaReplayProgress is sent as the metadata of system messages on the
+// source connection while a replay runs, and once more when it ends
+type KafkaReplayProgress struct {
+	ReplayID   string                    `json:"replayId"`
+	State      string                    `json:"state"` // "running", "completed", "limit" or "cancelled"
+	DryRun     bool                      `json:"dryRun"`
+	Scanned    int64                     `json:"scanned"`
+	Total      int64                     `json:"total"`
+	Matched    int                       `json:"matched"`
+	Written    int                       `json:"written"` // acknowledged by the target, or shown in a dry run
+	Failed     int                       `json:"failed"`
+	ElapsedMs  int64                     `json:"elapsedMs"`
+	Throughput float64                   `json:"throughput"` // written records per second
+	Partitions []SearchPartitionProgress `json:"partitions"`
+}
+
+type replayTransform struct {
+	path   []string
+	value  interface{}
+	remove bool
+}
+
+type kafkaReplay struct {
+	id         string
+	req        KafkaReplayRequest
+	search     *kafkaSearch
+	writer     *kafka.Writer // nil in a dry run
+	transforms []replayTransform
+	slots      chan struct{}
+	inFlight   sync.WaitGroup
+	started    time.Time
+	done       chan struct{} // closed once run has returned
+
+	mu       sync.Mutex
+	next     time.Time // when the next record may be written at Rate
+	written  int
+	failed   int
+	reported int // per-record errors emitted so far
+}
+
+// KafkaStartReplay resolves the source range like a search and copies the
+// matches in the background. Progress arrives on the source connection.
+func KafkaStartReplay(app AppInterface, req KafkaReplayRequest) (string, error) {
+	source, err := lookupKafkaConnection(req.Source.ConnectionID)
+	if err != nil {
+		return "", err
+	}
+	if req.Source.Topic == "" {
+		return "", fmt.Errorf("source topic is required")
+	}
+	if req.Source.EndTime > 0 && req.Source.EndTime < req.Source.StartTime {
+		return "", fmt.Errorf("end time is before start time")
+	}
+	if req.Rate < 0 {
+		return "", fmt.Errorf("rate can't be negative")
+	}
+	if req.TargetTopic == "" {
+		req.TargetTopic = req.Source.Topic
+	}
+
+	var target *KafkaConnection
+	if req.TargetConnectionID != "" || !req.DryRun {
+		if target, err = lookupKafkaConnection(req.TargetConnectionID); err != nil {
+			return "", fmt.Errorf("target: %w", err)
+		}
+		if target == source && req.TargetTopic == req.Source.Topic {
+			return "", fmt.Errorf("target is the source topic")
+		}
+		// fail now rather than once per record
+		partitions, err := lookupPartitions(target, req.TargetTopic)
+		if err != nil {
+			return "", fmt.Errorf("target topic %s: %w", req.TargetTopic, err)
+		}
+		if len(partitions) == 0 {
+			return "", fmt.Errorf("target topic %s doesn't exist", req.TargetTopic)
+		}
+	}
+
+	transforms := make([]replayTransform, 0, len(req.Transforms))
+	for _, t := range req.Transforms {
+		path, err := parseJSONPath(t.Path)
+		if err != nil {
+			return "", err
+		}
+		transform := replayTransform{path: path, remove: t.Remove}
+		if !t.Remove {
+			if transform.value, err = decodeJSONDocument(t.Value); err != nil {
+				transform.value = t.Value
+			}
+		}
+		transforms = append(transforms, transform)
+	}
+
+	if req.Source.MaxResults <= 0 {
+		req.Source.MaxResults = math.MaxInt
+		if req.DryRun {
+			req.Source.MaxResults = defaultSearchResults
+		}
+	}
+	search, err := newKafkaSearch(source, req.Source)
+	if err != nil {
+		return "", err
+	}
+
+	replay := &kafkaReplay{
+		id:         search.id,
+		req:        req,
+		search:     search,
+		transforms: transforms,
+		slots:      make(chan struct{}, batchMaxInFlight),
+		done:       make(chan struct{}),
+	}
+	if !req.DryRun {
+		partition := -1
+		if req.KeepPartitions {
+			partition = keepPartition
+		}
+		replay.writer = target.writer(req.TargetTopic, partition, "none", int(kafka.RequireAll))
+	}
+	search.onMatch = func(msg kafka.Message, key, value KafkaRecordData) {
+		replay.copy(app, msg)
+	}
+
+	source.mu.Lock()
+	source.Replays[replay.id] = replay
+	source.mu.Unlock()
+
+	log.Printf("[Kafka] Replay %s started: %s to %s (%d records in range)", replay.id, req.Source.Topic, req.TargetTopic, search.progress.Total)
+	go replay.run(app, source)
+
+	return replay.id, nil
+}
+
+// KafkaCancelReplay stops reading new records; ones already queued are
+// still acknowledged before the final progress message
+func KafkaCancelReplay(app AppInterface, connectionID, replayID string) error {
+	conn, err := lookupKafkaConnection(connectionID)
+	if err != nil {
+		return err
+	}
+
+	conn.mu.RLock()
+	replay, exists := conn.Replays[replayID]
+	conn.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("replay not found: %s", replayID)
+	}
+
+	replay.stop()
+	return nil
+}
+
+func (r *kafkaReplay) stop() {
+	r.search.setState("cancelled")
+	r.search.cancel()
+}
+
+func (r *kafkaReplay) run(app AppInterface, source *KafkaConnection) {
+	defer close(r.done)
+	defer r.search.cancel()
+
+	r.mu.Lock()
+	r.started = time.Now()
+	r.mu.Unlock()
+
+	r.emitProgress(app)
+	r.search.scan(app, source, func() { r.emitProgress(app) })
+
+	written := make(chan struct{})
+	go func() {
+		r.inFlight.Wait()
+		close(written)
+	}()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for waiting := true; waiting; {
+		select {
+		case <-written:
+			waiting = false
+		case <-ticker.C:
+			r.emitProgress(app)
+		}
+	}
+
+	r.search.setState("completed")
+	source.mu.Lock()
+	delete(source.Replays, r.id)
+	source.mu.Unlock()
+
+	progress := r.snapshot()
+	log.Printf("[Kafka] Replay %s %s: %d written, %d failed", r.id, progress.State, progress.Written, progress.Failed)
+	r.emitProgress(app)
+}
+
+// copy writes one matched record to the target, or shows it in a dry run.
+// It runs on the search's partition workers, so waiting for the rate or a
+// free slot holds back reading as well.
+func (r *kafkaReplay) copy(app AppInterface, src kafka.Message) {
+	ctx := r.search.ctx
+	if !r.pace(ctx) {
+		return
+	}
+
+	msg := kafka.Message{
+		Key:     src.Key,
+		Value:   src.Value,
+		Headers: src.Headers,
+		Time:    time.Now(),
+	}
+	if r.req.KeepTimestamps {
+		msg.Time = src.Time
+	}
+	if r.req.KeepPartitions {
+		msg.Partition = src.Partition
+	}
+	if len(r.transforms) > 0 {
+		value, err := applyReplayTransforms(src.Value, r.transforms)
+		if err != nil {
+			r.fail(app, src, err)
+			return
+		}
+		msg.Value = value
+	}
+
+	if r.writer == nil {
+		r.show(app, msg)
+		return
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+
+	r.inFlight.Add(1)
+	msg.WriterData = writeCallback(func(_ kafka.Message, err error) {
+		defer r.inFlight.Done()
+		<-r.slots
+		if err != nil {
+			r.fail(app, src, err)
+			return
+		}
+		r.mu.Lock()
+		r.written++
+		r.mu.Unlock()
+	})
+	if err := r.writer.WriteMessages(context.Background(), msg); err != nil {
+		r.inFlight.Done()
+		<-r.slots
+		r.fail(app, src, err)
+	}
+}
+
+// pace waits until the next record may be written at the replay's rate,
+// and reports false if the replay was cancelled meanwhile
+func (r *kafkaReplay) pace(ctx context.Context) bool {
+	if r.req.Rate <= 0 {
+		return ctx.Err() == nil
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	at := r.next
+	r.next = at.Add(time.Duration(float64(time.Second) / r.req.Rate))
+	r.mu.Unlock()
+
+	select {
+	case <-time.After(time.Until(at)):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// show emits a record a dry run would have written, as the target would
+// have received it
+func (r *kafkaReplay) show(app AppInterface, msg kafka.Message) {
+	r.mu.Lock()
+	r.written++
+	r.mu.Unlock()
+
+	msg.Topic, msg.Offset = r.req.TargetTopic, -1
+	if !r.req.KeepPartitions {
+		msg.Partition = -1
+	}
+	metadata := kafkaRecordMetadata(r.req.Source.ConnectionID, msg, newRecordData(msg.Key), newRecordData(msg.Value))
+	metadata["replayId"] = r.id
+	metadata["dryRun"] = true
+	emitKafkaRecord(app, "outbound", metadata)
+}
+
+// fail counts a failed record and reports the first few individually
+func (r *kafkaReplay) fail(app AppInterface, src kafka.Message, err error) {
+	r.mu.Lock()
+	r.failed++
+	report := r.reported < batchMaxErrorReports
+	if report {
+		r.reported++
+	}
+	last := r.reported == batchMaxErrorReports && report
+	r.mu.Unlock()
+
+	if !report {
+		return
+	}
+	payload := fmt.Sprintf("Replay of partition %d offset %d failed: %v", src.Partition, src.Offset, err)
+	if last {
+		payload += " (further errors are only counted)"
+	}
+	emitKafkaEvent(app, r.req.Source.ConnectionID, "error", payload, map[string]interface{}{
+		"replayId":  r.id,
+		"partition": src.Partition,
+		"offset":    src.Offset,
+		"error":     err.Error(),
+	})
+}
+
+func (r *kafkaReplay) snapshot() KafkaReplayProgress {
+	search := r.search.snapshot()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	progress := KafkaReplayProgress{
+		ReplayID:   r.id,
+		State:      search.State,
+		DryRun:     r.req.DryRun,
+		Scanned:    search.Scanned,
+		Total:      search.Total,
+		Matched:    search.Matched,
+		Written:    r.written,
+		Failed:     r.failed,
+		Partitions: search.Partitions,
+	}
+	if !r.started.IsZero() {
+		elapsed := time.Since(r.started)
+		progress.ElapsedMs = elapsed.Milliseconds()
+		if elapsed > 0 {
+			progress.Throughput = float64(r.written) / elapsed.Seconds()
+		}
+	}
+	return progress
+}
+
+func (r *kafkaReplay) emitProgress(app AppInterface) {
+	p := r.snapshot()
+
+	verb := "written"
+	if p.DryRun {
+		verb = "would be written"
+	}
+	text := fmt.Sprintf("Replay %s: scanned %d of %d, %d %s, %d failed", p.State, p.Scanned, p.Total, p.Written, verb, p.Failed)
+	switch p.State {
+	case "limit":
+		text = fmt.Sprintf("Replay stopped at %d records after scanning %d of %d, %d %s, %d failed", p.Matched, p.Scanned, p.Total, p.Written, verb, p.Failed)
+	case "running":
+		text = fmt.Sprintf("Replaying %s to %s: scanned %d of %d, %d %s, %d failed", r.req.Source.Topic, r.req.TargetTopic, p.Scanned, p.Total, p.Written, verb, p.Failed)
+	}
+
+	emitKafkaSystem(app, r.req.Source.ConnectionID, text, map[string]interface{}{
+		"replayId": r.id,
+		"replay":   p,
+	})
+}
+
+func applyReplayTransforms(value []byte, transforms []replayTransform) ([]byte, error) {
+	doc, err := decodeJSONDocument(string(value))
+	if err != nil {
+		return nil, fmt.Errorf("value isn't JSON, so it can't be transformed: %w", err)
+	}
+	for _, t := range transforms {
+		doc = rewriteJSONPath(doc, t.path, t.value, t.remove)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// rewriteJSONPath sets or removes what a parsed JSON path points at and
+// returns the updated node. Only the last segment may name a field that
+// doesn't exist yet. Values are copied in, since a transform's value is
+// shared by every record and later transforms may write into it.
+func rewriteJSONPath(node interface{}, path []string, value interface{}, remove bool) interface{} {
+	segment, rest := path[0], path[1:]
+	last := len(rest) == 0
+
+	switch v := node.(type) {
+	case map[string]interface{}:
+		keys := []string{segment}
+		if segment == "*" {
+			keys = keys[:0]
+			for k := range v {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			child, exists := v[k]
+			switch {
+			case last && remove:
+				delete(v, k)
+			case last:
+				v[k] = cloneJSONValue(value)
+			case exists:
+				v[k] = rewriteJSONPath(child, rest, value, remove)
+			}
+		}
+
+	case []interface{}:
+		selected := func(i int) bool {
+			return segment == "*" || strconv.Itoa(i) == segment
+		}
+		if last && remove {
+			kept := make([]interface{}, 0, len(v))
+			for i, child := range v {
+				if !selected(i) {
+					kept = append(kept, child)
+				}
+			}
+			return kept
+		}
+		for i, child := range v {
+			if !selected(i) {
+				continue
+			}
+			if last {
+				v[i] = cloneJSONValue(value)
+			} else {
+				v[i] = rewriteJSONPath(child, rest, value, remove)
+			}
+		}
+	}
+	return node
+}
+
+// cloneJSONValue deep-copies a decoded JSON value
+func cloneJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			out[k] = cloneJSONValue(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = cloneJSONValue(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
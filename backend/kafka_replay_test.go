@@ -0,0 +1,150 @@
+package backend
+
+import (
+	"sync"
+	"testing"
+)
+
+func mustTransforms(t *testing.T, specs ...ReplayTransform) []replayTransform {
+	t.Helper()
+
+	out := make([]replayTransform, 0, len(specs))
+	for _, spec := range specs {
+		path, err := parseJSONPath(spec.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transform := replayTransform{path: path, remove: spec.Remove}
+		if !spec.Remove {
+			if transform.value, err = decodeJSONDocument(spec.Value); err != nil {
+				transform.value = spec.Value
+			}
+		}
+		out = append(out, transform)
+	}
+	return out
+}
+
+func TestApplyReplayTransforms(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		transforms []ReplayTransform
+		want       string
+	}{
+		{
+			name:       "set field",
+			input:      `{"id":1,"env":"prod"}`,
+			transforms: []ReplayTransform{{Path: "$.env", Value: `"staging"`}},
+			want:       `{"env":"staging","id":1}`,
+		},
+		{
+			name:       "non-JSON value becomes a string",
+			input:      `{"env":"prod"}`,
+			transforms: []ReplayTransform{{Path: "$.env", Value: `staging`}},
+			want:       `{"env":"staging"}`,
+		},
+		{
+			name:       "add missing last field",
+			input:      `{"id":1}`,
+			transforms: []ReplayTransform{{Path: "$.replayed", Value: `true`}},
+			want:       `{"id":1,"replayed":true}`,
+		},
+		{
+			name:       "missing parent is left alone",
+			input:      `{"id":1}`,
+			transforms: []ReplayTransform{{Path: "$.meta.source", Value: `"replay"`}},
+			want:       `{"id":1}`,
+		},
+		{
+			name:       "remove field",
+			input:      `{"id":1,"secret":"x"}`,
+			transforms: []ReplayTransform{{Path: "$.secret", Remove: true}},
+			want:       `{"id":1}`,
+		},
+		{
+			name:       "wildcard over array",
+			input:      `{"items":[{"price":1},{"price":2}]}`,
+			transforms: []ReplayTransform{{Path: "$.items[*].price", Value: `0`}},
+			want:       `{"items":[{"price":0},{"price":0}]}`,
+		},
+		{
+			name:       "remove array element",
+			input:      `{"items":["a","b","c"]}`,
+			transforms: []ReplayTransform{{Path: "$.items[1]", Remove: true}},
+			want:       `{"items":["a","c"]}`,
+		},
+		{
+			name:  "later transform writes into an earlier value",
+			input: `{"id":1}`,
+			transforms: []ReplayTransform{
+				{Path: "$.meta", Value: `{}`},
+				{Path: "$.meta.source", Value: `"replay"`},
+			},
+			want: `{"id":1,"meta":{"source":"replay"}}`,
+		},
+		{
+			name:  "wildcard copies are independent",
+			input: `{"items":[{},{}]}`,
+			transforms: []ReplayTransform{
+				{Path: "$.items[*].tags", Value: `[]`},
+				{Path: "$.items[0].tags[*]", Value: `"x"`},
+			},
+			want: `{"items":[{"tags":[]},{"tags":[]}]}`,
+		},
+		{
+			name:       "large numbers keep their precision",
+			input:      `{"id":9007199254740993}`,
+			transforms: []ReplayTransform{{Path: "$.copy", Value: `9007199254740995`}},
+			want:       `{"copy":9007199254740995,"id":9007199254740993}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyReplayTransforms([]byte(tt.input), mustTransforms(t, tt.transforms...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyReplayTransformsRejectsNonJSON(t *testing.T) {
+	transforms := mustTransforms(t, ReplayTransform{Path: "$.id", Value: `1`})
+	if _, err := applyReplayTransforms([]byte("plain text"), transforms); err == nil {
+		t.Error("expected a non-JSON value to fail")
+	}
+}
+
+// Partition workers apply the same transforms at once; run with -race
+func TestApplyReplayTransformsConcurrently(t *testing.T) {
+	transforms := mustTransforms(t,
+		ReplayTransform{Path: "$.meta", Value: `{"tags":[]}`},
+		ReplayTransform{Path: "$.meta.source", Value: `"replay"`},
+		ReplayTransform{Path: "$.meta.tags[*]", Value: `"x"`},
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				got, err := applyReplayTransforms([]byte(`{"id":1}`), transforms)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if string(got) != `{"id":1,"meta":{"source":"replay","tags":[]}}` {
+					t.Errorf("got %s", got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,566 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSearchRequest scans a topic between two timestamps for records that
+// match every filter that is set. With no filters every record in the range
+// matches, which makes it a time-range seek.
+type KafkaSearchRequest struct {
+	ConnectionID  string `json:"connectionId"`
+	Topic         string `json:"topic"`
+	Partitions    []int  `json:"partitions"` // all when empty
+	StartTime     int64  `json:"startTime"`  // unix ms; 0 scans from the earliest offset
+	EndTime       int64  `json:"endTime"`    // unix ms; 0 scans up to the end as of the start of the search
+	Key           string `json:"key"`        // substring of the key
+	HeaderKey     string `json:"headerKey"`
+	HeaderValue   string `json:"headerValue"`   // substring; any header when HeaderKey is empty
+	JSONPath      string `json:"jsonPath"`      // e.g. "$.order.items[*].sku" into the value
+	JSONValue     string `json:"jsonValue"`     // expected value at JSONPath; empty only requires the path
+	ValueContains string `json:"valueContains"` // substring of the value
+	MaxResults    int    `json:"maxResults"`    // default 500
+	Concurrency   int    `json:"concurrency"`   // partitions scanned at once, default 4
+}
+
+// KafkaSearchProgress is sent as the metadata of system messages while a
+// search runs, and once more when it ends
+type KafkaSearchProgress struct {
+	SearchID   string                    `json:"searchId"`
+	State      string                    `json:"state"` // "running", "completed", "limit" or "cancelled"
+	Scanned    int64                     `json:"scanned"`
+	Matched    int                       `json:"matched"`
+	Total      int64                     `json:"total"` // records in the offset range across partitions
+	Partitions []SearchPartitionProgress `json:"partitions"`
+}
+
+type SearchPartitionProgress struct {
+	Partition   int    `json:"partition"`
+	StartOffset int64  `json:"startOffset"`
+	EndOffset   int64  `json:"endOffset"` // exclusive
+	Offset      int64  `json:"offset"`    // next offset to scan
+	Scanned     int64  `json:"scanned"`
+	Done        bool   `json:"done"`
+	Incomplete  bool   `json:"incomplete,omitempty"` // went idle before EndOffset; the rest was not scanned
+	Error       string `json:"error,omitempty"`
+}
+
+type kafkaSearch struct {
+	id       string
+	req      KafkaSearchRequest
+	matcher  *searchMatcher
+	registry *schemaRegistry
+	ctx      context.Context
+	cancel   context.CancelFunc
+	// onMatch receives matches instead of the stream viewer, see replays
+	onMatch func(msg kafka.Message, key, value KafkaRecordData)
+
+	mu       sync.Mutex
+	progress KafkaSearchProgress
+}
+
+const (
+	defaultSearchResults     = 500
+	defaultSearchConcurrency = 4
+)
+
+// searchIdleTimeout gives up on a partition that returns nothing for this
+// long. Its remaining offsets may all be gone (compaction, transaction
+// markers), or the broker may just be slow, so the partition is flagged
+// incomplete rather than finished.
+var searchIdleTimeout = 10 * time.Second
+
+// KafkaStartSearch resolves the time range to offsets on each partition and
+// scans it in the background. Matches arrive as inbound Kafka records with a
+// searchId in their metadata.
+func KafkaStartSearch(app AppInterface, req KafkaSearchRequest) (string, error) {
+	conn, err := lookupKafkaConnection(req.ConnectionID)
+	if err != nil {
+		return "", err
+	}
+	if req.Topic == "" {
+		return "", fmt.Errorf("topic is required")
+	}
+	if req.EndTime > 0 && req.EndTime < req.StartTime {
+		return "", fmt.Errorf("end time is before start time")
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultSearchResults
+	}
+
+	search, err := newKafkaSearch(conn, req)
+	if err != nil {
+		return "", err
+	}
+
+	conn.mu.Lock()
+	conn.Searches[search.id] = search
+	conn.mu.Unlock()
+
+	log.Printf("[Kafka] Search %s started on topic %s (%d records in range)", search.id, req.Topic, search.progress.Total)
+	go search.run(app, conn)
+
+	return search.id, nil
+}
+
+// newKafkaSearch checks the filters and resolves the time range to an
+// offset range on each partition
+func newKafkaSearch(conn *KafkaConnection, req KafkaSearchRequest) (*kafkaSearch, error) {
+	if req.Concurrency <= 0 {
+		req.Concurrency = defaultSearchConcurrency
+	}
+
+	matcher, err := newSearchMatcher(req)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := req.Partitions
+	if len(partitions) == 0 {
+		if partitions, err = lookupPartitions(conn, req.Topic); err != nil {
+			return nil, err
+		}
+	}
+
+	start := ConsumerConfig{OffsetStrategy: "earliest"}
+	if req.StartTime > 0 {
+		start = ConsumerConfig{OffsetStrategy: "timestamp", CustomTimestamp: req.StartTime}
+	}
+	startAt, err := startOffsets(conn, req.Topic, partitions, start)
+	if err != nil {
+		return nil, err
+	}
+	end := ConsumerConfig{OffsetStrategy: "latest"}
+	if req.EndTime > 0 {
+		// the first record after EndTime is where the range stops
+		end = ConsumerConfig{OffsetStrategy: "timestamp", CustomTimestamp: req.EndTime + 1}
+	}
+	endAt, err := startOffsets(conn, req.Topic, partitions, end)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	search := &kafkaSearch{
+		id:       uuid.New().String(),
+		req:      req,
+		matcher:  matcher,
+		registry: conn.registry,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	search.progress = KafkaSearchProgress{SearchID: search.id, State: "running"}
+	for _, p := range partitions {
+		part := SearchPartitionProgress{
+			Partition:   p,
+			StartOffset: startAt[p],
+			EndOffset:   max(endAt[p], startAt[p]),
+			Offset:      startAt[p],
+		}
+		part.Done = part.Offset >= part.EndOffset
+		search.progress.Total += part.EndOffset - part.StartOffset
+		search.progress.Partitions = append(search.progress.Partitions, part)
+	}
+
+	return search, nil
+}
+
+// KafkaCancelSearch stops a running search. The final progress message
+// reports it as cancelled.
+func KafkaCancelSearch(app AppInterface, connectionID, searchID string) error {
+	conn, err := lookupKafkaConnection(connectionID)
+	if err != nil {
+		return err
+	}
+
+	conn.mu.RLock()
+	search, exists := conn.Searches[searchID]
+	conn.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("search not found: %s", searchID)
+	}
+
+	search.setState("cancelled")
+	search.cancel()
+	return nil
+}
+
+func (s *kafkaSearch) run(app AppInterface, conn *KafkaConnection) {
+	defer s.cancel()
+	s.emitProgress(app)
+	s.scan(app, conn, func() { s.emitProgress(app) })
+
+	s.setState("completed")
+	conn.mu.Lock()
+	delete(conn.Searches, s.id)
+	conn.mu.Unlock()
+
+	progress := s.snapshot()
+	log.Printf("[Kafka] Search %s %s: %d matches in %d records", s.id, progress.State, progress.Matched, progress.Scanned)
+	s.emitProgress(app)
+}
+
+// scan reads every partition's offset range, calling tick once a second
+// until all of them are done or the search is cancelled
+func (s *kafkaSearch) scan(app AppInterface, conn *KafkaConnection, tick func()) {
+	ctx := s.ctx
+	jobs := make(chan int, len(s.progress.Partitions))
+	for i, part := range s.progress.Partitions {
+		if !part.Done {
+			jobs <- i
+		}
+	}
+	close(jobs)
+
+	// Workers drain jobs as they start, so size the pool up front
+	workers := min(s.req.Concurrency, len(jobs))
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				s.scanPartition(ctx, app, conn, i)
+			}
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for running := true; running; {
+		select {
+		case <-finished:
+			running = false
+		case <-ticker.C:
+			tick()
+		}
+	}
+}
+
+func (s *kafkaSearch) scanPartition(ctx context.Context, app AppInterface, conn *KafkaConnection, index int) {
+	s.mu.Lock()
+	part := s.progress.Partitions[index]
+	s.mu.Unlock()
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   conn.Brokers,
+		Topic:     s.req.Topic,
+		Partition: part.Partition,
+		Dialer:    conn.Dialer,
+		MaxWait:   500 * time.Millisecond,
+		MinBytes:  1,
+		MaxBytes:  10e6, // 10MB
+	})
+	defer reader.Close()
+
+	finish := func(err error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.progress.Partitions[index].Done = true
+		if errors.Is(err, context.DeadlineExceeded) {
+			s.progress.Partitions[index].Incomplete = true
+		} else if err != nil {
+			s.progress.Partitions[index].Error = err.Error()
+		}
+	}
+
+	if err := reader.SetOffset(part.StartOffset); err != nil {
+		finish(err)
+		return
+	}
+
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, searchIdleTimeout)
+		msg, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				finish(err)
+			}
+			return
+		}
+		if msg.Offset >= part.EndOffset {
+			finish(nil)
+			return
+		}
+
+		s.mu.Lock()
+		s.progress.Scanned++
+		s.progress.Partitions[index].Scanned++
+		s.progress.Partitions[index].Offset = msg.Offset + 1
+		s.mu.Unlock()
+
+		if s.inTimeRange(msg.Time) {
+			key := decodeRecordData(s.registry, msg.Key)
+			value := decodeRecordData(s.registry, msg.Value)
+			if s.matcher.match(msg, key, value) && !s.addMatch(app, msg, key, value) {
+				return
+			}
+		}
+
+		if msg.Offset+1 >= part.EndOffset {
+			finish(nil)
+			return
+		}
+	}
+}
+
+// inTimeRange filters on record timestamps as well as offsets, since
+// producers can set timestamps out of order
+func (s *kafkaSearch) inTimeRange(t time.Time) bool {
+	ms := t.UnixMilli()
+	if s.req.StartTime > 0 && ms < s.req.StartTime {
+		return false
+	}
+	if s.req.EndTime > 0 && ms > s.req.EndTime {
+		return false
+	}
+	return true
+}
+
+// addMatch emits a match and reports whether the search should go on
+func (s *kafkaSearch) addMatch(app AppInterface, msg kafka.Message, key, value KafkaRecordData) bool {
+	s.mu.Lock()
+	if s.progress.Matched >= s.req.MaxResults {
+		s.mu.Unlock()
+		return false
+	}
+	s.progress.Matched++
+	limited := s.progress.Matched >= s.req.MaxResults
+	s.mu.Unlock()
+
+	if s.onMatch != nil {
+		s.onMatch(msg, key, value)
+	} else {
+		metadata := kafkaRecordMetadata(s.req.ConnectionID, msg, key, value)
+		metadata["searchId"] = s.id
+		emitKafkaRecord(app, "inbound", metadata)
+	}
+
+	if limited {
+		s.setState("limit")
+		s.cancel()
+		return false
+	}
+	return true
+}
+
+// setState records why a search ended; the first reason wins
+func (s *kafkaSearch) setState(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.progress.State == "running" {
+		s.progress.State = state
+	}
+}
+
+func (s *kafkaSearch) snapshot() KafkaSearchProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	progress := s.progress
+	progress.Partitions = append([]SearchPartitionProgress(nil), s.progress.Partitions...)
+	return progress
+}
+
+// incomplete lists the partitions that gave up before their end offset
+func (p KafkaSearchProgress) incomplete() []int {
+	var partitions []int
+	for _, part := range p.Partitions {
+		if part.Incomplete {
+			partitions = append(partitions, part.Partition)
+		}
+	}
+	return partitions
+}
+
+func (s *kafkaSearch) emitProgress(app AppInterface) {
+	progress := s.snapshot()
+
+	text := fmt.Sprintf("Search %s: scanned %d of %d, %d matches", progress.State, progress.Scanned, progress.Total, progress.Matched)
+	switch progress.State {
+	case "limit":
+		text = fmt.Sprintf("Search stopped at %d matches after scanning %d of %d", progress.Matched, progress.Scanned, progress.Total)
+	case "running":
+		text = fmt.Sprintf("Searching: scanned %d of %d, %d matches", progress.Scanned, progress.Total, progress.Matched)
+	}
+	if idle := progress.incomplete(); len(idle) > 0 {
+		text += fmt.Sprintf("; partitions %v went idle before the end of the range", idle)
+	}
+
+	emitKafkaSystem(app, s.req.ConnectionID, text, map[string]interface{}{
+		"searchId": s.id,
+		"search":   progress,
+	})
+}
+
+// searchMatcher holds a search's filters, parsed once
+type searchMatcher struct {
+	key           string
+	headerKey     string
+	headerValue   string
+	path          []string
+	jsonValue     string
+	valueContains string
+}
+
+func newSearchMatcher(req KafkaSearchRequest) (*searchMatcher, error) {
+	m := &searchMatcher{
+		key:           req.Key,
+		headerKey:     req.HeaderKey,
+		headerValue:   req.HeaderValue,
+		jsonValue:     strings.TrimSpace(req.JSONValue),
+		valueContains: req.ValueContains,
+	}
+	if req.JSONPath != "" {
+		path, err := parseJSONPath(req.JSONPath)
+		if err != nil {
+			return nil, err
+		}
+		m.path = path
+	}
+	return m, nil
+}
+
+func (m *searchMatcher) match(msg kafka.Message, key, value KafkaRecordData) bool {
+	if m.key != "" && !strings.Contains(key.Text, m.key) {
+		return false
+	}
+	if m.valueContains != "" && !strings.Contains(value.Text, m.valueContains) {
+		return false
+	}
+	if (m.headerKey != "" || m.headerValue != "") && !m.matchHeaders(msg.Headers) {
+		return false
+	}
+	if m.path != nil && !m.matchJSON(value) {
+		return false
+	}
+	return true
+}
+
+func (m *searchMatcher) matchHeaders(headers []kafka.Header) bool {
+	for _, h := range headers {
+		if m.headerKey != "" && h.Key != m.headerKey {
+			continue
+		}
+		if strings.Contains(string(h.Value), m.headerValue) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *searchMatcher) matchJSON(value KafkaRecordData) bool {
+	if value.Format != "json" {
+		return false
+	}
+	doc, err := decodeJSONDocument(value.Text)
+	if err != nil {
+		return false
+	}
+
+	for _, found := range evalJSONPath(doc, m.path) {
+		if m.jsonValue == "" || jsonValueEquals(found, m.jsonValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJSONPath splits a dotted path with optional [n] or [*] indexes, such
+// as "$.items[*].sku" or "order.id". "*" matches every field or element.
+func parseJSONPath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			open := strings.IndexByte(part, '[')
+			if open < 0 {
+				segments = append(segments, part)
+				break
+			}
+			if open > 0 {
+				segments = append(segments, part[:open])
+			}
+			end := strings.IndexByte(part[open:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: unclosed [", path)
+			}
+			index := strings.Trim(part[open+1:open+end], `"'`)
+			if index == "" {
+				return nil, fmt.Errorf("invalid JSON path %q: empty []", path)
+			}
+			segments = append(segments, index)
+			part = part[open+end+1:]
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid JSON path %q", path)
+	}
+	return segments, nil
+}
+
+func evalJSONPath(node interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{node}
+	}
+
+	segment, rest := path[0], path[1:]
+	var found []interface{}
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if segment == "*" {
+			for _, child := range v {
+				found = append(found, evalJSONPath(child, rest)...)
+			}
+		} else if child, ok := v[segment]; ok {
+			found = evalJSONPath(child, rest)
+		}
+	case []interface{}:
+		if segment == "*" {
+			for _, child := range v {
+				found = append(found, evalJSONPath(child, rest)...)
+			}
+		} else if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(v) {
+			found = evalJSONPath(v[i], rest)
+		}
+	}
+	return found
+}
+
+// jsonValueEquals compares a value found in a record with the expected
+// text. Strings compare by content, anything else by its JSON encoding, so
+// 42, true and {"a":1} all work as expected values.
+func jsonValueEquals(found interface{}, expected string) bool {
+	if s, ok := found.(string); ok {
+		return s == expected || strconv.Quote(s) == expected
+	}
+	want, err := decodeJSONDocument(expected)
+	if err != nil {
+		return false
+	}
+	a, errA := json.Marshal(found)
+	b, errB := json.Marshal(want)
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
@@ -0,0 +1,226 @@
+package backend
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{path: "$.order.id", want: []string{"order", "id"}},
+		{path: "order.id", want: []string{"order", "id"}},
+		{path: " $.items[*].sku ", want: []string{"items", "*", "sku"}},
+		{path: "$.items[2]", want: []string{"items", "2"}},
+		{path: "$.matrix[0][1]", want: []string{"matrix", "0", "1"}},
+		{path: `$["first name"]`, want: []string{"first name"}},
+		{path: "$.meta['trace-id']", want: []string{"meta", "trace-id"}},
+		{path: "$.*", want: []string{"*"}},
+		{path: "$", wantErr: true},
+		{path: "", wantErr: true},
+		{path: "$.items[", wantErr: true},
+		{path: "$.items[]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseJSONPath(tt.path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseJSONPath(%q) = %v, want an error", tt.path, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseJSONPath(%q): %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseJSONPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSearchMatchJSON(t *testing.T) {
+	doc := `{"order":{"id":"A-1","total":42,"paid":true},"items":[{"sku":"x"},{"sku":"y"}]}`
+
+	tests := []struct {
+		path  string
+		value string
+		want  bool
+	}{
+		{"$.order.id", "A-1", true},
+		{"$.order.id", `"A-1"`, true},
+		{"$.order.id", "A-2", false},
+		{"$.order.total", "42", true},
+		{"$.order.total", "42.0", false},
+		{"$.order.paid", "true", true},
+		{"$.items[*].sku", "y", true},
+		{"$.items[0].sku", "y", false},
+		{"$.items[5].sku", "", false},
+		{"$.order.missing", "", false},
+		{"$.order", "", true},
+	}
+
+	for _, tt := range tests {
+		path, err := parseJSONPath(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &searchMatcher{path: path, jsonValue: tt.value}
+		if got := m.matchJSON(KafkaRecordData{Text: doc, Format: "json"}); got != tt.want {
+			t.Errorf("%s == %q: got %v, want %v", tt.path, tt.value, got, tt.want)
+		}
+	}
+
+	m := &searchMatcher{path: []string{"order"}}
+	if m.matchJSON(KafkaRecordData{Text: doc, Format: "utf8"}) {
+		t.Error("non-JSON records must not match a JSON path")
+	}
+}
+
+// searchResults collects a search's matches until its final progress
+func searchResults(t *testing.T, events <-chan StreamMessage, searchID string) ([]string, KafkaSearchProgress, string) {
+	t.Helper()
+
+	var matches []string
+	for {
+		msg := waitForMessage(t, events, func(msg StreamMessage) bool { return msg.Metadata["searchId"] == searchID })
+		if msg.Direction == "inbound" {
+			matches = append(matches, msg.Payload)
+			continue
+		}
+		if progress, ok := msg.Metadata["search"].(KafkaSearchProgress); ok && progress.State != "running" {
+			return matches, progress, msg.Payload
+		}
+	}
+}
+
+func TestKafkaSearch(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 2})
+	start := time.UnixMilli(1_700_000_000_000)
+	k.produce("orders", 0, start, `{"sku":"x","n":1}`, `{"sku":"y","n":2}`, `{"sku":"x","n":3}`, `{"sku":"x","n":4}`)
+	k.produce("orders", 1, start, `{"sku":"x","n":5}`, "x")
+	app, events := newTestApp(t)
+	connID := connectTestKafka(t, app, k)
+
+	// The range covers the first three seconds of each partition
+	searchID, err := KafkaStartSearch(app, KafkaSearchRequest{
+		ConnectionID: connID,
+		Topic:        "orders",
+		StartTime:    start.UnixMilli(),
+		EndTime:      start.Add(2 * time.Second).UnixMilli(),
+		JSONPath:     "$.sku",
+		JSONValue:    "x",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, progress, _ := searchResults(t, events, searchID)
+	if len(matches) != 3 || !strings.Contains(strings.Join(matches, ","), `"n":5`) {
+		t.Errorf("matches = %v", matches)
+	}
+	if progress.State != "completed" || progress.Total != 5 || progress.Scanned != 5 || progress.Matched != 3 {
+		t.Errorf("progress = %+v", progress)
+	}
+	for _, part := range progress.Partitions {
+		if !part.Done || part.Incomplete || part.Error != "" || part.Offset != part.EndOffset {
+			t.Errorf("partition = %+v", part)
+		}
+	}
+
+	conn, _ := lookupKafkaConnection(connID)
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	if len(conn.Searches) != 0 {
+		t.Error("the finished search is still registered")
+	}
+}
+
+func TestKafkaSearchLimit(t *testing.T) {
+	k := newTestKafka(t, map[string]int{"orders": 1})
+	k.produce("orders", 0, time.Now(), "a", "b", "c", "d", "e")
+	app, events := newTestApp(t)
+	connID := connectTestKafka(t, app, k)
+
+	searchID, err := KafkaStartSearch(app, KafkaSearchRequest{ConnectionID: connID, Topic: "orders", MaxResults: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, progress, text := searchResults(t, events, searchID)
+	if fmt.Sprint(matches) != "[a b]" || progress.State != "limit" || progress.Matched != 2 {
+		t.Errorf("matches = %v, progress = %+v", matches, progress)
+	}
+	if text != "Search stopped at 2 matches after scanning 2 of 5" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+// startStalledSearch runs a search over a range that claims more offsets
+// than the partition returns, as when compaction removed its tail
+func startStalledSearch(t *testing.T) (*testApp, <-chan StreamMessage, string, *kafkaSearch) {
+	t.Helper()
+
+	k := newTestKafka(t, map[string]int{"orders": 1})
+	k.produce("orders", 0, time.Now(), "a", "b", "c")
+	app, events := newTestApp(t)
+	connID := connectTestKafka(t, app, k)
+	conn, _ := lookupKafkaConnection(connID)
+
+	search, err := newKafkaSearch(conn, KafkaSearchRequest{ConnectionID: connID, Topic: "orders", MaxResults: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	search.progress.Partitions[0].EndOffset = 10
+
+	conn.mu.Lock()
+	conn.Searches[search.id] = search
+	conn.mu.Unlock()
+	go search.run(app, conn)
+
+	return app, events, connID, search
+}
+
+func TestKafkaSearchIdleTimeout(t *testing.T) {
+	idle := searchIdleTimeout
+	searchIdleTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { searchIdleTimeout = idle })
+
+	_, events, _, search := startStalledSearch(t)
+
+	// An idle partition ends the search, but isn't passed off as scanned
+	matches, progress, text := searchResults(t, events, search.id)
+	part := progress.Partitions[0]
+	if len(matches) != 3 || progress.State != "completed" || !part.Done || !part.Incomplete || part.Error != "" || part.Offset != 3 {
+		t.Errorf("matches = %v, progress = %+v", matches, progress)
+	}
+	if !strings.HasSuffix(text, "; partitions [0] went idle before the end of the range") {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestKafkaCancelSearch(t *testing.T) {
+	app, events, connID, search := startStalledSearch(t)
+
+	for i := 0; i < 3; i++ {
+		waitForMessage(t, events, inboundOn("orders"))
+	}
+	if err := KafkaCancelSearch(app, connID, search.id); err != nil {
+		t.Fatal(err)
+	}
+
+	_, progress, _ := searchResults(t, events, search.id)
+	if part := progress.Partitions[0]; progress.State != "cancelled" || part.Done || part.Incomplete {
+		t.Errorf("progress = %+v", progress)
+	}
+	if err := KafkaCancelSearch(app, connID, search.id); err == nil {
+		t.Error("cancelling a finished search should fail")
+	}
+}
@@ -0,0 +1,480 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// MQTTManager handles MQTT connections. MQTT 3.1 and 3.1.1 run on
+// paho.mqtt.golang (mqtt_v3.go), 5.0 on paho.golang (mqtt_v5.go); both
+// reconnect on their own and restore subscriptions afterwards.
+type MQTTManager struct {
+	app         AppInterface
+	connections map[string]*MQTTConnection
+	mu          sync.RWMutex
+	msgCounter  uint64 // Atomic counter for unique message IDs
+}
+
+type MQTTConnection struct {
+	ID              string
+	BrokerURL       string
+	ProtocolVersion string
+	Scopes          *VariableScopes
+	client          mqttClient
+	subscriptions   map[string]MQTTSubscribeRequest // by topic filter, replayed after a reconnect without a session
+	mu              sync.Mutex
+}
+
+// MQTTConnectRequest opens a connection. BrokerURL takes mqtt:// or tcp://,
+// mqtts://, ssl:// or tls://, ws:// and wss://; the port defaults to the
+// usual one for the scheme.
+type MQTTConnectRequest struct {
+	BrokerURL       string            `json:"brokerUrl"`
+	ProtocolVersion string            `json:"protocolVersion"` // "3.1", "3.1.1" or "5.0"
+	ClientID        string            `json:"clientId"`
+	Username        string            `json:"username"`
+	Password        string            `json:"password"`
+	CleanSession    bool              `json:"cleanSession"` // Clean Start on 5.0
+	KeepAlive       int               `json:"keepAlive"`    // seconds
+	ConnectTimeout  int               `json:"connectTimeout"`
+	SessionExpiry   int               `json:"sessionExpiry"`            // seconds, 5.0 only
+	UserProperties  map[string]string `json:"userProperties,omitempty"` // sent with CONNECT, 5.0 only
+	Will            *MQTTWill         `json:"will,omitempty"`
+	TLSProfile      string            `json:"tlsProfile,omitempty"`
+	TLSSkipVerify   bool              `json:"tlsSkipVerify"`
+	Scopes          *VariableScopes   `json:"scopes,omitempty"`
+}
+
+// MQTTWill is the last-will message the broker publishes when the client
+// goes away without disconnecting
+type MQTTWill struct {
+	Topic          string            `json:"topic"`
+	Payload        string            `json:"payload"`
+	QoS            byte              `json:"qos"`
+	Retain         bool              `json:"retain"`
+	ContentType    string            `json:"contentType,omitempty"`    // 5.0 only; paho.golang v0.23 does not send it yet
+	UserProperties map[string]string `json:"userProperties,omitempty"` // 5.0 only
+}
+
+type MQTTSubscribeRequest struct {
+	ConnectionID   string            `json:"connectionId"`
+	Topic          string            `json:"topic"` // may contain + and # wildcards
+	QoS            byte              `json:"qos"`
+	NoLocal        bool              `json:"noLocal"`                  // 5.0 only: skip our own publishes
+	UserProperties map[string]string `json:"userProperties,omitempty"` // 5.0 only
+}
+
+type MQTTPublishRequest struct {
+	ConnectionID   string            `json:"connectionId"`
+	Topic          string            `json:"topic"`
+	Payload        string            `json:"payload"`
+	QoS            byte              `json:"qos"`
+	Retain         bool              `json:"retain"`
+	ContentType    string            `json:"contentType,omitempty"`    // 5.0 only
+	ResponseTopic  string            `json:"responseTopic,omitempty"`  // 5.0 only
+	MessageExpiry  int               `json:"messageExpiry,omitempty"`  // seconds, 5.0 only
+	UserProperties map[string]string `json:"userProperties,omitempty"` // 5.0 only
+}
+
+// mqttClient is one protocol version's client library behind the manager
+type mqttClient interface {
+	subscribe(ctx context.Context, req MQTTSubscribeRequest) (byte, error) // granted QoS
+	unsubscribe(ctx context.Context, topic string) error
+	publish(ctx context.Context, req MQTTPublishRequest) error
+	disconnect()
+}
+
+// mqttMessage is an inbound PUBLISH in version-neutral form
+type mqttMessage struct {
+	Topic          string
+	Payload        []byte
+	QoS            byte
+	Retain         bool
+	Duplicate      bool
+	ContentType    string
+	ResponseTopic  string
+	UserProperties map[string]string
+}
+
+const mqttRequestTimeout = 10 * time.Second
+
+func NewMQTTManager(app AppInterface) *MQTTManager {
+	return &MQTTManager{
+		app:         app,
+		connections: make(map[string]*MQTTConnection),
+	}
+}
+
+func (m *MQTTManager) generateMessageID() string {
+	count := atomic.AddUint64(&m.msgCounter, 1)
+	return fmt.Sprintf("msg-%d-%d", time.Now().UnixNano(), count)
+}
+
+func (m *MQTTManager) Connect(req MQTTConnectRequest) (string, error) {
+	target, err := resolverFor(req.Scopes).ResolveMQTTConnectRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	broker, err := parseMQTTBrokerURL(target.BrokerURL)
+	if err != nil {
+		return "", err
+	}
+
+	var tlsConfig *tls.Config
+	if broker.Scheme == "ssl" || broker.Scheme == "wss" || req.TLSProfile != "" {
+		tlsConfig, err = clientTLSConfig(m.app.GetDataDirectory(), req.TLSProfile, broker.Hostname(), &tls.Config{InsecureSkipVerify: req.TLSSkipVerify})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if target.ClientID == "" {
+		target.ClientID = fmt.Sprintf("pulse-%d", time.Now().UnixNano())
+	}
+	if target.KeepAlive <= 0 {
+		target.KeepAlive = 60
+	}
+	if target.ConnectTimeout <= 0 {
+		target.ConnectTimeout = 10000
+	}
+	if target.Will != nil && target.Will.Topic == "" {
+		target.Will = nil
+	}
+
+	conn := &MQTTConnection{
+		ID:              fmt.Sprintf("mqtt-%d", time.Now().UnixNano()),
+		BrokerURL:       broker.String(),
+		ProtocolVersion: target.ProtocolVersion,
+		Scopes:          req.Scopes,
+		subscriptions:   make(map[string]MQTTSubscribeRequest),
+	}
+
+	switch target.ProtocolVersion {
+	case "5.0", "5":
+		conn.ProtocolVersion = "5.0"
+		conn.client, err = newMQTT5Client(m, conn, target, broker, tlsConfig)
+	case "3.1", "3.1.1", "":
+		if conn.ProtocolVersion == "" {
+			conn.ProtocolVersion = "3.1.1"
+		}
+		conn.client, err = newMQTT3Client(m, conn, target, broker, tlsConfig)
+	default:
+		return "", fmt.Errorf("unsupported MQTT protocol version %q: use 3.1, 3.1.1 or 5.0", target.ProtocolVersion)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	m.mu.Lock()
+	m.connections[conn.ID] = conn
+	m.mu.Unlock()
+
+	m.emitSystem(conn, fmt.Sprintf("Connected to %s (MQTT %s) as %s", conn.BrokerURL, conn.ProtocolVersion, target.ClientID), nil)
+
+	return conn.ID, nil
+}
+
+func (m *MQTTManager) Subscribe(req MQTTSubscribeRequest) (byte, error) {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err = resolverFor(conn.Scopes).ResolveMQTTSubscribeRequest(req)
+	if err != nil {
+		return 0, err
+	}
+	if req.Topic == "" {
+		return 0, fmt.Errorf("topic is required")
+	}
+	if req.QoS > 2 {
+		return 0, fmt.Errorf("invalid QoS %d", req.QoS)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mqttRequestTimeout)
+	defer cancel()
+
+	granted, err := conn.client.subscribe(ctx, req)
+	if err != nil {
+		m.emitError(conn, fmt.Sprintf("Subscribe to %s failed: %s", req.Topic, err.Error()))
+		return 0, err
+	}
+
+	conn.mu.Lock()
+	conn.subscriptions[req.Topic] = req
+	conn.mu.Unlock()
+
+	text := fmt.Sprintf("Subscribed to %s (QoS %d)", req.Topic, granted)
+	if granted != req.QoS {
+		text = fmt.Sprintf("Subscribed to %s (asked for QoS %d, broker granted %d)", req.Topic, req.QoS, granted)
+	}
+	m.emitSystem(conn, text, map[string]interface{}{"topic": req.Topic, "qos": granted})
+
+	return granted, nil
+}
+
+func (m *MQTTManager) Unsubscribe(connectionID, topic string) error {
+	conn, err := m.lookup(connectionID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mqttRequestTimeout)
+	defer cancel()
+
+	if err := conn.client.unsubscribe(ctx, topic); err != nil {
+		m.emitError(conn, fmt.Sprintf("Unsubscribe from %s failed: %s", topic, err.Error()))
+		return err
+	}
+
+	conn.mu.Lock()
+	delete(conn.subscriptions, topic)
+	conn.mu.Unlock()
+
+	m.emitSystem(conn, fmt.Sprintf("Unsubscribed from %s", topic), map[string]interface{}{"topic": topic})
+	return nil
+}
+
+// Publish sends a message and, for QoS 1 and 2, waits for the broker to
+// acknowledge it
+func (m *MQTTManager) Publish(req MQTTPublishRequest) error {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	req, err = resolverFor(conn.Scopes).ResolveMQTTPublishRequest(req)
+	if err != nil {
+		return err
+	}
+	if req.Topic == "" {
+		return fmt.Errorf("topic is required")
+	}
+	if strings.ContainsAny(req.Topic, "+#") {
+		return fmt.Errorf("cannot publish to a wildcard topic: %s", req.Topic)
+	}
+	if req.QoS > 2 {
+		return fmt.Errorf("invalid QoS %d", req.QoS)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mqttRequestTimeout)
+	defer cancel()
+
+	if err := conn.client.publish(ctx, req); err != nil {
+		m.emitError(conn, fmt.Sprintf("Failed to publish to %s: %s", req.Topic, err.Error()))
+		return err
+	}
+
+	metadata := map[string]interface{}{
+		"connectionId": conn.ID,
+		"topic":        req.Topic,
+		"qos":          req.QoS,
+		"retain":       req.Retain,
+	}
+	if len(req.UserProperties) > 0 {
+		metadata["userProperties"] = req.UserProperties
+	}
+	if req.ContentType != "" {
+		metadata["contentType"] = req.ContentType
+	}
+
+	m.emitMessage(StreamMessage{
+		ID:        m.generateMessageID(),
+		Direction: "outbound",
+		Protocol:  "MQTT",
+		Payload:   req.Payload,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+
+	return nil
+}
+
+func (m *MQTTManager) Disconnect(connectionID string) error {
+	m.mu.Lock()
+	conn, ok := m.connections[connectionID]
+	if ok {
+		delete(m.connections, connectionID)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("connection not found")
+	}
+
+	conn.client.disconnect()
+
+	m.emitSystem(conn, "Disconnected", nil)
+	return nil
+}
+
+func (m *MQTTManager) lookup(connectionID string) (*MQTTConnection, error) {
+	m.mu.RLock()
+	conn, ok := m.connections[connectionID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("connection not found: %s", connectionID)
+	}
+	return conn, nil
+}
+
+// restoreSubscriptions subscribes again after a reconnect the broker kept
+// no session for
+func (m *MQTTManager) restoreSubscriptions(conn *MQTTConnection) {
+	conn.mu.Lock()
+	subs := make([]MQTTSubscribeRequest, 0, len(conn.subscriptions))
+	for _, sub := range conn.subscriptions {
+		subs = append(subs, sub)
+	}
+	conn.mu.Unlock()
+
+	for _, sub := range subs {
+		ctx, cancel := context.WithTimeout(context.Background(), mqttRequestTimeout)
+		_, err := conn.client.subscribe(ctx, sub)
+		cancel()
+		if err != nil {
+			m.emitError(conn, fmt.Sprintf("Failed to restore subscription %s: %s", sub.Topic, err.Error()))
+		}
+	}
+}
+
+func (m *MQTTManager) onMessage(conn *MQTTConnection, msg mqttMessage) {
+	payload := string(msg.Payload)
+	if !utf8.Valid(msg.Payload) {
+		payload = fmt.Sprintf("[Binary data: %d bytes]", len(msg.Payload))
+	}
+
+	metadata := map[string]interface{}{
+		"connectionId": conn.ID,
+		"topic":        msg.Topic,
+		"qos":          msg.QoS,
+		"retain":       msg.Retain,
+		"duplicate":    msg.Duplicate,
+		"size":         len(msg.Payload),
+	}
+	if msg.ContentType != "" {
+		metadata["contentType"] = msg.ContentType
+	}
+	if msg.ResponseTopic != "" {
+		metadata["responseTopic"] = msg.ResponseTopic
+	}
+	if len(msg.UserProperties) > 0 {
+		metadata["userProperties"] = msg.UserProperties
+	}
+
+	m.emitMessage(StreamMessage{
+		ID:        m.generateMessageID(),
+		Direction: "inbound",
+		Protocol:  "MQTT",
+		Payload:   payload,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+func (m *MQTTManager) emitSystem(conn *MQTTConnection, text string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["connectionId"] = conn.ID
+
+	m.emitMessage(StreamMessage{
+		ID:        m.generateMessageID(),
+		Direction: "system",
+		Protocol:  "MQTT",
+		Payload:   text,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+func (m *MQTTManager) emitError(conn *MQTTConnection, text string) {
+	m.emitMessage(StreamMessage{
+		ID:        m.generateMessageID(),
+		Direction: "error",
+		Protocol:  "MQTT",
+		Payload:   text,
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"connectionId": conn.ID},
+	})
+}
+
+// emitMessage emits on the caller's goroutine, so the messages of a
+// subscription reach the viewer in the order the client received them
+func (m *MQTTManager) emitMessage(msg StreamMessage) {
+	if m.app == nil || m.app.GetCtx() == nil {
+		fmt.Printf("[MQTT] Cannot emit message - app context not initialized yet\n")
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("[MQTT] Event emit panic recovered: %v\n", r)
+		}
+	}()
+	emitStreamEvent(m.app.GetCtx(), msg)
+}
+
+// parseMQTTBrokerURL normalizes the scheme to tcp, ssl, ws or wss and
+// fills in the default port. A bare host:port means tcp.
+func parseMQTTBrokerURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("broker URL is required")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "tcp://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker URL: %w", err)
+	}
+
+	var port string
+	switch strings.ToLower(u.Scheme) {
+	case "mqtt", "tcp":
+		u.Scheme, port = "tcp", "1883"
+	case "mqtts", "ssl", "tls", "tcps":
+		u.Scheme, port = "ssl", "8883"
+	case "ws":
+		u.Scheme, port = "ws", "80"
+	case "wss":
+		u.Scheme, port = "wss", "443"
+	default:
+		return nil, fmt.Errorf("unsupported broker URL scheme %q: use mqtt, mqtts, ws or wss", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid broker URL: missing host")
+	}
+	if u.Port() == "" {
+		u.Host = u.Host + ":" + port
+	}
+	if (u.Scheme == "ws" || u.Scheme == "wss") && u.Path == "" {
+		u.Path = "/mqtt"
+	}
+	return u, nil
+}
+
+// sortedProperties visits user properties in key order, so they go out the
+// same way every time
+func sortedProperties(props map[string]string, fn func(key, value string)) {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fn(k, props[k])
+	}
+}
@@ -0,0 +1,588 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBroker is just enough of an MQTT 3.1, 3.1.1 and 5.0 broker to
+// subscribe, publish and deliver last wills. Everything is delivered at
+// QoS 0 and granted QoS is capped at 1.
+type testBroker struct {
+	ln      net.Listener
+	mu      sync.Mutex
+	clients map[string]*brokerClient
+	routed  []brokerMessage
+	closed  chan string // client IDs once their connection is gone
+}
+
+type brokerClient struct {
+	id      string
+	conn    net.Conn
+	version byte
+	will    *brokerMessage
+	subs    map[string]bool
+	writeMu sync.Mutex
+}
+
+type brokerMessage struct {
+	topic   string
+	payload []byte
+	props   []byte // 5.0 properties without their length
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testBroker{
+		ln:      ln,
+		clients: make(map[string]*brokerClient),
+		closed:  make(chan string, 16),
+	}
+	t.Cleanup(func() {
+		ln.Close()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for _, c := range b.clients {
+			c.conn.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *testBroker) url() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+// drop closes a client's connection without a DISCONNECT, as a crashed
+// client would
+func (b *testBroker) drop(t *testing.T, clientID string) {
+	t.Helper()
+
+	b.mu.Lock()
+	c, ok := b.clients[clientID]
+	b.mu.Unlock()
+	if !ok {
+		t.Fatalf("client %s is not connected", clientID)
+	}
+	c.conn.Close()
+}
+
+func (b *testBroker) waitClosed(t *testing.T, clientID string) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case id := <-b.closed:
+			if id == clientID {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("client %s did not go away", clientID)
+		}
+	}
+}
+
+func (b *testBroker) routedTo(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := 0
+	for _, msg := range b.routed {
+		if msg.topic == topic {
+			n++
+		}
+	}
+	return n
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	kind, body, err := readMQTTPacket(r)
+	if err != nil || kind>>4 != 1 {
+		return
+	}
+	c, err := parseConnect(conn, body)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	if old, ok := b.clients[c.id]; ok {
+		old.will = nil
+		old.conn.Close()
+	}
+	b.clients[c.id] = c
+	b.mu.Unlock()
+
+	if c.version == 5 {
+		c.write(0x20, []byte{0, 0, 0})
+	} else {
+		c.write(0x20, []byte{0, 0})
+	}
+
+	clean := false
+loop:
+	for {
+		kind, body, err := readMQTTPacket(r)
+		if err != nil {
+			break
+		}
+		p := &packetReader{data: body}
+
+		switch kind >> 4 {
+		case 3: // PUBLISH
+			qos := (kind >> 1) & 3
+			msg := brokerMessage{topic: p.str()}
+			var id []byte
+			if qos > 0 {
+				id = p.bytes(2)
+			}
+			if c.version == 5 {
+				msg.props = p.bytes(p.varint())
+			}
+			msg.payload = p.rest()
+			if p.err != nil {
+				break loop
+			}
+			switch qos {
+			case 1:
+				c.write(0x40, id)
+			case 2:
+				c.write(0x50, id)
+			}
+			b.route(msg)
+		case 6: // PUBREL
+			c.write(0x70, p.bytes(2))
+		case 8: // SUBSCRIBE
+			id := p.bytes(2)
+			if c.version == 5 {
+				p.bytes(p.varint())
+			}
+			ack := append([]byte{}, id...)
+			if c.version == 5 {
+				ack = append(ack, 0)
+			}
+			b.mu.Lock()
+			for p.err == nil && len(p.data) > 0 {
+				filter, options := p.str(), p.bytes(1)
+				if p.err != nil {
+					break
+				}
+				c.subs[filter] = true
+				ack = append(ack, min(options[0]&3, 1))
+			}
+			b.mu.Unlock()
+			c.write(0x90, ack)
+		case 10: // UNSUBSCRIBE
+			id := p.bytes(2)
+			if c.version == 5 {
+				p.bytes(p.varint())
+			}
+			ack := append([]byte{}, id...)
+			if c.version == 5 {
+				ack = append(ack, 0)
+			}
+			b.mu.Lock()
+			for p.err == nil && len(p.data) > 0 {
+				filter := p.str()
+				delete(c.subs, filter)
+				if c.version == 5 {
+					ack = append(ack, 0)
+				}
+			}
+			b.mu.Unlock()
+			c.write(0xB0, ack)
+		case 12: // PINGREQ
+			c.write(0xD0, nil)
+		case 14: // DISCONNECT
+			clean = true
+			break loop
+		}
+	}
+
+	b.mu.Lock()
+	if b.clients[c.id] == c {
+		delete(b.clients, c.id)
+	}
+	will := c.will
+	b.mu.Unlock()
+
+	if !clean && will != nil {
+		b.route(*will)
+	}
+	b.closed <- c.id
+}
+
+func (b *testBroker) route(msg brokerMessage) {
+	b.mu.Lock()
+	b.routed = append(b.routed, msg)
+	var targets []*brokerClient
+	for _, c := range b.clients {
+		for filter := range c.subs {
+			if mqttTopicMatches(filter, msg.topic) {
+				targets = append(targets, c)
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	for _, c := range targets {
+		body := appendMQTTString(nil, msg.topic)
+		if c.version == 5 {
+			body = appendMQTTVarint(body, len(msg.props))
+			body = append(body, msg.props...)
+		}
+		c.write(0x30, append(body, msg.payload...))
+	}
+}
+
+func (c *brokerClient) write(header byte, body []byte) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	packet := appendMQTTVarint([]byte{header}, len(body))
+	c.conn.Write(append(packet, body...))
+}
+
+func parseConnect(conn net.Conn, body []byte) (*brokerClient, error) {
+	p := &packetReader{data: body}
+
+	name := p.str()
+	c := &brokerClient{conn: conn, version: p.bytes(1)[0], subs: make(map[string]bool)}
+	flags := p.bytes(1)[0]
+	p.bytes(2) // keep alive
+	if c.version == 5 {
+		p.bytes(p.varint())
+	}
+	c.id = p.str()
+
+	if flags&0x04 != 0 {
+		will := &brokerMessage{}
+		if c.version == 5 {
+			will.props = p.bytes(p.varint())
+		}
+		will.topic = p.str()
+		will.payload = p.bytes(int(binary.BigEndian.Uint16(p.bytes(2))))
+		c.will = will
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	if name != "MQTT" && name != "MQIsdp" {
+		return nil, errors.New("unknown protocol " + name)
+	}
+	return c, nil
+}
+
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, shift := 0, 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func appendMQTTVarint(b []byte, n int) []byte {
+	for {
+		digit := byte(n & 0x7F)
+		n >>= 7
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// packetReader reads packet fields, remembering the first short read
+type packetReader struct {
+	data []byte
+	err  error
+}
+
+func (p *packetReader) bytes(n int) []byte {
+	if p.err != nil || n > len(p.data) {
+		p.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+	b := p.data[:n]
+	p.data = p.data[n:]
+	return b
+}
+
+func (p *packetReader) str() string {
+	return string(p.bytes(int(binary.BigEndian.Uint16(p.bytes(2)))))
+}
+
+func (p *packetReader) varint() int {
+	n, shift := 0, 0
+	for p.err == nil {
+		b := p.bytes(1)[0]
+		n |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+	}
+	return n
+}
+
+func (p *packetReader) rest() []byte {
+	b := p.data
+	p.data = nil
+	return b
+}
+
+func mqttTopicMatches(filter, topic string) bool {
+	fs, ts := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) || (f != "+" && f != ts[i]) {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
+
+func connectMQTT(t *testing.T, m *MQTTManager, req MQTTConnectRequest) string {
+	t.Helper()
+
+	id, err := m.Connect(req)
+	if err != nil {
+		t.Fatalf("connect %s: %v", req.ClientID, err)
+	}
+	t.Cleanup(func() { m.Disconnect(id) })
+	return id
+}
+
+var mqttTestVersions = []string{"3.1", "3.1.1", "5.0"}
+
+func TestMQTTSubscribeAndPublish(t *testing.T) {
+	for _, version := range mqttTestVersions {
+		t.Run(version, func(t *testing.T) {
+			broker := newTestBroker(t)
+			app, events := newTestApp(t)
+			m := NewMQTTManager(app)
+
+			id := connectMQTT(t, m, MQTTConnectRequest{BrokerURL: broker.url(), ProtocolVersion: version, ClientID: "sub"})
+
+			granted, err := m.Subscribe(MQTTSubscribeRequest{ConnectionID: id, Topic: "sensors/+/temp", QoS: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if granted != 1 {
+				t.Errorf("granted QoS %d, want the broker's 1", granted)
+			}
+			waitForMessage(t, events, func(msg StreamMessage) bool {
+				return msg.Direction == "system" && strings.Contains(msg.Payload, "broker granted 1")
+			})
+
+			for _, qos := range []byte{0, 1, 2} {
+				if err := m.Publish(MQTTPublishRequest{ConnectionID: id, Topic: "sensors/a/temp", Payload: "21.5", QoS: qos}); err != nil {
+					t.Fatalf("publish QoS %d: %v", qos, err)
+				}
+				msg := waitForMessage(t, events, inboundOn("sensors/a/temp"))
+				if msg.Payload != "21.5" {
+					t.Errorf("QoS %d: payload = %q", qos, msg.Payload)
+				}
+			}
+
+			if err := m.Unsubscribe(id, "sensors/+/temp"); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Publish(MQTTPublishRequest{ConnectionID: id, Topic: "sensors/b/temp", Payload: "late", QoS: 1}); err != nil {
+				t.Fatal(err)
+			}
+			quiet := time.After(200 * time.Millisecond)
+			for done := false; !done; {
+				select {
+				case msg := <-events:
+					if msg.Direction == "inbound" {
+						t.Errorf("received %q after unsubscribing", msg.Payload)
+					}
+				case <-quiet:
+					done = true
+				}
+			}
+		})
+	}
+}
+
+func TestMQTTInboundOrder(t *testing.T) {
+	broker := newTestBroker(t)
+	app, events := newTestApp(t)
+	m := NewMQTTManager(app)
+
+	sub := connectMQTT(t, m, MQTTConnectRequest{BrokerURL: broker.url(), ClientID: "sub"})
+	pub := connectMQTT(t, m, MQTTConnectRequest{BrokerURL: broker.url(), ClientID: "pub"})
+	if _, err := m.Subscribe(MQTTSubscribeRequest{ConnectionID: sub, Topic: "orders"}); err != nil {
+		t.Fatal(err)
+	}
+
+	const count = 200
+	for i := 0; i < count; i++ {
+		if err := m.Publish(MQTTPublishRequest{ConnectionID: pub, Topic: "orders", Payload: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < count; i++ {
+		msg := waitForMessage(t, events, inboundOn("orders"))
+		if msg.Payload != strconv.Itoa(i) {
+			t.Fatalf("message %d is %q", i, msg.Payload)
+		}
+	}
+}
+
+func TestMQTT5PublishProperties(t *testing.T) {
+	broker := newTestBroker(t)
+	app, events := newTestApp(t)
+	m := NewMQTTManager(app)
+
+	id := connectMQTT(t, m, MQTTConnectRequest{BrokerURL: broker.url(), ProtocolVersion: "5.0", ClientID: "props"})
+	if _, err := m.Subscribe(MQTTSubscribeRequest{ConnectionID: id, Topic: "orders/#", QoS: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := m.Publish(MQTTPublishRequest{
+		ConnectionID:   id,
+		Topic:          "orders/eu/1",
+		Payload:        `{"id":1}`,
+		QoS:            1,
+		ContentType:    "application/json",
+		ResponseTopic:  "replies/1",
+		UserProperties: map[string]string{"trace": "abc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := waitForMessage(t, events, inboundOn("orders/eu/1"))
+	if msg.Metadata["contentType"] != "application/json" || msg.Metadata["responseTopic"] != "replies/1" {
+		t.Errorf("metadata = %v", msg.Metadata)
+	}
+	if props, _ := msg.Metadata["userProperties"].(map[string]string); props["trace"] != "abc" {
+		t.Errorf("user properties = %v", msg.Metadata["userProperties"])
+	}
+}
+
+func TestMQTTPublishValidation(t *testing.T) {
+	broker := newTestBroker(t)
+	app, _ := newTestApp(t)
+	m := NewMQTTManager(app)
+
+	id := connectMQTT(t, m, MQTTConnectRequest{BrokerURL: broker.url(), ClientID: "pub"})
+
+	for _, req := range []MQTTPublishRequest{
+		{ConnectionID: id, Topic: "sensors/+/temp"},
+		{ConnectionID: id, Topic: "sensors/#"},
+		{ConnectionID: id, Topic: ""},
+		{ConnectionID: id, Topic: "sensors", QoS: 3},
+		{ConnectionID: "missing", Topic: "sensors"},
+	} {
+		if err := m.Publish(req); err == nil {
+			t.Errorf("publish %+v: expected an error", req)
+		}
+	}
+	if n := broker.routedTo("sensors"); n != 0 {
+		t.Errorf("invalid publishes reached the broker %d times", n)
+	}
+}
+
+func TestMQTTLastWill(t *testing.T) {
+	for _, version := range mqttTestVersions {
+		t.Run(version, func(t *testing.T) {
+			broker := newTestBroker(t)
+			app, events := newTestApp(t)
+			m := NewMQTTManager(app)
+
+			watcher := connectMQTT(t, m, MQTTConnectRequest{BrokerURL: broker.url(), ProtocolVersion: version, ClientID: "watcher"})
+			if _, err := m.Subscribe(MQTTSubscribeRequest{ConnectionID: watcher, Topic: "status/#", QoS: 1}); err != nil {
+				t.Fatal(err)
+			}
+
+			will := &MQTTWill{Topic: "status/device", Payload: "offline", QoS: 1, Retain: true}
+			if version == "5.0" {
+				will.UserProperties = map[string]string{"reason": "crash"}
+			}
+			connectMQTT(t, m, MQTTConnectRequest{BrokerURL: broker.url(), ProtocolVersion: version, ClientID: "device", Will: will})
+
+			broker.drop(t, "device")
+			msg := waitForMessage(t, events, inboundOn("status/device"))
+			if msg.Payload != "offline" {
+				t.Errorf("will payload = %q", msg.Payload)
+			}
+			if props, _ := msg.Metadata["userProperties"].(map[string]string); version == "5.0" && props["reason"] != "crash" {
+				t.Errorf("will user properties = %v", msg.Metadata["userProperties"])
+			}
+
+			// a clean disconnect discards the will
+			quiet := connectMQTT(t, m, MQTTConnectRequest{
+				BrokerURL:       broker.url(),
+				ProtocolVersion: version,
+				ClientID:        "quiet",
+				Will:            &MQTTWill{Topic: "status/quiet", Payload: "offline"},
+			})
+			if err := m.Disconnect(quiet); err != nil {
+				t.Fatal(err)
+			}
+			broker.waitClosed(t, "quiet")
+			if n := broker.routedTo("status/quiet"); n != 0 {
+				t.Errorf("will published %d times after a clean disconnect", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqtt3Client speaks MQTT 3.1 and 3.1.1 through paho.mqtt.golang
+type mqtt3Client struct {
+	client mqtt.Client
+}
+
+func newMQTT3Client(m *MQTTManager, conn *MQTTConnection, req MQTTConnectRequest, broker *url.URL, tlsConfig *tls.Config) (*mqtt3Client, error) {
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker.String()).
+		SetClientID(req.ClientID).
+		SetUsername(req.Username).
+		SetPassword(req.Password).
+		SetCleanSession(req.CleanSession).
+		SetKeepAlive(time.Duration(req.KeepAlive) * time.Second).
+		SetConnectTimeout(time.Duration(req.ConnectTimeout) * time.Millisecond).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(30 * time.Second).
+		SetProtocolVersion(4)
+	if req.ProtocolVersion == "3.1" {
+		opts.SetProtocolVersion(3)
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+	if req.Will != nil {
+		opts.SetWill(req.Will.Topic, req.Will.Payload, req.Will.QoS, req.Will.Retain)
+	}
+
+	// messages for subscriptions kept in a persistent session arrive
+	// before we subscribe again, so everything goes through the default
+	// handler rather than per-subscription callbacks
+	opts.SetDefaultPublishHandler(func(_ mqtt.Client, msg mqtt.Message) {
+		m.onMessage(conn, mqttMessage{
+			Topic:     msg.Topic(),
+			Payload:   msg.Payload(),
+			QoS:       msg.Qos(),
+			Retain:    msg.Retained(),
+			Duplicate: msg.Duplicate(),
+		})
+	})
+
+	var connected int32
+	opts.SetOnConnectHandler(func(mqtt.Client) {
+		if atomic.AddInt32(&connected, 1) == 1 {
+			return
+		}
+		m.emitSystem(conn, "Reconnected", nil)
+		m.restoreSubscriptions(conn)
+	})
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		m.emitError(conn, fmt.Sprintf("Connection lost: %s", err.Error()))
+	})
+	opts.SetReconnectingHandler(func(mqtt.Client, *mqtt.ClientOptions) {
+		m.emitSystem(conn, "Reconnecting...", nil)
+	})
+
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(time.Duration(req.ConnectTimeout) * time.Millisecond) {
+		client.Disconnect(0)
+		return nil, fmt.Errorf("timed out connecting to %s", broker.Host)
+	}
+	if err := token.Error(); err != nil {
+		return nil, err
+	}
+
+	return &mqtt3Client{client: client}, nil
+}
+
+func (c *mqtt3Client) subscribe(ctx context.Context, req MQTTSubscribeRequest) (byte, error) {
+	token := c.client.Subscribe(req.Topic, req.QoS, nil)
+	if err := waitMQTT3(ctx, token); err != nil {
+		return 0, err
+	}
+
+	granted, ok := token.(*mqtt.SubscribeToken).Result()[req.Topic]
+	if !ok {
+		return 0, fmt.Errorf("broker did not acknowledge the subscription")
+	}
+	if granted == 0x80 {
+		return 0, fmt.Errorf("broker rejected the subscription")
+	}
+	return granted, nil
+}
+
+func (c *mqtt3Client) unsubscribe(ctx context.Context, topic string) error {
+	return waitMQTT3(ctx, c.client.Unsubscribe(topic))
+}
+
+func (c *mqtt3Client) publish(ctx context.Context, req MQTTPublishRequest) error {
+	return waitMQTT3(ctx, c.client.Publish(req.Topic, req.QoS, req.Retain, []byte(req.Payload)))
+}
+
+func (c *mqtt3Client) disconnect() {
+	c.client.Disconnect(250)
+}
+
+func waitMQTT3(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return fmt.Errorf("no response from broker: %w", ctx.Err())
+	}
+}
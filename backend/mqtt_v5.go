@@ -0,0 +1,224 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// mqtt5Client speaks MQTT 5.0 through paho.golang's autopaho, which
+// reconnects with a backoff until the connection is closed
+type mqtt5Client struct {
+	manager *autopaho.ConnectionManager
+	cancel  context.CancelFunc
+}
+
+func newMQTT5Client(m *MQTTManager, conn *MQTTConnection, req MQTTConnectRequest, broker *url.URL, tlsConfig *tls.Config) (*mqtt5Client, error) {
+	connectTimeout := time.Duration(req.ConnectTimeout) * time.Millisecond
+
+	// the first failure ends Connect; afterwards autopaho keeps retrying
+	// and failures are only reported
+	var (
+		mu        sync.Mutex
+		connected bool
+		firstErr  = make(chan error, 1)
+	)
+
+	cfg := autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{broker},
+		TlsCfg:                        tlsConfig,
+		KeepAlive:                     uint16(req.KeepAlive),
+		CleanStartOnInitialConnection: req.CleanSession,
+		SessionExpiryInterval:         uint32(req.SessionExpiry),
+		ConnectTimeout:                connectTimeout,
+		ReconnectBackoff:              autopaho.DefaultExponentialBackoff(),
+		ConnectUsername:               req.Username,
+		ConnectPassword:               []byte(req.Password),
+		ClientConfig: paho.ClientConfig{
+			ClientID: req.ClientID,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+				func(pr paho.PublishReceived) (bool, error) {
+					m.onMessage(conn, mqtt5Message(pr.Packet))
+					return true, nil
+				},
+			},
+		},
+		OnConnectionUp: func(cm *autopaho.ConnectionManager, connack *paho.Connack) {
+			mu.Lock()
+			first := !connected
+			connected = true
+			mu.Unlock()
+			if first {
+				return
+			}
+			m.emitSystem(conn, "Reconnected", map[string]interface{}{"sessionPresent": connack.SessionPresent})
+			if !connack.SessionPresent {
+				go m.restoreSubscriptions(conn)
+			}
+		},
+		OnConnectionDown: func() bool {
+			m.emitError(conn, "Connection lost, reconnecting...")
+			return true
+		},
+		OnConnectError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !connected {
+				select {
+				case firstErr <- err:
+				default:
+				}
+				return
+			}
+			m.emitError(conn, fmt.Sprintf("Reconnection failed: %s", err.Error()))
+		},
+		ConnectPacketBuilder: func(cp *paho.Connect, _ *url.URL) (*paho.Connect, error) {
+			if len(req.UserProperties) > 0 {
+				if cp.Properties == nil {
+					cp.Properties = &paho.ConnectProperties{}
+				}
+				cp.Properties.User = mqtt5UserProperties(req.UserProperties)
+			}
+			return cp, nil
+		},
+	}
+
+	if req.Will != nil {
+		cfg.WillMessage = &paho.WillMessage{
+			Topic:   req.Will.Topic,
+			Payload: []byte(req.Will.Payload),
+			QoS:     req.Will.QoS,
+			Retain:  req.Will.Retain,
+		}
+		cfg.WillProperties = &paho.WillProperties{
+			ContentType: req.Will.ContentType,
+			User:        mqtt5UserProperties(req.Will.UserProperties),
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cm, err := autopaho.NewConnection(ctx, cfg)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	awaitCtx, awaitCancel := context.WithTimeout(ctx, connectTimeout)
+	defer awaitCancel()
+
+	up := make(chan error, 1)
+	go func() { up <- cm.AwaitConnection(awaitCtx) }()
+
+	select {
+	case err = <-up:
+	case err = <-firstErr:
+	}
+	if err != nil {
+		cancel()
+		if err == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out connecting to %s", broker.Host)
+		}
+		return nil, err
+	}
+
+	return &mqtt5Client{manager: cm, cancel: cancel}, nil
+}
+
+func (c *mqtt5Client) subscribe(ctx context.Context, req MQTTSubscribeRequest) (byte, error) {
+	sub := &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{
+			Topic:   req.Topic,
+			QoS:     req.QoS,
+			NoLocal: req.NoLocal,
+		}},
+	}
+	if len(req.UserProperties) > 0 {
+		sub.Properties = &paho.SubscribeProperties{User: mqtt5UserProperties(req.UserProperties)}
+	}
+
+	suback, err := c.manager.Subscribe(ctx, sub)
+	if err != nil {
+		return 0, err
+	}
+	if len(suback.Reasons) == 0 {
+		return 0, fmt.Errorf("broker did not acknowledge the subscription")
+	}
+	if reason := suback.Reasons[0]; reason >= 0x80 {
+		return 0, fmt.Errorf("broker rejected the subscription (reason code 0x%02x)", reason)
+	}
+	return suback.Reasons[0], nil
+}
+
+func (c *mqtt5Client) unsubscribe(ctx context.Context, topic string) error {
+	unsuback, err := c.manager.Unsubscribe(ctx, &paho.Unsubscribe{Topics: []string{topic}})
+	if err != nil {
+		return err
+	}
+	if len(unsuback.Reasons) > 0 && unsuback.Reasons[0] >= 0x80 {
+		return fmt.Errorf("broker rejected the unsubscribe (reason code 0x%02x)", unsuback.Reasons[0])
+	}
+	return nil
+}
+
+func (c *mqtt5Client) publish(ctx context.Context, req MQTTPublishRequest) error {
+	props := &paho.PublishProperties{
+		ContentType:   req.ContentType,
+		ResponseTopic: req.ResponseTopic,
+		User:          mqtt5UserProperties(req.UserProperties),
+	}
+	if req.MessageExpiry > 0 {
+		expiry := uint32(req.MessageExpiry)
+		props.MessageExpiry = &expiry
+	}
+
+	// errors on QoS 1 and 2 include the broker's reason code
+	_, err := c.manager.Publish(ctx, &paho.Publish{
+		Topic:      req.Topic,
+		QoS:        req.QoS,
+		Retain:     req.Retain,
+		Payload:    []byte(req.Payload),
+		Properties: props,
+	})
+	return err
+}
+
+func (c *mqtt5Client) disconnect() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c.manager.Disconnect(ctx)
+	c.cancel()
+}
+
+func mqtt5Message(p *paho.Publish) mqttMessage {
+	msg := mqttMessage{
+		Topic:   p.Topic,
+		Payload: p.Payload,
+		QoS:     p.QoS,
+		Retain:  p.Retain,
+	}
+	if p.Properties != nil {
+		msg.ContentType = p.Properties.ContentType
+		msg.ResponseTopic = p.Properties.ResponseTopic
+		if len(p.Properties.User) > 0 {
+			msg.UserProperties = make(map[string]string, len(p.Properties.User))
+			for _, prop := range p.Properties.User {
+				msg.UserProperties[prop.Key] = prop.Value
+			}
+		}
+	}
+	return msg
+}
+
+func mqtt5UserProperties(props map[string]string) paho.UserProperties {
+	var out paho.UserProperties
+	sortedProperties(props, func(key, value string) {
+		out.Add(key, value)
+	})
+	return out
+}
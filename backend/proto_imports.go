@@ -0,0 +1,441 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"gopkg.in/yaml.v3"
+
+	// Bundled Google API protos. Each package registers its descriptors in
+	// protoregistry.GlobalFiles, which bundledImport serves imports from.
+	// The google/protobuf well-known types are built into the parser.
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	_ "google.golang.org/genproto/googleapis/api/httpbody"
+	_ "google.golang.org/genproto/googleapis/rpc/code"
+	_ "google.golang.org/genproto/googleapis/rpc/status"
+	_ "google.golang.org/genproto/googleapis/type/color"
+	_ "google.golang.org/genproto/googleapis/type/date"
+	_ "google.golang.org/genproto/googleapis/type/datetime"
+	_ "google.golang.org/genproto/googleapis/type/dayofweek"
+	_ "google.golang.org/genproto/googleapis/type/decimal"
+	_ "google.golang.org/genproto/googleapis/type/expr"
+	_ "google.golang.org/genproto/googleapis/type/fraction"
+	_ "google.golang.org/genproto/googleapis/type/interval"
+	_ "google.golang.org/genproto/googleapis/type/latlng"
+	_ "google.golang.org/genproto/googleapis/type/money"
+	_ "google.golang.org/genproto/googleapis/type/month"
+	_ "google.golang.org/genproto/googleapis/type/phone_number"
+	_ "google.golang.org/genproto/googleapis/type/postaladdress"
+	_ "google.golang.org/genproto/googleapis/type/timeofday"
+)
+
+// ProtoDiagnostic is a parse problem tied to a position in a proto file
+type ProtoDiagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Message  string `json:"message"`
+}
+
+func (d ProtoDiagnostic) String() string {
+	if d.File == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// ProtoParseError carries every error found in a parse, not just the first
+type ProtoParseError struct {
+	Diagnostics []ProtoDiagnostic
+}
+
+func (e *ProtoParseError) Error() string {
+	lines := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// protoSources decides where the parser finds each file: uploaded contents
+// first, then the import roots on disk, then the bundled Google protos.
+type protoSources struct {
+	uploaded    map[string]string
+	roots       []string
+	diagnostics []ProtoDiagnostic
+}
+
+func newProtoSources(req ProtoFileUploadRequest) (*protoSources, []string, error) {
+	src := &protoSources{
+		uploaded: make(map[string]string),
+	}
+
+	var names []string
+	for _, file := range req.Files {
+		names = append(names, file.Name)
+		src.uploaded[file.Name] = file.Content
+	}
+
+	for _, dir := range req.ImportPaths {
+		if err := checkDir(dir); err != nil {
+			return nil, nil, fmt.Errorf("invalid import path: %w", err)
+		}
+		src.roots = append(src.roots, dir)
+	}
+
+	if req.BufWorkspace != "" {
+		ws, err := loadBufWorkspace(req.BufWorkspace)
+		if err != nil {
+			return nil, nil, err
+		}
+		src.roots = append(src.roots, ws.roots...)
+		src.roots = append(src.roots, ws.depRoots...)
+		src.diagnostics = append(src.diagnostics, ws.diagnostics...)
+
+		// With nothing uploaded, parse the whole workspace
+		if len(names) == 0 {
+			names = ws.files
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("no proto files to parse")
+	}
+
+	return src, names, nil
+}
+
+func (s *protoSources) parser() protoparse.Parser {
+	return protoparse.Parser{
+		IncludeSourceCodeInfo: true,
+		Accessor:              s.open,
+		LookupImportProto:     bundledImport,
+		ErrorReporter: func(err protoparse.ErrorWithPos) error {
+			s.report("error", err)
+			return nil // keep going so every problem is reported
+		},
+		WarningReporter: func(err protoparse.ErrorWithPos) {
+			s.report("warning", err)
+		},
+	}
+}
+
+func (s *protoSources) open(name string) (io.ReadCloser, error) {
+	if content, ok := s.uploaded[name]; ok {
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+
+	for _, root := range s.roots {
+		f, err := os.Open(filepath.Join(root, filepath.FromSlash(name)))
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return nil, importNotFoundError(name)
+}
+
+// importNotFoundError names the missing file, which protoparse requires, and
+// matches fs.ErrNotExist so the parser falls through to the bundled protos
+type importNotFoundError string
+
+func (e importNotFoundError) Error() string {
+	return fmt.Sprintf("%s not found in uploaded files, import paths or bundled protos", string(e))
+}
+
+func (e importNotFoundError) Is(target error) bool {
+	return target == fs.ErrNotExist
+}
+
+func (s *protoSources) report(severity string, err protoparse.ErrorWithPos) {
+	pos := err.GetPosition()
+	msg := err.Error()
+	if inner := errors.Unwrap(err); inner != nil {
+		msg = inner.Error()
+	}
+	s.diagnostics = append(s.diagnostics, ProtoDiagnostic{
+		File:     pos.Filename,
+		Line:     pos.Line,
+		Column:   pos.Col,
+		Severity: severity,
+		Message:  msg,
+	})
+}
+
+// failure turns a parse error into a ProtoParseError listing every
+// diagnostic, or wraps it as-is when nothing was reported
+func (s *protoSources) failure(err error) error {
+	// Import resolution fails fast without going through the reporter
+	var posErr protoparse.ErrorWithPos
+	if errors.As(err, &posErr) {
+		s.report("error", posErr)
+	}
+
+	var diags []ProtoDiagnostic
+	for _, d := range s.diagnostics {
+		if d.Severity == "error" {
+			diags = append(diags, d)
+		}
+	}
+	if len(diags) == 0 {
+		return fmt.Errorf("failed to parse proto files: %w", err)
+	}
+	return &ProtoParseError{Diagnostics: diags}
+}
+
+func (s *protoSources) warnings() []ProtoDiagnostic {
+	var out []ProtoDiagnostic
+	for _, d := range s.diagnostics {
+		if d.Severity == "warning" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// bundledImport serves google/... imports from the descriptors compiled
+// into the binary
+func bundledImport(name string) (*descriptorpb.FileDescriptorProto, error) {
+	if !strings.HasPrefix(name, "google/") {
+		return nil, fs.ErrNotExist
+	}
+	fd, err := protoregistry.GlobalFiles.FindFileByPath(name)
+	if err != nil {
+		return nil, fs.ErrNotExist
+	}
+	return protodesc.ToFileDescriptorProto(fd), nil
+}
+
+// bufWorkspace is what ParseProtoFiles needs from a buf.yaml: the module
+// roots to search and parse, and the roots of dependencies found in the
+// local buf cache
+type bufWorkspace struct {
+	roots       []string
+	depRoots    []string
+	files       []string
+	diagnostics []ProtoDiagnostic
+}
+
+type bufConfig struct {
+	Version string   `yaml:"version"`
+	Deps    []string `yaml:"deps"`
+	Build   struct {
+		Excludes []string `yaml:"excludes"`
+	} `yaml:"build"`
+	Modules []struct {
+		Path     string   `yaml:"path"`
+		Excludes []string `yaml:"excludes"`
+	} `yaml:"modules"`
+}
+
+type bufWorkConfig struct {
+	Directories []string `yaml:"directories"`
+}
+
+type bufLock struct {
+	Deps []struct {
+		Name       string `yaml:"name"` // v2
+		Remote     string `yaml:"remote"`
+		Owner      string `yaml:"owner"`
+		Repository string `yaml:"repository"`
+		Commit     string `yaml:"commit"`
+	} `yaml:"deps"`
+}
+
+// loadBufWorkspace reads buf.yaml (v1 or v2), or a v1 buf.work.yaml, from
+// dir. Dependencies pinned in buf.lock are taken from the local buf cache;
+// ones that aren't downloaded produce a warning rather than an error.
+func loadBufWorkspace(dir string) (*bufWorkspace, error) {
+	if err := checkDir(dir); err != nil {
+		return nil, fmt.Errorf("invalid buf workspace: %w", err)
+	}
+
+	ws := &bufWorkspace{}
+	type module struct {
+		root     string
+		excludes []string
+	}
+	var modules []module
+
+	var work bufWorkConfig
+	if err := readYAML(filepath.Join(dir, "buf.work.yaml"), &work); err == nil {
+		for _, d := range work.Directories {
+			root := filepath.Join(dir, d)
+			var cfg bufConfig
+			if err := readYAML(filepath.Join(root, "buf.yaml"), &cfg); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			modules = append(modules, module{root: root, excludes: joinPaths(root, cfg.Build.Excludes)})
+			ws.resolveDeps(root)
+		}
+	} else if errors.Is(err, fs.ErrNotExist) {
+		var cfg bufConfig
+		if err := readYAML(filepath.Join(dir, "buf.yaml"), &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.Version == "v2" && len(cfg.Modules) > 0 {
+			// v2 excludes are relative to buf.yaml, not to the module
+			for _, m := range cfg.Modules {
+				modules = append(modules, module{root: filepath.Join(dir, m.Path), excludes: joinPaths(dir, m.Excludes)})
+			}
+		} else {
+			modules = append(modules, module{root: dir, excludes: joinPaths(dir, cfg.Build.Excludes)})
+		}
+		ws.resolveDeps(dir)
+	} else {
+		return nil, err
+	}
+
+	for _, m := range modules {
+		files, err := listProtoFiles(m.root, m.excludes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read buf module %s: %w", m.root, err)
+		}
+		ws.roots = append(ws.roots, m.root)
+		ws.files = append(ws.files, files...)
+	}
+
+	return ws, nil
+}
+
+// resolveDeps adds the cached module directory of every dependency in
+// dir/buf.lock
+func (ws *bufWorkspace) resolveDeps(dir string) {
+	var lock bufLock
+	if err := readYAML(filepath.Join(dir, "buf.lock"), &lock); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			ws.warn(filepath.Join(dir, "buf.lock"), err.Error())
+		}
+		return
+	}
+
+	for _, dep := range lock.Deps {
+		name := dep.Name
+		if name == "" {
+			name = dep.Remote + "/" + dep.Owner + "/" + dep.Repository
+		}
+
+		if root := findBufCacheModule(name, dep.Commit); root != "" {
+			ws.depRoots = append(ws.depRoots, root)
+			continue
+		}
+		if strings.HasSuffix(name, "/googleapis/googleapis") {
+			continue // served by the bundled Google protos
+		}
+		ws.warn(filepath.Join(dir, "buf.lock"), fmt.Sprintf("dependency %s:%s is not in the local buf cache; run `buf dep update` to download it", name, dep.Commit))
+	}
+}
+
+func (ws *bufWorkspace) warn(file, msg string) {
+	ws.diagnostics = append(ws.diagnostics, ProtoDiagnostic{
+		File:     file,
+		Severity: "warning",
+		Message:  msg,
+	})
+}
+
+// findBufCacheModule looks for a downloaded module in the layouts used by
+// buf's v1, v2 and v3 caches
+func findBufCacheModule(name, commit string) string {
+	cacheDir := os.Getenv("BUF_CACHE_DIR")
+	if cacheDir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		cacheDir = filepath.Join(userCache, "buf")
+	}
+
+	module := filepath.FromSlash(name)
+	patterns := []string{
+		filepath.Join(cacheDir, "v3", "modules", "*", module, commit, "files"),
+		filepath.Join(cacheDir, "v2", "module", "data", module, commit),
+		filepath.Join(cacheDir, "v1", "module", "data", module, commit),
+	}
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, m := range matches {
+			if checkDir(m) == nil {
+				return m
+			}
+		}
+	}
+	return ""
+}
+
+// listProtoFiles returns the .proto files under root as slash separated
+// paths relative to it, skipping the excluded directories
+func listProtoFiles(root string, excludes []string) ([]string, error) {
+	skip := make(map[string]bool)
+	for _, e := range excludes {
+		skip[filepath.Clean(e)] = true
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if skip[filepath.Clean(path)] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".proto" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func joinPaths(dir string, paths []string) []string {
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = filepath.Join(dir, p)
+	}
+	return out
+}
+
+func readYAML(path string, out interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return nil
+}
+
+func checkDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package backend
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTree writes files, keyed by slash separated paths, under a new
+// temporary directory
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// bufCache points BUF_CACHE_DIR at a cache holding one v3 module
+func bufCache(t *testing.T, module, commit string, files map[string]string) string {
+	t.Helper()
+
+	tree := make(map[string]string)
+	for name, content := range files {
+		tree["v3/modules/b5/"+module+"/"+commit+"/files/"+name] = content
+	}
+	cache := writeTree(t, tree)
+	t.Setenv("BUF_CACHE_DIR", cache)
+	return filepath.Join(cache, "v3", "modules", "b5", filepath.FromSlash(module), commit, "files")
+}
+
+func TestLoadBufWorkspaceV1(t *testing.T) {
+	depRoot := bufCache(t, "buf.build/acme/money", "c1", map[string]string{"acme/money.proto": `syntax = "proto3";`})
+
+	dir := writeTree(t, map[string]string{
+		"buf.yaml": "version: v1\nbuild:\n  excludes:\n    - vendor\n",
+		"buf.lock": `version: v1
+deps:
+  - remote: buf.build
+    owner: acme
+    repository: money
+    commit: c1
+  - remote: buf.build
+    owner: acme
+    repository: missing
+    commit: c2
+  - remote: buf.build
+    owner: googleapis
+    repository: googleapis
+    commit: c3
+`,
+		"shop/v1/orders.proto": `syntax = "proto3";`,
+		"shop/v1/notes.txt":    "not a proto",
+		"vendor/skip.proto":    `syntax = "proto3";`,
+	})
+
+	ws, err := loadBufWorkspace(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ws.roots, []string{dir}) || !reflect.DeepEqual(ws.depRoots, []string{depRoot}) {
+		t.Errorf("roots = %v, deps = %v", ws.roots, ws.depRoots)
+	}
+	if !reflect.DeepEqual(ws.files, []string{"shop/v1/orders.proto"}) {
+		t.Errorf("files = %v", ws.files)
+	}
+
+	// Only the missing dependency warns; googleapis is bundled
+	if len(ws.diagnostics) != 1 || !strings.Contains(ws.diagnostics[0].Message, "buf.build/acme/missing:c2 is not in the local buf cache") {
+		t.Errorf("diagnostics = %+v", ws.diagnostics)
+	}
+	if d := ws.diagnostics[0]; d.Severity != "warning" || d.File != filepath.Join(dir, "buf.lock") {
+		t.Errorf("diagnostic = %+v", d)
+	}
+}
+
+func TestLoadBufWorkspaceV2Modules(t *testing.T) {
+	depRoot := bufCache(t, "buf.build/acme/money", "c1", map[string]string{"acme/money.proto": `syntax = "proto3";`})
+
+	dir := writeTree(t, map[string]string{
+		"buf.yaml": `version: v2
+modules:
+  - path: proto
+    excludes:
+      - proto/internal
+  - path: third_party
+`,
+		"buf.lock":                  "version: v2\ndeps:\n  - name: buf.build/acme/money\n    commit: c1\n",
+		"proto/shop/orders.proto":   `syntax = "proto3";`,
+		"proto/internal/x.proto":    `syntax = "proto3";`,
+		"third_party/ext/ext.proto": `syntax = "proto3";`,
+	})
+
+	ws, err := loadBufWorkspace(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRoots := []string{filepath.Join(dir, "proto"), filepath.Join(dir, "third_party")}
+	if !reflect.DeepEqual(ws.roots, wantRoots) || !reflect.DeepEqual(ws.depRoots, []string{depRoot}) {
+		t.Errorf("roots = %v, deps = %v", ws.roots, ws.depRoots)
+	}
+	if !reflect.DeepEqual(ws.files, []string{"shop/orders.proto", "ext/ext.proto"}) {
+		t.Errorf("files = %v", ws.files)
+	}
+	if len(ws.diagnostics) != 0 {
+		t.Errorf("diagnostics = %+v", ws.diagnostics)
+	}
+}
+
+func TestLoadBufWorkWorkspace(t *testing.T) {
+	t.Setenv("BUF_CACHE_DIR", t.TempDir())
+
+	dir := writeTree(t, map[string]string{
+		"buf.work.yaml":          "version: v1\ndirectories:\n  - api\n  - lib\n",
+		"api/buf.yaml":           "version: v1\nbuild:\n  excludes:\n    - gen\n",
+		"api/svc.proto":          `syntax = "proto3";`,
+		"api/gen/out.proto":      `syntax = "proto3";`,
+		"api/buf.lock":           "version: v1\ndeps: [\n",
+		"lib/common/types.proto": `syntax = "proto3";`,
+	})
+
+	ws, err := loadBufWorkspace(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ws.files, []string{"svc.proto", "common/types.proto"}) {
+		t.Errorf("files = %v", ws.files)
+	}
+	// A broken buf.lock is a warning, not a failure
+	if len(ws.diagnostics) != 1 || ws.diagnostics[0].File != filepath.Join(dir, "api", "buf.lock") {
+		t.Errorf("diagnostics = %+v", ws.diagnostics)
+	}
+}
+
+func TestLoadBufWorkspaceErrors(t *testing.T) {
+	if _, err := loadBufWorkspace(filepath.Join(t.TempDir(), "nope")); err == nil || !strings.Contains(err.Error(), "invalid buf workspace") {
+		t.Errorf("missing directory: err = %v", err)
+	}
+	if _, err := loadBufWorkspace(t.TempDir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("no buf.yaml: err = %v", err)
+	}
+	dir := writeTree(t, map[string]string{"buf.yaml": "version: [v1"})
+	if _, err := loadBufWorkspace(dir); err == nil || !strings.Contains(err.Error(), "invalid "+filepath.Join(dir, "buf.yaml")) {
+		t.Errorf("bad yaml: err = %v", err)
+	}
+}
+
+// Parsing a workspace with nothing uploaded parses every file in it, with
+// imports from the buf cache and the bundled Google protos
+func TestParseBufWorkspace(t *testing.T) {
+	bufCache(t, "buf.build/acme/money", "c1", map[string]string{
+		"acme/money.proto": "syntax = \"proto3\";\npackage acme;\nmessage Amount { int64 cents = 1; }\n",
+	})
+	dir := writeTree(t, map[string]string{
+		"buf.yaml": "version: v1\n",
+		"buf.lock": "version: v1\ndeps:\n  - remote: buf.build\n    owner: acme\n    repository: money\n    commit: c1\n",
+		"shop/orders.proto": `syntax = "proto3";
+package shop;
+import "acme/money.proto";
+import "google/type/date.proto";
+message Order { acme.Amount total = 1; google.type.Date due = 2; }
+service Orders { rpc Get(Order) returns (Order); }
+`,
+	})
+
+	g := NewGrpcStreamManager(nil)
+	parsed, err := g.ParseProtoFiles(ProtoFileUploadRequest{BufWorkspace: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Services) != 1 || parsed.Services[0].FullName != "shop.Orders" {
+		t.Errorf("services = %+v", parsed.Services)
+	}
+	if len(parsed.Diagnostics) != 0 {
+		t.Errorf("diagnostics = %+v", parsed.Diagnostics)
+	}
+}
+
+func TestProtoParseDiagnostics(t *testing.T) {
+	g := NewGrpcStreamManager(nil)
+
+	_, err := g.ParseProtoFiles(ProtoFileUploadRequest{Files: []ProtoFile{{
+		Name: "bad.proto",
+		Content: `syntax = "proto3";
+message A { Missing one = 1; }
+message B { Unknown two = 1; }
+`,
+	}}})
+	var parseErr *ProtoParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("err = %v, want a ProtoParseError", err)
+	}
+	if len(parseErr.Diagnostics) != 2 {
+		t.Fatalf("diagnostics = %+v, want both errors", parseErr.Diagnostics)
+	}
+	for i, line := range []int{2, 3} {
+		d := parseErr.Diagnostics[i]
+		if d.File != "bad.proto" || d.Line != line || d.Column == 0 || d.Severity != "error" || strings.HasPrefix(d.Message, "bad.proto") {
+			t.Errorf("diagnostic %d = %+v", i, d)
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "bad.proto:2:") {
+		t.Errorf("error text = %q", err.Error())
+	}
+
+	_, err = g.ParseProtoFiles(ProtoFileUploadRequest{Files: []ProtoFile{{
+		Name:    "main.proto",
+		Content: "syntax = \"proto3\";\nimport \"nowhere/x.proto\";\n",
+	}}})
+	if !errors.As(err, &parseErr) || len(parseErr.Diagnostics) != 1 || !strings.Contains(parseErr.Diagnostics[0].Message, "nowhere/x.proto not found") {
+		t.Errorf("missing import: err = %v", err)
+	}
+
+	parsed, err := g.ParseProtoFiles(ProtoFileUploadRequest{Files: []ProtoFile{{
+		Name:    "warn.proto",
+		Content: "syntax = \"proto3\";\nimport \"google/protobuf/empty.proto\";\nmessage A {}\n",
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Diagnostics) != 1 || parsed.Diagnostics[0].Severity != "warning" || parsed.Diagnostics[0].Line != 2 {
+		t.Errorf("warnings = %+v, want the unused import", parsed.Diagnostics)
+	}
+}
+
+func TestNewProtoSourcesErrors(t *testing.T) {
+	if _, _, err := newProtoSources(ProtoFileUploadRequest{}); err == nil || err.Error() != "no proto files to parse" {
+		t.Errorf("empty request: err = %v", err)
+	}
+	if _, _, err := newProtoSources(ProtoFileUploadRequest{
+		Files:       []ProtoFile{{Name: "a.proto"}},
+		ImportPaths: []string{filepath.Join(t.TempDir(), "nope")},
+	}); err == nil || !strings.Contains(err.Error(), "invalid import path") {
+		t.Errorf("missing import path: err = %v", err)
+	}
+}
+
+func TestProtoSourcesOpen(t *testing.T) {
+	first := writeTree(t, map[string]string{"a/x.proto": "first"})
+	second := writeTree(t, map[string]string{"a/x.proto": "second", "b/y.proto": "second"})
+	src := &protoSources{uploaded: map[string]string{"b/y.proto": "uploaded"}, roots: []string{first, second}}
+
+	read := func(name string) string {
+		f, err := src.open(name)
+		if err != nil {
+			return err.Error()
+		}
+		defer f.Close()
+		data, _ := io.ReadAll(f)
+		return string(data)
+	}
+	if got := read("a/x.proto"); got != "first" {
+		t.Errorf("a/x.proto = %q, want the first root", got)
+	}
+	if got := read("b/y.proto"); got != "uploaded" {
+		t.Errorf("b/y.proto = %q, want the upload", got)
+	}
+	if _, err := src.open("c/z.proto"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: err = %v, want ErrNotExist", err)
+	}
+
+	if _, err := bundledImport("google/type/date.proto"); err != nil {
+		t.Errorf("bundled date.proto: %v", err)
+	}
+	if _, err := bundledImport("acme/x.proto"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("non-Google import: err = %v", err)
+	}
+}
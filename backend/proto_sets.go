@@ -0,0 +1,352 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ProtoSet is a named group of descriptors from uploaded proto files or
+// server reflection. Saved sets live under dataDir/protos/<id> and are
+// reloaded at startup, so collections can reference them by ID.
+type ProtoSet struct {
+	ID                string      `json:"id"`
+	Name              string      `json:"name"`
+	WorkspaceID       string      `json:"workspaceId"`
+	Source            string      `json:"source"` // "files" or "reflection"
+	ServerURL         string      `json:"serverUrl,omitempty"`
+	ReflectionVersion string      `json:"reflectionVersion,omitempty"`
+	Files             []ProtoFile `json:"files,omitempty"` // original sources, for re-editing
+	Services          []string    `json:"services"`        // fully qualified names
+	Saved             bool        `json:"saved"`
+	CreatedAt         time.Time   `json:"createdAt"`
+	UpdatedAt         time.Time   `json:"updatedAt"`
+}
+
+type SaveProtoSetRequest struct {
+	ProtoSetID  string `json:"protoSetId"`
+	Name        string `json:"name"`
+	WorkspaceID string `json:"workspaceId"`
+}
+
+// protoSet is a ProtoSet together with its parsed descriptors
+type protoSet struct {
+	info     ProtoSet
+	files    []*desc.FileDescriptor
+	services map[string]*desc.ServiceDescriptor
+}
+
+const (
+	protoSetInfoFile        = "set.json"
+	protoSetDescriptorsFile = "descriptors.pb"
+
+	// maxUnsavedProtoSets bounds the parsed and reflected sets that were
+	// never saved, which are only kept for open service pickers
+	maxUnsavedProtoSets = 16
+)
+
+func newProtoSet(source, serverURL string, sources []ProtoFile) *protoSet {
+	now := time.Now()
+	return &protoSet{
+		info: ProtoSet{
+			ID:        uuid.New().String(),
+			Source:    source,
+			ServerURL: serverURL,
+			Files:     sources,
+			Services:  []string{},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		services: make(map[string]*desc.ServiceDescriptor),
+	}
+}
+
+func (s *protoSet) addFile(fd *desc.FileDescriptor) {
+	for _, existing := range s.files {
+		if existing.GetName() == fd.GetName() {
+			return
+		}
+	}
+	s.files = append(s.files, fd)
+}
+
+func (s *protoSet) addService(svc *desc.ServiceDescriptor) {
+	name := svc.GetFullyQualifiedName()
+	if _, ok := s.services[name]; !ok {
+		s.info.Services = append(s.info.Services, name)
+	}
+	s.services[name] = svc
+	s.addFile(svc.GetFile())
+}
+
+// sourceKey identifies where an unsaved set came from, so parsing the same
+// files or reflecting the same server again replaces it
+func (s *protoSet) sourceKey() string {
+	if s.info.Source == "reflection" {
+		return "reflection:" + s.info.ServerURL
+	}
+
+	names := make([]string, 0, len(s.info.Files))
+	for _, f := range s.info.Files {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	return "files:" + strings.Join(names, "\x00")
+}
+
+func (s *protoSet) response() *ParsedProtoResponse {
+	response := &ParsedProtoResponse{
+		ProtoSetID: s.info.ID,
+		Services:   []ServiceInfo{},
+	}
+	for _, name := range s.info.Services {
+		response.Services = append(response.Services, describeService(s.services[name]))
+	}
+	return response
+}
+
+// SaveProtoSet names a parsed or reflected set and writes it to disk. Saving
+// an already saved set renames it.
+func (g *GrpcStreamManager) SaveProtoSet(req SaveProtoSetRequest) (*ProtoSet, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("proto set name is required")
+	}
+
+	g.protoRegistry.mu.Lock()
+	defer g.protoRegistry.mu.Unlock()
+
+	set, ok := g.protoRegistry.sets[req.ProtoSetID]
+	if !ok {
+		return nil, fmt.Errorf("proto set not found: %s", req.ProtoSetID)
+	}
+
+	info := set.info
+	info.Name = req.Name
+	info.WorkspaceID = req.WorkspaceID
+	info.Saved = true
+	info.UpdatedAt = time.Now()
+
+	if err := writeProtoSet(g.app.GetDataDirectory(), info, set.files); err != nil {
+		return nil, err
+	}
+
+	set.info = info
+	return &info, nil
+}
+
+// ListProtoSets returns the saved sets of a workspace, or of every workspace
+// when workspaceID is empty, sorted by name
+func (g *GrpcStreamManager) ListProtoSets(workspaceID string) []ProtoSet {
+	g.protoRegistry.mu.RLock()
+	defer g.protoRegistry.mu.RUnlock()
+
+	out := []ProtoSet{}
+	for _, set := range g.protoRegistry.sets {
+		if !set.info.Saved {
+			continue
+		}
+		if workspaceID != "" && set.info.WorkspaceID != workspaceID {
+			continue
+		}
+		out = append(out, set.info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// LoadProtoSet makes a set's services available to connections that don't
+// name a set and returns them for the service picker
+func (g *GrpcStreamManager) LoadProtoSet(id string) (*ParsedProtoResponse, error) {
+	g.protoRegistry.mu.Lock()
+	defer g.protoRegistry.mu.Unlock()
+
+	set, ok := g.protoRegistry.sets[id]
+	if !ok {
+		return nil, fmt.Errorf("proto set not found: %s", id)
+	}
+
+	for _, fd := range set.files {
+		g.registerFile(fd)
+	}
+	for name, svc := range set.services {
+		g.protoRegistry.services[name] = svc
+	}
+
+	return set.response(), nil
+}
+
+func (g *GrpcStreamManager) DeleteProtoSet(id string) error {
+	g.protoRegistry.mu.Lock()
+	defer g.protoRegistry.mu.Unlock()
+
+	set, ok := g.protoRegistry.sets[id]
+	if !ok {
+		return fmt.Errorf("proto set not found: %s", id)
+	}
+
+	if set.info.Saved {
+		if err := os.RemoveAll(protoSetDir(g.app.GetDataDirectory(), id)); err != nil {
+			return fmt.Errorf("failed to delete proto set: %w", err)
+		}
+	}
+
+	delete(g.protoRegistry.sets, id)
+	return nil
+}
+
+// LoadSavedProtoSets reads every saved set from disk. A set that fails to
+// load is logged and skipped so one bad file can't hide the rest.
+func (g *GrpcStreamManager) LoadSavedProtoSets() error {
+	root := filepath.Join(g.app.GetDataDirectory(), "protos")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read proto sets: %w", err)
+	}
+
+	g.protoRegistry.mu.Lock()
+	defer g.protoRegistry.mu.Unlock()
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		set, err := readProtoSet(filepath.Join(root, entry.Name()))
+		if err != nil {
+			fmt.Printf("[gRPC] Skipping proto set %s: %v\n", entry.Name(), err)
+			continue
+		}
+		g.protoRegistry.sets[set.info.ID] = set
+	}
+
+	return nil
+}
+
+// addUnsavedSet registers a freshly parsed or reflected set. It replaces the
+// unsaved set from the same source and evicts the oldest unsaved sets beyond
+// maxUnsavedProtoSets, so re-parsing while editing doesn't grow without
+// bound. The caller must hold protoRegistry.mu.
+func (g *GrpcStreamManager) addUnsavedSet(set *protoSet) {
+	key := set.sourceKey()
+
+	var unsaved []*protoSet
+	for id, existing := range g.protoRegistry.sets {
+		if existing.info.Saved {
+			continue
+		}
+		if existing.sourceKey() == key {
+			delete(g.protoRegistry.sets, id)
+			continue
+		}
+		unsaved = append(unsaved, existing)
+	}
+
+	sort.Slice(unsaved, func(i, j int) bool {
+		return unsaved[i].info.CreatedAt.Before(unsaved[j].info.CreatedAt)
+	})
+	for len(unsaved) >= maxUnsavedProtoSets {
+		delete(g.protoRegistry.sets, unsaved[0].info.ID)
+		unsaved = unsaved[1:]
+	}
+
+	g.protoRegistry.sets[set.info.ID] = set
+}
+
+// lookupProtoSet returns the services of a set, which must already be loaded
+func (g *GrpcStreamManager) lookupProtoSet(id string) (map[string]*desc.ServiceDescriptor, error) {
+	g.protoRegistry.mu.RLock()
+	defer g.protoRegistry.mu.RUnlock()
+
+	set, ok := g.protoRegistry.sets[id]
+	if !ok {
+		return nil, fmt.Errorf("proto set not found: %s", id)
+	}
+	return set.services, nil
+}
+
+func protoSetDir(dataDir, id string) string {
+	return filepath.Join(dataDir, "protos", id)
+}
+
+func writeProtoSet(dataDir string, info ProtoSet, files []*desc.FileDescriptor) error {
+	dir := protoSetDir(dataDir, info.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create proto set directory: %w", err)
+	}
+
+	// The descriptor set carries every transitive import, so reflected sets
+	// reload without the server and parsed sets without re-parsing.
+	descriptors, err := proto.Marshal(desc.ToFileDescriptorSet(files...))
+	if err != nil {
+		return fmt.Errorf("failed to encode descriptors: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, protoSetDescriptorsFile), descriptors, 0644); err != nil {
+		return fmt.Errorf("failed to write descriptors: %w", err)
+	}
+
+	jsonData, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, protoSetInfoFile), jsonData, 0644)
+}
+
+func readProtoSet(dir string) (*protoSet, error) {
+	jsonData, err := os.ReadFile(filepath.Join(dir, protoSetInfoFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var info ProtoSet
+	if err := json.Unmarshal(jsonData, &info); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", protoSetInfoFile, err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, protoSetDescriptorsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var fdset descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(raw, &fdset); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", protoSetDescriptorsFile, err)
+	}
+
+	files, err := desc.CreateFileDescriptorsFromSet(&fdset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build descriptors: %w", err)
+	}
+
+	set := &protoSet{
+		info:     info,
+		services: make(map[string]*desc.ServiceDescriptor),
+	}
+	set.info.Saved = true
+
+	for _, name := range info.Services {
+		for _, fd := range files {
+			if svc := fd.FindService(name); svc != nil {
+				set.services[name] = svc
+				set.addFile(fd)
+				break
+			}
+		}
+		if _, ok := set.services[name]; !ok {
+			return nil, fmt.Errorf("service %s missing from descriptors", name)
+		}
+	}
+
+	return set, nil
+}
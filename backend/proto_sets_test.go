@@ -0,0 +1,176 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testOrdersProto = `syntax = "proto3";
+package shop;
+
+import "google/protobuf/timestamp.proto";
+
+message Order {
+  string id = 1;
+  google.protobuf.Timestamp placed_at = 2;
+}
+
+service Orders {
+  rpc Get(Order) returns (Order);
+  rpc Watch(Order) returns (stream Order);
+}
+`
+
+func TestProtoSetRoundTrip(t *testing.T) {
+	app, _ := newTestApp(t)
+	g := NewGrpcStreamManager(app)
+
+	parsed, err := g.ParseProtoFiles(ProtoFileUploadRequest{Files: []ProtoFile{{Name: "orders.proto", Content: testOrdersProto}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.ListProtoSets("")) != 0 {
+		t.Error("an unsaved set is listed")
+	}
+
+	saved, err := g.SaveProtoSet(SaveProtoSetRequest{ProtoSetID: parsed.ProtoSetID, Name: "Shop", WorkspaceID: "w1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !saved.Saved || saved.Name != "Shop" {
+		t.Errorf("saved = %+v", saved)
+	}
+
+	// A fresh manager, as after a restart
+	reloaded := NewGrpcStreamManager(app)
+	if err := reloaded.LoadSavedProtoSets(); err != nil {
+		t.Fatal(err)
+	}
+
+	sets := reloaded.ListProtoSets("w1")
+	if len(sets) != 1 || len(reloaded.ListProtoSets("w2")) != 0 {
+		t.Fatalf("sets = %+v", sets)
+	}
+	got := sets[0]
+	if got.ID != parsed.ProtoSetID || got.Name != "Shop" || got.Source != "files" || !reflect.DeepEqual(got.Services, []string{"shop.Orders"}) {
+		t.Errorf("reloaded info = %+v", got)
+	}
+	if len(got.Files) != 1 || got.Files[0].Content != testOrdersProto {
+		t.Errorf("sources were not kept: %+v", got.Files)
+	}
+
+	// The timestamp import comes back from the descriptor set, not a re-parse
+	loaded, err := reloaded.LoadProtoSet(got.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Services, parsed.Services) {
+		t.Errorf("services = %+v, want %+v", loaded.Services, parsed.Services)
+	}
+	if reloaded.findMessageDescriptor("google.protobuf.Timestamp") == nil {
+		t.Error("the timestamp import did not survive the round trip")
+	}
+
+	if err := reloaded.DeleteProtoSet(got.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(protoSetDir(app.dir, got.ID)); !os.IsNotExist(err) {
+		t.Errorf("set directory still exists: %v", err)
+	}
+}
+
+func TestLoadSavedProtoSetsSkipsBrokenSets(t *testing.T) {
+	app, _ := newTestApp(t)
+	g := NewGrpcStreamManager(app)
+
+	parsed, err := g.ParseProtoFiles(ProtoFileUploadRequest{Files: []ProtoFile{{Name: "orders.proto", Content: testOrdersProto}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.SaveProtoSet(SaveProtoSetRequest{ProtoSetID: parsed.ProtoSetID, Name: "Shop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := filepath.Join(app.dir, "protos", "broken")
+	os.MkdirAll(broken, 0755)
+	os.WriteFile(filepath.Join(broken, protoSetInfoFile), []byte("{"), 0644)
+
+	// A set whose info names a service the descriptors don't have
+	info := ProtoSet{ID: "stale", Name: "Stale", Services: []string{"shop.Gone"}}
+	if err := writeProtoSet(app.dir, info, g.protoRegistry.sets[parsed.ProtoSetID].files); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readProtoSet(protoSetDir(app.dir, "stale")); err == nil || !strings.Contains(err.Error(), "service shop.Gone missing") {
+		t.Errorf("stale set: err = %v", err)
+	}
+
+	reloaded := NewGrpcStreamManager(app)
+	if err := reloaded.LoadSavedProtoSets(); err != nil {
+		t.Fatal(err)
+	}
+	if sets := reloaded.ListProtoSets(""); len(sets) != 1 || sets[0].Name != "Shop" {
+		t.Errorf("sets = %+v, want only Shop", sets)
+	}
+}
+
+func TestAddUnsavedSet(t *testing.T) {
+	g := NewGrpcStreamManager(nil)
+	start := time.Now()
+
+	add := func(i int, file string) *protoSet {
+		set := newProtoSet("files", "", []ProtoFile{{Name: file}})
+		set.info.CreatedAt = start.Add(time.Duration(i) * time.Second)
+		g.addUnsavedSet(set)
+		return set
+	}
+
+	saved := newProtoSet("files", "", []ProtoFile{{Name: "saved.proto"}})
+	saved.info.Saved = true
+	saved.info.CreatedAt = start.Add(-time.Hour)
+	g.protoRegistry.sets[saved.info.ID] = saved
+
+	first := add(0, "a.proto")
+	again := add(1, "a.proto")
+	if _, ok := g.protoRegistry.sets[first.info.ID]; ok {
+		t.Error("re-parsing the same files kept the old set")
+	}
+
+	var sets []*protoSet
+	for i := 2; i < maxUnsavedProtoSets+2; i++ {
+		sets = append(sets, add(i, fmt.Sprintf("f%d.proto", i)))
+	}
+
+	if _, ok := g.protoRegistry.sets[again.info.ID]; ok {
+		t.Error("the oldest unsaved set was not evicted")
+	}
+	if _, ok := g.protoRegistry.sets[saved.info.ID]; !ok {
+		t.Error("a saved set was evicted")
+	}
+	for _, set := range sets {
+		if _, ok := g.protoRegistry.sets[set.info.ID]; !ok {
+			t.Errorf("%s was evicted", set.info.Files[0].Name)
+		}
+	}
+	if got := len(g.protoRegistry.sets); got != maxUnsavedProtoSets+1 {
+		t.Errorf("%d sets, want %d unsaved and the saved one", got, maxUnsavedProtoSets)
+	}
+}
+
+func TestProtoSetSourceKey(t *testing.T) {
+	a := newProtoSet("files", "", []ProtoFile{{Name: "b.proto"}, {Name: "a.proto"}})
+	b := newProtoSet("files", "", []ProtoFile{{Name: "a.proto"}, {Name: "b.proto"}})
+	if a.sourceKey() != b.sourceKey() {
+		t.Error("file order changed the source key")
+	}
+
+	r1 := newProtoSet("reflection", "localhost:50051", nil)
+	r2 := newProtoSet("reflection", "localhost:50052", nil)
+	if r1.sourceKey() == r2.sourceKey() || r1.sourceKey() == newProtoSet("files", "", nil).sourceKey() {
+		t.Error("different sources share a key")
+	}
+}
@@ -0,0 +1,479 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisManager handles Redis connections: stream producers, readers and
+// consumer groups (redis_streams.go), and Pub/Sub subscriptions.
+type RedisManager struct {
+	app         AppInterface
+	connections map[string]*RedisConnection
+	mu          sync.RWMutex
+	msgCounter  uint64 // Atomic counter for unique message IDs
+	idCounter   uint64
+}
+
+type RedisConnection struct {
+	ID      string
+	Address string
+	Scopes  *VariableScopes
+	client  *redis.Client
+	options *redis.Options // readers open their own client with these
+	ctx     context.Context
+	cancel  context.CancelFunc
+	readers map[string]*redisReader
+	pubsub  *redis.PubSub // created on the first subscription
+	mu      sync.Mutex
+}
+
+// RedisConnectRequest opens a connection. URL takes redis:// or rediss://
+// URLs with optional user, password and database, or a bare host:port.
+type RedisConnectRequest struct {
+	URL            string          `json:"url"`
+	Username       string          `json:"username"` // overrides the URL's
+	Password       string          `json:"password"` // overrides the URL's
+	DB             int             `json:"db"`       // overrides the URL's when above 0
+	TLS            bool            `json:"tls"`      // implied by rediss://
+	TLSSkipVerify  bool            `json:"tlsSkipVerify"`
+	TLSProfile     string          `json:"tlsProfile,omitempty"`
+	ConnectTimeout int             `json:"connectTimeout"` // milliseconds
+	Scopes         *VariableScopes `json:"scopes,omitempty"`
+}
+
+type RedisPublishRequest struct {
+	ConnectionID string `json:"connectionId"`
+	Channel      string `json:"channel"`
+	Message      string `json:"message"`
+}
+
+// RedisSubscribeRequest adds or removes Pub/Sub subscriptions. Pattern
+// switches to PSUBSCRIBE/PUNSUBSCRIBE, where channels are glob patterns.
+type RedisSubscribeRequest struct {
+	ConnectionID string   `json:"connectionId"`
+	Channels     []string `json:"channels"`
+	Pattern      bool     `json:"pattern"`
+}
+
+const redisRequestTimeout = 10 * time.Second
+
+func NewRedisManager(app AppInterface) *RedisManager {
+	return &RedisManager{
+		app:         app,
+		connections: make(map[string]*RedisConnection),
+	}
+}
+
+func (m *RedisManager) generateMessageID() string {
+	count := atomic.AddUint64(&m.msgCounter, 1)
+	return fmt.Sprintf("msg-%d-%d", time.Now().UnixNano(), count)
+}
+
+func (m *RedisManager) generateID(prefix string) string {
+	return fmt.Sprintf("%s-%d-%d", prefix, time.Now().UnixNano(), atomic.AddUint64(&m.idCounter, 1))
+}
+
+func (m *RedisManager) Connect(req RedisConnectRequest) (string, error) {
+	target, err := resolverFor(req.Scopes).ResolveRedisConnectRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	opts, err := parseRedisURL(target.URL)
+	if err != nil {
+		return "", err
+	}
+	if target.Username != "" {
+		opts.Username = target.Username
+	}
+	if target.Password != "" {
+		opts.Password = target.Password
+	}
+	if target.DB > 0 {
+		opts.DB = target.DB
+	}
+	if target.ConnectTimeout > 0 {
+		opts.DialTimeout = time.Duration(target.ConnectTimeout) * time.Millisecond
+	}
+	// Blocking reads wait on the server, so reads get no client timeout;
+	// each command carries its own context deadline instead
+	opts.ReadTimeout = -1
+	opts.ContextTimeoutEnabled = true
+
+	if opts.TLSConfig != nil || target.TLS || target.TLSProfile != "" {
+		host, _, _ := net.SplitHostPort(opts.Addr)
+		fallback := &tls.Config{ServerName: host, InsecureSkipVerify: target.TLSSkipVerify}
+		opts.TLSConfig, err = clientTLSConfig(m.app.GetDataDirectory(), target.TLSProfile, host, fallback)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisRequestTimeout)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	connCtx, connCancel := context.WithCancel(context.Background())
+	conn := &RedisConnection{
+		ID:      m.generateID("redis"),
+		Address: opts.Addr,
+		Scopes:  req.Scopes,
+		client:  client,
+		options: opts,
+		ctx:     connCtx,
+		cancel:  connCancel,
+		readers: make(map[string]*redisReader),
+	}
+
+	m.mu.Lock()
+	m.connections[conn.ID] = conn
+	m.mu.Unlock()
+
+	text := fmt.Sprintf("Connected to %s", conn.Address)
+	metadata := map[string]interface{}{"address": conn.Address, "db": opts.DB}
+	if version := redisServerVersion(ctx, client); version != "" {
+		text += fmt.Sprintf(" (Redis %s)", version)
+		metadata["version"] = version
+	}
+	if opts.TLSConfig != nil {
+		text += " over TLS"
+	}
+	m.emitSystem(conn, text, metadata)
+
+	return conn.ID, nil
+}
+
+func (m *RedisManager) Disconnect(connectionID string) error {
+	m.mu.Lock()
+	conn, ok := m.connections[connectionID]
+	if ok {
+		delete(m.connections, connectionID)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("connection not found")
+	}
+
+	conn.cancel()
+
+	conn.mu.Lock()
+	readers := make([]*redisReader, 0, len(conn.readers))
+	for _, r := range conn.readers {
+		readers = append(readers, r)
+	}
+	pubsub := conn.pubsub
+	conn.pubsub = nil
+	conn.mu.Unlock()
+
+	for _, r := range readers {
+		r.stop()
+	}
+	if pubsub != nil {
+		pubsub.Close()
+	}
+	conn.client.Close()
+
+	m.emitSystem(conn, "Disconnected", map[string]interface{}{"closed": true})
+	return nil
+}
+
+func (m *RedisManager) Publish(req RedisPublishRequest) (int64, error) {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err = resolverFor(conn.Scopes).ResolveRedisPublishRequest(req)
+	if err != nil {
+		return 0, err
+	}
+	if req.Channel == "" {
+		return 0, fmt.Errorf("channel is required")
+	}
+
+	ctx, cancel := context.WithTimeout(conn.ctx, redisRequestTimeout)
+	defer cancel()
+
+	receivers, err := conn.client.Publish(ctx, req.Channel, req.Message).Result()
+	if err != nil {
+		m.emitError(conn, fmt.Sprintf("Failed to publish to %s: %s", req.Channel, err.Error()), nil)
+		return 0, err
+	}
+
+	m.emitMessage(StreamMessage{
+		ID:        m.generateMessageID(),
+		Direction: "outbound",
+		Protocol:  "Redis",
+		Payload:   redisText(req.Message),
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"connectionId": conn.ID,
+			"channel":      req.Channel,
+			"receivers":    receivers,
+		},
+	})
+
+	return receivers, nil
+}
+
+func (m *RedisManager) Subscribe(req RedisSubscribeRequest) error {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	req, err = resolverFor(conn.Scopes).ResolveRedisSubscribeRequest(req)
+	if err != nil {
+		return err
+	}
+	channels, err := redisChannels(req.Channels)
+	if err != nil {
+		return err
+	}
+
+	conn.mu.Lock()
+	if conn.pubsub == nil {
+		// an empty SUBSCRIBE only sets up the connection; the channels
+		// follow below so both kinds share it
+		conn.pubsub = conn.client.Subscribe(conn.ctx)
+		go m.receive(conn, conn.pubsub)
+	}
+	pubsub := conn.pubsub
+	conn.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(conn.ctx, redisRequestTimeout)
+	defer cancel()
+
+	if req.Pattern {
+		err = pubsub.PSubscribe(ctx, channels...)
+	} else {
+		err = pubsub.Subscribe(ctx, channels...)
+	}
+	if err != nil {
+		m.emitError(conn, fmt.Sprintf("Subscribe to %s failed: %s", strings.Join(channels, ", "), err.Error()), nil)
+		return err
+	}
+	return nil
+}
+
+func (m *RedisManager) Unsubscribe(req RedisSubscribeRequest) error {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	req, err = resolverFor(conn.Scopes).ResolveRedisSubscribeRequest(req)
+	if err != nil {
+		return err
+	}
+	channels, err := redisChannels(req.Channels)
+	if err != nil {
+		return err
+	}
+
+	conn.mu.Lock()
+	pubsub := conn.pubsub
+	conn.mu.Unlock()
+
+	if pubsub == nil {
+		return fmt.Errorf("not subscribed to anything")
+	}
+
+	ctx, cancel := context.WithTimeout(conn.ctx, redisRequestTimeout)
+	defer cancel()
+
+	if req.Pattern {
+		err = pubsub.PUnsubscribe(ctx, channels...)
+	} else {
+		err = pubsub.Unsubscribe(ctx, channels...)
+	}
+	if err != nil {
+		m.emitError(conn, fmt.Sprintf("Unsubscribe from %s failed: %s", strings.Join(channels, ", "), err.Error()), nil)
+		return err
+	}
+	return nil
+}
+
+// receive relays Pub/Sub messages and the server's subscription
+// confirmations until the connection closes. go-redis resubscribes on its
+// own after a reconnect.
+func (m *RedisManager) receive(conn *RedisConnection, pubsub *redis.PubSub) {
+	for event := range pubsub.ChannelWithSubscriptions() {
+		switch e := event.(type) {
+		case *redis.Subscription:
+			verb := map[string]string{
+				"subscribe":    "Subscribed to",
+				"psubscribe":   "Subscribed to pattern",
+				"unsubscribe":  "Unsubscribed from",
+				"punsubscribe": "Unsubscribed from pattern",
+			}[e.Kind]
+			if verb == "" {
+				continue
+			}
+			m.emitSystem(conn, fmt.Sprintf("%s %s", verb, e.Channel), map[string]interface{}{
+				"channel":       e.Channel,
+				"pattern":       strings.HasPrefix(e.Kind, "p"),
+				"subscribed":    !strings.Contains(e.Kind, "unsubscribe"),
+				"subscriptions": e.Count,
+			})
+
+		case *redis.Message:
+			metadata := map[string]interface{}{
+				"connectionId": conn.ID,
+				"channel":      e.Channel,
+				"size":         len(e.Payload),
+			}
+			if e.Pattern != "" {
+				metadata["pattern"] = e.Pattern
+			}
+			m.emitMessage(StreamMessage{
+				ID:        m.generateMessageID(),
+				Direction: "inbound",
+				Protocol:  "Redis",
+				Payload:   redisText(e.Payload),
+				Timestamp: time.Now(),
+				Metadata:  metadata,
+			})
+		}
+	}
+}
+
+func (m *RedisManager) lookup(connectionID string) (*RedisConnection, error) {
+	m.mu.RLock()
+	conn, ok := m.connections[connectionID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("connection not found: %s", connectionID)
+	}
+	return conn, nil
+}
+
+func (m *RedisManager) emitSystem(conn *RedisConnection, text string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["connectionId"] = conn.ID
+
+	m.emitMessage(StreamMessage{
+		ID:        m.generateMessageID(),
+		Direction: "system",
+		Protocol:  "Redis",
+		Payload:   text,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+func (m *RedisManager) emitError(conn *RedisConnection, text string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["connectionId"] = conn.ID
+
+	m.emitMessage(StreamMessage{
+		ID:        m.generateMessageID(),
+		Direction: "error",
+		Protocol:  "Redis",
+		Payload:   text,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+// emitMessage emits on the caller's goroutine, like emitKafkaRecord, so
+// entries from XRANGE and a reader show up in stream order
+func (m *RedisManager) emitMessage(msg StreamMessage) {
+	if m.app == nil || m.app.GetCtx() == nil {
+		fmt.Printf("[Redis] Cannot emit message - app context not initialized yet\n")
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("[Redis] Event emit panic recovered: %v\n", r)
+		}
+	}()
+	emitStreamEvent(m.app.GetCtx(), msg)
+}
+
+// parseRedisURL accepts redis:// and rediss:// URLs and bare host:port
+// addresses, which default to port 6379
+func parseRedisURL(raw string) (*redis.Options, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("Redis URL is required")
+	}
+	if !strings.Contains(raw, "://") {
+		if _, _, err := net.SplitHostPort(raw); err != nil {
+			raw = net.JoinHostPort(raw, "6379")
+		}
+		return &redis.Options{Addr: raw}, nil
+	}
+
+	opts, err := redis.ParseURL(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	return opts, nil
+}
+
+// redisServerVersion reads redis_version from INFO; servers and proxies
+// that don't offer INFO just go without
+func redisServerVersion(ctx context.Context, client *redis.Client) string {
+	info, err := client.Info(ctx, "server").Result()
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if version, ok := strings.CutPrefix(strings.TrimSpace(line), "redis_version:"); ok {
+			return version
+		}
+	}
+	return ""
+}
+
+func redisChannels(channels []string) ([]string, error) {
+	var out []string
+	for _, ch := range channels {
+		if ch = strings.TrimSpace(ch); ch != "" {
+			out = append(out, ch)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("at least one channel is required")
+	}
+	return out, nil
+}
+
+// redisText renders a value for the message list, with binary values
+// summarized the way the MQTT viewer does
+func redisText(value string) string {
+	if !utf8.ValidString(value) {
+		return fmt.Sprintf("[Binary data: %d bytes]", len(value))
+	}
+	return value
+}
+
+// isRedisClosed reports errors that mean the connection is gone for good
+func isRedisClosed(err error) bool {
+	return errors.Is(err, redis.ErrClosed) || errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,838 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisField is one field of a stream entry. Entries are read with raw
+// commands so fields keep the order they were added in.
+type RedisField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type RedisStreamEntry struct {
+	ID     string       `json:"id"`
+	Fields []RedisField `json:"fields"`
+}
+
+// RedisXAddRequest appends an entry. MaxLen above 0 trims the stream,
+// approximately (MAXLEN ~) unless Exact is set.
+type RedisXAddRequest struct {
+	ConnectionID string       `json:"connectionId"`
+	Stream       string       `json:"stream"`
+	ID           string       `json:"id"` // "*" (default) lets the server pick
+	Fields       []RedisField `json:"fields"`
+	MaxLen       int64        `json:"maxLen"`
+	Exact        bool         `json:"exact"`
+}
+
+// RedisRangeRequest browses a stream with XRANGE, or XREVRANGE when
+// Reverse is set. The entries arrive as stream messages.
+type RedisRangeRequest struct {
+	ConnectionID string `json:"connectionId"`
+	Stream       string `json:"stream"`
+	Start        string `json:"start"` // "-" (default), an entry ID, or "(" + ID to exclude it
+	End          string `json:"end"`   // "+" (default)
+	Count        int64  `json:"count"` // default 100
+	Reverse      bool   `json:"reverse"`
+}
+
+// RedisRangeResult lets the UI page: the next page starts after LastID
+type RedisRangeResult struct {
+	Count   int    `json:"count"`
+	FirstID string `json:"firstId"`
+	LastID  string `json:"lastId"`
+	Length  int64  `json:"length"` // XLEN of the whole stream
+}
+
+// RedisReadRequest starts a blocking reader: XREAD, or XREADGROUP when
+// Group is set. Entries arrive as stream messages until the reader stops.
+type RedisReadRequest struct {
+	ConnectionID string   `json:"connectionId"`
+	Streams      []string `json:"streams"`
+	// StartID is "$" (default) for only new entries or an ID to read after.
+	// With a group it is ">" (default) for new entries, or "0" to go
+	// through this consumer's pending entries first.
+	StartID     string `json:"startId"`
+	Group       string `json:"group"`
+	Consumer    string `json:"consumer"`    // defaults to a generated pulse-... name
+	CreateGroup bool   `json:"createGroup"` // XGROUP CREATE ... MKSTREAM if it doesn't exist
+	Count       int64  `json:"count"`       // per read, default 100
+	Block       int    `json:"block"`       // milliseconds per read, default 5000
+	NoAck       bool   `json:"noAck"`       // XREADGROUP NOACK: nothing goes to the pending list
+	AutoAck     bool   `json:"autoAck"`     // XACK each entry once it is shown
+}
+
+type RedisAckRequest struct {
+	ConnectionID string   `json:"connectionId"`
+	Stream       string   `json:"stream"`
+	Group        string   `json:"group"`
+	IDs          []string `json:"ids"`
+}
+
+// RedisClaimRequest moves pending entries idle for at least MinIdle to
+// Consumer: the listed IDs with XCLAIM, or with no IDs the next Count
+// entries from Start with XAUTOCLAIM
+type RedisClaimRequest struct {
+	ConnectionID string   `json:"connectionId"`
+	Stream       string   `json:"stream"`
+	Group        string   `json:"group"`
+	Consumer     string   `json:"consumer"`
+	MinIdle      int64    `json:"minIdle"` // milliseconds
+	IDs          []string `json:"ids"`
+	Start        string   `json:"start"` // XAUTOCLAIM cursor, default "0-0"
+	Count        int64    `json:"count"` // XAUTOCLAIM, default 100
+}
+
+type RedisClaimResult struct {
+	Claimed   []string `json:"claimed"`
+	NextStart string   `json:"nextStart,omitempty"` // XAUTOCLAIM: "0-0" once the whole list was scanned
+	Deleted   []string `json:"deleted,omitempty"`   // XAUTOCLAIM: pending IDs no longer in the stream
+}
+
+type RedisPendingRequest struct {
+	ConnectionID string `json:"connectionId"`
+	Stream       string `json:"stream"`
+	Group        string `json:"group"`
+	Consumer     string `json:"consumer"` // only this consumer's entries
+	MinIdle      int64  `json:"minIdle"`  // milliseconds, Redis 6.2+
+	Start        string `json:"start"`    // "-" (default)
+	End          string `json:"end"`      // "+" (default)
+	Count        int64  `json:"count"`    // default 100
+}
+
+type RedisPendingResult struct {
+	Count     int64               `json:"count"` // pending entries in the whole group
+	Lowest    string              `json:"lowest"`
+	Highest   string              `json:"highest"`
+	Consumers map[string]int64    `json:"consumers"`
+	Entries   []RedisPendingEntry `json:"entries"`
+}
+
+type RedisPendingEntry struct {
+	ID         string `json:"id"`
+	Consumer   string `json:"consumer"`
+	Idle       int64  `json:"idle"` // milliseconds
+	Deliveries int64  `json:"deliveries"`
+}
+
+type RedisGroupRequest struct {
+	ConnectionID string `json:"connectionId"`
+	Stream       string `json:"stream"`
+	Group        string `json:"group"`
+	StartID      string `json:"startId"` // "$" (default) or "0" for the whole stream
+}
+
+type RedisGroupInfo struct {
+	Name            string `json:"name"`
+	Consumers       int64  `json:"consumers"`
+	Pending         int64  `json:"pending"`
+	LastDeliveredID string `json:"lastDeliveredId"`
+	Lag             int64  `json:"lag"` // -1 when the server can't tell
+}
+
+// redisReader owns a single-connection client, so closing it ends a
+// blocking read right away
+type redisReader struct {
+	id     string
+	req    RedisReadRequest
+	client *redis.Client
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+const (
+	defaultRedisCount = 100
+	defaultRedisBlock = 5000
+)
+
+func (m *RedisManager) XAdd(req RedisXAddRequest) (string, error) {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return "", err
+	}
+
+	req, err = resolverFor(conn.Scopes).ResolveRedisXAddRequest(req)
+	if err != nil {
+		return "", err
+	}
+	if req.Stream == "" {
+		return "", fmt.Errorf("stream is required")
+	}
+
+	args := []interface{}{"XADD", req.Stream}
+	if req.MaxLen > 0 {
+		if req.Exact {
+			args = append(args, "MAXLEN", req.MaxLen)
+		} else {
+			args = append(args, "MAXLEN", "~", req.MaxLen)
+		}
+	}
+	id := req.ID
+	if id == "" {
+		id = "*"
+	}
+	args = append(args, id)
+
+	fields := make([]RedisField, 0, len(req.Fields))
+	for _, f := range req.Fields {
+		if f.Name == "" {
+			continue
+		}
+		fields = append(fields, f)
+		args = append(args, f.Name, f.Value)
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("at least one field is required")
+	}
+
+	ctx, cancel := context.WithTimeout(conn.ctx, redisRequestTimeout)
+	defer cancel()
+
+	entryID, err := conn.client.Do(ctx, args...).Text()
+	if err != nil {
+		m.emitError(conn, fmt.Sprintf("XADD to %s failed: %s", req.Stream, err.Error()), nil)
+		return "", err
+	}
+
+	m.emitEntry(conn, "outbound", req.Stream, RedisStreamEntry{ID: entryID, Fields: fields}, nil)
+	return entryID, nil
+}
+
+func (m *RedisManager) XRange(req RedisRangeRequest) (*RedisRangeResult, error) {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Stream == "" {
+		return nil, fmt.Errorf("stream is required")
+	}
+
+	start, end := req.Start, req.End
+	if start == "" {
+		start = "-"
+	}
+	if end == "" {
+		end = "+"
+	}
+	count := req.Count
+	if count <= 0 {
+		count = defaultRedisCount
+	}
+
+	// XREVRANGE takes the bounds the other way round
+	args := []interface{}{"XRANGE", req.Stream, start, end, "COUNT", count}
+	if req.Reverse {
+		args = []interface{}{"XREVRANGE", req.Stream, end, start, "COUNT", count}
+	}
+
+	ctx, cancel := context.WithTimeout(conn.ctx, redisRequestTimeout)
+	defer cancel()
+
+	raw, err := conn.client.Do(ctx, args...).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseRedisEntries(raw)
+	if err != nil {
+		return nil, err
+	}
+	length, err := conn.client.XLen(ctx, req.Stream).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		m.emitEntry(conn, "inbound", req.Stream, entry, map[string]interface{}{"source": "range"})
+	}
+
+	result := &RedisRangeResult{Count: len(entries), Length: length}
+	if len(entries) > 0 {
+		result.FirstID = entries[0].ID
+		result.LastID = entries[len(entries)-1].ID
+	}
+	m.emitSystem(conn, fmt.Sprintf("%s: %d of %d entries", req.Stream, len(entries), length), map[string]interface{}{"stream": req.Stream})
+	return result, nil
+}
+
+// StartReader runs XREAD or XREADGROUP in a loop until StopReader or
+// Disconnect
+func (m *RedisManager) StartReader(req RedisReadRequest) (string, error) {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return "", err
+	}
+
+	var streams []string
+	for _, s := range req.Streams {
+		if s = strings.TrimSpace(s); s != "" {
+			streams = append(streams, s)
+		}
+	}
+	if len(streams) == 0 {
+		return "", fmt.Errorf("at least one stream is required")
+	}
+	req.Streams = streams
+
+	if req.Count <= 0 {
+		req.Count = defaultRedisCount
+	}
+	if req.Block <= 0 {
+		req.Block = defaultRedisBlock
+	}
+
+	if req.Group != "" {
+		if req.StartID == "" {
+			req.StartID = ">"
+		}
+		if req.Consumer == "" {
+			req.Consumer = fmt.Sprintf("pulse-%d", time.Now().UnixNano())
+		}
+		if req.CreateGroup {
+			for _, stream := range streams {
+				if err := m.createGroup(conn, stream, req.Group, "$"); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+					return "", err
+				}
+			}
+		}
+	} else {
+		if req.StartID == "" {
+			req.StartID = "$"
+		}
+		if req.StartID == ">" {
+			return "", fmt.Errorf("\">\" only works with a consumer group")
+		}
+	}
+
+	opts := *conn.options
+	opts.PoolSize = 1
+	opts.MinIdleConns = 0
+
+	ctx, cancel := context.WithCancel(conn.ctx)
+	reader := &redisReader{
+		id:     m.generateID("reader"),
+		req:    req,
+		client: redis.NewClient(&opts),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	conn.mu.Lock()
+	conn.readers[reader.id] = reader
+	conn.mu.Unlock()
+
+	go m.read(ctx, conn, reader)
+
+	text := fmt.Sprintf("Reading %s with XREAD from %s", strings.Join(streams, ", "), req.StartID)
+	if req.Group != "" {
+		text = fmt.Sprintf("Reading %s as %s in group %s from %s", strings.Join(streams, ", "), req.Consumer, req.Group, req.StartID)
+	}
+	m.emitSystem(conn, text, reader.metadata(nil))
+
+	return reader.id, nil
+}
+
+func (m *RedisManager) StopReader(connectionID, readerID string) error {
+	conn, err := m.lookup(connectionID)
+	if err != nil {
+		return err
+	}
+
+	conn.mu.Lock()
+	reader, ok := conn.readers[readerID]
+	conn.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("reader not found: %s", readerID)
+	}
+
+	reader.stop()
+	return nil
+}
+
+func (r *redisReader) stop() {
+	r.cancel()
+	r.client.Close()
+	<-r.done
+}
+
+func (m *RedisManager) read(ctx context.Context, conn *RedisConnection, reader *redisReader) {
+	defer close(reader.done)
+	// a reader can also end on its own, e.g. when its group is deleted
+	defer reader.client.Close()
+	defer func() {
+		conn.mu.Lock()
+		delete(conn.readers, reader.id)
+		conn.mu.Unlock()
+	}()
+
+	req := reader.req
+	// where each stream continues; "$" and ">" stay as they are, anything
+	// else moves past the entries already shown
+	next := make(map[string]string, len(req.Streams))
+	for _, s := range req.Streams {
+		next[s] = req.StartID
+	}
+
+	for ctx.Err() == nil {
+		args := []interface{}{"XREAD"}
+		if req.Group != "" {
+			args = []interface{}{"XREADGROUP", "GROUP", req.Group, req.Consumer}
+		}
+		args = append(args, "COUNT", req.Count, "BLOCK", req.Block)
+		if req.Group != "" && req.NoAck {
+			args = append(args, "NOACK")
+		}
+		args = append(args, "STREAMS")
+		for _, s := range req.Streams {
+			args = append(args, s)
+		}
+		for _, s := range req.Streams {
+			args = append(args, next[s])
+		}
+
+		readCtx, cancel := context.WithTimeout(ctx, time.Duration(req.Block)*time.Millisecond+redisRequestTimeout)
+		raw, err := reader.client.Do(readCtx, args...).Result()
+		cancel()
+
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil || isRedisClosed(err) {
+				break
+			}
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				m.emitError(conn, fmt.Sprintf("Reader stopped: %s", err.Error()), reader.metadata(map[string]interface{}{"stopped": true}))
+				return
+			}
+			m.emitError(conn, fmt.Sprintf("Read failed: %s", err.Error()), reader.metadata(nil))
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		batches, err := parseRedisStreams(raw)
+		if err != nil {
+			m.emitError(conn, fmt.Sprintf("Unexpected XREAD reply: %s", err.Error()), reader.metadata(nil))
+			continue
+		}
+
+		for _, batch := range batches {
+			for _, entry := range batch.entries {
+				metadata := reader.metadata(map[string]interface{}{"source": "read"})
+				m.emitEntry(conn, "inbound", batch.stream, entry, metadata)
+			}
+			if req.Group != "" && req.AutoAck && !req.NoAck && len(batch.entries) > 0 {
+				ids := make([]string, len(batch.entries))
+				for i, entry := range batch.entries {
+					ids[i] = entry.ID
+				}
+				ackCtx, cancel := context.WithTimeout(ctx, redisRequestTimeout)
+				if err := conn.client.XAck(ackCtx, batch.stream, req.Group, ids...).Err(); err != nil && ctx.Err() == nil {
+					m.emitError(conn, fmt.Sprintf("Auto-ack failed: %s", err.Error()), reader.metadata(nil))
+				}
+				cancel()
+			}
+
+			switch {
+			case next[batch.stream] == "$" || next[batch.stream] == ">":
+				if req.Group == "" && len(batch.entries) > 0 {
+					next[batch.stream] = batch.entries[len(batch.entries)-1].ID
+				}
+			case req.Group != "" && len(batch.entries) == 0:
+				// this consumer's pending history is done, carry on with
+				// new entries
+				next[batch.stream] = ">"
+			case len(batch.entries) > 0:
+				next[batch.stream] = batch.entries[len(batch.entries)-1].ID
+			}
+		}
+	}
+
+	m.emitSystem(conn, "Reader stopped", reader.metadata(map[string]interface{}{"stopped": true}))
+}
+
+func (m *RedisManager) Ack(req RedisAckRequest) (int64, error) {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return 0, err
+	}
+	if req.Stream == "" || req.Group == "" {
+		return 0, fmt.Errorf("stream and group are required")
+	}
+	if len(req.IDs) == 0 {
+		return 0, fmt.Errorf("no entries to acknowledge")
+	}
+
+	ctx, cancel := context.WithTimeout(conn.ctx, redisRequestTimeout)
+	defer cancel()
+
+	acked, err := conn.client.XAck(ctx, req.Stream, req.Group, req.IDs...).Result()
+	if err != nil {
+		m.emitError(conn, fmt.Sprintf("XACK failed: %s", err.Error()), nil)
+		return 0, err
+	}
+
+	m.emitSystem(conn, fmt.Sprintf("Acknowledged %d of %d entries in %s/%s", acked, len(req.IDs), req.Stream, req.Group), map[string]interface{}{
+		"stream": req.Stream,
+		"group":  req.Group,
+		"ids":    req.IDs,
+		"acked":  acked,
+	})
+	return acked, nil
+}
+
+func (m *RedisManager) Claim(req RedisClaimRequest) (*RedisClaimResult, error) {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Stream == "" || req.Group == "" || req.Consumer == "" {
+		return nil, fmt.Errorf("stream, group and consumer are required")
+	}
+	if req.MinIdle < 0 {
+		return nil, fmt.Errorf("minimum idle time can't be negative")
+	}
+
+	ctx, cancel := context.WithTimeout(conn.ctx, redisRequestTimeout)
+	defer cancel()
+
+	result := &RedisClaimResult{Claimed: []string{}}
+	var entries []RedisStreamEntry
+
+	if len(req.IDs) > 0 {
+		args := []interface{}{"XCLAIM", req.Stream, req.Group, req.Consumer, req.MinIdle}
+		for _, id := range req.IDs {
+			args = append(args, id)
+		}
+		raw, err := conn.client.Do(ctx, args...).Result()
+		if err != nil {
+			m.emitError(conn, fmt.Sprintf("XCLAIM failed: %s", err.Error()), nil)
+			return nil, err
+		}
+		if entries, err = parseRedisEntries(raw); err != nil {
+			return nil, err
+		}
+	} else {
+		start := req.Start
+		if start == "" {
+			start = "0-0"
+		}
+		count := req.Count
+		if count <= 0 {
+			count = defaultRedisCount
+		}
+		raw, err := conn.client.Do(ctx, "XAUTOCLAIM", req.Stream, req.Group, req.Consumer, req.MinIdle, start, "COUNT", count).Slice()
+		if err != nil {
+			m.emitError(conn, fmt.Sprintf("XAUTOCLAIM failed: %s", err.Error()), nil)
+			return nil, err
+		}
+		if len(raw) < 2 {
+			return nil, fmt.Errorf("unexpected XAUTOCLAIM reply")
+		}
+		result.NextStart = fmt.Sprint(raw[0])
+		if entries, err = parseRedisEntries(raw[1]); err != nil {
+			return nil, err
+		}
+		// Redis 7 also lists pending IDs whose entries were deleted
+		if len(raw) > 2 {
+			if deleted, ok := raw[2].([]interface{}); ok {
+				for _, id := range deleted {
+					result.Deleted = append(result.Deleted, fmt.Sprint(id))
+				}
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		result.Claimed = append(result.Claimed, entry.ID)
+		m.emitEntry(conn, "inbound", req.Stream, entry, map[string]interface{}{
+			"source":   "claim",
+			"group":    req.Group,
+			"consumer": req.Consumer,
+		})
+	}
+
+	m.emitSystem(conn, fmt.Sprintf("%s claimed %d entries in %s/%s", req.Consumer, len(result.Claimed), req.Stream, req.Group), map[string]interface{}{
+		"stream":   req.Stream,
+		"group":    req.Group,
+		"consumer": req.Consumer,
+		"claimed":  result.Claimed,
+	})
+	return result, nil
+}
+
+// Pending combines the XPENDING summary with the extended form's
+// per-entry list
+func (m *RedisManager) Pending(req RedisPendingRequest) (*RedisPendingResult, error) {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Stream == "" || req.Group == "" {
+		return nil, fmt.Errorf("stream and group are required")
+	}
+
+	ctx, cancel := context.WithTimeout(conn.ctx, redisRequestTimeout)
+	defer cancel()
+
+	summary, err := conn.client.XPending(ctx, req.Stream, req.Group).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RedisPendingResult{
+		Count:     summary.Count,
+		Lowest:    summary.Lower,
+		Highest:   summary.Higher,
+		Consumers: summary.Consumers,
+		Entries:   []RedisPendingEntry{},
+	}
+	if result.Consumers == nil {
+		result.Consumers = map[string]int64{}
+	}
+	if summary.Count == 0 {
+		return result, nil
+	}
+
+	args := &redis.XPendingExtArgs{
+		Stream:   req.Stream,
+		Group:    req.Group,
+		Idle:     time.Duration(req.MinIdle) * time.Millisecond,
+		Start:    req.Start,
+		End:      req.End,
+		Count:    req.Count,
+		Consumer: req.Consumer,
+	}
+	if args.Start == "" {
+		args.Start = "-"
+	}
+	if args.End == "" {
+		args.End = "+"
+	}
+	if args.Count <= 0 {
+		args.Count = defaultRedisCount
+	}
+
+	entries, err := conn.client.XPendingExt(ctx, args).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		result.Entries = append(result.Entries, RedisPendingEntry{
+			ID:         e.ID,
+			Consumer:   e.Consumer,
+			Idle:       e.Idle.Milliseconds(),
+			Deliveries: e.RetryCount,
+		})
+	}
+	return result, nil
+}
+
+func (m *RedisManager) ListGroups(connectionID, stream string) ([]RedisGroupInfo, error) {
+	conn, err := m.lookup(connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if stream == "" {
+		return nil, fmt.Errorf("stream is required")
+	}
+
+	ctx, cancel := context.WithTimeout(conn.ctx, redisRequestTimeout)
+	defer cancel()
+
+	groups, err := conn.client.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]RedisGroupInfo, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, RedisGroupInfo{
+			Name:            g.Name,
+			Consumers:       g.Consumers,
+			Pending:         g.Pending,
+			LastDeliveredID: g.LastDeliveredID,
+			Lag:             g.Lag,
+		})
+	}
+	return result, nil
+}
+
+func (m *RedisManager) CreateGroup(req RedisGroupRequest) error {
+	conn, err := m.lookup(req.ConnectionID)
+	if err != nil {
+		return err
+	}
+	if req.Stream == "" || req.Group == "" {
+		return fmt.Errorf("stream and group are required")
+	}
+
+	start := req.StartID
+	if start == "" {
+		start = "$"
+	}
+	if err := m.createGroup(conn, req.Stream, req.Group, start); err != nil {
+		m.emitError(conn, fmt.Sprintf("Failed to create group %s: %s", req.Group, err.Error()), nil)
+		return err
+	}
+	return nil
+}
+
+func (m *RedisManager) createGroup(conn *RedisConnection, stream, group, start string) error {
+	ctx, cancel := context.WithTimeout(conn.ctx, redisRequestTimeout)
+	defer cancel()
+
+	if err := conn.client.XGroupCreateMkStream(ctx, stream, group, start).Err(); err != nil {
+		return err
+	}
+	m.emitSystem(conn, fmt.Sprintf("Created group %s on %s from %s", group, stream, start), map[string]interface{}{
+		"stream": stream,
+		"group":  group,
+	})
+	return nil
+}
+
+func (r *redisReader) metadata(extra map[string]interface{}) map[string]interface{} {
+	metadata := map[string]interface{}{"readerId": r.id}
+	if r.req.Group != "" {
+		metadata["group"] = r.req.Group
+		metadata["consumer"] = r.req.Consumer
+	}
+	for k, v := range extra {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+func (m *RedisManager) emitEntry(conn *RedisConnection, direction, stream string, entry RedisStreamEntry, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["connectionId"] = conn.ID
+	metadata["stream"] = stream
+	metadata["entryId"] = entry.ID
+	metadata["fields"] = entry.Fields
+	if ms, err := strconv.ParseInt(strings.SplitN(entry.ID, "-", 2)[0], 10, 64); err == nil {
+		metadata["entryTime"] = ms
+	}
+
+	m.emitMessage(StreamMessage{
+		ID:        m.generateMessageID(),
+		Direction: direction,
+		Protocol:  "Redis",
+		Payload:   redisFieldsText(entry.Fields),
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+// redisFieldsText shows an entry as a JSON object in field order. A field
+// whose value is JSON itself is embedded rather than quoted.
+func redisFieldsText(fields []RedisField) string {
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for i, f := range fields {
+		name, _ := json.Marshal(f.Name)
+		buf.WriteString("  ")
+		buf.Write(name)
+		buf.WriteString(": ")
+
+		trimmed := strings.TrimSpace(f.Value)
+		var indented bytes.Buffer
+		if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Indent(&indented, []byte(trimmed), "  ", "  ") == nil {
+			buf.Write(indented.Bytes())
+		} else {
+			value, _ := json.Marshal(redisText(f.Value))
+			buf.Write(value)
+		}
+		if i < len(fields)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+type redisStreamBatch struct {
+	stream  string
+	entries []RedisStreamEntry
+}
+
+// parseRedisStreams reads an XREAD/XREADGROUP reply, which is an array of
+// [stream, entries] pairs over RESP2 and a map over RESP3
+func parseRedisStreams(raw interface{}) ([]redisStreamBatch, error) {
+	var batches []redisStreamBatch
+	add := func(stream, entries interface{}) error {
+		parsed, err := parseRedisEntries(entries)
+		if err != nil {
+			return err
+		}
+		batches = append(batches, redisStreamBatch{stream: fmt.Sprint(stream), entries: parsed})
+		return nil
+	}
+
+	switch v := raw.(type) {
+	case map[interface{}]interface{}:
+		for stream, entries := range v {
+			if err := add(stream, entries); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			pair, ok := item.([]interface{})
+			if !ok || len(pair) != 2 {
+				return nil, fmt.Errorf("expected [stream, entries], got %T", item)
+			}
+			if err := add(pair[0], pair[1]); err != nil {
+				return nil, err
+			}
+		}
+	case nil:
+	default:
+		return nil, fmt.Errorf("expected a list of streams, got %T", raw)
+	}
+	return batches, nil
+}
+
+// parseRedisEntries reads a list of [id, [field, value, ...]] entries.
+// XCLAIM can return nil in place of entries deleted meanwhile; those are
+// skipped.
+func parseRedisEntries(raw interface{}) ([]RedisStreamEntry, error) {
+	items, ok := raw.([]interface{})
+	if !ok {
+		if raw == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("expected a list of entries, got %T", raw)
+	}
+
+	entries := make([]RedisStreamEntry, 0, len(items))
+	for _, item := range items {
+		pair, ok := item.([]interface{})
+		if !ok || len(pair) != 2 {
+			continue
+		}
+		entry := RedisStreamEntry{ID: fmt.Sprint(pair[0]), Fields: []RedisField{}}
+		values, _ := pair[1].([]interface{})
+		for i := 0; i+1 < len(values); i += 2 {
+			entry.Fields = append(entry.Fields, RedisField{Name: fmt.Sprint(values[i]), Value: fmt.Sprint(values[i+1])})
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,544 @@
+package backend
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Replies a fakeRedis handler returns besides strings (bulk), ints, nil
+// and []interface{} (arrays)
+type (
+	respStatus string
+	respError  string
+	// respMap is a RESP3 map of alternating keys and values. Over RESP2
+	// it goes out the way XREAD sends it there, as [key, value] pairs.
+	respMap []interface{}
+)
+
+// fakeRedis answers the connection handshake itself and hands every other
+// command to handle. With resp3 it accepts HELLO 3, otherwise it rejects
+// HELLO like a Redis 5 server and the client stays on RESP2.
+type fakeRedis struct {
+	ln     net.Listener
+	resp3  bool
+	handle func(conn int, args []string) interface{}
+	closed chan int // connection numbers as they close
+	conns  int32
+}
+
+func newFakeRedis(t *testing.T, resp3 bool, handle func(conn int, args []string) interface{}) *fakeRedis {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, resp3: resp3, handle: handle, closed: make(chan int, 16)}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(int(atomic.AddInt32(&f.conns, 1)), conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(id int, conn net.Conn) {
+	defer func() {
+		conn.Close()
+		select {
+		case f.closed <- id:
+		default:
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	resp3 := false
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+
+		args[0] = strings.ToUpper(args[0])
+		var reply interface{}
+		switch args[0] {
+		case "HELLO":
+			if !f.resp3 {
+				reply = respError("ERR unknown command 'HELLO'")
+				break
+			}
+			resp3 = true
+			reply = respMap{"server", "redis", "version", "7.2.0", "proto", 3}
+		case "CLIENT":
+			reply = respError("ERR unsupported")
+			if strings.EqualFold(args[1], "SETINFO") {
+				reply = respStatus("OK")
+			}
+		case "PING":
+			reply = respStatus("PONG")
+		case "INFO":
+			reply = "# Server\r\nredis_version:7.2.0\r\n"
+		default:
+			reply = f.handle(id, args)
+		}
+
+		var out strings.Builder
+		writeRESP(&out, reply, resp3)
+		if _, err := io.WriteString(conn, out.String()); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) connect(t *testing.T, m *RedisManager) string {
+	t.Helper()
+
+	id, err := m.Connect(RedisConnectRequest{URL: f.ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Disconnect(id) })
+	return id
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("expected an array, got %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func writeRESP(b *strings.Builder, v interface{}, resp3 bool) {
+	switch v := v.(type) {
+	case nil:
+		if resp3 {
+			b.WriteString("_\r\n")
+		} else {
+			b.WriteString("*-1\r\n")
+		}
+	case respStatus:
+		fmt.Fprintf(b, "+%s\r\n", v)
+	case respError:
+		fmt.Fprintf(b, "-%s\r\n", v)
+	case int:
+		fmt.Fprintf(b, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(b, "$%d\r\n%s\r\n", len(v), v)
+	case []interface{}:
+		fmt.Fprintf(b, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeRESP(b, item, resp3)
+		}
+	case respMap:
+		if resp3 {
+			fmt.Fprintf(b, "%%%d\r\n", len(v)/2)
+			for _, item := range v {
+				writeRESP(b, item, resp3)
+			}
+			return
+		}
+		fmt.Fprintf(b, "*%d\r\n", len(v)/2)
+		for i := 0; i+1 < len(v); i += 2 {
+			writeRESP(b, []interface{}{v[i], v[i+1]}, resp3)
+		}
+	default:
+		panic(fmt.Sprintf("no RESP encoding for %T", v))
+	}
+}
+
+// entry builds a stream entry reply: [id, [field, value, ...]]
+func entry(id string, fieldValues ...interface{}) interface{} {
+	return []interface{}{id, fieldValues}
+}
+
+func entries(items ...interface{}) []interface{} {
+	return items
+}
+
+var redisProtocols = []struct {
+	name  string
+	resp3 bool
+}{{"RESP2", false}, {"RESP3", true}}
+
+func TestParseRedisStreams(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     interface{}
+		want    map[string][]RedisStreamEntry
+		wantErr bool
+	}{
+		{
+			name: "RESP2 pairs",
+			raw: []interface{}{
+				[]interface{}{"orders", entries(entry("1-1", "id", "A"), entry("1-2", "id", "B", "qty", "2"))},
+				[]interface{}{"refunds", entries()},
+			},
+			want: map[string][]RedisStreamEntry{
+				"orders": {
+					{ID: "1-1", Fields: []RedisField{{"id", "A"}}},
+					{ID: "1-2", Fields: []RedisField{{"id", "B"}, {"qty", "2"}}},
+				},
+				"refunds": {},
+			},
+		},
+		{
+			name: "RESP3 map",
+			raw: map[interface{}]interface{}{
+				"orders":  entries(entry("1-1", "id", "A")),
+				"refunds": entries(entry("2-1", "order", "A")),
+			},
+			want: map[string][]RedisStreamEntry{
+				"orders":  {{ID: "1-1", Fields: []RedisField{{"id", "A"}}}},
+				"refunds": {{ID: "2-1", Fields: []RedisField{{"order", "A"}}}},
+			},
+		},
+		{
+			name: "deleted entries and odd fields",
+			raw:  []interface{}{[]interface{}{"orders", entries(nil, entry("1-2", "id", "B", "dangling"), entry("1-3"))}},
+			want: map[string][]RedisStreamEntry{
+				"orders": {
+					{ID: "1-2", Fields: []RedisField{{"id", "B"}}},
+					{ID: "1-3", Fields: []RedisField{}},
+				},
+			},
+		},
+		{name: "timeout", raw: nil, want: map[string][]RedisStreamEntry{}},
+		{name: "not a pair", raw: []interface{}{[]interface{}{"orders"}}, wantErr: true},
+		{name: "not a list", raw: "orders", wantErr: true},
+		{name: "entries not a list", raw: []interface{}{[]interface{}{"orders", "1-1"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches, err := parseRedisStreams(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %v, want an error", batches)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string][]RedisStreamEntry)
+			for _, batch := range batches {
+				got[batch.stream] = batch.entries
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisXRangeOrder(t *testing.T) {
+	for _, proto := range redisProtocols {
+		t.Run(proto.name, func(t *testing.T) {
+			const count = 500
+			var commands []string
+			var mu sync.Mutex
+
+			fake := newFakeRedis(t, proto.resp3, func(_ int, args []string) interface{} {
+				mu.Lock()
+				commands = append(commands, strings.Join(args, " "))
+				mu.Unlock()
+
+				switch args[0] {
+				case "XRANGE", "XREVRANGE":
+					var list []interface{}
+					for i := 0; i < count; i++ {
+						list = append(list, entry(fmt.Sprintf("%d-0", i+1), "n", strconv.Itoa(i)))
+					}
+					return list
+				case "XLEN":
+					return 2 * count
+				}
+				return respError("ERR unexpected " + args[0])
+			})
+
+			app, events := newTestApp(t)
+			m := NewRedisManager(app)
+			id := fake.connect(t, m)
+
+			result, err := m.XRange(RedisRangeRequest{ConnectionID: id, Stream: "orders", Start: "(5-0", Count: count})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := RedisRangeResult{Count: count, FirstID: "1-0", LastID: fmt.Sprintf("%d-0", count), Length: 2 * count}
+			if *result != want {
+				t.Errorf("result = %+v, want %+v", *result, want)
+			}
+
+			for i := 0; i < count; i++ {
+				msg := waitForMessage(t, events, func(msg StreamMessage) bool { return msg.Direction == "inbound" })
+				if got := msg.Metadata["entryId"]; got != fmt.Sprintf("%d-0", i+1) {
+					t.Fatalf("message %d is entry %v", i, got)
+				}
+			}
+
+			if _, err := m.XRange(RedisRangeRequest{ConnectionID: id, Stream: "orders", Reverse: true}); err != nil {
+				t.Fatal(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			wantCommands := []string{
+				fmt.Sprintf("XRANGE orders (5-0 + COUNT %d", count),
+				"XLEN orders",
+				"XREVRANGE orders + - COUNT 100",
+				"XLEN orders",
+			}
+			if !reflect.DeepEqual(commands, wantCommands) {
+				t.Errorf("commands = %q, want %q", commands, wantCommands)
+			}
+		})
+	}
+}
+
+// TestRedisReaderCursor checks which IDs each XREAD or XREADGROUP asks for
+// after the replies before it
+func TestRedisReaderCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     RedisReadRequest
+		replies []interface{} // nil is a BLOCK timeout
+		want    [][]string    // IDs per read, in stream order
+	}{
+		{
+			name:    "XREAD from new entries",
+			req:     RedisReadRequest{Streams: []string{"orders"}},
+			replies: []interface{}{respMap{"orders", entries(entry("1-1", "id", "A"), entry("1-2", "id", "B"))}, nil, respMap{"orders", entries(entry("1-3", "id", "C"))}},
+			want:    [][]string{{"$"}, {"1-2"}, {"1-2"}, {"1-3"}},
+		},
+		{
+			name:    "XREAD stays on $ until something arrives",
+			req:     RedisReadRequest{Streams: []string{"orders"}, StartID: "$"},
+			replies: []interface{}{nil, nil},
+			want:    [][]string{{"$"}, {"$"}, {"$"}},
+		},
+		{
+			name:    "XREAD streams move on their own",
+			req:     RedisReadRequest{Streams: []string{"orders", "refunds"}, StartID: "0"},
+			replies: []interface{}{respMap{"refunds", entries(entry("5-1", "id", "A"))}, respMap{"orders", entries(entry("1-1", "id", "B")), "refunds", entries(entry("5-2", "id", "C"))}},
+			want:    [][]string{{"0", "0"}, {"0", "5-1"}, {"1-1", "5-2"}},
+		},
+		{
+			name:    "group reads new entries",
+			req:     RedisReadRequest{Streams: []string{"orders"}, Group: "billing", Consumer: "c1"},
+			replies: []interface{}{respMap{"orders", entries(entry("1-1", "id", "A"))}},
+			want:    [][]string{{">"}, {">"}},
+		},
+		{
+			name: "group history then new entries",
+			req:  RedisReadRequest{Streams: []string{"orders"}, Group: "billing", Consumer: "c1", StartID: "0"},
+			replies: []interface{}{
+				respMap{"orders", entries(entry("1-1", "id", "A"), entry("1-2", "id", "B"))},
+				respMap{"orders", entries()},
+				respMap{"orders", entries(entry("1-3", "id", "C"))},
+			},
+			want: [][]string{{"0"}, {"1-2"}, {">"}, {">"}},
+		},
+	}
+
+	for _, proto := range redisProtocols {
+		for _, tt := range tests {
+			t.Run(proto.name+"/"+tt.name, func(t *testing.T) {
+				reads := make(chan []string, 16)
+				var calls int32
+
+				fake := newFakeRedis(t, proto.resp3, func(_ int, args []string) interface{} {
+					if args[0] != "XREAD" && args[0] != "XREADGROUP" {
+						return respError("ERR unexpected " + args[0])
+					}
+					if tt.req.Group != "" && (args[0] != "XREADGROUP" || args[2] != tt.req.Group || args[3] != tt.req.Consumer) {
+						return respError("ERR expected XREADGROUP GROUP billing c1")
+					}
+
+					streams := len(tt.req.Streams)
+					select {
+					case reads <- args[len(args)-streams:]:
+					default:
+					}
+
+					n := int(atomic.AddInt32(&calls, 1))
+					if n <= len(tt.replies) {
+						return tt.replies[n-1]
+					}
+					time.Sleep(10 * time.Millisecond)
+					return nil
+				})
+
+				app, events := newTestApp(t)
+				m := NewRedisManager(app)
+				id := fake.connect(t, m)
+
+				req := tt.req
+				req.ConnectionID = id
+				req.Block = 50
+				readerID, err := m.StartReader(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var got [][]string
+				for len(got) < len(tt.want) {
+					select {
+					case ids := <-reads:
+						got = append(got, ids)
+					case <-time.After(5 * time.Second):
+						t.Fatalf("only %d reads: %q", len(got), got)
+					}
+				}
+				if err := m.StopReader(id, readerID); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("read IDs %q, want %q", got, tt.want)
+				}
+
+				for len(events) > 0 {
+					if msg := <-events; msg.Direction == "error" {
+						t.Errorf("reader error: %s", msg.Payload)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRedisReaderClosesItsClient(t *testing.T) {
+	var readerConn int32
+	fake := newFakeRedis(t, true, func(conn int, args []string) interface{} {
+		if args[0] == "XREADGROUP" {
+			atomic.StoreInt32(&readerConn, int32(conn))
+			return respError("NOGROUP No such key 'orders' or consumer group 'billing'")
+		}
+		return respError("ERR unexpected " + args[0])
+	})
+
+	app, events := newTestApp(t)
+	m := NewRedisManager(app)
+	id := fake.connect(t, m)
+
+	readerID, err := m.StartReader(RedisReadRequest{ConnectionID: id, Streams: []string{"orders"}, Group: "billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := waitForMessage(t, events, func(msg StreamMessage) bool { return msg.Direction == "error" })
+	if !strings.Contains(msg.Payload, "NOGROUP") || msg.Metadata["stopped"] != true {
+		t.Errorf("error = %q %v", msg.Payload, msg.Metadata)
+	}
+
+	timeout := time.After(5 * time.Second)
+	for closed := false; !closed; {
+		select {
+		case conn := <-fake.closed:
+			closed = int32(conn) == atomic.LoadInt32(&readerConn)
+		case <-timeout:
+			t.Fatal("the reader's connection stayed open")
+		}
+	}
+
+	if err := m.StopReader(id, readerID); err == nil {
+		t.Error("a stopped reader can still be found")
+	}
+}
+
+func TestRedisAutoClaim(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   interface{}
+		want    RedisClaimResult
+		wantErr bool
+	}{
+		{
+			name:  "Redis 7 with deleted IDs",
+			reply: []interface{}{"1-5", entries(entry("1-1", "id", "A"), entry("1-3", "id", "C")), []interface{}{"1-2"}},
+			want:  RedisClaimResult{Claimed: []string{"1-1", "1-3"}, NextStart: "1-5", Deleted: []string{"1-2"}},
+		},
+		{
+			name:  "Redis 6.2",
+			reply: []interface{}{"0-0", entries(entry("1-1", "id", "A"))},
+			want:  RedisClaimResult{Claimed: []string{"1-1"}, NextStart: "0-0"},
+		},
+		{
+			name:  "nothing to claim",
+			reply: []interface{}{"0-0", entries(), []interface{}{}},
+			want:  RedisClaimResult{Claimed: []string{}, NextStart: "0-0"},
+		},
+		{name: "short reply", reply: []interface{}{"0-0"}, wantErr: true},
+		{name: "server error", reply: respError("NOGROUP No such key"), wantErr: true},
+	}
+
+	for _, proto := range redisProtocols {
+		for _, tt := range tests {
+			t.Run(proto.name+"/"+tt.name, func(t *testing.T) {
+				fake := newFakeRedis(t, proto.resp3, func(_ int, args []string) interface{} {
+					want := "XAUTOCLAIM orders billing c2 60000 0-0 COUNT 100"
+					if got := strings.Join(args, " "); got != want {
+						return respError(fmt.Sprintf("ERR got %q, want %q", got, want))
+					}
+					return tt.reply
+				})
+
+				app, _ := newTestApp(t)
+				m := NewRedisManager(app)
+				id := fake.connect(t, m)
+
+				result, err := m.Claim(RedisClaimRequest{ConnectionID: id, Stream: "orders", Group: "billing", Consumer: "c2", MinIdle: 60000})
+				if tt.wantErr {
+					if err == nil {
+						t.Errorf("got %+v, want an error", result)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(*result, tt.want) {
+					t.Errorf("got %+v, want %+v", *result, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package backend
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RunOptions selects which saved requests a headless collection run executes.
+// Each selector matches either an ID or a name; empty selects everything.
+type RunOptions struct {
+	Workspace   string
+	Collection  string
+	Environment string
+}
+
+// RunResult is the outcome of a single saved request
+type RunResult struct {
+	CollectionID   string          `json:"collectionId"`
+	CollectionName string          `json:"collectionName"`
+	RequestID      string          `json:"requestId"`
+	RequestName    string          `json:"requestName"`
+	Method         string          `json:"method"`
+	URL            string          `json:"url"`
+	StatusCode     int             `json:"statusCode"`
+	DurationMs     int64           `json:"durationMs"`
+	Passed         bool            `json:"passed"`
+	Skipped        bool            `json:"skipped,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	Timing         *ResponseTiming `json:"timing,omitempty"`
+}
+
+// RunReport summarizes a whole collection run
+type RunReport struct {
+	StartedAt   time.Time   `json:"startedAt"`
+	DurationMs  int64       `json:"durationMs"`
+	Environment string      `json:"environment,omitempty"`
+	Total       int         `json:"total"`
+	Passed      int         `json:"passed"`
+	Failed      int         `json:"failed"`
+	Skipped     int         `json:"skipped"`
+	Results     []RunResult `json:"results"`
+}
+
+// CollectionRunner executes saved collections without the desktop UI
+type CollectionRunner struct {
+	handler *HTTPHandler
+	graphql *GraphQLHandler
+}
+
+func NewCollectionRunner(handler *HTTPHandler) *CollectionRunner {
+	return &CollectionRunner{
+		handler: handler,
+		graphql: NewGraphQLHandler(handler),
+	}
+}
+
+func (r *CollectionRunner) Run(opts RunOptions) (*RunReport, error) {
+	workspaceID, err := r.resolveWorkspace(opts.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := r.resolveEnvironment(opts.Environment, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	collections, err := r.handler.LoadCollections()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load collections: %w", err)
+	}
+
+	var selected []Collection
+	for _, c := range collections {
+		if workspaceID != "" && c.WorkspaceID != workspaceID {
+			continue
+		}
+		if opts.Collection != "" && c.ID != opts.Collection && c.Name != opts.Collection {
+			continue
+		}
+		selected = append(selected, c)
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no collections matched")
+	}
+
+	report := &RunReport{
+		StartedAt: time.Now(),
+		Results:   []RunResult{},
+	}
+
+	environmentID := ""
+	if env != nil {
+		report.Environment = env.Name
+		environmentID = env.ID
+	}
+
+	for _, c := range selected {
+		scopes, err := r.handler.LoadVariableScopes(c.WorkspaceID, c.ID, environmentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load variables: %w", err)
+		}
+		resolver := NewVariableResolver(scopes)
+
+		for _, saved := range c.Requests {
+			var result RunResult
+			if saved.Request.StreamingConfig != nil {
+				// Streaming requests need the desktop UI to drive them
+				result = skippedResult(c, saved, "streaming requests are not supported by pulse run")
+			} else {
+				result = r.runRequest(c, saved, resolver)
+			}
+
+			report.Results = append(report.Results, result)
+			report.Total++
+			if result.Skipped {
+				report.Skipped++
+			} else if result.Passed {
+				report.Passed++
+			} else {
+				report.Failed++
+			}
+		}
+	}
+
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	return report, nil
+}
+
+// skippedResult records a saved request the runner cannot execute, so it
+// still shows up in the summary and reports
+func skippedResult(c Collection, saved CollectionRequest, reason string) RunResult {
+	return RunResult{
+		CollectionID:   c.ID,
+		CollectionName: c.Name,
+		RequestID:      saved.ID,
+		RequestName:    saved.Name,
+		Method:         saved.Request.Method,
+		URL:            saved.Request.URL,
+		Skipped:        true,
+		Error:          reason,
+	}
+}
+
+func (r *CollectionRunner) runRequest(c Collection, saved CollectionRequest, resolver *VariableResolver) RunResult {
+	req, err := prepareRequest(saved.Request, resolver)
+
+	result := RunResult{
+		CollectionID:   c.ID,
+		CollectionName: c.Name,
+		RequestID:      saved.ID,
+		RequestName:    saved.Name,
+		Method:         req.Method,
+		URL:            req.URL,
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	var resp *ResponseData
+	if req.BodyType == "graphql" {
+		resp, err = r.sendGraphQL(req)
+	} else {
+		resp, err = r.handler.send(req)
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.StatusCode = resp.StatusCode
+	result.Timing = resp.Timing
+	if resp.StatusCode >= 400 {
+		result.Error = fmt.Sprintf("unexpected status: %s", resp.StatusText)
+		return result
+	}
+
+	result.Passed = true
+	return result
+}
+
+// sendGraphQL sends a saved GraphQL request; validation failures and errors
+// in the response body fail the request even when the status is 200
+func (r *CollectionRunner) sendGraphQL(req RequestData) (*ResponseData, error) {
+	var opts GraphQLOptions
+	if req.GraphQL != nil {
+		opts = *req.GraphQL
+	}
+
+	resp, err := r.graphql.send(GraphQLRequest{
+		URL:            req.URL,
+		Headers:        req.Headers,
+		Auth:           req.Auth,
+		TLSProfile:     req.TLSProfile,
+		Query:          req.Body,
+		OperationName:  opts.OperationName,
+		Variables:      opts.Variables,
+		PersistedQuery: opts.PersistedQuery,
+		Uploads:        opts.Uploads,
+		SkipValidation: opts.SkipValidation,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.ValidationErrors) > 0 {
+		return nil, fmt.Errorf("invalid operation: %s", resp.ValidationErrors[0].Message)
+	}
+	if len(resp.Errors) > 0 {
+		return nil, fmt.Errorf("graphql error: %s", resp.Errors[0].Message)
+	}
+	return resp.Response, nil
+}
+
+func (r *CollectionRunner) resolveWorkspace(selector string) (string, error) {
+	if selector == "" {
+		return "", nil
+	}
+
+	workspaces, err := r.handler.LoadWorkspaces()
+	if err != nil {
+		return "", fmt.Errorf("failed to load workspaces: %w", err)
+	}
+
+	for _, ws := range workspaces {
+		if ws.ID == selector || ws.Name == selector {
+			return ws.ID, nil
+		}
+	}
+	return "", fmt.Errorf("workspace not found: %s", selector)
+}
+
+func (r *CollectionRunner) resolveEnvironment(selector, workspaceID string) (*Environment, error) {
+	if selector == "" {
+		return nil, nil
+	}
+
+	environments, err := r.handler.LoadEnvironments()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environments: %w", err)
+	}
+
+	// An ID is unique, but without a workspace the same name can exist in
+	// several of them and picking one would run against the wrong variables
+	var named []*Environment
+	for i, env := range environments {
+		if workspaceID != "" && env.WorkspaceID != workspaceID {
+			continue
+		}
+		if env.ID == selector {
+			return &environments[i], nil
+		}
+		if env.Name == selector {
+			named = append(named, &environments[i])
+		}
+	}
+
+	switch len(named) {
+	case 0:
+		return nil, fmt.Errorf("environment not found: %s", selector)
+	case 1:
+		return named[0], nil
+	}
+	return nil, fmt.Errorf("environment %s exists in %d workspaces; select one with -workspace or pass the environment ID", selector, len(named))
+}
+
+// prepareRequest substitutes variables and folds enabled params into the URL.
+// The runner and the request builder both send through it.
+func prepareRequest(req RequestData, resolver *VariableResolver) (RequestData, error) {
+	out, err := resolver.ResolveRequestData(req)
+	if err != nil {
+		return out, err
+	}
+
+	var query []string
+	for _, p := range out.Params {
+		if !p.Enabled || p.Key == "" {
+			continue
+		}
+		query = append(query, url.QueryEscape(p.Key)+"="+url.QueryEscape(p.Value))
+	}
+	if len(query) > 0 {
+		sep := "?"
+		if strings.Contains(out.URL, "?") {
+			sep = "&"
+		}
+		out.URL += sep + strings.Join(query, "&")
+	}
+
+	return out, nil
+}
+
+func (rep *RunReport) WriteSummary(w io.Writer) {
+	current := ""
+	for _, res := range rep.Results {
+		if res.CollectionName != current {
+			current = res.CollectionName
+			fmt.Fprintf(w, "\n%s\n", current)
+		}
+
+		status := "PASS"
+		if res.Skipped {
+			status = "SKIP"
+		} else if !res.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "  %s  %s %s (%dms)\n", status, res.Method, res.RequestName, res.DurationMs)
+		if res.Error != "" {
+			fmt.Fprintf(w, "        %s\n", res.Error)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d requests, %d passed, %d failed, %d skipped in %dms\n", rep.Total, rep.Passed, rep.Failed, rep.Skipped, rep.DurationMs)
+}
+
+func (rep *RunReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (rep *RunReport) WriteJUnit(w io.Writer) error {
+	suites := junitTestSuites{
+		Name:     "pulse",
+		Tests:    rep.Total,
+		Failures: rep.Failed,
+		Skipped:  rep.Skipped,
+		Time:     junitSeconds(rep.DurationMs),
+	}
+
+	index := make(map[string]int)
+	var suiteMs []int64
+	for _, res := range rep.Results {
+		i, ok := index[res.CollectionID]
+		if !ok {
+			i = len(suites.Suites)
+			index[res.CollectionID] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: res.CollectionName})
+			suiteMs = append(suiteMs, 0)
+		}
+
+		tc := junitTestCase{
+			Name:      res.RequestName,
+			ClassName: res.CollectionName,
+			Time:      junitSeconds(res.DurationMs),
+		}
+		if res.Skipped {
+			tc.Skipped = &junitSkipped{Message: res.Error}
+			suites.Suites[i].Skipped++
+		} else if !res.Passed {
+			tc.Failure = &junitFailure{
+				Message: res.Error,
+				Type:    "RequestFailure",
+				Text:    fmt.Sprintf("%s %s\n%s", res.Method, res.URL, res.Error),
+			}
+			suites.Suites[i].Failures++
+		}
+
+		suites.Suites[i].Tests++
+		suites.Suites[i].Cases = append(suites.Suites[i].Cases, tc)
+		suiteMs[i] += res.DurationMs
+	}
+
+	for i := range suites.Suites {
+		suites.Suites[i].Time = junitSeconds(suiteMs[i])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitSeconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
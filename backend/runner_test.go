@@ -0,0 +1,254 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRunner stores the given data the way the desktop app does and
+// returns a runner reading from it
+func newTestRunner(t *testing.T, workspaces []Workspace, collections []Collection, environments []Environment) *CollectionRunner {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, sub := range []string{"workspaces", "collections", "environments"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewHTTPHandler(nil, dir)
+	if err := h.SaveWorkspaces(workspaces); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SaveCollections(collections); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SaveEnvironments(environments); err != nil {
+		t.Fatal(err)
+	}
+	return NewCollectionRunner(h)
+}
+
+func savedRequest(id, method, url string) CollectionRequest {
+	return CollectionRequest{ID: id, Name: id, Request: RequestData{Method: method, URL: url}}
+}
+
+func TestCollectionRunnerRun(t *testing.T) {
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("X-Env"))
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	orders := Collection{
+		ID:          "c1",
+		Name:        "Orders",
+		WorkspaceID: "w1",
+		Variables:   map[string]string{"base": server.URL},
+		Requests: []CollectionRequest{
+			{ID: "list", Name: "list", Request: RequestData{
+				Method:  "GET",
+				URL:     "{{base}}/orders",
+				Params:  []KeyValue{{Key: "page", Value: "{{page}}", Enabled: true}, {Key: "off", Value: "x"}},
+				Headers: []KeyValue{{Key: "X-Env", Value: "{{env}}", Enabled: true}},
+			}},
+			savedRequest("missing", "GET", "{{base}}/missing"),
+			{ID: "unresolved", Name: "unresolved", Request: RequestData{
+				Method: "GET",
+				URL:    "{{base}}/orders",
+				Params: []KeyValue{{Key: "q", Value: "{{nope}}", Enabled: true}},
+			}},
+			{ID: "ws", Name: "ws", Request: RequestData{
+				Method:          "WS",
+				URL:             "ws://localhost/feed",
+				StreamingConfig: &StreamingConfig{Protocol: "websocket"},
+			}},
+		},
+	}
+	other := Collection{ID: "c2", Name: "Other", WorkspaceID: "w2", Requests: []CollectionRequest{savedRequest("x", "GET", server.URL)}}
+
+	runner := newTestRunner(t,
+		[]Workspace{{ID: "w1", Name: "Shop"}, {ID: "w2", Name: "Other"}},
+		[]Collection{orders, other},
+		[]Environment{{ID: "e1", Name: "staging", WorkspaceID: "w1", Variables: map[string]string{"env": "staging", "page": "2"}}},
+	)
+
+	report, err := runner.Run(RunOptions{Workspace: "Shop", Environment: "staging"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Environment != "staging" || report.Total != 4 || report.Passed != 1 || report.Failed != 2 || report.Skipped != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	want := []string{"GET /orders?page=2 staging", "GET /missing "}
+	if strings.Join(seen, "|") != strings.Join(want, "|") {
+		t.Errorf("server saw %q, want %q", seen, want)
+	}
+
+	results := report.Results
+	if !results[0].Passed || results[0].StatusCode != 200 || results[0].URL != server.URL+"/orders?page=2" {
+		t.Errorf("list = %+v", results[0])
+	}
+	if results[1].Passed || results[1].StatusCode != 404 || !strings.Contains(results[1].Error, "unexpected status") {
+		t.Errorf("missing = %+v", results[1])
+	}
+	if results[2].Passed || !strings.Contains(results[2].Error, "nope (params[0].value)") || results[2].StatusCode != 0 {
+		t.Errorf("unresolved = %+v", results[2])
+	}
+	// the URL of a request that failed to resolve isn't extended with params
+	if results[2].URL != server.URL+"/orders" {
+		t.Errorf("unresolved URL = %s", results[2].URL)
+	}
+	if !results[3].Skipped || results[3].Passed {
+		t.Errorf("ws = %+v", results[3])
+	}
+}
+
+func TestCollectionRunnerSelection(t *testing.T) {
+	workspaces := []Workspace{{ID: "w1", Name: "Shop"}, {ID: "w2", Name: "Billing"}}
+	collections := []Collection{
+		{ID: "c1", Name: "Orders", WorkspaceID: "w1"},
+		{ID: "c2", Name: "Invoices", WorkspaceID: "w2"},
+	}
+	environments := []Environment{
+		{ID: "e1", Name: "staging", WorkspaceID: "w1"},
+		{ID: "e2", Name: "staging", WorkspaceID: "w2"},
+		{ID: "e3", Name: "prod", WorkspaceID: "w2"},
+	}
+
+	tests := []struct {
+		name    string
+		opts    RunOptions
+		wantEnv string
+		wantErr string
+	}{
+		{name: "environment by name in a workspace", opts: RunOptions{Workspace: "w2", Environment: "staging"}, wantEnv: "e2"},
+		{name: "unique environment name", opts: RunOptions{Environment: "prod"}, wantEnv: "e3"},
+		{name: "environment by ID", opts: RunOptions{Environment: "e1"}, wantEnv: "e1"},
+		{name: "ambiguous environment name", opts: RunOptions{Environment: "staging"}, wantErr: "exists in 2 workspaces"},
+		{name: "environment from another workspace", opts: RunOptions{Workspace: "Shop", Environment: "prod"}, wantErr: "environment not found"},
+		{name: "unknown workspace", opts: RunOptions{Workspace: "Ops"}, wantErr: "workspace not found"},
+		{name: "unknown collection", opts: RunOptions{Collection: "Refunds"}, wantErr: "no collections matched"},
+	}
+
+	runner := newTestRunner(t, workspaces, collections, environments)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceID, err := runner.resolveWorkspace(tt.opts.Workspace)
+			var env *Environment
+			if err == nil {
+				env, err = runner.resolveEnvironment(tt.opts.Environment, workspaceID)
+			}
+			if err == nil && tt.wantEnv == "" {
+				_, err = runner.Run(tt.opts)
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if env == nil || env.ID != tt.wantEnv {
+				t.Errorf("environment = %+v, want %s", env, tt.wantEnv)
+			}
+		})
+	}
+}
+
+func testReport() *RunReport {
+	return &RunReport{
+		DurationMs: 1500,
+		Total:      3,
+		Passed:     1,
+		Failed:     1,
+		Skipped:    1,
+		Results: []RunResult{
+			{CollectionID: "c1", CollectionName: "Orders", RequestName: "list", Method: "GET", URL: "http://api/orders", StatusCode: 200, DurationMs: 250, Passed: true},
+			{CollectionID: "c1", CollectionName: "Orders", RequestName: "create", Method: "POST", URL: "http://api/orders", StatusCode: 500, DurationMs: 1000, Error: "unexpected status: 500 Internal Server Error"},
+			{CollectionID: "c2", CollectionName: "Feeds", RequestName: "ws", Method: "WS", Skipped: true, Error: "streaming requests are not supported by pulse run"},
+		},
+	}
+}
+
+func TestRunReportWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var got RunReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Total != 3 || got.Failed != 1 || len(got.Results) != 3 || !got.Results[2].Skipped || got.Results[1].StatusCode != 500 {
+		t.Errorf("read back %+v", got)
+	}
+}
+
+func TestRunReportWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteJUnit(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("missing XML header:\n%s", buf.String())
+	}
+
+	var got junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Tests != 3 || got.Failures != 1 || got.Skipped != 1 || got.Time != "1.500" || len(got.Suites) != 2 {
+		t.Fatalf("suites = %+v", got)
+	}
+
+	orders := got.Suites[0]
+	if orders.Name != "Orders" || orders.Tests != 2 || orders.Failures != 1 || orders.Time != "1.250" {
+		t.Errorf("orders suite = %+v", orders)
+	}
+	if orders.Cases[0].Failure != nil || orders.Cases[0].Time != "0.250" {
+		t.Errorf("passing case = %+v", orders.Cases[0])
+	}
+	failure := orders.Cases[1].Failure
+	if failure == nil || failure.Type != "RequestFailure" || failure.Text != "POST http://api/orders\nunexpected status: 500 Internal Server Error" {
+		t.Errorf("failure = %+v", failure)
+	}
+
+	feeds := got.Suites[1]
+	if feeds.Skipped != 1 || feeds.Cases[0].Skipped == nil || feeds.Cases[0].Skipped.Message != "streaming requests are not supported by pulse run" {
+		t.Errorf("feeds suite = %+v", feeds)
+	}
+}
+
+func TestRunReportWriteSummary(t *testing.T) {
+	var buf bytes.Buffer
+	testReport().WriteSummary(&buf)
+
+	for _, want := range []string{
+		"\nOrders\n  PASS  GET list (250ms)\n  FAIL  POST create (1000ms)\n        unexpected status",
+		"\nFeeds\n  SKIP  WS ws (0ms)\n",
+		"3 requests, 1 passed, 1 failed, 1 skipped in 1500ms",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("summary is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,846 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/linkedin/goavro/v2"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// SchemaRegistryConfig points a Kafka connection at a Confluent-compatible
+// schema registry, used to decode framed keys and values and to serialize
+// produced ones
+type SchemaRegistryConfig struct {
+	URL           string `json:"url"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	TLSProfile    string `json:"tlsProfile,omitempty"`
+	TLSSkipVerify bool   `json:"tlsSkipVerify,omitempty"`
+}
+
+// SchemaSelection picks the schema a produced key or value is serialized
+// with. An empty Subject means "<topic>-key" or "<topic>-value" and Version
+// 0 means the latest version. MessageType picks the message of a Protobuf
+// schema and defaults to its first message.
+type SchemaSelection struct {
+	Subject     string `json:"subject"`
+	Version     int    `json:"version,omitempty"`
+	MessageType string `json:"messageType,omitempty"`
+}
+
+// Confluent wire format: a zero magic byte, then the big-endian schema ID
+const (
+	schemaMagicByte  = 0
+	schemaHeaderSize = 5
+)
+
+// schemaFailureTTL is how long a failed ID lookup is remembered. Data that
+// merely looks framed would otherwise cost a registry round trip per record.
+const schemaFailureTTL = 30 * time.Second
+
+type schemaRegistry struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+
+	mu       sync.Mutex
+	byID     map[int]*registeredSchema
+	versions map[string]int // "subject/version" -> schema ID, for fixed versions only
+	failed   map[int]schemaFailure
+}
+
+// schemaFailure is a cached error for a schema ID that failed to load
+type schemaFailure struct {
+	err     error
+	expires time.Time
+}
+
+// registeredSchema is a registry schema compiled for its type
+type registeredSchema struct {
+	id         int
+	schemaType string // "AVRO", "PROTOBUF" or "JSON"
+	avro       *goavro.Codec
+	proto      *desc.FileDescriptor
+	json       *jsonschema.Schema
+}
+
+// schemaResponse covers both /schemas/ids/{id} and
+// /subjects/{subject}/versions/{version}
+type schemaResponse struct {
+	Subject    string            `json:"subject"`
+	Version    int               `json:"version"`
+	ID         int               `json:"id"`
+	Schema     string            `json:"schema"`
+	SchemaType string            `json:"schemaType"`
+	References []schemaReference `json:"references"`
+}
+
+type schemaReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+func newSchemaRegistry(dataDir string, cfg *SchemaRegistryConfig) (*schemaRegistry, error) {
+	if cfg == nil || cfg.URL == "" {
+		return nil, nil
+	}
+
+	tlsConfig, err := clientTLSConfig(dataDir, cfg.TLSProfile, dialedHost(cfg.URL), &tls.Config{InsecureSkipVerify: cfg.TLSSkipVerify})
+	if err != nil {
+		return nil, err
+	}
+
+	return &schemaRegistry{
+		baseURL:  strings.TrimRight(cfg.URL, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+		byID:     make(map[int]*registeredSchema),
+		versions: make(map[string]int),
+		failed:   make(map[int]schemaFailure),
+	}, nil
+}
+
+func (r *schemaRegistry) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", r.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("schema registry request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read schema registry response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var regErr struct {
+			Code    int    `json:"error_code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &regErr) == nil && regErr.Message != "" {
+			return fmt.Errorf("schema registry: %s (%d)", regErr.Message, regErr.Code)
+		}
+		return fmt.Errorf("schema registry returned %s", resp.Status)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid schema registry response: %w", err)
+	}
+	return nil
+}
+
+func (r *schemaRegistry) subjects(ctx context.Context) ([]string, error) {
+	var subjects []string
+	if err := r.get(ctx, "/subjects", &subjects); err != nil {
+		return nil, err
+	}
+	sort.Strings(subjects)
+	return subjects, nil
+}
+
+func (r *schemaRegistry) subjectVersions(ctx context.Context, subject string) ([]int, error) {
+	var versions []int
+	if err := r.get(ctx, "/subjects/"+url.PathEscape(subject)+"/versions", &versions); err != nil {
+		return nil, err
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+func (r *schemaRegistry) fetchVersion(ctx context.Context, subject string, version int) (*schemaResponse, error) {
+	v := "latest"
+	if version > 0 {
+		v = fmt.Sprint(version)
+	}
+
+	var resp schemaResponse
+	if err := r.get(ctx, "/subjects/"+url.PathEscape(subject)+"/versions/"+v, &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s version %s: %w", subject, v, err)
+	}
+	return &resp, nil
+}
+
+// schemaByID returns the compiled schema a framed message points at.
+// Failures are cached for schemaFailureTTL so a stream of unknown IDs
+// doesn't block the consumer on the registry for every record.
+func (r *schemaRegistry) schemaByID(ctx context.Context, id int) (*registeredSchema, error) {
+	r.mu.Lock()
+	schema, ok := r.byID[id]
+	failure, failed := r.failed[id]
+	r.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+	if failed && time.Now().Before(failure.expires) {
+		return nil, failure.err
+	}
+
+	schema, err := r.fetchByID(ctx, id)
+	if err != nil {
+		r.mu.Lock()
+		r.failed[id] = schemaFailure{err: err, expires: time.Now().Add(schemaFailureTTL)}
+		r.mu.Unlock()
+		return nil, err
+	}
+
+	r.mu.Lock()
+	delete(r.failed, id)
+	r.mu.Unlock()
+	return schema, nil
+}
+
+func (r *schemaRegistry) fetchByID(ctx context.Context, id int) (*registeredSchema, error) {
+	var resp schemaResponse
+	if err := r.get(ctx, fmt.Sprintf("/schemas/ids/%d", id), &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch schema %d: %w", id, err)
+	}
+	resp.ID = id
+
+	return r.compile(ctx, &resp)
+}
+
+// schemaBySubject returns the compiled schema of a subject version.
+// Fixed versions never change, so their IDs are cached; latest is looked up
+// every time.
+func (r *schemaRegistry) schemaBySubject(ctx context.Context, subject string, version int) (*registeredSchema, int, error) {
+	key := fmt.Sprintf("%s/%d", subject, version)
+
+	r.mu.Lock()
+	id, ok := r.versions[key]
+	schema := r.byID[id]
+	r.mu.Unlock()
+	if ok && schema != nil {
+		return schema, version, nil
+	}
+
+	resp, err := r.fetchVersion(ctx, subject, version)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r.mu.Lock()
+	r.versions[fmt.Sprintf("%s/%d", subject, resp.Version)] = resp.ID
+	schema = r.byID[resp.ID]
+	r.mu.Unlock()
+	if schema != nil {
+		return schema, resp.Version, nil
+	}
+
+	schema, err = r.compile(ctx, resp)
+	return schema, resp.Version, err
+}
+
+func (r *schemaRegistry) compile(ctx context.Context, resp *schemaResponse) (*registeredSchema, error) {
+	refs := make(map[string]string)
+	if err := r.collectReferences(ctx, resp.References, refs); err != nil {
+		return nil, err
+	}
+
+	schema := &registeredSchema{id: resp.ID, schemaType: resp.SchemaType}
+	if schema.schemaType == "" {
+		schema.schemaType = "AVRO"
+	}
+
+	var err error
+	switch schema.schemaType {
+	case "AVRO":
+		schema.avro, err = compileAvro(resp.Schema, refs)
+	case "PROTOBUF":
+		schema.proto, err = compileProtobuf(resp.ID, resp.Schema, refs)
+	case "JSON":
+		schema.json, err = compileJSONSchema(resp.ID, resp.Schema, refs)
+	default:
+		err = fmt.Errorf("unsupported schema type: %s", schema.schemaType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema %d: %w", resp.ID, err)
+	}
+
+	r.mu.Lock()
+	r.byID[resp.ID] = schema
+	r.mu.Unlock()
+	return schema, nil
+}
+
+// collectReferences fetches referenced schemas, and theirs, keyed by the
+// name the referencing schema uses for them
+func (r *schemaRegistry) collectReferences(ctx context.Context, refs []schemaReference, out map[string]string) error {
+	for _, ref := range refs {
+		if _, ok := out[ref.Name]; ok {
+			continue
+		}
+		resp, err := r.fetchVersion(ctx, ref.Subject, ref.Version)
+		if err != nil {
+			return fmt.Errorf("failed to resolve reference %s: %w", ref.Name, err)
+		}
+		out[ref.Name] = resp.Schema
+		if err := r.collectReferences(ctx, resp.References, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode renders Confluent-framed data as JSON. ok is false when the data
+// isn't framed, so the caller shows it as-is.
+func (r *schemaRegistry) decode(ctx context.Context, data []byte) (text string, schema *registeredSchema, ok bool, err error) {
+	if len(data) < schemaHeaderSize || data[0] != schemaMagicByte {
+		return "", nil, false, nil
+	}
+
+	id := int(binary.BigEndian.Uint32(data[1:schemaHeaderSize]))
+	schema, err = r.schemaByID(ctx, id)
+	if err != nil {
+		return "", &registeredSchema{id: id}, true, err
+	}
+	body := data[schemaHeaderSize:]
+
+	switch schema.schemaType {
+	case "AVRO":
+		native, _, err := schema.avro.NativeFromBinary(body)
+		if err != nil {
+			return "", schema, true, err
+		}
+		textual, err := schema.avro.TextualFromNative(nil, native)
+		if err != nil {
+			return "", schema, true, err
+		}
+		textual, err = orderAvroFields(schema.avro, textual)
+		return string(textual), schema, true, err
+
+	case "PROTOBUF":
+		md, rest, err := schema.messageFromIndexes(body)
+		if err != nil {
+			return "", schema, true, err
+		}
+		msg := dynamic.NewMessage(md)
+		if err := msg.Unmarshal(rest); err != nil {
+			return "", schema, true, err
+		}
+		textual, err := msg.MarshalJSON()
+		return string(textual), schema, true, err
+
+	default: // JSON
+		return string(body), schema, true, nil
+	}
+}
+
+// encode serializes JSON input with a subject's schema and frames it
+func (r *schemaRegistry) encode(ctx context.Context, sel SchemaSelection, input string) ([]byte, *registeredSchema, int, error) {
+	schema, version, err := r.schemaBySubject(ctx, sel.Subject, sel.Version)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	out := make([]byte, schemaHeaderSize, schemaHeaderSize+len(input))
+	out[0] = schemaMagicByte
+	binary.BigEndian.PutUint32(out[1:], uint32(schema.id))
+
+	switch schema.schemaType {
+	case "AVRO":
+		native, _, err := schema.avro.NativeFromTextual([]byte(input))
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("input does not match Avro schema: %w", err)
+		}
+		out, err = schema.avro.BinaryFromNative(out, native)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("input does not match Avro schema: %w", err)
+		}
+
+	case "PROTOBUF":
+		md, indexes, err := schema.messageByName(sel.MessageType)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		msg := dynamic.NewMessage(md)
+		if err := msg.UnmarshalJSON([]byte(input)); err != nil {
+			return nil, nil, 0, fmt.Errorf("input does not match %s: %w", md.GetFullyQualifiedName(), err)
+		}
+		encoded, err := msg.Marshal()
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		out = appendMessageIndexes(out, indexes)
+		out = append(out, encoded...)
+
+	default: // JSON
+		doc, err := decodeJSONDocument(input)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if err := schema.json.Validate(doc); err != nil {
+			return nil, nil, 0, fmt.Errorf("input does not match JSON schema: %w", err)
+		}
+		out = append(out, input...)
+	}
+
+	return out, schema, version, nil
+}
+
+func (s *registeredSchema) String() string {
+	if s.schemaType == "" {
+		return fmt.Sprintf("#%d", s.id)
+	}
+	return fmt.Sprintf("%s #%d", s.schemaType, s.id)
+}
+
+// messageFromIndexes reads the message-index path Confluent puts before
+// Protobuf payloads: a zigzag varint count, then that many indexes into
+// nested message types. A lone 0 is shorthand for the first message.
+func (s *registeredSchema) messageFromIndexes(data []byte) (*desc.MessageDescriptor, []byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 {
+		return nil, nil, fmt.Errorf("invalid message indexes")
+	}
+	data = data[n:]
+
+	indexes := []int{0}
+	if count > 0 {
+		indexes = make([]int, count)
+		for i := range indexes {
+			idx, n := binary.Varint(data)
+			if n <= 0 {
+				return nil, nil, fmt.Errorf("invalid message indexes")
+			}
+			indexes[i] = int(idx)
+			data = data[n:]
+		}
+	}
+
+	messages := s.proto.GetMessageTypes()
+	var md *desc.MessageDescriptor
+	for _, idx := range indexes {
+		if idx < 0 || idx >= len(messages) {
+			return nil, nil, fmt.Errorf("message index %v out of range", indexes)
+		}
+		md = messages[idx]
+		messages = md.GetNestedMessageTypes()
+	}
+	return md, data, nil
+}
+
+// messageByName finds a message by full or short name, returning its
+// index path for the wire header
+func (s *registeredSchema) messageByName(name string) (*desc.MessageDescriptor, []int, error) {
+	if s.schemaType != "PROTOBUF" {
+		return nil, nil, fmt.Errorf("schema #%d is not Protobuf", s.id)
+	}
+
+	var search func(messages []*desc.MessageDescriptor, path []int) (*desc.MessageDescriptor, []int)
+	search = func(messages []*desc.MessageDescriptor, path []int) (*desc.MessageDescriptor, []int) {
+		for i, md := range messages {
+			p := append(append([]int{}, path...), i)
+			if name == "" || md.GetFullyQualifiedName() == name || md.GetName() == name {
+				return md, p
+			}
+			if found, fp := search(md.GetNestedMessageTypes(), p); found != nil {
+				return found, fp
+			}
+		}
+		return nil, nil
+	}
+
+	md, path := search(s.proto.GetMessageTypes(), nil)
+	if md == nil {
+		if name == "" {
+			return nil, nil, fmt.Errorf("schema #%d has no messages", s.id)
+		}
+		return nil, nil, fmt.Errorf("message %s not found in schema #%d", name, s.id)
+	}
+	return md, path, nil
+}
+
+func appendMessageIndexes(out []byte, indexes []int) []byte {
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return append(out, 0)
+	}
+	out = binary.AppendVarint(out, int64(len(indexes)))
+	for _, idx := range indexes {
+		out = binary.AppendVarint(out, int64(idx))
+	}
+	return out
+}
+
+// compileAvro builds a codec that reads and writes plain JSON, without Avro's
+// union wrappers. goavro has no shared type registry, so named types from
+// references are inlined where the schema first mentions them.
+func compileAvro(schema string, refs map[string]string) (*goavro.Codec, error) {
+	if len(refs) == 0 {
+		return goavro.NewCodecForStandardJSONFull(schema)
+	}
+
+	named := make(map[string]interface{}, len(refs))
+	for name, text := range refs {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+			return nil, fmt.Errorf("invalid referenced schema %s: %w", name, err)
+		}
+		named[name] = parsed
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		return nil, err
+	}
+
+	inlined, err := json.Marshal(inlineAvroReferences(parsed, named, map[string]bool{}))
+	if err != nil {
+		return nil, err
+	}
+	return goavro.NewCodecForStandardJSONFull(string(inlined))
+}
+
+func inlineAvroReferences(node interface{}, named map[string]interface{}, defined map[string]bool) interface{} {
+	switch v := node.(type) {
+	case string:
+		if ref, ok := named[v]; ok && !defined[v] {
+			defined[v] = true
+			return inlineAvroReferences(ref, named, defined)
+		}
+		return v
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = inlineAvroReferences(item, named, defined)
+		}
+		return out
+	case map[string]interface{}:
+		if name, ok := v["name"].(string); ok {
+			full := name
+			if ns, ok := v["namespace"].(string); ok && !strings.Contains(name, ".") {
+				full = ns + "." + name
+			}
+			defined[full] = true
+		}
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			switch key {
+			case "type", "items", "values", "fields":
+				out[key] = inlineAvroReferences(value, named, defined)
+			default:
+				out[key] = value
+			}
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// orderAvroFields puts decoded record fields back in schema order. goavro
+// writes records from a map, so its JSON comes out in random field order.
+func orderAvroFields(codec *goavro.Codec, textual []byte) ([]byte, error) {
+	var schema interface{}
+	if err := json.Unmarshal([]byte(codec.Schema()), &schema); err != nil {
+		return textual, nil
+	}
+	value, err := decodeJSONDocument(string(textual))
+	if err != nil {
+		return nil, err
+	}
+
+	named := make(map[string]interface{})
+	collectAvroNames(schema, "", named)
+	return json.Marshal(orderAvroValue(schema, value, "", named))
+}
+
+// avroFullName qualifies a named type with its namespace, or the enclosing one
+func avroFullName(def map[string]interface{}, namespace string) string {
+	name, _ := def["name"].(string)
+	if ns, ok := def["namespace"].(string); ok {
+		namespace = ns
+	}
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func avroNamespace(fullName string) string {
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		return fullName[:i]
+	}
+	return ""
+}
+
+func collectAvroNames(node interface{}, namespace string, named map[string]interface{}) {
+	switch v := node.(type) {
+	case []interface{}:
+		for _, item := range v {
+			collectAvroNames(item, namespace, named)
+		}
+	case map[string]interface{}:
+		if _, ok := v["name"].(string); ok {
+			full := avroFullName(v, namespace)
+			named[full] = v
+			namespace = avroNamespace(full)
+		}
+		for _, key := range []string{"type", "items", "values"} {
+			collectAvroNames(v[key], namespace, named)
+		}
+		if fields, ok := v["fields"].([]interface{}); ok {
+			for _, field := range fields {
+				if f, ok := field.(map[string]interface{}); ok {
+					collectAvroNames(f["type"], namespace, named)
+				}
+			}
+		}
+	}
+}
+
+func resolveAvroName(schema interface{}, namespace string, named map[string]interface{}) interface{} {
+	name, ok := schema.(string)
+	if !ok {
+		return schema
+	}
+	if def, ok := named[name]; ok {
+		return def
+	}
+	if def, ok := named[namespace+"."+name]; ok && namespace != "" {
+		return def
+	}
+	return schema
+}
+
+func orderAvroValue(schema, value interface{}, namespace string, named map[string]interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	switch s := resolveAvroName(schema, namespace, named).(type) {
+	case []interface{}:
+		// Union: the standard JSON codec drops the wrapper, so pick the
+		// first branch whose shape fits the value
+		for _, branch := range s {
+			if avroShapeMatches(resolveAvroName(branch, namespace, named), value) {
+				return orderAvroValue(branch, value, namespace, named)
+			}
+		}
+		return value
+
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record", "error":
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				return value
+			}
+			fieldNS := avroNamespace(avroFullName(s, namespace))
+			fields, _ := s["fields"].([]interface{})
+			out := make(orderedObject, 0, len(fields))
+			for _, field := range fields {
+				f, _ := field.(map[string]interface{})
+				name, _ := f["name"].(string)
+				if v, ok := obj[name]; ok {
+					out = append(out, orderedField{key: name, value: orderAvroValue(f["type"], v, fieldNS, named)})
+				}
+			}
+			return out
+		case "array":
+			items, ok := value.([]interface{})
+			if !ok {
+				return value
+			}
+			for i, item := range items {
+				items[i] = orderAvroValue(s["items"], item, namespace, named)
+			}
+			return items
+		case "map":
+			entries, ok := value.(map[string]interface{})
+			if !ok {
+				return value
+			}
+			for key, item := range entries {
+				entries[key] = orderAvroValue(s["values"], item, namespace, named)
+			}
+			return entries
+		case "enum", "fixed":
+			return value
+		default:
+			// {"type": "string", "logicalType": ...} or a nested type
+			return orderAvroValue(s["type"], value, namespace, named)
+		}
+	}
+	return value
+}
+
+func avroShapeMatches(schema, value interface{}) bool {
+	kind := schema
+	if def, ok := schema.(map[string]interface{}); ok {
+		kind = def["type"]
+	}
+	switch value.(type) {
+	case map[string]interface{}:
+		return kind == "record" || kind == "error" || kind == "map"
+	case []interface{}:
+		return kind == "array"
+	default:
+		return kind != "null" && kind != "record" && kind != "error" && kind != "map" && kind != "array"
+	}
+}
+
+// compileProtobuf parses a registry .proto with its references as imports.
+// Google's well-known types come from the bundled descriptors, as registries
+// usually don't store them.
+func compileProtobuf(id int, schema string, refs map[string]string) (*desc.FileDescriptor, error) {
+	name := fmt.Sprintf("registry-%d.proto", id)
+	files := map[string]string{name: schema}
+	for refName, text := range refs {
+		files[refName] = text
+	}
+
+	parser := protoparse.Parser{
+		Accessor:          protoparse.FileContentsFromMap(files),
+		LookupImportProto: bundledImport,
+	}
+	fds, err := parser.ParseFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	return fds[0], nil
+}
+
+func compileJSONSchema(id int, schema string, refs map[string]string) (*jsonschema.Schema, error) {
+	const base = "mem://registry/"
+
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = func(s string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("%s is not among the schema's references", strings.TrimPrefix(s, base))
+	}
+	for refName, text := range refs {
+		if err := compiler.AddResource(base+refName, strings.NewReader(text)); err != nil {
+			return nil, fmt.Errorf("invalid referenced schema %s: %w", refName, err)
+		}
+	}
+
+	main := fmt.Sprintf("%sschema-%d.json", base, id)
+	if err := compiler.AddResource(main, strings.NewReader(schema)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(main)
+}
+
+func decodeJSONDocument(input string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return doc, nil
+}
+
+// decodeRecordData describes a consumed key or value, decoding it through
+// the registry when it is schema-framed
+func decodeRecordData(registry *schemaRegistry, data []byte) KafkaRecordData {
+	record := newRecordData(data)
+	if registry == nil {
+		return record
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	decoded, schema, ok, err := registry.decode(ctx, data)
+	if !ok {
+		return record
+	}
+	record.Schema = schema.String()
+	if err != nil {
+		record.SchemaError = fmt.Sprintf("decode failed: %v", err)
+		return record
+	}
+
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, []byte(decoded), "", "  ") == nil {
+		decoded = pretty.String()
+	}
+	record.Text = decoded
+	record.Format = "json"
+	return record
+}
+
+// serializeRecordData encodes a produced key or value through the registry
+// when a schema is selected, and otherwise sends the text as-is
+func serializeRecordData(ctx context.Context, conn *KafkaConnection, sel *SchemaSelection, defaultSubject, input string) ([]byte, string, error) {
+	if sel == nil {
+		return []byte(input), "", nil
+	}
+	if conn.registry == nil {
+		return nil, "", fmt.Errorf("no schema registry configured for this connection")
+	}
+
+	selection := *sel
+	if selection.Subject == "" {
+		selection.Subject = defaultSubject
+	}
+
+	data, schema, version, err := conn.registry.encode(ctx, selection, input)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, fmt.Sprintf("%s (%s v%d)", schema, selection.Subject, version), nil
+}
+
+func KafkaListSchemaSubjects(app AppInterface, connectionID string) ([]string, error) {
+	conn, err := lookupKafkaConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.registry == nil {
+		return nil, fmt.Errorf("no schema registry configured for this connection")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return conn.registry.subjects(ctx)
+}
+
+func KafkaListSchemaVersions(app AppInterface, connectionID, subject string) ([]int, error) {
+	conn, err := lookupKafkaConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.registry == nil {
+		return nil, fmt.Errorf("no schema registry configured for this connection")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return conn.registry.subjectVersions(ctx, subject)
+}
@@ -0,0 +1,256 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testAvroSchema = `{
+	"type": "record",
+	"name": "Order",
+	"namespace": "shop",
+	"fields": [
+		{"name": "id", "type": "string"},
+		{"name": "qty", "type": "int"}
+	]
+}`
+
+const testProtoSchema = `syntax = "proto3";
+package shop;
+
+message Order {
+	string id = 1;
+	message Line {
+		string sku = 1;
+	}
+}
+
+message Refund {
+	string order_id = 1;
+}
+`
+
+// fakeRegistry serves a fixed set of schemas the way a Confluent registry
+// does and counts requests per path
+type fakeRegistry struct {
+	mu       sync.Mutex
+	requests map[string]int
+	schemas  map[string]schemaResponse // path -> response
+}
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *schemaRegistry) {
+	t.Helper()
+
+	fake := &fakeRegistry{
+		requests: make(map[string]int),
+		schemas: map[string]schemaResponse{
+			"/schemas/ids/1":                    {Schema: testAvroSchema},
+			"/subjects/orders-value/versions/1": {Subject: "orders-value", Version: 1, ID: 1, Schema: testAvroSchema},
+			"/subjects/orders-value/versions/latest": {
+				Subject: "orders-value", Version: 1, ID: 1, Schema: testAvroSchema,
+			},
+			"/schemas/ids/2": {Schema: testProtoSchema, SchemaType: "PROTOBUF"},
+			"/subjects/refunds-value/versions/latest": {
+				Subject: "refunds-value", Version: 3, ID: 2, Schema: testProtoSchema, SchemaType: "PROTOBUF",
+			},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		fake.requests[r.URL.Path]++
+		resp, ok := fake.schemas[r.URL.Path]
+		fake.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error_code": 40403, "message": "Schema not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	registry, err := newSchemaRegistry(t.TempDir(), &SchemaRegistryConfig{URL: server.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, registry
+}
+
+func (f *fakeRegistry) count(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path]
+}
+
+func TestSchemaRegistryAvroRoundTrip(t *testing.T) {
+	fake, registry := newFakeRegistry(t)
+	ctx := context.Background()
+
+	data, schema, version, err := registry.encode(ctx, SchemaSelection{Subject: "orders-value"}, `{"id": "A-1", "qty": 3}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.id != 1 || version != 1 {
+		t.Fatalf("encoded with schema %d v%d, want 1 v1", schema.id, version)
+	}
+	if !bytes.Equal(data[:schemaHeaderSize], []byte{0, 0, 0, 0, 1}) {
+		t.Fatalf("header = % x, want magic byte and big-endian ID 1", data[:schemaHeaderSize])
+	}
+
+	text, decodedWith, ok, err := registry.decode(ctx, data)
+	if err != nil || !ok {
+		t.Fatalf("decode: ok=%v err=%v", ok, err)
+	}
+	if text != `{"id":"A-1","qty":3}` {
+		t.Errorf("decoded %s", text)
+	}
+	if decodedWith.String() != "AVRO #1" {
+		t.Errorf("schema = %s", decodedWith)
+	}
+
+	// The encode compiled schema 1, so decoding must not fetch it by ID
+	if n := fake.count("/schemas/ids/1"); n != 0 {
+		t.Errorf("fetched /schemas/ids/1 %d times", n)
+	}
+}
+
+func TestSchemaRegistryDecodeUnframed(t *testing.T) {
+	fake, registry := newFakeRegistry(t)
+
+	for _, data := range [][]byte{
+		[]byte(`{"plain": true}`),
+		{0, 0, 1},
+		nil,
+	} {
+		_, _, ok, err := registry.decode(context.Background(), data)
+		if ok || err != nil {
+			t.Errorf("decode(% x): ok=%v err=%v, want passthrough", data, ok, err)
+		}
+	}
+
+	if len(fake.requests) != 0 {
+		t.Errorf("unframed data reached the registry: %v", fake.requests)
+	}
+}
+
+func TestSchemaByIDCachesFailures(t *testing.T) {
+	fake, registry := newFakeRegistry(t)
+	ctx := context.Background()
+
+	framed := []byte{0, 0, 0, 0, 99, 'x'}
+	for i := 0; i < 3; i++ {
+		_, schema, ok, err := registry.decode(ctx, framed)
+		if !ok || err == nil {
+			t.Fatalf("decode unknown ID: ok=%v err=%v", ok, err)
+		}
+		if !strings.Contains(err.Error(), "Schema not found") {
+			t.Errorf("err = %v", err)
+		}
+		if schema.id != 99 {
+			t.Errorf("schema id = %d", schema.id)
+		}
+	}
+
+	if n := fake.count("/schemas/ids/99"); n != 1 {
+		t.Errorf("fetched unknown ID %d times, want 1", n)
+	}
+}
+
+func TestSchemaByIDCachesSchemas(t *testing.T) {
+	fake, registry := newFakeRegistry(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		schema, err := registry.schemaByID(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if schema.schemaType != "AVRO" {
+			t.Errorf("schema type = %s, want AVRO default", schema.schemaType)
+		}
+	}
+	if n := fake.count("/schemas/ids/1"); n != 1 {
+		t.Errorf("fetched schema 1 %d times, want 1", n)
+	}
+}
+
+func TestSchemaBySubjectCachesFixedVersions(t *testing.T) {
+	fake, registry := newFakeRegistry(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, version, err := registry.schemaBySubject(ctx, "orders-value", 1); err != nil || version != 1 {
+			t.Fatalf("fixed version: v%d err=%v", version, err)
+		}
+		if _, version, err := registry.schemaBySubject(ctx, "orders-value", 0); err != nil || version != 1 {
+			t.Fatalf("latest: v%d err=%v", version, err)
+		}
+	}
+
+	if n := fake.count("/subjects/orders-value/versions/1"); n != 1 {
+		t.Errorf("fetched fixed version %d times, want 1", n)
+	}
+	if n := fake.count("/subjects/orders-value/versions/latest"); n != 2 {
+		t.Errorf("fetched latest %d times, want every lookup", n)
+	}
+
+	if _, _, err := registry.schemaBySubject(ctx, "missing-value", 0); err == nil {
+		t.Error("expected an error for an unknown subject")
+	}
+}
+
+func TestSchemaRegistryProtobufMessageIndexes(t *testing.T) {
+	_, registry := newFakeRegistry(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		messageType string
+		header      []byte
+		want        string
+	}{
+		{"", []byte{0}, "shop.Order"},
+		{"Refund", []byte{2, 2}, "shop.Refund"},
+		{"shop.Order.Line", []byte{4, 0, 0}, "shop.Order.Line"},
+	}
+
+	for _, tt := range tests {
+		data, schema, version, err := registry.encode(ctx, SchemaSelection{Subject: "refunds-value", MessageType: tt.messageType}, `{}`)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.messageType, err)
+		}
+		if schema.id != 2 || version != 3 {
+			t.Errorf("%s: encoded with schema %d v%d", tt.messageType, schema.id, version)
+		}
+		if got := data[schemaHeaderSize:]; !bytes.Equal(got, tt.header) {
+			t.Errorf("%s: indexes = % x, want % x", tt.messageType, got, tt.header)
+		}
+
+		md, rest, err := schema.messageFromIndexes(data[schemaHeaderSize:])
+		if err != nil {
+			t.Fatalf("%s: %v", tt.messageType, err)
+		}
+		if md.GetFullyQualifiedName() != tt.want || len(rest) != 0 {
+			t.Errorf("%s: read back %s with %d bytes left", tt.messageType, md.GetFullyQualifiedName(), len(rest))
+		}
+	}
+
+	schema, err := registry.schemaByID(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := schema.messageFromIndexes([]byte{2, 10}); err == nil {
+		t.Error("expected an out of range index to fail")
+	}
+	if _, _, err := schema.messageByName("Missing"); err == nil {
+		t.Error("expected an unknown message to fail")
+	}
+}
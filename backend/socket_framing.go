@@ -0,0 +1,266 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SocketFraming splits a TCP byte stream into messages. UDP datagrams are
+// always one message each.
+type SocketFraming struct {
+	Mode string `json:"mode"` // "none", "delimiter", "fixed" or "length-prefix"
+	// Delimiter takes escapes such as \n, \r\n, \0 or \x03
+	Delimiter        string `json:"delimiter,omitempty"`
+	IncludeDelimiter bool   `json:"includeDelimiter,omitempty"` // keep it at the end of each message
+	Length           int    `json:"length,omitempty"`           // bytes per message in fixed mode
+	PrefixBytes      int    `json:"prefixBytes,omitempty"`      // 1, 2, 4 or 8
+	LittleEndian     bool   `json:"littleEndian,omitempty"`
+	// PrefixInclusive means the length counts the prefix itself
+	PrefixInclusive bool `json:"prefixInclusive,omitempty"`
+	MaxFrameSize    int  `json:"maxFrameSize,omitempty"` // bytes, defaults to 1 MiB
+}
+
+const defaultMaxFrameSize = 1 << 20
+
+// socketFramer buffers stream data until whole messages are available
+type socketFramer struct {
+	framing   SocketFraming
+	delimiter []byte
+	maxSize   int
+	buf       []byte
+}
+
+func newSocketFramer(f SocketFraming) (*socketFramer, error) {
+	fr := &socketFramer{framing: f, maxSize: f.MaxFrameSize}
+	if fr.maxSize <= 0 {
+		fr.maxSize = defaultMaxFrameSize
+	}
+
+	switch f.Mode {
+	case "", "none":
+		fr.framing.Mode = "none"
+	case "delimiter":
+		delim, err := decodeDelimiter(f.Delimiter)
+		if err != nil {
+			return nil, err
+		}
+		if len(delim) == 0 {
+			return nil, fmt.Errorf("delimiter framing needs a delimiter")
+		}
+		fr.delimiter = delim
+	case "fixed":
+		if f.Length <= 0 {
+			return nil, fmt.Errorf("fixed framing needs a length above 0")
+		}
+	case "length-prefix":
+		switch f.PrefixBytes {
+		case 1, 2, 4, 8:
+		default:
+			return nil, fmt.Errorf("length prefix must be 1, 2, 4 or 8 bytes, got %d", f.PrefixBytes)
+		}
+	default:
+		return nil, fmt.Errorf("unknown framing mode %q", f.Mode)
+	}
+	return fr, nil
+}
+
+// feed adds received bytes and returns the messages they complete
+func (fr *socketFramer) feed(data []byte) ([][]byte, error) {
+	if fr.framing.Mode == "none" {
+		return [][]byte{append([]byte(nil), data...)}, nil
+	}
+
+	fr.buf = append(fr.buf, data...)
+
+	var frames [][]byte
+	for {
+		frame, n, err := fr.next()
+		if err != nil {
+			return frames, err
+		}
+		if n == 0 {
+			break
+		}
+		frames = append(frames, frame)
+		fr.buf = fr.buf[n:]
+	}
+
+	if len(fr.buf) > fr.maxSize {
+		return frames, fmt.Errorf("%d bytes buffered without a complete message (max %d)", len(fr.buf), fr.maxSize)
+	}
+	// compact so a long-lived connection doesn't keep every old message
+	fr.buf = append([]byte(nil), fr.buf...)
+	return frames, nil
+}
+
+// next returns the first whole message in the buffer and how many bytes it
+// used, or 0 bytes when more data is needed
+func (fr *socketFramer) next() ([]byte, int, error) {
+	switch fr.framing.Mode {
+	case "delimiter":
+		i := bytes.Index(fr.buf, fr.delimiter)
+		if i < 0 {
+			return nil, 0, nil
+		}
+		end := i + len(fr.delimiter)
+		if fr.framing.IncludeDelimiter {
+			return append([]byte(nil), fr.buf[:end]...), end, nil
+		}
+		return append([]byte(nil), fr.buf[:i]...), end, nil
+
+	case "fixed":
+		if len(fr.buf) < fr.framing.Length {
+			return nil, 0, nil
+		}
+		return append([]byte(nil), fr.buf[:fr.framing.Length]...), fr.framing.Length, nil
+
+	case "length-prefix":
+		size := fr.framing.PrefixBytes
+		if len(fr.buf) < size {
+			return nil, 0, nil
+		}
+		length := fr.readPrefix(fr.buf[:size])
+		if fr.framing.PrefixInclusive {
+			if length < uint64(size) {
+				return nil, 0, fmt.Errorf("length prefix %d is shorter than the prefix itself", length)
+			}
+			length -= uint64(size)
+		}
+		if length > uint64(fr.maxSize) {
+			return nil, 0, fmt.Errorf("message of %d bytes exceeds the %d byte limit", length, fr.maxSize)
+		}
+		end := size + int(length)
+		if len(fr.buf) < end {
+			return nil, 0, nil
+		}
+		return append([]byte(nil), fr.buf[size:end]...), end, nil
+	}
+	return nil, 0, nil
+}
+
+// rest returns what is left in the buffer when the stream ends
+func (fr *socketFramer) rest() []byte {
+	rest := fr.buf
+	fr.buf = nil
+	return rest
+}
+
+// frame wraps an outgoing message the way the peer expects to read it
+func (fr *socketFramer) frame(payload []byte) ([]byte, error) {
+	switch fr.framing.Mode {
+	case "delimiter":
+		return append(append([]byte(nil), payload...), fr.delimiter...), nil
+	case "fixed":
+		if len(payload) != fr.framing.Length {
+			return nil, fmt.Errorf("fixed framing expects %d bytes, payload has %d", fr.framing.Length, len(payload))
+		}
+		return payload, nil
+	case "length-prefix":
+		size := fr.framing.PrefixBytes
+		length := uint64(len(payload))
+		if fr.framing.PrefixInclusive {
+			length += uint64(size)
+		}
+		if size < 8 && length >= 1<<(8*size) {
+			return nil, fmt.Errorf("payload of %d bytes doesn't fit a %d byte length prefix", len(payload), size)
+		}
+		out := make([]byte, size, size+len(payload))
+		fr.writePrefix(out, length)
+		return append(out, payload...), nil
+	}
+	return payload, nil
+}
+
+func (fr *socketFramer) readPrefix(b []byte) uint64 {
+	var order binary.ByteOrder = binary.BigEndian
+	if fr.framing.LittleEndian {
+		order = binary.LittleEndian
+	}
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(order.Uint16(b))
+	case 4:
+		return uint64(order.Uint32(b))
+	default:
+		return order.Uint64(b)
+	}
+}
+
+func (fr *socketFramer) writePrefix(b []byte, length uint64) {
+	var order binary.ByteOrder = binary.BigEndian
+	if fr.framing.LittleEndian {
+		order = binary.LittleEndian
+	}
+	switch len(b) {
+	case 1:
+		b[0] = byte(length)
+	case 2:
+		order.PutUint16(b, uint16(length))
+	case 4:
+		order.PutUint32(b, uint32(length))
+	default:
+		order.PutUint64(b, length)
+	}
+}
+
+// decodeDelimiter reads a delimiter written with Go string escapes
+func decodeDelimiter(s string) ([]byte, error) {
+	unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(s, `"`, `\"`) + `"`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delimiter %q: use escapes like \\n, \\r\\n or \\x03", s)
+	}
+	return []byte(unquoted), nil
+}
+
+// decodeSocketPayload turns the text typed in the UI into bytes
+func decodeSocketPayload(payload, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "text":
+		return []byte(payload), nil
+	case "hex":
+		clean := strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) || r == ':' || r == '-' {
+				return -1
+			}
+			return r
+		}, payload)
+		clean = strings.ReplaceAll(strings.ReplaceAll(clean, "0x", ""), "0X", "")
+		data, err := hex.DecodeString(clean)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex payload: %w", err)
+		}
+		return data, nil
+	case "base64":
+		clean := strings.Join(strings.Fields(payload), "")
+		data, err := base64.StdEncoding.DecodeString(clean)
+		if err != nil {
+			if data, err = base64.RawStdEncoding.DecodeString(clean); err != nil {
+				return nil, fmt.Errorf("invalid base64 payload: %w", err)
+			}
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unknown payload encoding %q: use text, hex or base64", encoding)
+	}
+}
+
+// socketText renders bytes for the message list: valid UTF-8 as is,
+// anything else with control and invalid bytes escaped
+func socketText(data []byte) string {
+	if utf8.Valid(data) && !bytes.ContainsFunc(data, func(r rune) bool {
+		return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+	}) {
+		return string(data)
+	}
+	quoted := strconv.Quote(string(data))
+	return quoted[1 : len(quoted)-1]
+}
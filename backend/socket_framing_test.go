@@ -0,0 +1,255 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSocketFramerFeed(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing SocketFraming
+		chunks  []string
+		want    []string
+		rest    string
+	}{
+		{
+			name:    "none passes chunks through",
+			framing: SocketFraming{Mode: "none"},
+			chunks:  []string{"ab", "c"},
+			want:    []string{"ab", "c"},
+		},
+		{
+			name:    "newline delimiter",
+			framing: SocketFraming{Mode: "delimiter", Delimiter: `\n`},
+			chunks:  []string{"one\ntw", "o\n\nthr"},
+			want:    []string{"one", "two", ""},
+			rest:    "thr",
+		},
+		{
+			name:    "delimiter split across chunks",
+			framing: SocketFraming{Mode: "delimiter", Delimiter: `\r\n`, IncludeDelimiter: true},
+			chunks:  []string{"a\r", "\nb\r\n"},
+			want:    []string{"a\r\n", "b\r\n"},
+		},
+		{
+			name:    "hex escape delimiter",
+			framing: SocketFraming{Mode: "delimiter", Delimiter: `\x03`},
+			chunks:  []string{"\x02msg\x03\x02"},
+			want:    []string{"\x02msg"},
+			rest:    "\x02",
+		},
+		{
+			name:    "fixed length",
+			framing: SocketFraming{Mode: "fixed", Length: 3},
+			chunks:  []string{"abcd", "ef", "ghi"},
+			want:    []string{"abc", "def", "ghi"},
+		},
+		{
+			name:    "one byte prefix",
+			framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 1},
+			chunks:  []string{"\x02hi\x00\x03ab", "c"},
+			want:    []string{"hi", "", "abc"},
+		},
+		{
+			name:    "big-endian two byte prefix split in the prefix",
+			framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 2},
+			chunks:  []string{"\x00", "\x03abc\x00\x05ab"},
+			want:    []string{"abc"},
+			rest:    "\x00\x05ab",
+		},
+		{
+			name:    "little-endian four byte prefix",
+			framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 4, LittleEndian: true},
+			chunks:  []string{"\x02\x00\x00\x00ok"},
+			want:    []string{"ok"},
+		},
+		{
+			name:    "inclusive eight byte prefix",
+			framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 8, PrefixInclusive: true},
+			chunks:  []string{"\x00\x00\x00\x00\x00\x00\x00\x0bxyz"},
+			want:    []string{"xyz"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr, err := newSocketFramer(tt.framing)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, chunk := range tt.chunks {
+				frames, err := fr.feed([]byte(chunk))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, frame := range frames {
+					got = append(got, string(frame))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("frames = %q, want %q", got, tt.want)
+			}
+			if rest := string(fr.rest()); rest != tt.rest {
+				t.Errorf("rest = %q, want %q", rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestSocketFramerFeedErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing SocketFraming
+		data    string
+		want    []string // frames returned along with the error
+	}{
+		{
+			name:    "prefix over the limit",
+			framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 2, MaxFrameSize: 4},
+			data:    "\x00\x01a\x00\x05abcde",
+			want:    []string{"a"},
+		},
+		{
+			name:    "inclusive prefix shorter than itself",
+			framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 4, PrefixInclusive: true},
+			data:    "\x00\x00\x00\x02",
+		},
+		{
+			name:    "no delimiter within the limit",
+			framing: SocketFraming{Mode: "delimiter", Delimiter: `\n`, MaxFrameSize: 4},
+			data:    "ok\nlonger",
+			want:    []string{"ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr, err := newSocketFramer(tt.framing)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			frames, err := fr.feed([]byte(tt.data))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var got []string
+			for _, frame := range frames {
+				got = append(got, string(frame))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("frames = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSocketFramerRejects(t *testing.T) {
+	for _, f := range []SocketFraming{
+		{Mode: "delimiter"},
+		{Mode: "delimiter", Delimiter: `\q`},
+		{Mode: "fixed"},
+		{Mode: "length-prefix", PrefixBytes: 3},
+		{Mode: "lines"},
+	} {
+		if _, err := newSocketFramer(f); err == nil {
+			t.Errorf("%+v: expected an error", f)
+		}
+	}
+}
+
+func TestSocketFramerFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing SocketFraming
+		payload string
+		want    string
+		wantErr bool
+	}{
+		{name: "none", framing: SocketFraming{Mode: "none"}, payload: "abc", want: "abc"},
+		{name: "delimiter", framing: SocketFraming{Mode: "delimiter", Delimiter: `\r\n`}, payload: "abc", want: "abc\r\n"},
+		{name: "fixed", framing: SocketFraming{Mode: "fixed", Length: 3}, payload: "abc", want: "abc"},
+		{name: "fixed wrong size", framing: SocketFraming{Mode: "fixed", Length: 4}, payload: "abc", wantErr: true},
+		{name: "prefix", framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 2}, payload: "abc", want: "\x00\x03abc"},
+		{name: "little-endian prefix", framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 4, LittleEndian: true}, payload: "abc", want: "\x03\x00\x00\x00abc"},
+		{name: "inclusive prefix", framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 1, PrefixInclusive: true}, payload: "abc", want: "\x04abc"},
+		{name: "prefix too small", framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 1}, payload: strings.Repeat("x", 256), wantErr: true},
+		{name: "inclusive prefix too small", framing: SocketFraming{Mode: "length-prefix", PrefixBytes: 1, PrefixInclusive: true}, payload: strings.Repeat("x", 255), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr, err := newSocketFramer(tt.framing)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := fr.frame([]byte(tt.payload))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("framed %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, []byte(tt.want)) {
+				t.Errorf("framed %q, want %q", got, tt.want)
+			}
+
+			// what we send must read back as the same message
+			frames, err := fr.feed(got)
+			if err != nil || len(frames) != 1 || string(frames[0]) != tt.payload {
+				t.Errorf("read back %q, err %v", frames, err)
+			}
+		})
+	}
+}
+
+// Messages from one connection must reach the viewer in the order they
+// were received
+func TestSocketInboundOrder(t *testing.T) {
+	app, events := newTestApp(t)
+	s := NewSocketManager(app)
+
+	listener, err := s.Listen(SocketListenRequest{
+		Network: "tcp",
+		Address: "127.0.0.1:0",
+		Framing: SocketFraming{Mode: "delimiter", Delimiter: `\n`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.StopListening(listener.ListenerID)
+
+	conn, err := net.Dial("tcp", listener.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	const count = 200
+	var data bytes.Buffer
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&data, "%d\n", i)
+	}
+	if _, err := conn.Write(data.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < count; i++ {
+		msg := waitForMessage(t, events, func(msg StreamMessage) bool { return msg.Direction == "inbound" })
+		if msg.Payload != strconv.Itoa(i) {
+			t.Fatalf("message %d is %q", i, msg.Payload)
+		}
+	}
+}
@@ -0,0 +1,637 @@
+package backend
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SocketManager handles raw TCP and UDP sockets: outgoing connections and
+// local listeners whose accepted connections behave like outgoing ones.
+type SocketManager struct {
+	app         AppInterface
+	connections map[string]*SocketConnection
+	listeners   map[string]*SocketListener
+	mu          sync.RWMutex
+	msgCounter  uint64 // Atomic counter for unique message IDs
+	idCounter   uint64
+}
+
+type SocketConnection struct {
+	ID         string
+	Network    string // "tcp" or "udp"
+	ListenerID string // set for connections a listener accepted
+	LocalAddr  string
+	RemoteAddr string
+	Scopes     *VariableScopes
+	conn       net.Conn
+	packet     net.PacketConn // UDP listeners, which reply to any peer
+	framer     *socketFramer
+	lastPeer   net.Addr
+	closed     atomic.Bool
+	writeMu    sync.Mutex
+	mu         sync.Mutex
+}
+
+type SocketListener struct {
+	ID       string
+	Network  string
+	Address  string // the bound address, with the real port when 0 was asked for
+	Scopes   *VariableScopes
+	listener net.Listener
+	framing  SocketFraming
+	closed   atomic.Bool
+}
+
+type SocketConnectRequest struct {
+	Network        string          `json:"network"` // "tcp" or "udp"
+	Address        string          `json:"address"` // host:port
+	TLS            bool            `json:"tls"`     // tcp only
+	TLSSkipVerify  bool            `json:"tlsSkipVerify"`
+	TLSProfile     string          `json:"tlsProfile,omitempty"`
+	ConnectTimeout int             `json:"connectTimeout"` // milliseconds
+	Framing        SocketFraming   `json:"framing"`
+	Scopes         *VariableScopes `json:"scopes,omitempty"`
+}
+
+// SocketListenRequest opens a local port. TCP listeners accept any number
+// of connections; a UDP listener is itself the connection to send on.
+type SocketListenRequest struct {
+	Network string          `json:"network"` // "tcp" or "udp"
+	Address string          `json:"address"` // e.g. ":9000" or "127.0.0.1:0"
+	Framing SocketFraming   `json:"framing"`
+	Scopes  *VariableScopes `json:"scopes,omitempty"`
+}
+
+type SocketListenResult struct {
+	ListenerID   string `json:"listenerId"`
+	ConnectionID string `json:"connectionId,omitempty"` // UDP only
+	Address      string `json:"address"`
+}
+
+type SocketSendRequest struct {
+	ConnectionID string `json:"connectionId"`
+	Payload      string `json:"payload"`
+	Encoding     string `json:"encoding"` // "text", "hex" or "base64"
+	// Framed wraps the payload in the connection's framing: appends the
+	// delimiter or prepends the length
+	Framed bool   `json:"framed"`
+	Peer   string `json:"peer,omitempty"` // UDP listeners: host:port to send to, defaults to the last sender
+}
+
+// hexdumps above this size are cut short; the hex and base64 copies stay
+// complete
+const socketHexdumpLimit = 64 << 10
+
+func NewSocketManager(app AppInterface) *SocketManager {
+	return &SocketManager{
+		app:         app,
+		connections: make(map[string]*SocketConnection),
+		listeners:   make(map[string]*SocketListener),
+	}
+}
+
+func (s *SocketManager) generateMessageID() string {
+	count := atomic.AddUint64(&s.msgCounter, 1)
+	return fmt.Sprintf("msg-%d-%d", time.Now().UnixNano(), count)
+}
+
+func (s *SocketManager) generateID(prefix string) string {
+	return fmt.Sprintf("%s-%d-%d", prefix, time.Now().UnixNano(), atomic.AddUint64(&s.idCounter, 1))
+}
+
+func (s *SocketManager) Connect(req SocketConnectRequest) (string, error) {
+	target, err := resolverFor(req.Scopes).ResolveSocketConnectRequest(req)
+	if err != nil {
+		return "", err
+	}
+	if err := checkSocketNetwork(target.Network); err != nil {
+		return "", err
+	}
+	if target.Address == "" {
+		return "", fmt.Errorf("address is required")
+	}
+
+	framer, err := newSocketFramer(target.Framing)
+	if err != nil {
+		return "", err
+	}
+
+	timeout := time.Duration(target.ConnectTimeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	if target.TLS || target.TLSProfile != "" {
+		if target.Network != "tcp" {
+			return "", fmt.Errorf("TLS is only available over TCP")
+		}
+		tlsConfig, err := clientTLSConfig(s.app.GetDataDirectory(), target.TLSProfile, dialedHost(target.Address), &tls.Config{InsecureSkipVerify: target.TLSSkipVerify})
+		if err != nil {
+			return "", err
+		}
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).Dial("tcp", target.Address)
+		if err != nil {
+			return "", fmt.Errorf("failed to connect: %w", err)
+		}
+	} else {
+		conn, err = dialer.Dial(target.Network, target.Address)
+		if err != nil {
+			return "", fmt.Errorf("failed to connect: %w", err)
+		}
+	}
+
+	sc := &SocketConnection{
+		ID:         s.generateID(target.Network),
+		Network:    target.Network,
+		LocalAddr:  conn.LocalAddr().String(),
+		RemoteAddr: conn.RemoteAddr().String(),
+		Scopes:     req.Scopes,
+		conn:       conn,
+		framer:     framer,
+	}
+
+	s.mu.Lock()
+	s.connections[sc.ID] = sc
+	s.mu.Unlock()
+
+	go s.readStream(sc)
+
+	text := fmt.Sprintf("Connected to %s (%s)", sc.RemoteAddr, socketProtocol(sc.Network))
+	metadata := map[string]interface{}{"localAddr": sc.LocalAddr, "remoteAddr": sc.RemoteAddr}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		text += fmt.Sprintf(" over %s", tls.VersionName(state.Version))
+		metadata["tls"] = tls.VersionName(state.Version)
+	}
+	s.emitSystem(sc, text, metadata)
+
+	return sc.ID, nil
+}
+
+func (s *SocketManager) Listen(req SocketListenRequest) (*SocketListenResult, error) {
+	target, err := resolverFor(req.Scopes).ResolveSocketListenRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSocketNetwork(target.Network); err != nil {
+		return nil, err
+	}
+	if target.Address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+	if _, err := newSocketFramer(target.Framing); err != nil {
+		return nil, err
+	}
+
+	if target.Network == "udp" {
+		packet, err := net.ListenPacket("udp", target.Address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+
+		sc := &SocketConnection{
+			ID:        s.generateID("udp-listen"),
+			Network:   "udp",
+			LocalAddr: packet.LocalAddr().String(),
+			Scopes:    req.Scopes,
+			packet:    packet,
+		}
+		sc.ListenerID = sc.ID
+
+		s.mu.Lock()
+		s.connections[sc.ID] = sc
+		s.mu.Unlock()
+
+		go s.readPackets(sc)
+
+		s.emitSystem(sc, fmt.Sprintf("Listening on %s (UDP)", sc.LocalAddr), map[string]interface{}{"localAddr": sc.LocalAddr})
+		return &SocketListenResult{ListenerID: sc.ID, ConnectionID: sc.ID, Address: sc.LocalAddr}, nil
+	}
+
+	listener, err := net.Listen("tcp", target.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	sl := &SocketListener{
+		ID:       s.generateID("tcp-listen"),
+		Network:  "tcp",
+		Address:  listener.Addr().String(),
+		Scopes:   req.Scopes,
+		listener: listener,
+		framing:  target.Framing,
+	}
+
+	s.mu.Lock()
+	s.listeners[sl.ID] = sl
+	s.mu.Unlock()
+
+	go s.accept(sl)
+
+	s.emitMessage(StreamMessage{
+		ID:        s.generateMessageID(),
+		Direction: "system",
+		Protocol:  "TCP",
+		Payload:   fmt.Sprintf("Listening on %s (TCP)", sl.Address),
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"listenerId": sl.ID, "localAddr": sl.Address},
+	})
+
+	return &SocketListenResult{ListenerID: sl.ID, Address: sl.Address}, nil
+}
+
+func (s *SocketManager) Send(req SocketSendRequest) error {
+	sc, err := s.lookup(req.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	req, err = resolverFor(sc.Scopes).ResolveSocketSendRequest(req)
+	if err != nil {
+		return err
+	}
+
+	data, err := decodeSocketPayload(req.Payload, req.Encoding)
+	if err != nil {
+		return err
+	}
+	if req.Framed && sc.framer != nil {
+		if data, err = sc.framer.frame(data); err != nil {
+			return err
+		}
+	}
+
+	metadata := socketBytesMetadata(data)
+
+	sc.writeMu.Lock()
+	if sc.packet != nil {
+		var peer net.Addr
+		peer, err = s.udpPeer(sc, req.Peer)
+		if err == nil {
+			_, err = sc.packet.WriteTo(data, peer)
+			metadata["remoteAddr"] = peer.String()
+		}
+	} else {
+		_, err = sc.conn.Write(data)
+	}
+	sc.writeMu.Unlock()
+
+	if err != nil {
+		s.emitError(sc, fmt.Sprintf("Failed to send: %s", err.Error()), nil)
+		return err
+	}
+
+	s.emitData(sc, "outbound", data, metadata)
+	return nil
+}
+
+// Close closes one connection, including those a listener accepted
+func (s *SocketManager) Close(connectionID string) error {
+	s.mu.Lock()
+	sc, ok := s.connections[connectionID]
+	if ok {
+		delete(s.connections, connectionID)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("connection not found")
+	}
+
+	sc.closed.Store(true)
+	if sc.packet != nil {
+		sc.packet.Close()
+	} else {
+		sc.conn.Close()
+	}
+
+	s.emitSystem(sc, "Closed", map[string]interface{}{"closed": true})
+	return nil
+}
+
+// StopListening closes a listener and every connection it accepted
+func (s *SocketManager) StopListening(listenerID string) error {
+	s.mu.Lock()
+	sl, ok := s.listeners[listenerID]
+	if ok {
+		delete(s.listeners, listenerID)
+	}
+	var accepted []string
+	for id, sc := range s.connections {
+		if sc.ListenerID == listenerID {
+			accepted = append(accepted, id)
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok && len(accepted) == 0 {
+		return fmt.Errorf("listener not found")
+	}
+
+	if sl != nil {
+		sl.closed.Store(true)
+		sl.listener.Close()
+	}
+	for _, id := range accepted {
+		s.Close(id)
+	}
+
+	if sl != nil {
+		s.emitMessage(StreamMessage{
+			ID:        s.generateMessageID(),
+			Direction: "system",
+			Protocol:  "TCP",
+			Payload:   fmt.Sprintf("Stopped listening on %s", sl.Address),
+			Timestamp: time.Now(),
+			Metadata:  map[string]interface{}{"listenerId": sl.ID, "closed": true},
+		})
+	}
+	return nil
+}
+
+func (s *SocketManager) lookup(connectionID string) (*SocketConnection, error) {
+	s.mu.RLock()
+	sc, ok := s.connections[connectionID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("connection not found: %s", connectionID)
+	}
+	return sc, nil
+}
+
+func (s *SocketManager) accept(sl *SocketListener) {
+	for {
+		conn, err := sl.listener.Accept()
+		if err != nil {
+			if !sl.closed.Load() {
+				s.emitMessage(StreamMessage{
+					ID:        s.generateMessageID(),
+					Direction: "error",
+					Protocol:  "TCP",
+					Payload:   fmt.Sprintf("Listener failed: %s", err.Error()),
+					Timestamp: time.Now(),
+					Metadata:  map[string]interface{}{"listenerId": sl.ID, "closed": true},
+				})
+			}
+			return
+		}
+
+		// checked by Listen already
+		framer, _ := newSocketFramer(sl.framing)
+		sc := &SocketConnection{
+			ID:         s.generateID("tcp"),
+			Network:    "tcp",
+			ListenerID: sl.ID,
+			LocalAddr:  conn.LocalAddr().String(),
+			RemoteAddr: conn.RemoteAddr().String(),
+			Scopes:     sl.Scopes,
+			conn:       conn,
+			framer:     framer,
+		}
+
+		s.mu.Lock()
+		s.connections[sc.ID] = sc
+		s.mu.Unlock()
+
+		s.emitSystem(sc, fmt.Sprintf("Accepted connection from %s", sc.RemoteAddr), map[string]interface{}{
+			"accepted":   true,
+			"localAddr":  sc.LocalAddr,
+			"remoteAddr": sc.RemoteAddr,
+		})
+
+		go s.readStream(sc)
+	}
+}
+
+// readStream reads a TCP connection, or a dialed UDP socket where every
+// read is one datagram
+func (s *SocketManager) readStream(sc *SocketConnection) {
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := sc.conn.Read(buf)
+		if n > 0 {
+			s.onData(sc, buf[:n])
+		}
+		if err == nil {
+			continue
+		}
+		if sc.closed.Load() {
+			return
+		}
+
+		// a dialed UDP socket reports ICMP port unreachable on the next
+		// read; the socket itself is still usable
+		var opErr *net.OpError
+		if sc.Network == "udp" && errors.As(err, &opErr) && !opErr.Timeout() && !errors.Is(err, net.ErrClosed) {
+			s.emitError(sc, fmt.Sprintf("Receive failed: %s", err.Error()), nil)
+			continue
+		}
+
+		s.mu.Lock()
+		delete(s.connections, sc.ID)
+		s.mu.Unlock()
+		sc.closed.Store(true)
+		sc.conn.Close()
+
+		if sc.framer != nil {
+			if rest := sc.framer.rest(); len(rest) > 0 {
+				metadata := socketBytesMetadata(rest)
+				metadata["partial"] = true
+				s.emitData(sc, "inbound", rest, metadata)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			s.emitSystem(sc, "Connection closed by peer", map[string]interface{}{"closed": true})
+		} else {
+			s.emitError(sc, fmt.Sprintf("Connection lost: %s", err.Error()), map[string]interface{}{"closed": true})
+		}
+		return
+	}
+}
+
+func (s *SocketManager) readPackets(sc *SocketConnection) {
+	buf := make([]byte, 64<<10)
+	for {
+		n, peer, err := sc.packet.ReadFrom(buf)
+		if err != nil {
+			if sc.closed.Load() {
+				return
+			}
+			if errors.Is(err, net.ErrClosed) {
+				s.mu.Lock()
+				delete(s.connections, sc.ID)
+				s.mu.Unlock()
+				s.emitError(sc, fmt.Sprintf("Listener failed: %s", err.Error()), map[string]interface{}{"closed": true})
+				return
+			}
+			s.emitError(sc, fmt.Sprintf("Receive failed: %s", err.Error()), nil)
+			continue
+		}
+
+		sc.mu.Lock()
+		sc.lastPeer = peer
+		sc.mu.Unlock()
+
+		metadata := socketBytesMetadata(buf[:n])
+		metadata["remoteAddr"] = peer.String()
+		s.emitData(sc, "inbound", buf[:n], metadata)
+	}
+}
+
+func (s *SocketManager) onData(sc *SocketConnection, data []byte) {
+	if sc.Network == "udp" {
+		s.emitData(sc, "inbound", data, socketBytesMetadata(data))
+		return
+	}
+
+	frames, err := sc.framer.feed(data)
+	for _, frame := range frames {
+		s.emitData(sc, "inbound", frame, socketBytesMetadata(frame))
+	}
+	if err != nil {
+		// the buffer can't be trusted any more; show it raw and start over
+		rest := sc.framer.rest()
+		metadata := socketBytesMetadata(rest)
+		metadata["partial"] = true
+		s.emitError(sc, fmt.Sprintf("Framing failed: %s", err.Error()), nil)
+		s.emitData(sc, "inbound", rest, metadata)
+	}
+}
+
+// udpPeer picks where a UDP listener sends to
+func (s *SocketManager) udpPeer(sc *SocketConnection, peer string) (net.Addr, error) {
+	if peer != "" {
+		addr, err := net.ResolveUDPAddr("udp", peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer address: %w", err)
+		}
+		return addr, nil
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.lastPeer == nil {
+		return nil, fmt.Errorf("no peer to send to yet: enter a peer address or wait for a datagram")
+	}
+	return sc.lastPeer, nil
+}
+
+func (s *SocketManager) emitData(sc *SocketConnection, direction string, data []byte, metadata map[string]interface{}) {
+	metadata["connectionId"] = sc.ID
+	if sc.ListenerID != "" {
+		metadata["listenerId"] = sc.ListenerID
+	}
+	if _, ok := metadata["remoteAddr"]; !ok && sc.RemoteAddr != "" {
+		metadata["remoteAddr"] = sc.RemoteAddr
+	}
+
+	s.emitMessage(StreamMessage{
+		ID:        s.generateMessageID(),
+		Direction: direction,
+		Protocol:  socketProtocol(sc.Network),
+		Payload:   socketText(data),
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+func (s *SocketManager) emitSystem(sc *SocketConnection, text string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["connectionId"] = sc.ID
+	if sc.ListenerID != "" {
+		metadata["listenerId"] = sc.ListenerID
+	}
+
+	s.emitMessage(StreamMessage{
+		ID:        s.generateMessageID(),
+		Direction: "system",
+		Protocol:  socketProtocol(sc.Network),
+		Payload:   text,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+func (s *SocketManager) emitError(sc *SocketConnection, text string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["connectionId"] = sc.ID
+	if sc.ListenerID != "" {
+		metadata["listenerId"] = sc.ListenerID
+	}
+
+	s.emitMessage(StreamMessage{
+		ID:        s.generateMessageID(),
+		Direction: "error",
+		Protocol:  socketProtocol(sc.Network),
+		Payload:   text,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	})
+}
+
+// emitMessage emits on the caller's goroutine, like emitKafkaRecord, so
+// chunks read from one connection reach the viewer in the order they
+// arrived
+func (s *SocketManager) emitMessage(msg StreamMessage) {
+	if s.app == nil || s.app.GetCtx() == nil {
+		fmt.Printf("[Socket] Cannot emit message - app context not initialized yet\n")
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("[Socket] Event emit panic recovered: %v\n", r)
+		}
+	}()
+	emitStreamEvent(s.app.GetCtx(), msg)
+}
+
+// socketBytesMetadata carries the raw bytes alongside the text rendering
+func socketBytesMetadata(data []byte) map[string]interface{} {
+	dump := data
+	truncated := false
+	if len(dump) > socketHexdumpLimit {
+		dump, truncated = dump[:socketHexdumpLimit], true
+	}
+
+	metadata := map[string]interface{}{
+		"size":    len(data),
+		"hex":     hex.EncodeToString(data),
+		"base64":  base64.StdEncoding.EncodeToString(data),
+		"hexdump": hex.Dump(dump),
+	}
+	if truncated {
+		metadata["hexdumpTruncated"] = true
+	}
+	return metadata
+}
+
+func checkSocketNetwork(network string) error {
+	if network != "tcp" && network != "udp" {
+		return fmt.Errorf("unsupported network %q: use tcp or udp", network)
+	}
+	return nil
+}
+
+func socketProtocol(network string) string {
+	if network == "udp" {
+		return "UDP"
+	}
+	return "TCP"
+}
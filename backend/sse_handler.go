@@ -9,8 +9,6 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
-
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 var (
@@ -38,6 +36,7 @@ type SSEConnection struct {
 	WithCredentials bool
 	Headers         map[string]string
 	EventTypeFilter []string
+	Scopes          *VariableScopes
 	reconnectCount  int
 	maxReconnects   int
 }
@@ -50,6 +49,9 @@ type SSEConnectRequest struct {
 	AutoReconnect   bool              `json:"autoReconnect"`
 	Headers         map[string]string `json:"customHeaders"`
 	EventTypeFilter []string          `json:"eventTypeFilter"`
+	TLSProfile      string            `json:"tlsProfile,omitempty"`
+	TLSSkipVerify   bool              `json:"tlsSkipVerify"`
+	Scopes          *VariableScopes   `json:"scopes,omitempty"`
 }
 
 func NewSSEManager(app AppInterface) *SSEManager {
@@ -70,14 +72,24 @@ func getNextMessageID() string {
 }
 
 func (s *SSEManager) Connect(req SSEConnectRequest) (string, error) {
+	target, err := resolverFor(req.Scopes).ResolveSSEConnectRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	tlsConfig, err := clientTLSConfig(s.app.GetDataDirectory(), req.TLSProfile, dialedHost(target.URL), &tls.Config{InsecureSkipVerify: req.TLSSkipVerify})
+	if err != nil {
+		return "", err
+	}
+
 	client := &http.Client{
 		Timeout: 0, // No timeout for streaming
 		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // TODO: Make configurable
+			TLSClientConfig: tlsConfig,
 		},
 	}
 
-	httpReq, err := http.NewRequest("GET", req.URL, nil)
+	httpReq, err := http.NewRequest("GET", target.URL, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -86,12 +98,12 @@ func (s *SSEManager) Connect(req SSEConnectRequest) (string, error) {
 	httpReq.Header.Set("Cache-Control", "no-cache")
 	httpReq.Header.Set("Connection", "keep-alive")
 
-	for key, value := range req.Headers {
+	for key, value := range target.Headers {
 		httpReq.Header.Set(key, value)
 	}
 
-	if req.LastEventID != "" {
-		httpReq.Header.Set("Last-Event-ID", req.LastEventID)
+	if target.LastEventID != "" {
+		httpReq.Header.Set("Last-Event-ID", target.LastEventID)
 	}
 
 	resp, err := client.Do(httpReq)
@@ -122,10 +134,11 @@ func (s *SSEManager) Connect(req SSEConnectRequest) (string, error) {
 		Cancel:          cancel,
 		AutoReconnect:   req.AutoReconnect,
 		RetryTimeout:    req.RetryTimeout,
-		LastEventID:     req.LastEventID,
+		LastEventID:     target.LastEventID,
 		WithCredentials: req.WithCredentials,
 		Headers:         req.Headers,
 		EventTypeFilter: req.EventTypeFilter,
+		Scopes:          req.Scopes,
 		maxReconnects:   10,
 	}
 
@@ -139,7 +152,7 @@ func (s *SSEManager) Connect(req SSEConnectRequest) (string, error) {
 		ID:        getNextMessageID(),
 		Direction: "system",
 		Protocol:  "SSE",
-		Payload:   fmt.Sprintf("Connected to %s", req.URL),
+		Payload:   fmt.Sprintf("Connected to %s", target.URL),
 		Timestamp: time.Now(),
 	})
 
@@ -337,7 +350,19 @@ func (s *SSEManager) attemptReconnect(conn *SSEConnection) {
 		conn.Response.Body.Close()
 	}
 
-	httpReq, err := http.NewRequest("GET", conn.URL, nil)
+	url, headers, err := resolveEndpoint(conn.Scopes, conn.URL, conn.Headers)
+	if err != nil {
+		s.emitMessage(StreamMessage{
+			ID:        getNextMessageID(),
+			Direction: "error",
+			Protocol:  "SSE",
+			Payload:   fmt.Sprintf("Reconnection failed: %v", err),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	httpReq, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		s.emitMessage(StreamMessage{
 			ID:        getNextMessageID(),
@@ -357,7 +382,7 @@ func (s *SSEManager) attemptReconnect(conn *SSEConnection) {
 	httpReq.Header.Set("Cache-Control", "no-cache")
 	httpReq.Header.Set("Connection", "keep-alive")
 
-	for key, value := range conn.Headers {
+	for key, value := range headers {
 		httpReq.Header.Set(key, value)
 	}
 
@@ -425,7 +450,7 @@ func (s *SSEManager) emitMessage(msg StreamMessage) {
 				fmt.Printf("[SSE] Event emit panic recovered: %v\n", r)
 			}
 		}()
-		runtime.EventsEmit(s.app.GetCtx(), "stream-message", msg)
+		emitStreamEvent(s.app.GetCtx(), msg)
 		fmt.Println("[SSE] ✅ Message emitted successfully")
 	}()
 }
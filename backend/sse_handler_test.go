@@ -0,0 +1,37 @@
+package backend
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSSEVerifiesCertificates(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // the rejected handshake is expected
+	server.StartTLS()
+	defer server.Close()
+
+	app, _ := newTestApp(t)
+	s := NewSSEManager(app)
+
+	if _, err := s.Connect(SSEConnectRequest{URL: server.URL}); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("connect to an untrusted server: err = %v, want a certificate error", err)
+	}
+
+	id, err := s.Connect(SSEConnectRequest{URL: server.URL, TLSSkipVerify: true})
+	if err != nil {
+		t.Fatalf("connect with verification skipped: %v", err)
+	}
+	if err := s.Disconnect(id); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,226 @@
+package backend
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TLSProfile is a reusable TLS setup that HTTP, gRPC, WebSocket, SSE and
+// Kafka connections reference by ID.
+type TLSProfile struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	WorkspaceID string `json:"workspaceId"`
+	CACert      string `json:"caCert"`     // PEM bundle added to the system roots
+	ClientCert  string `json:"clientCert"` // PEM, used together with ClientKey for mTLS
+	ClientKey   string `json:"clientKey"`
+	ServerName  string `json:"serverName"` // SNI and verification name override
+	MinVersion  string `json:"minVersion"` // "1.0", "1.1", "1.2" or "1.3"
+	// VerifyMode is "full" (default), "ca-only" to check the chain but not
+	// the hostname, or "skip" to disable verification entirely.
+	VerifyMode string `json:"verifyMode"`
+	// SkipVerifyHosts disables verification for matching hosts only.
+	// Entries may start with "*." to match any subdomain.
+	SkipVerifyHosts []string `json:"skipVerifyHosts"`
+}
+
+type TLSProfileData struct {
+	Profiles []TLSProfile `json:"profiles"`
+}
+
+func (h *HTTPHandler) SaveTLSProfiles(profiles []TLSProfile) error {
+	data := TLSProfileData{Profiles: profiles}
+	return h.saveJSON(tlsProfilesPath(h.dataDir), data)
+}
+
+func (h *HTTPHandler) LoadTLSProfiles() ([]TLSProfile, error) {
+	profiles, err := loadTLSProfiles(h.dataDir)
+	if err != nil {
+		return []TLSProfile{}, nil
+	}
+	return profiles, nil
+}
+
+func tlsProfilesPath(dataDir string) string {
+	return filepath.Join(dataDir, "tls-profiles", "data.json")
+}
+
+func loadTLSProfiles(dataDir string) ([]TLSProfile, error) {
+	jsonData, err := os.ReadFile(tlsProfilesPath(dataDir))
+	if err != nil {
+		return nil, err
+	}
+
+	var data TLSProfileData
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return nil, err
+	}
+	return data.Profiles, nil
+}
+
+// clientTLSConfig builds the tls.Config for the profile with the given ID.
+// Names are only unique within a workspace, so they aren't matched. When no
+// profile is selected it returns fallback, which keeps each protocol's
+// previous behaviour. host is the host being dialed; see TLSProfile.Config.
+func clientTLSConfig(dataDir, profileID, host string, fallback *tls.Config) (*tls.Config, error) {
+	if profileID == "" {
+		return fallback, nil
+	}
+
+	profiles, err := loadTLSProfiles(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS profiles: %w", err)
+	}
+
+	for _, p := range profiles {
+		if p.ID == profileID {
+			return p.Config(host)
+		}
+	}
+	return nil, fmt.Errorf("TLS profile not found: %s", profileID)
+}
+
+// Config converts the profile into a client tls.Config. host is the host
+// being dialed, or empty when the config is shared by several hosts like a
+// Kafka cluster's brokers. Connections by IP address send no server name,
+// so host is what they are verified against and matched with
+// SkipVerifyHosts.
+func (p *TLSProfile) Config(host string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName: p.ServerName,
+	}
+
+	switch p.MinVersion {
+	case "", "1.2":
+		cfg.MinVersion = tls.VersionTLS12
+	case "1.0":
+		cfg.MinVersion = tls.VersionTLS10
+	case "1.1":
+		cfg.MinVersion = tls.VersionTLS11
+	case "1.3":
+		cfg.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported TLS version: %s", p.MinVersion)
+	}
+
+	var roots *x509.CertPool
+	if strings.TrimSpace(p.CACert) != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(p.CACert)) {
+			return nil, fmt.Errorf("TLS profile %s: no certificates found in CA PEM", p.Name)
+		}
+		roots = pool
+		cfg.RootCAs = pool
+	}
+
+	if p.ClientCert != "" || p.ClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(p.ClientCert), []byte(p.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("TLS profile %s: invalid client certificate: %w", p.Name, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	mode := p.VerifyMode
+	if mode == "" {
+		mode = "full"
+	}
+
+	switch mode {
+	case "full":
+		if len(p.SkipVerifyHosts) == 0 {
+			return cfg, nil
+		}
+	case "skip":
+		cfg.InsecureSkipVerify = true
+		return cfg, nil
+	case "ca-only":
+	default:
+		return nil, fmt.Errorf("unsupported verify mode: %s", p.VerifyMode)
+	}
+
+	// Standard verification can't be toggled per host or told to ignore the
+	// hostname, so take it over in VerifyConnection.
+	skipHosts := p.SkipVerifyHosts
+	override := p.ServerName
+	cfg.InsecureSkipVerify = true
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		// crypto/tls leaves ServerName empty when the name is an IP address
+		// because IPs aren't sent as SNI; an override still names the host,
+		// otherwise it's the dialed IP, which Verify matches to IP SANs.
+		name := cs.ServerName
+		if name == "" {
+			name = override
+		}
+		if name == "" {
+			name = host
+		}
+
+		if hostMatches(skipHosts, name) {
+			return nil
+		}
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("server presented no certificates")
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+		}
+		if mode == "full" {
+			// An empty DNSName would make Verify skip the hostname check
+			if name == "" {
+				return fmt.Errorf("cannot verify the server hostname when connecting by IP address; set a server name on the profile or use ca-only verification")
+			}
+			opts.DNSName = name
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+
+	return cfg, nil
+}
+
+// dialedHost returns the host a URL or host:port address connects to, or
+// an empty string when it can't tell
+func dialedHost(target string) string {
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return ""
+		}
+		return u.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return host
+	}
+	return strings.Trim(target, "[]")
+}
+
+func hostMatches(patterns []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == host {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) {
+			return true
+		}
+	}
+	return false
+}
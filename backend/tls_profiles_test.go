@@ -0,0 +1,264 @@
+package backend
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Pulse Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+// issue returns a key pair for the given DNS names and IP addresses, in
+// PEM as a profile stores it
+func (ca *testCA) issue(t *testing.T, dnsNames []string, ips []net.IP, usage x509.ExtKeyUsage) (certPEM, keyPEM string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "pulse test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+// tlsTestServer completes handshakes on 127.0.0.1 with a certificate for
+// api.test and 127.0.0.1
+func tlsTestServer(t *testing.T, ca *testCA) string {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, []string{"api.test"}, []net.IP{net.IPv4(127, 0, 0, 1)}, x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestTLSProfileVerification(t *testing.T) {
+	ca := newTestCA(t)
+	addr := tlsTestServer(t, ca)
+
+	tests := []struct {
+		name    string
+		profile TLSProfile
+		host    string // passed to Config; the test always dials 127.0.0.1
+		wantErr string
+	}{
+		{name: "full by IP", profile: TLSProfile{CACert: ca.pem}, host: "127.0.0.1"},
+		{name: "full with an untrusted CA", profile: TLSProfile{}, host: "127.0.0.1", wantErr: "unknown authority"},
+		{name: "full by name", profile: TLSProfile{CACert: ca.pem, ServerName: "api.test"}},
+		{name: "full by the wrong name", profile: TLSProfile{CACert: ca.pem, ServerName: "other.test"}, wantErr: "other.test"},
+		{name: "skip list, verified IP SAN", profile: TLSProfile{CACert: ca.pem, SkipVerifyHosts: []string{"other.test"}}, host: "127.0.0.1"},
+		{name: "skip list, IP that isn't in the certificate", profile: TLSProfile{CACert: ca.pem, SkipVerifyHosts: []string{"other.test"}}, host: "127.0.0.2", wantErr: "127.0.0.2"},
+		{name: "skip list, unknown IP", profile: TLSProfile{CACert: ca.pem, SkipVerifyHosts: []string{"other.test"}}, wantErr: "cannot verify the server hostname"},
+		{name: "skip list matches the IP", profile: TLSProfile{SkipVerifyHosts: []string{"127.0.0.1"}}, host: "127.0.0.1"},
+		{name: "skip list misses the IP", profile: TLSProfile{SkipVerifyHosts: []string{"127.0.0.2"}}, host: "127.0.0.1", wantErr: "unknown authority"},
+		{name: "skip list, verified override", profile: TLSProfile{CACert: ca.pem, ServerName: "api.test", SkipVerifyHosts: []string{"other.test"}}, host: "127.0.0.1"},
+		{name: "skip list wildcard", profile: TLSProfile{ServerName: "api.test", SkipVerifyHosts: []string{"*.TEST"}}},
+		{name: "ca-only ignores the name", profile: TLSProfile{CACert: ca.pem, ServerName: "other.test", VerifyMode: "ca-only"}},
+		{name: "ca-only by IP", profile: TLSProfile{CACert: ca.pem, VerifyMode: "ca-only"}},
+		{name: "ca-only with an untrusted CA", profile: TLSProfile{VerifyMode: "ca-only"}, wantErr: "unknown authority"},
+		{name: "skip", profile: TLSProfile{ServerName: "other.test", VerifyMode: "skip"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.profile.Config(tt.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := tls.Dial("tcp", addr, cfg)
+			if err == nil {
+				conn.Close()
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("handshake failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSProfileConfig(t *testing.T) {
+	ca := newTestCA(t)
+	clientCert, clientKey := ca.issue(t, nil, nil, x509.ExtKeyUsageClientAuth)
+
+	versions := map[string]uint16{"": tls.VersionTLS12, "1.0": tls.VersionTLS10, "1.1": tls.VersionTLS11, "1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13}
+	for v, want := range versions {
+		cfg, err := (&TLSProfile{MinVersion: v}).Config("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.MinVersion != want {
+			t.Errorf("min version %q = %x, want %x", v, cfg.MinVersion, want)
+		}
+	}
+
+	cfg, err := (&TLSProfile{ServerName: "api.test", ClientCert: clientCert, ClientKey: clientKey}).Config("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerName != "api.test" || len(cfg.Certificates) != 1 || cfg.InsecureSkipVerify || cfg.VerifyConnection != nil {
+		t.Errorf("full verification config = %+v", cfg)
+	}
+
+	invalid := []struct {
+		name    string
+		profile TLSProfile
+		want    string
+	}{
+		{name: "version", profile: TLSProfile{MinVersion: "1.4"}, want: "unsupported TLS version: 1.4"},
+		{name: "mode", profile: TLSProfile{VerifyMode: "lenient"}, want: "unsupported verify mode: lenient"},
+		{name: "CA PEM", profile: TLSProfile{Name: "corp", CACert: "not a certificate"}, want: "TLS profile corp: no certificates found in CA PEM"},
+		{name: "client key pair", profile: TLSProfile{Name: "corp", ClientCert: clientCert}, want: "TLS profile corp: invalid client certificate"},
+	}
+	for _, tt := range invalid {
+		if _, err := tt.profile.Config(""); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Dir(tlsProfilesPath(dir)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHTTPHandler(nil, dir)
+	if err := h.SaveTLSProfiles([]TLSProfile{
+		{ID: "p1", Name: "corp", WorkspaceID: "w1", ServerName: "one.test"},
+		{ID: "p2", Name: "corp", WorkspaceID: "w2", ServerName: "two.test"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	fallback := &tls.Config{}
+	if cfg, err := clientTLSConfig(dir, "", "", fallback); err != nil || cfg != fallback {
+		t.Errorf("no profile: got %v, %v; want the fallback", cfg, err)
+	}
+	if cfg, err := clientTLSConfig(dir, "p2", "", fallback); err != nil || cfg.ServerName != "two.test" {
+		t.Errorf("p2: got %+v, %v", cfg, err)
+	}
+	if _, err := clientTLSConfig(dir, "corp", "", fallback); err == nil || !strings.Contains(err.Error(), "TLS profile not found: corp") {
+		t.Errorf("lookup by name: err = %v, want not found", err)
+	}
+}
+
+func TestHostMatches(t *testing.T) {
+	patterns := []string{" Internal.Example ", "*.dev.example", "10.0.0.5"}
+
+	for host, want := range map[string]bool{
+		"internal.example":     true,
+		"INTERNAL.example":     true,
+		"api.dev.example":      true,
+		"a.b.dev.example":      true,
+		"dev.example":          false,
+		"evil-dev.example":     false,
+		"10.0.0.5":             true,
+		"10.0.0.50":            false,
+		"":                     false,
+		"internal.example.com": false,
+	} {
+		if got := hostMatches(patterns, host); got != want {
+			t.Errorf("hostMatches(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestDialedHost(t *testing.T) {
+	for target, want := range map[string]string{
+		"https://api.test:8443/v1": "api.test",
+		"wss://[::1]:443/ws":       "::1",
+		"mqtt://10.0.0.5":          "10.0.0.5",
+		"10.0.0.5:9092":            "10.0.0.5",
+		"[fe80::1]:6379":           "fe80::1",
+		"localhost":                "localhost",
+		"https://%zz":              "",
+	} {
+		if got := dialedHost(target); got != want {
+			t.Errorf("dialedHost(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
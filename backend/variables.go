@@ -0,0 +1,513 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var variablePattern = regexp.MustCompile(`\{\{([^}]+)\}\}`)
+
+// VariableScopes holds every source a {{variable}} can come from. When the
+// same name is defined more than once the narrower scope wins:
+// global < workspace < collection < environment.
+type VariableScopes struct {
+	Global      map[string]string `json:"global,omitempty"`
+	Workspace   map[string]string `json:"workspace,omitempty"`
+	Collection  map[string]string `json:"collection,omitempty"`
+	Environment *Environment      `json:"environment,omitempty"`
+}
+
+// UnresolvedVariable names a placeholder that no scope defines and the
+// request field it was found in, e.g. "headers[2].value" or "metadata.token".
+// Reason is set when a dynamic variable failed to evaluate.
+type UnresolvedVariable struct {
+	Name   string `json:"name"`
+	Field  string `json:"field"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// UnresolvedVariablesError is returned when substitution leaves placeholders
+// behind. The resolved value is still returned alongside it.
+type UnresolvedVariablesError struct {
+	Variables []UnresolvedVariable `json:"variables"`
+}
+
+func (e *UnresolvedVariablesError) Error() string {
+	parts := make([]string, 0, len(e.Variables))
+	for _, v := range e.Variables {
+		part := fmt.Sprintf("%s (%s)", v.Name, v.Field)
+		if v.Reason != "" {
+			part += ": " + v.Reason
+		}
+		parts = append(parts, part)
+	}
+	return "unresolved variables: " + strings.Join(parts, ", ")
+}
+
+// ResolvedRequest pairs a substituted request with anything left unresolved
+type ResolvedRequest struct {
+	Request    RequestData          `json:"request"`
+	Unresolved []UnresolvedVariable `json:"unresolved"`
+}
+
+// VariableResolver substitutes {{variable}} placeholders using merged scopes.
+// Placeholders starting with $ are dynamic variables, evaluated fresh for
+// every occurrence.
+type VariableResolver struct {
+	vars    map[string]string
+	lenient bool
+}
+
+func NewVariableResolver(scopes VariableScopes) *VariableResolver {
+	vars := make(map[string]string)
+	for _, scope := range []map[string]string{scopes.Global, scopes.Workspace, scopes.Collection} {
+		for k, v := range scope {
+			vars[k] = v
+		}
+	}
+	if scopes.Environment != nil {
+		for k, v := range scopes.Environment.Variables {
+			vars[k] = v
+		}
+	}
+
+	return &VariableResolver{vars: vars}
+}
+
+// resolverFor returns the resolver for a request's optional scopes. Without
+// scopes the frontend has already substituted its own variables, so only
+// dynamic variables are expanded and other placeholders pass through.
+func resolverFor(scopes *VariableScopes) *VariableResolver {
+	if scopes == nil {
+		return &VariableResolver{vars: map[string]string{}, lenient: true}
+	}
+	return NewVariableResolver(*scopes)
+}
+
+// Lookup returns the effective value of a variable
+func (r *VariableResolver) Lookup(name string) (string, bool) {
+	value, ok := r.vars[name]
+	return value, ok
+}
+
+// resolution tracks unresolved placeholders across the fields of one value
+type resolution struct {
+	resolver *VariableResolver
+	fallback map[string]string // values for names no scope defines, e.g. a batch's index
+	missing  []UnresolvedVariable
+}
+
+func (r *VariableResolver) begin() *resolution {
+	return &resolution{resolver: r}
+}
+
+func (res *resolution) lookup(name string) (string, bool) {
+	if value, ok := res.resolver.Lookup(name); ok {
+		return value, true
+	}
+	value, ok := res.fallback[name]
+	return value, ok
+}
+
+func (res *resolution) text(field, text string) string {
+	if text == "" {
+		return text
+	}
+
+	return variablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := strings.TrimSpace(match[2 : len(match)-2])
+
+		if strings.HasPrefix(name, "$") {
+			value, err := evaluateDynamicVariable(name[1:], res.lookup)
+			if err != nil {
+				// A lenient resolver leaves {{$base64 name}} alone when name
+				// is undefined, just like {{name}}
+				if res.resolver.lenient && errors.Is(err, errUnknownVariable) {
+					return match
+				}
+				res.missing = append(res.missing, UnresolvedVariable{Name: name, Field: field, Reason: err.Error()})
+				return match
+			}
+			return value
+		}
+
+		if value, ok := res.lookup(name); ok {
+			return value
+		}
+		if !res.resolver.lenient {
+			res.missing = append(res.missing, UnresolvedVariable{Name: name, Field: field})
+		}
+		return match
+	})
+}
+
+func (res *resolution) stringMap(field string, m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(map[string]string, len(m))
+	for _, k := range keys {
+		key := res.text(field+"."+k, k)
+		out[key] = res.text(field+"."+k, m[k])
+	}
+	return out
+}
+
+func (res *resolution) keyValues(field string, kvs []KeyValue) []KeyValue {
+	if kvs == nil {
+		return nil
+	}
+
+	out := make([]KeyValue, len(kvs))
+	for i, kv := range kvs {
+		if kv.Enabled {
+			kv.Key = res.text(fmt.Sprintf("%s[%d].key", field, i), kv.Key)
+			kv.Value = res.text(fmt.Sprintf("%s[%d].value", field, i), kv.Value)
+		}
+		out[i] = kv
+	}
+	return out
+}
+
+func (res *resolution) auth(field string, auth *RequestAuth) *RequestAuth {
+	if auth == nil {
+		return nil
+	}
+
+	out := *auth
+	out.Username = res.text(field+".username", auth.Username)
+	out.Password = res.text(field+".password", auth.Password)
+	out.Token = res.text(field+".token", auth.Token)
+	out.Key = res.text(field+".key", auth.Key)
+	out.Value = res.text(field+".value", auth.Value)
+	return &out
+}
+
+func (res *resolution) err() error {
+	if len(res.missing) == 0 {
+		return nil
+	}
+	return &UnresolvedVariablesError{Variables: res.missing}
+}
+
+func (r *VariableResolver) ResolveString(text string) (string, error) {
+	return r.ResolveField("value", text)
+}
+
+// ResolveField is ResolveString with the field name used in error reports
+func (r *VariableResolver) ResolveField(field, text string) (string, error) {
+	res := r.begin()
+	out := res.text(field, text)
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveRequestData(req RequestData) (RequestData, error) {
+	res := r.begin()
+
+	out := req
+	out.URL = res.text("url", req.URL)
+	out.Params = res.keyValues("params", req.Params)
+	out.Headers = res.keyValues("headers", req.Headers)
+	out.Body = res.text("body", req.Body)
+	out.Auth = res.auth("auth", req.Auth)
+
+	if req.GraphQL != nil {
+		gql := *req.GraphQL
+		gql.OperationName = res.text("graphql.operationName", gql.OperationName)
+		gql.Variables = res.text("graphql.variables", gql.Variables)
+		out.GraphQL = &gql
+	}
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveGraphQLRequest(req GraphQLRequest) (GraphQLRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.URL = res.text("url", req.URL)
+	out.Headers = res.keyValues("headers", req.Headers)
+	out.Auth = res.auth("auth", req.Auth)
+	out.Query = res.text("query", req.Query)
+	out.OperationName = res.text("operationName", req.OperationName)
+	out.Variables = res.text("variables", req.Variables)
+
+	if req.Uploads != nil {
+		out.Uploads = make([]GraphQLUpload, len(req.Uploads))
+		for i, upload := range req.Uploads {
+			upload.FilePath = res.text(fmt.Sprintf("uploads[%d].filePath", i), upload.FilePath)
+			out.Uploads[i] = upload
+		}
+	}
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveGraphQLSchemaRequest(req GraphQLSchemaRequest) (GraphQLSchemaRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.URL = res.text("url", req.URL)
+	out.Headers = res.keyValues("headers", req.Headers)
+	out.Auth = res.auth("auth", req.Auth)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveGrpcConnectRequest(req GrpcConnectRequest) (GrpcConnectRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.ServerURL = res.text("serverUrl", req.ServerURL)
+	out.Service = res.text("service", req.Service)
+	out.Method = res.text("method", req.Method)
+	out.Metadata = res.stringMap("metadata", req.Metadata)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveGrpcReflectionRequest(req GrpcReflectionRequest) (GrpcReflectionRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.ServerURL = res.text("serverUrl", req.ServerURL)
+	out.Metadata = res.stringMap("metadata", req.Metadata)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveKafkaConfig(cfg KafkaConfig) (KafkaConfig, error) {
+	res := r.begin()
+
+	out := cfg
+	out.BootstrapServers = make([]string, len(cfg.BootstrapServers))
+	for i, server := range cfg.BootstrapServers {
+		out.BootstrapServers[i] = res.text(fmt.Sprintf("bootstrapServers[%d]", i), server)
+	}
+	out.ClientID = res.text("clientId", cfg.ClientID)
+	out.SaslUsername = res.text("saslUsername", cfg.SaslUsername)
+	out.SaslPassword = res.text("saslPassword", cfg.SaslPassword)
+	if cfg.OAuth != nil {
+		oauth := *cfg.OAuth
+		oauth.TokenURL = res.text("oauth.tokenUrl", oauth.TokenURL)
+		oauth.ClientID = res.text("oauth.clientId", oauth.ClientID)
+		oauth.ClientSecret = res.text("oauth.clientSecret", oauth.ClientSecret)
+		oauth.Scope = res.text("oauth.scope", oauth.Scope)
+		oauth.Audience = res.text("oauth.audience", oauth.Audience)
+		oauth.Extensions = res.stringMap("oauth.extensions", oauth.Extensions)
+		out.OAuth = &oauth
+	}
+	if cfg.AWS != nil {
+		aws := *cfg.AWS
+		aws.Region = res.text("aws.region", aws.Region)
+		aws.Profile = res.text("aws.profile", aws.Profile)
+		aws.AccessKeyID = res.text("aws.accessKeyId", aws.AccessKeyID)
+		aws.SecretAccessKey = res.text("aws.secretAccessKey", aws.SecretAccessKey)
+		aws.SessionToken = res.text("aws.sessionToken", aws.SessionToken)
+		out.AWS = &aws
+	}
+	if cfg.SchemaRegistry != nil {
+		registry := *cfg.SchemaRegistry
+		registry.URL = res.text("schemaRegistry.url", registry.URL)
+		registry.Username = res.text("schemaRegistry.username", registry.Username)
+		registry.Password = res.text("schemaRegistry.password", registry.Password)
+		out.SchemaRegistry = &registry
+	}
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveProducerConfig(cfg ProducerConfig) (ProducerConfig, error) {
+	res := r.begin()
+
+	out := cfg
+	out.Topic = res.text("topic", cfg.Topic)
+	out.Key = res.text("key", cfg.Key)
+	out.Value = res.text("value", cfg.Value)
+	out.Headers = res.stringMap("headers", cfg.Headers)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveSSEConnectRequest(req SSEConnectRequest) (SSEConnectRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.URL = res.text("url", req.URL)
+	out.LastEventID = res.text("lastEventId", req.LastEventID)
+	out.Headers = res.stringMap("customHeaders", req.Headers)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveWebSocketConnectRequest(req WebSocketConnectRequest) (WebSocketConnectRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.URL = res.text("url", req.URL)
+	out.Subprotocol = res.text("subprotocol", req.Subprotocol)
+	out.Headers = res.stringMap("customHeaders", req.Headers)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveGraphQLWSConnectRequest(req GraphQLWSConnectRequest) (GraphQLWSConnectRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.URL = res.text("url", req.URL)
+	out.Subprotocol = res.text("subprotocol", req.Subprotocol)
+	out.Headers = res.stringMap("headers", req.Headers)
+	out.ConnectionParams = res.text("connectionParams", req.ConnectionParams)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveGraphQLSubscribeRequest(req GraphQLSubscribeRequest) (GraphQLSubscribeRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.Query = res.text("query", req.Query)
+	out.OperationName = res.text("operationName", req.OperationName)
+	out.Variables = res.text("variables", req.Variables)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveSocketConnectRequest(req SocketConnectRequest) (SocketConnectRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.Address = res.text("address", req.Address)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveSocketListenRequest(req SocketListenRequest) (SocketListenRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.Address = res.text("address", req.Address)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveSocketSendRequest(req SocketSendRequest) (SocketSendRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.Payload = res.text("payload", req.Payload)
+	out.Peer = res.text("peer", req.Peer)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveMQTTConnectRequest(req MQTTConnectRequest) (MQTTConnectRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.BrokerURL = res.text("brokerUrl", req.BrokerURL)
+	out.ClientID = res.text("clientId", req.ClientID)
+	out.Username = res.text("username", req.Username)
+	out.Password = res.text("password", req.Password)
+	out.UserProperties = res.stringMap("userProperties", req.UserProperties)
+	if req.Will != nil {
+		will := *req.Will
+		will.Topic = res.text("will.topic", will.Topic)
+		will.Payload = res.text("will.payload", will.Payload)
+		will.UserProperties = res.stringMap("will.userProperties", will.UserProperties)
+		out.Will = &will
+	}
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveMQTTSubscribeRequest(req MQTTSubscribeRequest) (MQTTSubscribeRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.Topic = res.text("topic", req.Topic)
+	out.UserProperties = res.stringMap("userProperties", req.UserProperties)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveMQTTPublishRequest(req MQTTPublishRequest) (MQTTPublishRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.Topic = res.text("topic", req.Topic)
+	out.Payload = res.text("payload", req.Payload)
+	out.ResponseTopic = res.text("responseTopic", req.ResponseTopic)
+	out.UserProperties = res.stringMap("userProperties", req.UserProperties)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveRedisConnectRequest(req RedisConnectRequest) (RedisConnectRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.URL = res.text("url", req.URL)
+	out.Username = res.text("username", req.Username)
+	out.Password = res.text("password", req.Password)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveRedisXAddRequest(req RedisXAddRequest) (RedisXAddRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.Stream = res.text("stream", req.Stream)
+	out.Fields = make([]RedisField, len(req.Fields))
+	for i, f := range req.Fields {
+		out.Fields[i] = RedisField{
+			Name:  res.text(fmt.Sprintf("fields[%d].name", i), f.Name),
+			Value: res.text(fmt.Sprintf("fields[%d].value", i), f.Value),
+		}
+	}
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveRedisPublishRequest(req RedisPublishRequest) (RedisPublishRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.Channel = res.text("channel", req.Channel)
+	out.Message = res.text("message", req.Message)
+
+	return out, res.err()
+}
+
+func (r *VariableResolver) ResolveRedisSubscribeRequest(req RedisSubscribeRequest) (RedisSubscribeRequest, error) {
+	res := r.begin()
+
+	out := req
+	out.Channels = make([]string, len(req.Channels))
+	for i, ch := range req.Channels {
+		out.Channels[i] = res.text(fmt.Sprintf("channels[%d]", i), ch)
+	}
+
+	return out, res.err()
+}
+
+// resolveEndpoint substitutes variables into a URL and header set. The
+// streaming managers keep the raw templates and call this on every reconnect.
+func resolveEndpoint(scopes *VariableScopes, rawURL string, headers map[string]string) (string, map[string]string, error) {
+	res := resolverFor(scopes).begin()
+	resolvedURL := res.text("url", rawURL)
+	resolvedHeaders := res.stringMap("customHeaders", headers)
+	return resolvedURL, resolvedHeaders, res.err()
+}
@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestVariableResolverPrecedence(t *testing.T) {
+	r := NewVariableResolver(VariableScopes{
+		Global:     map[string]string{"host": "global", "a": "g", "b": "g", "c": "g"},
+		Workspace:  map[string]string{"a": "w", "b": "w", "c": "w"},
+		Collection: map[string]string{"b": "c", "c": "c"},
+		Environment: &Environment{
+			Variables: map[string]string{"c": "e"},
+		},
+	})
+
+	got, err := r.ResolveString("{{host}} {{a}} {{b}} {{ c }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "global w c e"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestVariableResolverUnresolved(t *testing.T) {
+	r := NewVariableResolver(VariableScopes{Global: map[string]string{"host": "api"}})
+
+	got, err := r.ResolveRequestData(RequestData{
+		URL: "https://{{host}}/{{path}}",
+		Headers: []KeyValue{
+			{Key: "X-Off", Value: "{{ignored}}"},
+			{Key: "X-Token", Value: "{{token}}", Enabled: true},
+		},
+		Auth: &RequestAuth{Password: "{{$base64 secret}}"},
+	})
+
+	if got.URL != "https://api/{{path}}" {
+		t.Errorf("URL = %s", got.URL)
+	}
+	var unresolved *UnresolvedVariablesError
+	if !errors.As(err, &unresolved) {
+		t.Fatalf("err = %v, want UnresolvedVariablesError", err)
+	}
+	want := []UnresolvedVariable{
+		{Name: "path", Field: "url"},
+		{Name: "token", Field: "headers[1].value"},
+		{Name: "$base64 secret", Field: "auth.password", Reason: "unknown variable: secret"},
+	}
+	if !reflect.DeepEqual(unresolved.Variables, want) {
+		t.Errorf("unresolved = %+v, want %+v", unresolved.Variables, want)
+	}
+}
+
+// Without scopes the frontend has substituted already; anything left is
+// sent as typed, whether it names a variable or is the argument of a helper
+func TestLenientResolver(t *testing.T) {
+	r := resolverFor(nil)
+
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "{{name}}", want: "{{name}}"},
+		{text: "{{$base64 name}}", want: "{{$base64 name}}"},
+		{text: "{{$hex name}}", want: "{{$hex name}}"},
+		{text: "{{$nope}}", wantErr: true},
+		{text: "{{$randomInt 5 1}}", wantErr: true},
+		{text: "{{$base64}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := r.ResolveString(tt.text)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %q, want an error", tt.text, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v; want %q", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestResolveStringMapKeys(t *testing.T) {
+	r := NewVariableResolver(VariableScopes{Global: map[string]string{"h": "X-Trace", "v": "1"}})
+
+	got, err := r.ResolveSSEConnectRequest(SSEConnectRequest{Headers: map[string]string{"{{h}}": "{{v}}"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"X-Trace": "1"}; !reflect.DeepEqual(got.Headers, want) {
+		t.Errorf("headers = %v, want %v", got.Headers, want)
+	}
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	scopes := &VariableScopes{Global: map[string]string{"host": "feed", "token": "secret"}}
+
+	url, headers, err := resolveEndpoint(scopes, "wss://{{host}}/ws", map[string]string{"Authorization": "Basic {{$base64 token}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "wss://feed/ws" || headers["Authorization"] != "Basic "+base64.StdEncoding.EncodeToString([]byte("secret")) {
+		t.Errorf("got %s %v", url, headers)
+	}
+
+	if _, _, err := resolveEndpoint(scopes, "wss://{{missing}}", nil); err == nil {
+		t.Error("expected an error for an undefined variable")
+	}
+}
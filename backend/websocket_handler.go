@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // WebSocketManager handles WebSocket connections
@@ -33,6 +32,8 @@ type WebSocketConnection struct {
 	PingInterval   int // milliseconds
 	Subprotocol    string
 	Headers        map[string]string
+	Scopes         *VariableScopes
+	TLSConfig      *tls.Config
 	pingTicker     *time.Ticker
 	reconnectCount int
 	maxReconnects  int
@@ -46,6 +47,9 @@ type WebSocketConnectRequest struct {
 	PingEnabled    bool              `json:"enablePingPong"`
 	PingInterval   int               `json:"pingInterval"` // milliseconds
 	Headers        map[string]string `json:"customHeaders"`
+	TLSProfile     string            `json:"tlsProfile,omitempty"`
+	TLSSkipVerify  bool              `json:"tlsSkipVerify"`
+	Scopes         *VariableScopes   `json:"scopes,omitempty"`
 }
 
 type WebSocketSendRequest struct {
@@ -68,23 +72,33 @@ func (w *WebSocketManager) generateMessageID() string {
 }
 
 func (w *WebSocketManager) Connect(req WebSocketConnectRequest) (string, error) {
+	target, err := resolverFor(req.Scopes).ResolveWebSocketConnectRequest(req)
+	if err != nil {
+		return "", err
+	}
+
 	headers := http.Header{}
-	for key, value := range req.Headers {
+	for key, value := range target.Headers {
 		headers.Add(key, value)
 	}
 
 	var subprotocols []string
-	if req.Subprotocol != "" {
-		subprotocols = append(subprotocols, req.Subprotocol)
+	if target.Subprotocol != "" {
+		subprotocols = append(subprotocols, target.Subprotocol)
+	}
+
+	tlsConfig, err := clientTLSConfig(w.app.GetDataDirectory(), req.TLSProfile, dialedHost(target.URL), &tls.Config{InsecureSkipVerify: req.TLSSkipVerify})
+	if err != nil {
+		return "", err
 	}
 
 	dialer := websocket.Dialer{
 		Subprotocols:     subprotocols,
 		HandshakeTimeout: 10 * time.Second,
-		TLSClientConfig:  &tls.Config{InsecureSkipVerify: true}, // TODO: Make this configurable
+		TLSClientConfig:  tlsConfig,
 	}
 
-	conn, _, err := dialer.Dial(req.URL, headers)
+	conn, _, err := dialer.Dial(target.URL, headers)
 	if err != nil {
 		return "", fmt.Errorf("failed to connect: %w", err)
 	}
@@ -102,8 +116,10 @@ func (w *WebSocketManager) Connect(req WebSocketConnectRequest) (string, error)
 		ReconnectDelay: req.ReconnectDelay,
 		PingEnabled:    req.PingEnabled,
 		PingInterval:   req.PingInterval,
-		Subprotocol:    req.Subprotocol,
+		Subprotocol:    target.Subprotocol,
 		Headers:        req.Headers,
+		Scopes:         req.Scopes,
+		TLSConfig:      tlsConfig,
 		maxReconnects:  10, // Maximum reconnection attempts
 	}
 
@@ -121,7 +137,7 @@ func (w *WebSocketManager) Connect(req WebSocketConnectRequest) (string, error)
 		ID:        w.generateMessageID(),
 		Direction: "system",
 		Protocol:  "WebSocket",
-		Payload:   fmt.Sprintf("Connected to %s", req.URL),
+		Payload:   fmt.Sprintf("Connected to %s", target.URL),
 		Timestamp: time.Now(),
 	})
 
@@ -137,7 +153,12 @@ func (w *WebSocketManager) SendMessage(req WebSocketSendRequest) error {
 		return fmt.Errorf("connection not found: %s", req.ConnectionID)
 	}
 
-	var err error
+	message, err := resolverFor(conn.Scopes).ResolveField("message", req.Message)
+	if err != nil {
+		return err
+	}
+	req.Message = message
+
 	var messageType int
 
 	switch req.MessageType {
@@ -349,8 +370,20 @@ func (w *WebSocketManager) attemptReconnect(conn *WebSocketConnection) {
 	case <-time.After(time.Duration(conn.ReconnectDelay) * time.Millisecond):
 	}
 
+	url, resolvedHeaders, err := resolveEndpoint(conn.Scopes, conn.URL, conn.Headers)
+	if err != nil {
+		w.emitMessage(StreamMessage{
+			ID:        w.generateMessageID(),
+			Direction: "error",
+			Protocol:  "WebSocket",
+			Payload:   fmt.Sprintf("Reconnection failed: %s", err.Error()),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
 	headers := http.Header{}
-	for key, value := range conn.Headers {
+	for key, value := range resolvedHeaders {
 		headers.Add(key, value)
 	}
 
@@ -362,10 +395,10 @@ func (w *WebSocketManager) attemptReconnect(conn *WebSocketConnection) {
 	dialer := websocket.Dialer{
 		Subprotocols:     subprotocols,
 		HandshakeTimeout: 10 * time.Second,
-		TLSClientConfig:  &tls.Config{InsecureSkipVerify: true},
+		TLSClientConfig:  conn.TLSConfig,
 	}
 
-	newConn, _, err := dialer.Dial(conn.URL, headers)
+	newConn, _, err := dialer.Dial(url, headers)
 	if err != nil {
 		w.emitMessage(StreamMessage{
 			ID:        w.generateMessageID(),
@@ -411,6 +444,6 @@ func (w *WebSocketManager) emitMessage(msg StreamMessage) {
 				fmt.Printf("[WS] Event emit panic recovered: %v\n", r)
 			}
 		}()
-		runtime.EventsEmit(w.app.GetCtx(), "stream-message", msg)
+		emitStreamEvent(w.app.GetCtx(), msg)
 	}()
 }
@@ -0,0 +1,47 @@
+package backend
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketVerifiesCertificates(t *testing.T) {
+	var upgrader websocket.Upgrader
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // the rejected handshake is expected
+	server.StartTLS()
+	defer server.Close()
+	url := "wss" + strings.TrimPrefix(server.URL, "https")
+
+	app, _ := newTestApp(t)
+	w := NewWebSocketManager(app)
+
+	if _, err := w.Connect(WebSocketConnectRequest{URL: url}); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("connect to an untrusted server: err = %v, want a certificate error", err)
+	}
+
+	id, err := w.Connect(WebSocketConnectRequest{URL: url, TLSSkipVerify: true})
+	if err != nil {
+		t.Fatalf("connect with verification skipped: %v", err)
+	}
+	if err := w.Disconnect(id); err != nil {
+		t.Error(err)
+	}
+}
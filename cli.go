@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"pulse/backend"
+)
+
+// runCLI implements `pulse run`, which executes saved collections headlessly.
+// It returns the process exit code: 0 when every request passed, 1 when any
+// failed and 2 for usage or setup errors.
+func runCLI(args []string) int {
+	homeDir, _ := os.UserHomeDir()
+
+	fs := flag.NewFlagSet("pulse run", flag.ContinueOnError)
+	dataDir := fs.String("data-dir", filepath.Join(homeDir, ".pulse"), "Pulse data directory")
+	workspace := fs.String("workspace", "", "workspace ID or name to run")
+	collection := fs.String("collection", "", "collection ID or name to run (default: all)")
+	environment := fs.String("env", "", "environment ID or name used to resolve {{variables}}")
+	jsonPath := fs.String("json", "", "write a JSON report to this file")
+	junitPath := fs.String("junit", "", "write a JUnit XML report to this file")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	runner := backend.NewCollectionRunner(backend.NewHTTPHandler(nil, *dataDir))
+
+	report, err := runner.Run(backend.RunOptions{
+		Workspace:   *workspace,
+		Collection:  *collection,
+		Environment: *environment,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pulse run: %v\n", err)
+		return 2
+	}
+
+	report.WriteSummary(os.Stdout)
+
+	if *jsonPath != "" {
+		if err := writeReport(*jsonPath, report.WriteJSON); err != nil {
+			fmt.Fprintf(os.Stderr, "pulse run: failed to write JSON report: %v\n", err)
+			return 2
+		}
+	}
+
+	if *junitPath != "" {
+		if err := writeReport(*junitPath, report.WriteJUnit); err != nil {
+			fmt.Fprintf(os.Stderr, "pulse run: failed to write JUnit report: %v\n", err)
+			return 2
+		}
+	}
+
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// writeReport writes a report file, surfacing Close errors so a report that
+// was only partly flushed to disk fails the run instead of exiting 0
+func writeReport(path string, write func(w io.Writer) error) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return write(f)
+}
@@ -8,6 +8,7 @@ interface StreamMessage {
     protocol: string;
     payload: string;
     timestamp: Date;
+    metadata?: Record<string, any>;
 }
 
 interface StreamStore {
@@ -103,9 +104,11 @@ export const filteredMessages = derived(
     $store => {
         return $store.messages.filter(msg => {
             const matchesDirection = $store.filterDirection === 'all' || msg.direction === $store.filterDirection;
-            const matchesSearch = !$store.searchTerm ||
-                msg.payload.toLowerCase().includes($store.searchTerm.toLowerCase()) ||
-                msg.protocol.toLowerCase().includes($store.searchTerm.toLowerCase());
+            const term = $store.searchTerm.toLowerCase();
+            const matchesSearch = !term ||
+                msg.payload.toLowerCase().includes(term) ||
+                msg.protocol.toLowerCase().includes(term) ||
+                (msg.metadata?.key?.text ?? '').toLowerCase().includes(term);
             return matchesDirection && matchesSearch;
         });
     }
@@ -1,7 +1,7 @@
 // Streaming protocol management store
 import { writable } from 'svelte/store';
 
-export type StreamingProtocol = 'websocket' | 'sse' | 'grpc-stream' | 'kafka' | 'mqtt';
+export type StreamingProtocol = 'websocket' | 'sse' | 'grpc-stream' | 'kafka' | 'mqtt' | 'socket' | 'redis';
 
 export interface StreamingRequest {
     protocol: StreamingProtocol;
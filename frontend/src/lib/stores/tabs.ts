@@ -1,7 +1,7 @@
 // Tab management store
 import { writable, derived, get } from 'svelte/store';
 
-export type TabProtocol = 'http' | 'websocket' | 'sse' | 'grpc-stream' | 'kafka' | 'mqtt' | 'grpc';
+export type TabProtocol = 'http' | 'websocket' | 'sse' | 'grpc-stream' | 'kafka' | 'mqtt' | 'socket' | 'redis' | 'grpc';
 
 export interface TabState {
     id: string;
@@ -51,6 +51,8 @@ function createTabsStore() {
             'grpc-stream': 'gRPC Stream',
             'kafka': 'Kafka',
             'mqtt': 'MQTT',
+            'socket': 'Socket',
+            'redis': 'Redis',
             'grpc': 'gRPC'
         };
 
@@ -37,8 +37,23 @@ export interface RequestData {
     params: KeyValue[];
     headers: KeyValue[];
     body: string;
-    bodyType: 'none' | 'json' | 'xml' | 'text' | 'form-data' | 'x-www-form-urlencoded';
+    bodyType: 'none' | 'json' | 'xml' | 'text' | 'form-data' | 'x-www-form-urlencoded' | 'graphql';
     auth: RequestAuth | null;
+    graphql?: GraphQLOptions;
+}
+
+// GraphQL settings of a request whose body holds the query document
+export interface GraphQLOptions {
+    operationName?: string;
+    variables?: string;
+    persistedQuery?: boolean;
+    skipValidation?: boolean;
+    uploads?: GraphQLUpload[];
+}
+
+export interface GraphQLUpload {
+    variable: string;
+    filePath: string;
 }
 
 export interface RequestAuth {
@@ -65,6 +80,27 @@ export interface ResponseData {
     body: string;
     time: string;
     size: string;
+    timing?: ResponseTiming;
+    remoteAddr?: string;
+    protocol?: string;
+    tls?: TLSInfo;
+}
+
+export interface ResponseTiming {
+    dnsLookup: number;
+    tcpConnect: number;
+    tlsHandshake: number;
+    timeToFirstByte: number;
+    contentTransfer: number;
+    total: number;
+    reusedConn: boolean;
+}
+
+export interface TLSInfo {
+    version: string;
+    cipherSuite: string;
+    serverName: string;
+    alpn?: string;
 }
 
 export interface HistoryItem {
@@ -1,6 +1,25 @@
 // Variable substitution utilities
 // Handles replacing {{variable}} patterns in text with actual values
 
+import { get } from 'svelte/store';
+import { LoadVariableScopes } from '../../../wailsjs/go/main/App';
+import { backend } from '../../../wailsjs/go/models';
+import { environmentStore } from '../stores/environment';
+import { workspaceStore } from '../stores/workspace';
+
+// activeScopes returns the variable scopes requests are resolved against.
+// The backend substitutes {{variables}} and {{$dynamic}} variables itself and
+// reports any left unresolved. The active environment is taken from the store
+// so edits not yet saved to disk still apply.
+export async function activeScopes(): Promise<backend.VariableScopes> {
+    const { activeWorkspaceId } = get(workspaceStore);
+    const { environments, activeEnvironmentId } = get(environmentStore);
+
+    const scopes = await LoadVariableScopes(activeWorkspaceId || '', '', '');
+    const environment = environments.find(e => e.id === activeEnvironmentId);
+    return backend.VariableScopes.createFrom({ ...scopes, environment });
+}
+
 export function substituteVariables(
     text: string,
     variables: Record<string, string>
@@ -14,6 +33,19 @@ export function substituteVariables(
     });
 }
 
+// appendParams folds enabled query params into a URL that is still
+// unresolved. Placeholders are left readable so the backend can substitute
+// them along with the rest of the request.
+export function appendParams(url: string, params: { key: string; value: string; enabled: boolean }[]): string {
+    const enabled = params.filter(p => p.enabled && p.key);
+    if (enabled.length === 0) return url;
+
+    const encode = (text: string) => encodeURIComponent(text)
+        .replace(/%7B%7B(.+?)%7D%7D/g, (_, name) => `{{${decodeURIComponent(name)}}}`);
+    const query = enabled.map(p => `${encode(p.key)}=${encode(p.value)}`).join('&');
+    return url + (url.includes('?') ? '&' : '?') + query;
+}
+
 export function substituteInObject(
     obj: any,
     variables: Record<string, string>
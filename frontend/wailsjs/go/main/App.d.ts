@@ -9,6 +9,20 @@ export function GetCtx():Promise<context.Context>;
 
 export function GetDataDirectory():Promise<string>;
 
+export function GraphQLIntrospect(arg1:backend.GraphQLSchemaRequest):Promise<backend.GraphQLSchemaInfo>;
+
+export function GraphQLSend(arg1:backend.GraphQLRequest):Promise<backend.GraphQLResponse>;
+
+export function GraphQLWSConnect(arg1:backend.GraphQLWSConnectRequest):Promise<string>;
+
+export function GraphQLWSDisconnect(arg1:string):Promise<void>;
+
+export function GraphQLWSSubscribe(arg1:backend.GraphQLSubscribeRequest):Promise<string>;
+
+export function GraphQLWSUnsubscribe(arg1:string,arg2:string):Promise<void>;
+
+export function GrpcCloseSend(arg1:string):Promise<void>;
+
 export function GrpcConnect(arg1:backend.GrpcConnectRequest):Promise<string>;
 
 export function GrpcDisconnect(arg1:string):Promise<void>;
@@ -17,7 +31,15 @@ export function GrpcParseProtoFiles(arg1:backend.ProtoFileUploadRequest):Promise
 
 export function GrpcSendMessage(arg1:backend.GrpcSendMessageRequest):Promise<void>;
 
-export function GrpcUseReflection(arg1:string,arg2:boolean):Promise<backend.ParsedProtoResponse>;
+export function GrpcUseReflection(arg1:backend.GrpcReflectionRequest):Promise<backend.ParsedProtoResponse>;
+
+export function KafkaCancelReplay(arg1:string,arg2:string):Promise<void>;
+
+export function KafkaCancelSearch(arg1:string,arg2:string):Promise<void>;
+
+export function KafkaClusterOverview(arg1:string):Promise<backend.ClusterOverview>;
+
+export function KafkaCommitOffsets(arg1:backend.CommitOffsetsRequest):Promise<Array<backend.PartitionOffset>>;
 
 export function KafkaConnect(arg1:backend.KafkaConfig):Promise<string>;
 
@@ -25,12 +47,22 @@ export function KafkaDisconnect(arg1:string):Promise<void>;
 
 export function KafkaListTopics(arg1:string):Promise<Array<backend.TopicInfo>>;
 
-export function KafkaProduceMessage(arg1:backend.ProducerConfig):Promise<void>;
+export function KafkaProduceMessage(arg1:backend.ProducerConfig):Promise<backend.ProduceResult>;
+
+export function KafkaStartBatchProduce(arg1:backend.BatchProduceRequest):Promise<string>;
 
 export function KafkaStartConsumer(arg1:backend.ConsumerConfig):Promise<string>;
 
+export function KafkaStartReplay(arg1:backend.KafkaReplayRequest):Promise<string>;
+
+export function KafkaStartSearch(arg1:backend.KafkaSearchRequest):Promise<string>;
+
+export function KafkaStopBatchProduce(arg1:string,arg2:string):Promise<void>;
+
 export function KafkaStopConsumer(arg1:string,arg2:string):Promise<void>;
 
+export function KafkaTestOAuthToken(arg1:backend.KafkaConfig):Promise<backend.OAuthTokenInfo>;
+
 export function LoadCollections():Promise<Array<backend.Collection>>;
 
 export function LoadEnvironments():Promise<Array<backend.Environment>>;
@@ -39,8 +71,48 @@ export function LoadHistory():Promise<Array<backend.HistoryItem>>;
 
 export function LoadSettings():Promise<backend.Settings>;
 
+export function LoadVariableScopes(arg1:string,arg2:string,arg3:string):Promise<backend.VariableScopes>;
+
 export function LoadWorkspaces():Promise<Array<backend.Workspace>>;
 
+export function MQTTConnect(arg1:backend.MQTTConnectRequest):Promise<string>;
+
+export function MQTTDisconnect(arg1:string):Promise<void>;
+
+export function MQTTPublish(arg1:backend.MQTTPublishRequest):Promise<void>;
+
+export function MQTTSubscribe(arg1:backend.MQTTSubscribeRequest):Promise<number>;
+
+export function MQTTUnsubscribe(arg1:string,arg2:string):Promise<void>;
+
+export function RedisAck(arg1:backend.RedisAckRequest):Promise<number>;
+
+export function RedisClaim(arg1:backend.RedisClaimRequest):Promise<backend.RedisClaimResult>;
+
+export function RedisConnect(arg1:backend.RedisConnectRequest):Promise<string>;
+
+export function RedisCreateGroup(arg1:backend.RedisGroupRequest):Promise<void>;
+
+export function RedisDisconnect(arg1:string):Promise<void>;
+
+export function RedisListGroups(arg1:string,arg2:string):Promise<Array<backend.RedisGroupInfo>>;
+
+export function RedisPending(arg1:backend.RedisPendingRequest):Promise<backend.RedisPendingResult>;
+
+export function RedisPublish(arg1:backend.RedisPublishRequest):Promise<number>;
+
+export function RedisStartReader(arg1:backend.RedisReadRequest):Promise<string>;
+
+export function RedisStopReader(arg1:string,arg2:string):Promise<void>;
+
+export function RedisSubscribe(arg1:backend.RedisSubscribeRequest):Promise<void>;
+
+export function RedisUnsubscribe(arg1:backend.RedisSubscribeRequest):Promise<void>;
+
+export function RedisXAdd(arg1:backend.RedisXAddRequest):Promise<string>;
+
+export function RedisXRange(arg1:backend.RedisRangeRequest):Promise<backend.RedisRangeResult>;
+
 export function SSEConnect(arg1:backend.SSEConnectRequest):Promise<string>;
 
 export function SSEDisconnect(arg1:string):Promise<void>;
@@ -57,6 +129,18 @@ export function SaveWorkspaces(arg1:Array<backend.Workspace>):Promise<void>;
 
 export function SendRequest(arg1:backend.RequestData):Promise<backend.ResponseData>;
 
+export function SendRequestWithVariables(arg1:backend.RequestData,arg2:backend.VariableScopes):Promise<backend.ResponseData>;
+
+export function SocketClose(arg1:string):Promise<void>;
+
+export function SocketConnect(arg1:backend.SocketConnectRequest):Promise<string>;
+
+export function SocketListen(arg1:backend.SocketListenRequest):Promise<backend.SocketListenResult>;
+
+export function SocketSend(arg1:backend.SocketSendRequest):Promise<void>;
+
+export function SocketStopListening(arg1:string):Promise<void>;
+
 export function WebSocketConnect(arg1:backend.WebSocketConnectRequest):Promise<string>;
 
 export function WebSocketDisconnect(arg1:string):Promise<void>;
@@ -14,6 +14,34 @@ export function GetDataDirectory() {
   return window['go']['main']['App']['GetDataDirectory']();
 }
 
+export function GraphQLIntrospect(arg1) {
+  return window['go']['main']['App']['GraphQLIntrospect'](arg1);
+}
+
+export function GraphQLSend(arg1) {
+  return window['go']['main']['App']['GraphQLSend'](arg1);
+}
+
+export function GraphQLWSConnect(arg1) {
+  return window['go']['main']['App']['GraphQLWSConnect'](arg1);
+}
+
+export function GraphQLWSDisconnect(arg1) {
+  return window['go']['main']['App']['GraphQLWSDisconnect'](arg1);
+}
+
+export function GraphQLWSSubscribe(arg1) {
+  return window['go']['main']['App']['GraphQLWSSubscribe'](arg1);
+}
+
+export function GraphQLWSUnsubscribe(arg1, arg2) {
+  return window['go']['main']['App']['GraphQLWSUnsubscribe'](arg1, arg2);
+}
+
+export function GrpcCloseSend(arg1) {
+  return window['go']['main']['App']['GrpcCloseSend'](arg1);
+}
+
 export function GrpcConnect(arg1) {
   return window['go']['main']['App']['GrpcConnect'](arg1);
 }
@@ -30,8 +58,24 @@ export function GrpcSendMessage(arg1) {
   return window['go']['main']['App']['GrpcSendMessage'](arg1);
 }
 
-export function GrpcUseReflection(arg1, arg2) {
-  return window['go']['main']['App']['GrpcUseReflection'](arg1, arg2);
+export function GrpcUseReflection(arg1) {
+  return window['go']['main']['App']['GrpcUseReflection'](arg1);
+}
+
+export function KafkaCancelReplay(arg1, arg2) {
+  return window['go']['main']['App']['KafkaCancelReplay'](arg1, arg2);
+}
+
+export function KafkaCancelSearch(arg1, arg2) {
+  return window['go']['main']['App']['KafkaCancelSearch'](arg1, arg2);
+}
+
+export function KafkaClusterOverview(arg1) {
+  return window['go']['main']['App']['KafkaClusterOverview'](arg1);
+}
+
+export function KafkaCommitOffsets(arg1) {
+  return window['go']['main']['App']['KafkaCommitOffsets'](arg1);
 }
 
 export function KafkaConnect(arg1) {
@@ -50,14 +94,34 @@ export function KafkaProduceMessage(arg1) {
   return window['go']['main']['App']['KafkaProduceMessage'](arg1);
 }
 
+export function KafkaStartBatchProduce(arg1) {
+  return window['go']['main']['App']['KafkaStartBatchProduce'](arg1);
+}
+
 export function KafkaStartConsumer(arg1) {
   return window['go']['main']['App']['KafkaStartConsumer'](arg1);
 }
 
+export function KafkaStartReplay(arg1) {
+  return window['go']['main']['App']['KafkaStartReplay'](arg1);
+}
+
+export function KafkaStartSearch(arg1) {
+  return window['go']['main']['App']['KafkaStartSearch'](arg1);
+}
+
+export function KafkaStopBatchProduce(arg1, arg2) {
+  return window['go']['main']['App']['KafkaStopBatchProduce'](arg1, arg2);
+}
+
 export function KafkaStopConsumer(arg1, arg2) {
   return window['go']['main']['App']['KafkaStopConsumer'](arg1, arg2);
 }
 
+export function KafkaTestOAuthToken(arg1) {
+  return window['go']['main']['App']['KafkaTestOAuthToken'](arg1);
+}
+
 export function LoadCollections() {
   return window['go']['main']['App']['LoadCollections']();
 }
@@ -74,10 +138,90 @@ export function LoadSettings() {
   return window['go']['main']['App']['LoadSettings']();
 }
 
+export function LoadVariableScopes(arg1, arg2, arg3) {
+  return window['go']['main']['App']['LoadVariableScopes'](arg1, arg2, arg3);
+}
+
 export function LoadWorkspaces() {
   return window['go']['main']['App']['LoadWorkspaces']();
 }
 
+export function MQTTConnect(arg1) {
+  return window['go']['main']['App']['MQTTConnect'](arg1);
+}
+
+export function MQTTDisconnect(arg1) {
+  return window['go']['main']['App']['MQTTDisconnect'](arg1);
+}
+
+export function MQTTPublish(arg1) {
+  return window['go']['main']['App']['MQTTPublish'](arg1);
+}
+
+export function MQTTSubscribe(arg1) {
+  return window['go']['main']['App']['MQTTSubscribe'](arg1);
+}
+
+export function MQTTUnsubscribe(arg1, arg2) {
+  return window['go']['main']['App']['MQTTUnsubscribe'](arg1, arg2);
+}
+
+export function RedisAck(arg1) {
+  return window['go']['main']['App']['RedisAck'](arg1);
+}
+
+export function RedisClaim(arg1) {
+  return window['go']['main']['App']['RedisClaim'](arg1);
+}
+
+export function RedisConnect(arg1) {
+  return window['go']['main']['App']['RedisConnect'](arg1);
+}
+
+export function RedisCreateGroup(arg1) {
+  return window['go']['main']['App']['RedisCreateGroup'](arg1);
+}
+
+export function RedisDisconnect(arg1) {
+  return window['go']['main']['App']['RedisDisconnect'](arg1);
+}
+
+export function RedisListGroups(arg1, arg2) {
+  return window['go']['main']['App']['RedisListGroups'](arg1, arg2);
+}
+
+export function RedisPending(arg1) {
+  return window['go']['main']['App']['RedisPending'](arg1);
+}
+
+export function RedisPublish(arg1) {
+  return window['go']['main']['App']['RedisPublish'](arg1);
+}
+
+export function RedisStartReader(arg1) {
+  return window['go']['main']['App']['RedisStartReader'](arg1);
+}
+
+export function RedisStopReader(arg1, arg2) {
+  return window['go']['main']['App']['RedisStopReader'](arg1, arg2);
+}
+
+export function RedisSubscribe(arg1) {
+  return window['go']['main']['App']['RedisSubscribe'](arg1);
+}
+
+export function RedisUnsubscribe(arg1) {
+  return window['go']['main']['App']['RedisUnsubscribe'](arg1);
+}
+
+export function RedisXAdd(arg1) {
+  return window['go']['main']['App']['RedisXAdd'](arg1);
+}
+
+export function RedisXRange(arg1) {
+  return window['go']['main']['App']['RedisXRange'](arg1);
+}
+
 export function SSEConnect(arg1) {
   return window['go']['main']['App']['SSEConnect'](arg1);
 }
@@ -110,6 +254,30 @@ export function SendRequest(arg1) {
   return window['go']['main']['App']['SendRequest'](arg1);
 }
 
+export function SendRequestWithVariables(arg1, arg2) {
+  return window['go']['main']['App']['SendRequestWithVariables'](arg1, arg2);
+}
+
+export function SocketClose(arg1) {
+  return window['go']['main']['App']['SocketClose'](arg1);
+}
+
+export function SocketConnect(arg1) {
+  return window['go']['main']['App']['SocketConnect'](arg1);
+}
+
+export function SocketListen(arg1) {
+  return window['go']['main']['App']['SocketListen'](arg1);
+}
+
+export function SocketSend(arg1) {
+  return window['go']['main']['App']['SocketSend'](arg1);
+}
+
+export function SocketStopListening(arg1) {
+  return window['go']['main']['App']['SocketStopListening'](arg1);
+}
+
 export function WebSocketConnect(arg1) {
   return window['go']['main']['App']['WebSocketConnect'](arg1);
 }
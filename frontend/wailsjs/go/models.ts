@@ -42,6 +42,58 @@ export namespace backend {
 	        this.description = source["description"];
 	    }
 	}
+	export class GraphQLUpload {
+	    variable: string;
+	    filePath: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphQLUpload(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.variable = source["variable"];
+	        this.filePath = source["filePath"];
+	    }
+	}
+	export class GraphQLOptions {
+	    operationName?: string;
+	    variables?: string;
+	    persistedQuery?: boolean;
+	    skipValidation?: boolean;
+	    uploads?: GraphQLUpload[];
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphQLOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.operationName = source["operationName"];
+	        this.variables = source["variables"];
+	        this.persistedQuery = source["persistedQuery"];
+	        this.skipValidation = source["skipValidation"];
+	        this.uploads = this.convertValues(source["uploads"], GraphQLUpload);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class RequestData {
 	    method: string;
 	    url: string;
@@ -50,6 +102,7 @@ export namespace backend {
 	    body: string;
 	    bodyType: string;
 	    auth?: RequestAuth;
+	    graphql?: GraphQLOptions;
 	
 	    static createFrom(source: any = {}) {
 	        return new RequestData(source);
@@ -64,6 +117,7 @@ export namespace backend {
 	        this.body = source["body"];
 	        this.bodyType = source["bodyType"];
 	        this.auth = this.convertValues(source["auth"], RequestAuth);
+	        this.graphql = this.convertValues(source["graphql"], GraphQLOptions);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -159,125 +213,178 @@ export namespace backend {
 		    return a;
 		}
 	}
-	
-	export class ConsumerConfig {
-	    connectionId: string;
-	    topic: string;
-	    partitions: number[];
-	    consumerGroup: string;
-	    offsetStrategy: string;
-	    customOffset: number;
-	    autoCommit: boolean;
+	export class BrokerInfo {
+	    id: number;
+	    host: string;
+	    port: number;
+	    rack?: string;
+	    controller: boolean;
+	    reachable: boolean;
+	    error?: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new ConsumerConfig(source);
+	        return new BrokerInfo(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.connectionId = source["connectionId"];
-	        this.topic = source["topic"];
-	        this.partitions = source["partitions"];
-	        this.consumerGroup = source["consumerGroup"];
-	        this.offsetStrategy = source["offsetStrategy"];
-	        this.customOffset = source["customOffset"];
-	        this.autoCommit = source["autoCommit"];
+	        this.id = source["id"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.rack = source["rack"];
+	        this.controller = source["controller"];
+	        this.reachable = source["reachable"];
+	        this.error = source["error"];
 	    }
 	}
-	export class Environment {
-	    id: string;
-	    name: string;
-	    variables: Record<string, string>;
-	    workspaceId: string;
+	export class ClusterOverview {
+	    clusterId: string;
+	    controller: number;
+	    brokers: BrokerInfo[];
+	    topics: number;
+	    partitions: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new Environment(source);
+	        return new ClusterOverview(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.name = source["name"];
-	        this.variables = source["variables"];
-	        this.workspaceId = source["workspaceId"];
+	        this.clusterId = source["clusterId"];
+	        this.controller = source["controller"];
+	        this.brokers = this.convertValues(source["brokers"], BrokerInfo);
+	        this.topics = source["topics"];
+	        this.partitions = source["partitions"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class GrpcConnectRequest {
-	    serverUrl: string;
-	    service: string;
-	    method: string;
-	    useTLS: boolean;
-	    deadline: number;
-	    compression: string;
-	    metadata: Record<string, string>;
+	
+	export class PartitionOffset {
+	    partition: number;
+	    offset: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new GrpcConnectRequest(source);
+	        return new PartitionOffset(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.serverUrl = source["serverUrl"];
-	        this.service = source["service"];
-	        this.method = source["method"];
-	        this.useTLS = source["useTLS"];
-	        this.deadline = source["deadline"];
-	        this.compression = source["compression"];
-	        this.metadata = source["metadata"];
+	        this.partition = source["partition"];
+	        this.offset = source["offset"];
 	    }
 	}
-	export class GrpcSendMessageRequest {
+	export class CommitOffsetsRequest {
 	    connectionId: string;
-	    message: string;
+	    consumerId: string;
+	    offsets: PartitionOffset[];
 	
 	    static createFrom(source: any = {}) {
-	        return new GrpcSendMessageRequest(source);
+	        return new CommitOffsetsRequest(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.connectionId = source["connectionId"];
-	        this.message = source["message"];
+	        this.consumerId = source["consumerId"];
+	        this.offsets = this.convertValues(source["offsets"], PartitionOffset);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class ResponseData {
-	    statusCode: number;
-	    statusText: string;
-	    headers: Record<string, string>;
-	    body: string;
+	export class ConsumerConfig {
+	    connectionId: string;
+	    topic: string;
+	    partitions: number[];
+	    consumerGroup: string;
+	    offsetStrategy: string;
+	    customOffset: number;
+	    customTimestamp: number;
+	    autoCommit: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new ResponseData(source);
+	        return new ConsumerConfig(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.statusCode = source["statusCode"];
-	        this.statusText = source["statusText"];
-	        this.headers = source["headers"];
-	        this.body = source["body"];
+	        this.connectionId = source["connectionId"];
+	        this.topic = source["topic"];
+	        this.partitions = source["partitions"];
+	        this.consumerGroup = source["consumerGroup"];
+	        this.offsetStrategy = source["offsetStrategy"];
+	        this.customOffset = source["customOffset"];
+	        this.customTimestamp = source["customTimestamp"];
+	        this.autoCommit = source["autoCommit"];
 	    }
 	}
-	export class HistoryItem {
+	export class Environment {
 	    id: string;
-	    request: RequestData;
-	    response?: ResponseData;
-	    // Go type: time
-	    timestamp: any;
+	    name: string;
+	    variables: Record<string, string>;
 	    workspaceId: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new HistoryItem(source);
+	        return new Environment(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
-	        this.request = this.convertValues(source["request"], RequestData);
-	        this.response = this.convertValues(source["response"], ResponseData);
-	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.name = source["name"];
+	        this.variables = source["variables"];
 	        this.workspaceId = source["workspaceId"];
 	    }
+	}
+	export class VariableScopes {
+	    global?: Record<string, string>;
+	    workspace?: Record<string, string>;
+	    collection?: Record<string, string>;
+	    environment?: Environment;
+	
+	    static createFrom(source: any = {}) {
+	        return new VariableScopes(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.global = source["global"];
+	        this.workspace = source["workspace"];
+	        this.collection = source["collection"];
+	        this.environment = this.convertValues(source["environment"], Environment);
+	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
 		    if (!a) {
@@ -297,63 +404,69 @@ export namespace backend {
 		    return a;
 		}
 	}
-	export class KafkaConfig {
-	    bootstrapServers: string[];
-	    clientId: string;
-	    authMechanism: string;
-	    saslUsername: string;
-	    saslPassword: string;
-	    useTLS: boolean;
-	    tlsSkipVerify: boolean;
-	    connectionTimeout: number;
+	export class GraphQLSchemaRequest {
+	    url: string;
+	    headers: KeyValue[];
+	    auth?: RequestAuth;
+	    tlsProfile?: string;
+	    refresh: boolean;
+	    scopes?: VariableScopes;
 	
 	    static createFrom(source: any = {}) {
-	        return new KafkaConfig(source);
+	        return new GraphQLSchemaRequest(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.bootstrapServers = source["bootstrapServers"];
-	        this.clientId = source["clientId"];
-	        this.authMechanism = source["authMechanism"];
-	        this.saslUsername = source["saslUsername"];
-	        this.saslPassword = source["saslPassword"];
-	        this.useTLS = source["useTLS"];
-	        this.tlsSkipVerify = source["tlsSkipVerify"];
-	        this.connectionTimeout = source["connectionTimeout"];
-	    }
-	}
-	
-	export class MethodInfo {
-	    name: string;
-	    type: string;
-	    inputType: string;
-	    outputType: string;
-	
-	    static createFrom(source: any = {}) {
-	        return new MethodInfo(source);
+	        this.url = source["url"];
+	        this.headers = this.convertValues(source["headers"], KeyValue);
+	        this.auth = this.convertValues(source["auth"], RequestAuth);
+	        this.tlsProfile = source["tlsProfile"];
+	        this.refresh = source["refresh"];
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
 	    }
 	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.name = source["name"];
-	        this.type = source["type"];
-	        this.inputType = source["inputType"];
-	        this.outputType = source["outputType"];
-	    }
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class ServiceInfo {
-	    name: string;
-	    methods: MethodInfo[];
+	export class GraphQLSchemaInfo {
+	    url: string;
+	    sdl: string;
+	    queryType: string;
+	    mutationType?: string;
+	    subscriptionType?: string;
+	    types: string[];
+	    // Go type: time
+	    fetchedAt: any;
 	
 	    static createFrom(source: any = {}) {
-	        return new ServiceInfo(source);
+	        return new GraphQLSchemaInfo(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.name = source["name"];
-	        this.methods = this.convertValues(source["methods"], MethodInfo);
+	        this.url = source["url"];
+	        this.sdl = source["sdl"];
+	        this.queryType = source["queryType"];
+	        this.mutationType = source["mutationType"];
+	        this.subscriptionType = source["subscriptionType"];
+	        this.types = source["types"];
+	        this.fetchedAt = this.convertValues(source["fetchedAt"], null);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -374,16 +487,36 @@ export namespace backend {
 		    return a;
 		}
 	}
-	export class ParsedProtoResponse {
-	    services: ServiceInfo[];
+	export class GraphQLRequest {
+	    url: string;
+	    headers: KeyValue[];
+	    auth?: RequestAuth;
+	    tlsProfile?: string;
+	    query: string;
+	    operationName?: string;
+	    variables?: string;
+	    persistedQuery: boolean;
+	    uploads?: GraphQLUpload[];
+	    skipValidation: boolean;
+	    scopes?: VariableScopes;
 	
 	    static createFrom(source: any = {}) {
-	        return new ParsedProtoResponse(source);
+	        return new GraphQLRequest(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.services = this.convertValues(source["services"], ServiceInfo);
+	        this.url = source["url"];
+	        this.headers = this.convertValues(source["headers"], KeyValue);
+	        this.auth = this.convertValues(source["auth"], RequestAuth);
+	        this.tlsProfile = source["tlsProfile"];
+	        this.query = source["query"];
+	        this.operationName = source["operationName"];
+	        this.variables = source["variables"];
+	        this.persistedQuery = source["persistedQuery"];
+	        this.uploads = this.convertValues(source["uploads"], GraphQLUpload);
+	        this.skipValidation = source["skipValidation"];
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -404,56 +537,1322 @@ export namespace backend {
 		    return a;
 		}
 	}
-	export class ProducerConfig {
-	    connectionId: string;
-	    topic: string;
-	    partition: number;
+	export class GraphQLLocation {
+	    line: number;
+	    column: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphQLLocation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.line = source["line"];
+	        this.column = source["column"];
+	    }
+	}
+	export class GraphQLError {
+	    message: string;
+	    locations?: GraphQLLocation[];
+	    path?: any[];
+	    extensions?: Record<string, any>;
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphQLError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.message = source["message"];
+	        this.locations = this.convertValues(source["locations"], GraphQLLocation);
+	        this.path = source["path"];
+	        this.extensions = source["extensions"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ResponseData {
+	    statusCode: number;
+	    statusText: string;
+	    headers: Record<string, string>;
+	    body: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ResponseData(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.statusCode = source["statusCode"];
+	        this.statusText = source["statusText"];
+	        this.headers = source["headers"];
+	        this.body = source["body"];
+	    }
+	}
+	export class GraphQLResponse {
+	    response?: ResponseData;
+	    data?: any;
+	    errors?: GraphQLError[];
+	    extensions?: Record<string, any>;
+	    validationErrors?: GraphQLError[];
+	    operationName?: string;
+	    operationType?: string;
+	    persistedQuery?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphQLResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.response = this.convertValues(source["response"], ResponseData);
+	        this.data = source["data"];
+	        this.errors = this.convertValues(source["errors"], GraphQLError);
+	        this.extensions = source["extensions"];
+	        this.validationErrors = this.convertValues(source["validationErrors"], GraphQLError);
+	        this.operationName = source["operationName"];
+	        this.operationType = source["operationType"];
+	        this.persistedQuery = source["persistedQuery"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GraphQLWSConnectRequest {
+	    url: string;
+	    subprotocol: string;
+	    headers: Record<string, string>;
+	    connectionParams?: string;
+	    ackTimeout: number;
+	    pingInterval: number;
+	    tlsProfile?: string;
+	    tlsSkipVerify: boolean;
+	    scopes?: VariableScopes;
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphQLWSConnectRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.subprotocol = source["subprotocol"];
+	        this.headers = source["headers"];
+	        this.connectionParams = source["connectionParams"];
+	        this.ackTimeout = source["ackTimeout"];
+	        this.pingInterval = source["pingInterval"];
+	        this.tlsProfile = source["tlsProfile"];
+	        this.tlsSkipVerify = source["tlsSkipVerify"];
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GraphQLSubscribeRequest {
+	    connectionId: string;
+	    query: string;
+	    operationName?: string;
+	    variables?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphQLSubscribeRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.query = source["query"];
+	        this.operationName = source["operationName"];
+	        this.variables = source["variables"];
+	    }
+	}
+	export class GrpcConnectRequest {
+	    serverUrl: string;
+	    service: string;
+	    method: string;
+	    useTLS: boolean;
+	    deadline: number;
+	    compression: string;
+	    metadata: Record<string, string>;
+	    protoSetId?: string;
+	    scopes?: VariableScopes;
+	
+	    static createFrom(source: any = {}) {
+	        return new GrpcConnectRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.serverUrl = source["serverUrl"];
+	        this.service = source["service"];
+	        this.method = source["method"];
+	        this.useTLS = source["useTLS"];
+	        this.deadline = source["deadline"];
+	        this.compression = source["compression"];
+	        this.metadata = source["metadata"];
+	        this.protoSetId = source["protoSetId"];
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GrpcReflectionRequest {
+	    serverUrl: string;
+	    useTLS: boolean;
+	    tlsProfile?: string;
+	    metadata: Record<string, string>;
+	    deadline: number;
+	    scopes?: VariableScopes;
+	
+	    static createFrom(source: any = {}) {
+	        return new GrpcReflectionRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.serverUrl = source["serverUrl"];
+	        this.useTLS = source["useTLS"];
+	        this.tlsProfile = source["tlsProfile"];
+	        this.metadata = source["metadata"];
+	        this.deadline = source["deadline"];
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GrpcServiceError {
+	    service: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GrpcServiceError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.service = source["service"];
+	        this.error = source["error"];
+	    }
+	}
+	export class GrpcSendMessageRequest {
+	    connectionId: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GrpcSendMessageRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.message = source["message"];
+	    }
+	}
+	export class HistoryItem {
+	    id: string;
+	    request: RequestData;
+	    response?: ResponseData;
+	    // Go type: time
+	    timestamp: any;
+	    workspaceId: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HistoryItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.request = this.convertValues(source["request"], RequestData);
+	        this.response = this.convertValues(source["response"], ResponseData);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.workspaceId = source["workspaceId"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class KafkaAWSConfig {
+	    region?: string;
+	    profile?: string;
+	    accessKeyId?: string;
+	    secretAccessKey?: string;
+	    sessionToken?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new KafkaAWSConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.region = source["region"];
+	        this.profile = source["profile"];
+	        this.accessKeyId = source["accessKeyId"];
+	        this.secretAccessKey = source["secretAccessKey"];
+	        this.sessionToken = source["sessionToken"];
+	    }
+	}
+	export class KafkaOAuthConfig {
+	    tokenUrl: string;
+	    clientId: string;
+	    clientSecret: string;
+	    scope?: string;
+	    audience?: string;
+	    extensions?: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new KafkaOAuthConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tokenUrl = source["tokenUrl"];
+	        this.clientId = source["clientId"];
+	        this.clientSecret = source["clientSecret"];
+	        this.scope = source["scope"];
+	        this.audience = source["audience"];
+	        this.extensions = source["extensions"];
+	    }
+	}
+	export class SchemaRegistryConfig {
+	    url: string;
+	    username?: string;
+	    password?: string;
+	    tlsProfile?: string;
+	    tlsSkipVerify?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SchemaRegistryConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.tlsProfile = source["tlsProfile"];
+	        this.tlsSkipVerify = source["tlsSkipVerify"];
+	    }
+	}
+	export class KafkaConfig {
+	    bootstrapServers: string[];
+	    clientId: string;
+	    authMechanism: string;
+	    saslUsername: string;
+	    saslPassword: string;
+	    useTLS: boolean;
+	    tlsSkipVerify: boolean;
+	    connectionTimeout: number;
+	    schemaRegistry?: SchemaRegistryConfig;
+	    oauth?: KafkaOAuthConfig;
+	    aws?: KafkaAWSConfig;
+	    scopes?: VariableScopes;
+	
+	    static createFrom(source: any = {}) {
+	        return new KafkaConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.bootstrapServers = source["bootstrapServers"];
+	        this.clientId = source["clientId"];
+	        this.authMechanism = source["authMechanism"];
+	        this.saslUsername = source["saslUsername"];
+	        this.saslPassword = source["saslPassword"];
+	        this.useTLS = source["useTLS"];
+	        this.tlsSkipVerify = source["tlsSkipVerify"];
+	        this.connectionTimeout = source["connectionTimeout"];
+	        this.schemaRegistry = this.convertValues(source["schemaRegistry"], SchemaRegistryConfig);
+	        this.oauth = this.convertValues(source["oauth"], KafkaOAuthConfig);
+	        this.aws = this.convertValues(source["aws"], KafkaAWSConfig);
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class KafkaSearchRequest {
+	    connectionId: string;
+	    topic: string;
+	    partitions: number[];
+	    startTime: number;
+	    endTime: number;
+	    key: string;
+	    headerKey: string;
+	    headerValue: string;
+	    jsonPath: string;
+	    jsonValue: string;
+	    valueContains: string;
+	    maxResults: number;
+	    concurrency: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new KafkaSearchRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.topic = source["topic"];
+	        this.partitions = source["partitions"];
+	        this.startTime = source["startTime"];
+	        this.endTime = source["endTime"];
+	        this.key = source["key"];
+	        this.headerKey = source["headerKey"];
+	        this.headerValue = source["headerValue"];
+	        this.jsonPath = source["jsonPath"];
+	        this.jsonValue = source["jsonValue"];
+	        this.valueContains = source["valueContains"];
+	        this.maxResults = source["maxResults"];
+	        this.concurrency = source["concurrency"];
+	    }
+	}
+	export class ReplayTransform {
+	    path: string;
+	    value: string;
+	    remove: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReplayTransform(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.value = source["value"];
+	        this.remove = source["remove"];
+	    }
+	}
+	export class KafkaReplayRequest {
+	    source: KafkaSearchRequest;
+	    targetConnectionId: string;
+	    targetTopic: string;
+	    keepPartitions: boolean;
+	    keepTimestamps: boolean;
+	    transforms: ReplayTransform[];
+	    rate: number;
+	    dryRun: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new KafkaReplayRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = this.convertValues(source["source"], KafkaSearchRequest);
+	        this.targetConnectionId = source["targetConnectionId"];
+	        this.targetTopic = source["targetTopic"];
+	        this.keepPartitions = source["keepPartitions"];
+	        this.keepTimestamps = source["keepTimestamps"];
+	        this.transforms = this.convertValues(source["transforms"], ReplayTransform);
+	        this.rate = source["rate"];
+	        this.dryRun = source["dryRun"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MethodInfo {
+	    name: string;
+	    type: string;
+	    inputType: string;
+	    outputType: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MethodInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.inputType = source["inputType"];
+	        this.outputType = source["outputType"];
+	    }
+	}
+	export class ServiceInfo {
+	    name: string;
+	    fullName: string;
+	    methods: MethodInfo[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ServiceInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.fullName = source["fullName"];
+	        this.methods = this.convertValues(source["methods"], MethodInfo);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OAuthTokenInfo {
+	    tokenType: string;
+	    scope?: string;
+	    // Go type: time
+	    expiresAt: any;
+	    // Go type: time
+	    refreshAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new OAuthTokenInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tokenType = source["tokenType"];
+	        this.scope = source["scope"];
+	        this.expiresAt = this.convertValues(source["expiresAt"], null);
+	        this.refreshAt = this.convertValues(source["refreshAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MQTTWill {
+	    topic: string;
+	    payload: string;
+	    qos: number;
+	    retain: boolean;
+	    contentType?: string;
+	    userProperties?: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new MQTTWill(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.topic = source["topic"];
+	        this.payload = source["payload"];
+	        this.qos = source["qos"];
+	        this.retain = source["retain"];
+	        this.contentType = source["contentType"];
+	        this.userProperties = source["userProperties"];
+	    }
+	}
+	export class MQTTConnectRequest {
+	    brokerUrl: string;
+	    protocolVersion: string;
+	    clientId: string;
+	    username: string;
+	    password: string;
+	    cleanSession: boolean;
+	    keepAlive: number;
+	    connectTimeout: number;
+	    sessionExpiry: number;
+	    userProperties?: Record<string, string>;
+	    will?: MQTTWill;
+	    tlsProfile?: string;
+	    tlsSkipVerify: boolean;
+	    scopes?: VariableScopes;
+	
+	    static createFrom(source: any = {}) {
+	        return new MQTTConnectRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.brokerUrl = source["brokerUrl"];
+	        this.protocolVersion = source["protocolVersion"];
+	        this.clientId = source["clientId"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.cleanSession = source["cleanSession"];
+	        this.keepAlive = source["keepAlive"];
+	        this.connectTimeout = source["connectTimeout"];
+	        this.sessionExpiry = source["sessionExpiry"];
+	        this.userProperties = source["userProperties"];
+	        this.will = this.convertValues(source["will"], MQTTWill);
+	        this.tlsProfile = source["tlsProfile"];
+	        this.tlsSkipVerify = source["tlsSkipVerify"];
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MQTTPublishRequest {
+	    connectionId: string;
+	    topic: string;
+	    payload: string;
+	    qos: number;
+	    retain: boolean;
+	    contentType?: string;
+	    responseTopic?: string;
+	    messageExpiry?: number;
+	    userProperties?: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new MQTTPublishRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.topic = source["topic"];
+	        this.payload = source["payload"];
+	        this.qos = source["qos"];
+	        this.retain = source["retain"];
+	        this.contentType = source["contentType"];
+	        this.responseTopic = source["responseTopic"];
+	        this.messageExpiry = source["messageExpiry"];
+	        this.userProperties = source["userProperties"];
+	    }
+	}
+	export class MQTTSubscribeRequest {
+	    connectionId: string;
+	    topic: string;
+	    qos: number;
+	    noLocal: boolean;
+	    userProperties?: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new MQTTSubscribeRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.topic = source["topic"];
+	        this.qos = source["qos"];
+	        this.noLocal = source["noLocal"];
+	        this.userProperties = source["userProperties"];
+	    }
+	}
+	export class ParsedProtoResponse {
+	    protoSetId?: string;
+	    services: ServiceInfo[];
+	    reflectionVersion?: string;
+	    serviceErrors?: GrpcServiceError[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ParsedProtoResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.protoSetId = source["protoSetId"];
+	        this.services = this.convertValues(source["services"], ServiceInfo);
+	        this.reflectionVersion = source["reflectionVersion"];
+	        this.serviceErrors = this.convertValues(source["serviceErrors"], GrpcServiceError);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProduceResult {
+	    topic: string;
+	    partition: number;
+	    offset: number;
+	    // Go type: time
+	    timestamp: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProduceResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.topic = source["topic"];
+	        this.partition = source["partition"];
+	        this.offset = source["offset"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SchemaSelection {
+	    subject: string;
+	    version?: number;
+	    messageType?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SchemaSelection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.subject = source["subject"];
+	        this.version = source["version"];
+	        this.messageType = source["messageType"];
+	    }
+	}
+	export class ProducerConfig {
+	    connectionId: string;
+	    topic: string;
+	    partition: number;
 	    key: string;
 	    value: string;
 	    headers: Record<string, string>;
 	    compression: string;
 	    acks: number;
+	    keySchema?: SchemaSelection;
+	    valueSchema?: SchemaSelection;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProducerConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.topic = source["topic"];
+	        this.partition = source["partition"];
+	        this.key = source["key"];
+	        this.value = source["value"];
+	        this.headers = source["headers"];
+	        this.compression = source["compression"];
+	        this.acks = source["acks"];
+	        this.keySchema = this.convertValues(source["keySchema"], SchemaSelection);
+	        this.valueSchema = this.convertValues(source["valueSchema"], SchemaSelection);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BatchProduceRequest {
+	    connectionId: string;
+	    topic: string;
+	    partition: number;
+	    compression: string;
+	    acks: number;
+	    filePath?: string;
+	    keyTemplate?: string;
+	    valueTemplate?: string;
+	    headers?: Record<string, string>;
+	    keySchema?: SchemaSelection;
+	    valueSchema?: SchemaSelection;
+	    count: number;
+	    rate: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchProduceRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.topic = source["topic"];
+	        this.partition = source["partition"];
+	        this.compression = source["compression"];
+	        this.acks = source["acks"];
+	        this.filePath = source["filePath"];
+	        this.keyTemplate = source["keyTemplate"];
+	        this.valueTemplate = source["valueTemplate"];
+	        this.headers = source["headers"];
+	        this.keySchema = this.convertValues(source["keySchema"], SchemaSelection);
+	        this.valueSchema = this.convertValues(source["valueSchema"], SchemaSelection);
+	        this.count = source["count"];
+	        this.rate = source["rate"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProtoFile {
+	    name: string;
+	    content: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProtoFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.content = source["content"];
+	    }
+	}
+	export class ProtoFileUploadRequest {
+	    files: ProtoFile[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProtoFileUploadRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.files = this.convertValues(source["files"], ProtoFile);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	export class RedisAckRequest {
+	    connectionId: string;
+	    stream: string;
+	    group: string;
+	    ids: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisAckRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.stream = source["stream"];
+	        this.group = source["group"];
+	        this.ids = source["ids"];
+	    }
+	}
+	export class RedisClaimRequest {
+	    connectionId: string;
+	    stream: string;
+	    group: string;
+	    consumer: string;
+	    minIdle: number;
+	    ids: string[];
+	    start: string;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisClaimRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.stream = source["stream"];
+	        this.group = source["group"];
+	        this.consumer = source["consumer"];
+	        this.minIdle = source["minIdle"];
+	        this.ids = source["ids"];
+	        this.start = source["start"];
+	        this.count = source["count"];
+	    }
+	}
+	export class RedisClaimResult {
+	    claimed: string[];
+	    nextStart?: string;
+	    deleted?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisClaimResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.claimed = source["claimed"];
+	        this.nextStart = source["nextStart"];
+	        this.deleted = source["deleted"];
+	    }
+	}
+	export class RedisConnectRequest {
+	    url: string;
+	    username: string;
+	    password: string;
+	    db: number;
+	    tls: boolean;
+	    tlsSkipVerify: boolean;
+	    tlsProfile?: string;
+	    connectTimeout: number;
+	    scopes?: VariableScopes;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisConnectRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.db = source["db"];
+	        this.tls = source["tls"];
+	        this.tlsSkipVerify = source["tlsSkipVerify"];
+	        this.tlsProfile = source["tlsProfile"];
+	        this.connectTimeout = source["connectTimeout"];
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RedisGroupRequest {
+	    connectionId: string;
+	    stream: string;
+	    group: string;
+	    startId: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new ProducerConfig(source);
+	        return new RedisGroupRequest(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.connectionId = source["connectionId"];
-	        this.topic = source["topic"];
-	        this.partition = source["partition"];
-	        this.key = source["key"];
-	        this.value = source["value"];
-	        this.headers = source["headers"];
-	        this.compression = source["compression"];
-	        this.acks = source["acks"];
+	        this.stream = source["stream"];
+	        this.group = source["group"];
+	        this.startId = source["startId"];
 	    }
 	}
-	export class ProtoFile {
+	export class RedisGroupInfo {
 	    name: string;
-	    content: string;
+	    consumers: number;
+	    pending: number;
+	    lastDeliveredId: string;
+	    lag: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new ProtoFile(source);
+	        return new RedisGroupInfo(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.name = source["name"];
-	        this.content = source["content"];
+	        this.consumers = source["consumers"];
+	        this.pending = source["pending"];
+	        this.lastDeliveredId = source["lastDeliveredId"];
+	        this.lag = source["lag"];
 	    }
 	}
-	export class ProtoFileUploadRequest {
-	    files: ProtoFile[];
+	export class RedisPendingRequest {
+	    connectionId: string;
+	    stream: string;
+	    group: string;
+	    consumer: string;
+	    minIdle: number;
+	    start: string;
+	    end: string;
+	    count: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new ProtoFileUploadRequest(source);
+	        return new RedisPendingRequest(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.files = this.convertValues(source["files"], ProtoFile);
+	        this.connectionId = source["connectionId"];
+	        this.stream = source["stream"];
+	        this.group = source["group"];
+	        this.consumer = source["consumer"];
+	        this.minIdle = source["minIdle"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.count = source["count"];
+	    }
+	}
+	export class RedisPendingEntry {
+	    id: string;
+	    consumer: string;
+	    idle: number;
+	    deliveries: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisPendingEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.consumer = source["consumer"];
+	        this.idle = source["idle"];
+	        this.deliveries = source["deliveries"];
+	    }
+	}
+	export class RedisPendingResult {
+	    count: number;
+	    lowest: string;
+	    highest: string;
+	    consumers: Record<string, number>;
+	    entries: RedisPendingEntry[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisPendingResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.count = source["count"];
+	        this.lowest = source["lowest"];
+	        this.highest = source["highest"];
+	        this.consumers = source["consumers"];
+	        this.entries = this.convertValues(source["entries"], RedisPendingEntry);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RedisPublishRequest {
+	    connectionId: string;
+	    channel: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisPublishRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.channel = source["channel"];
+	        this.message = source["message"];
+	    }
+	}
+	export class RedisReadRequest {
+	    connectionId: string;
+	    streams: string[];
+	    startId: string;
+	    group: string;
+	    consumer: string;
+	    createGroup: boolean;
+	    count: number;
+	    block: number;
+	    noAck: boolean;
+	    autoAck: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisReadRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.streams = source["streams"];
+	        this.startId = source["startId"];
+	        this.group = source["group"];
+	        this.consumer = source["consumer"];
+	        this.createGroup = source["createGroup"];
+	        this.count = source["count"];
+	        this.block = source["block"];
+	        this.noAck = source["noAck"];
+	        this.autoAck = source["autoAck"];
+	    }
+	}
+	export class RedisSubscribeRequest {
+	    connectionId: string;
+	    channels: string[];
+	    pattern: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisSubscribeRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.channels = source["channels"];
+	        this.pattern = source["pattern"];
+	    }
+	}
+	export class RedisField {
+	    name: string;
+	    value: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisField(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.value = source["value"];
+	    }
+	}
+	export class RedisXAddRequest {
+	    connectionId: string;
+	    stream: string;
+	    id: string;
+	    fields: RedisField[];
+	    maxLen: number;
+	    exact: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisXAddRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.stream = source["stream"];
+	        this.id = source["id"];
+	        this.fields = this.convertValues(source["fields"], RedisField);
+	        this.maxLen = source["maxLen"];
+	        this.exact = source["exact"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -474,9 +1873,46 @@ export namespace backend {
 		    return a;
 		}
 	}
+	export class RedisRangeRequest {
+	    connectionId: string;
+	    stream: string;
+	    start: string;
+	    end: string;
+	    count: number;
+	    reverse: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisRangeRequest(source);
+	    }
 	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.stream = source["stream"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.count = source["count"];
+	        this.reverse = source["reverse"];
+	    }
+	}
+	export class RedisRangeResult {
+	    count: number;
+	    firstId: string;
+	    lastId: string;
+	    length: number;
 	
+	    static createFrom(source: any = {}) {
+	        return new RedisRangeResult(source);
+	    }
 	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.count = source["count"];
+	        this.firstId = source["firstId"];
+	        this.lastId = source["lastId"];
+	        this.length = source["length"];
+	    }
+	}
 	export class SSEConnectRequest {
 	    url: string;
 	    withCredentials: boolean;
@@ -485,6 +1921,9 @@ export namespace backend {
 	    autoReconnect: boolean;
 	    customHeaders: Record<string, string>;
 	    eventTypeFilter: string[];
+	    tlsProfile?: string;
+	    tlsSkipVerify: boolean;
+	    scopes?: VariableScopes;
 	
 	    static createFrom(source: any = {}) {
 	        return new SSEConnectRequest(source);
@@ -499,7 +1938,28 @@ export namespace backend {
 	        this.autoReconnect = source["autoReconnect"];
 	        this.customHeaders = source["customHeaders"];
 	        this.eventTypeFilter = source["eventTypeFilter"];
+	        this.tlsProfile = source["tlsProfile"];
+	        this.tlsSkipVerify = source["tlsSkipVerify"];
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	
 	export class Settings {
@@ -526,6 +1986,148 @@ export namespace backend {
 	        this.prettyPrintByDefault = source["prettyPrintByDefault"];
 	    }
 	}
+	export class SocketFraming {
+	    mode: string;
+	    delimiter?: string;
+	    includeDelimiter?: boolean;
+	    length?: number;
+	    prefixBytes?: number;
+	    littleEndian?: boolean;
+	    prefixInclusive?: boolean;
+	    maxFrameSize?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SocketFraming(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.delimiter = source["delimiter"];
+	        this.includeDelimiter = source["includeDelimiter"];
+	        this.length = source["length"];
+	        this.prefixBytes = source["prefixBytes"];
+	        this.littleEndian = source["littleEndian"];
+	        this.prefixInclusive = source["prefixInclusive"];
+	        this.maxFrameSize = source["maxFrameSize"];
+	    }
+	}
+	export class SocketConnectRequest {
+	    network: string;
+	    address: string;
+	    tls: boolean;
+	    tlsSkipVerify: boolean;
+	    tlsProfile?: string;
+	    connectTimeout: number;
+	    framing: SocketFraming;
+	    scopes?: VariableScopes;
+	
+	    static createFrom(source: any = {}) {
+	        return new SocketConnectRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.network = source["network"];
+	        this.address = source["address"];
+	        this.tls = source["tls"];
+	        this.tlsSkipVerify = source["tlsSkipVerify"];
+	        this.tlsProfile = source["tlsProfile"];
+	        this.connectTimeout = source["connectTimeout"];
+	        this.framing = this.convertValues(source["framing"], SocketFraming);
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SocketListenRequest {
+	    network: string;
+	    address: string;
+	    framing: SocketFraming;
+	    scopes?: VariableScopes;
+	
+	    static createFrom(source: any = {}) {
+	        return new SocketListenRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.network = source["network"];
+	        this.address = source["address"];
+	        this.framing = this.convertValues(source["framing"], SocketFraming);
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SocketListenResult {
+	    listenerId: string;
+	    connectionId?: string;
+	    address: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SocketListenResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.listenerId = source["listenerId"];
+	        this.connectionId = source["connectionId"];
+	        this.address = source["address"];
+	    }
+	}
+	export class SocketSendRequest {
+	    connectionId: string;
+	    payload: string;
+	    encoding: string;
+	    framed: boolean;
+	    peer?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SocketSendRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.payload = source["payload"];
+	        this.encoding = source["encoding"];
+	        this.framed = source["framed"];
+	        this.peer = source["peer"];
+	    }
+	}
 	export class TopicInfo {
 	    name: string;
 	    partitions: number;
@@ -548,6 +2150,9 @@ export namespace backend {
 	    enablePingPong: boolean;
 	    pingInterval: number;
 	    customHeaders: Record<string, string>;
+	    tlsProfile?: string;
+	    tlsSkipVerify: boolean;
+	    scopes?: VariableScopes;
 	
 	    static createFrom(source: any = {}) {
 	        return new WebSocketConnectRequest(source);
@@ -562,7 +2167,28 @@ export namespace backend {
 	        this.enablePingPong = source["enablePingPong"];
 	        this.pingInterval = source["pingInterval"];
 	        this.customHeaders = source["customHeaders"];
+	        this.tlsProfile = source["tlsProfile"];
+	        this.tlsSkipVerify = source["tlsSkipVerify"];
+	        this.scopes = this.convertValues(source["scopes"], VariableScopes);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class WebSocketSendRequest {
 	    connectionId: string;